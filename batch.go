@@ -27,6 +27,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/private"
 	"github.com/cockroachdb/pebble/internal/rangedel"
 	"github.com/cockroachdb/pebble/internal/rangekey"
+	"github.com/cockroachdb/pebble/internal/rangemerge"
 	"github.com/cockroachdb/pebble/internal/rawalloc"
 	"github.com/cockroachdb/pebble/internal/treeprinter"
 )
@@ -174,13 +175,16 @@ func (d DeferredBatchOp) Finish() error {
 //	InternalKeyKindRangeKeySet    varstring varstring
 //	InternalKeyKindRangeKeyUnset  varstring varstring
 //	InternalKeyKindRangeKeyDelete varstring varstring
+//	InternalKeyKindRangeMerge     varstring varstring
 //
 // The intuitive understanding here are that the arguments to Delete, Set,
 // Merge, DeleteRange and RangeKeyDelete are encoded into the batch. The
 // RangeKeySet and RangeKeyUnset operations are slightly more complicated,
 // encoding their end key, suffix and value [in the case of RangeKeySet] within
 // the Value varstring. For more information on the value encoding for
-// RangeKeySet and RangeKeyUnset, see the internal/rangekey package.
+// RangeKeySet and RangeKeyUnset, see the internal/rangekey package. Similarly,
+// RangeMerge encodes its end key and merge operand within the Value varstring;
+// see the internal/rangemerge package.
 //
 // The internal batch representation is the on disk format for a batch in the
 // WAL, and thus stable. New record kinds may be added, but the existing ones
@@ -301,6 +305,10 @@ type batchInternal struct {
 	// every time a RANGEKEYSET, RANGEKEYUNSET or RANGEKEYDEL key is added.
 	countRangeKeys uint64
 
+	// The count of range merges in the batch. Updated every time a RANGEMERGE
+	// key is added.
+	countRangeMerges uint64
+
 	// A deferredOp struct, stored in the Batch so that a pointer can be returned
 	// from the *Deferred() methods rather than a value.
 	deferredOp DeferredBatchOp
@@ -328,6 +336,11 @@ type batchInternal struct {
 	rangeKeys       []keyspan.Span
 	rangeKeysSeqNum base.SeqNum
 
+	// The secondary index entries of the primary keys written to the batch,
+	// keyed by primary key. Only populated if the DB has secondary indexes.
+	// See Batch.maintainSecondaryIndexes.
+	secondaryIndexEntries map[string][][]byte
+
 	// The flushableBatch wrapper if the batch is too large to fit in the
 	// memtable.
 	flushable *flushableBatch
@@ -421,6 +434,14 @@ type BatchCommitStats struct {
 	// duration for the WAL sync (if requested). The former should be tiny and
 	// one can assume that this is all due to the WAL sync.
 	CommitWaitDuration time.Duration
+	// AdmissionDelayDuration is the delay imposed by write admission control
+	// before the commit began (see Options.Experimental.WriteAdmission). It
+	// is not included in TotalDuration.
+	AdmissionDelayDuration time.Duration
+	// PriorityWaitDuration is the time a write of WritePriorityLow waited
+	// for a write stall to end before the commit began. It is not included in
+	// TotalDuration.
+	PriorityWaitDuration time.Duration
 }
 
 var _ Reader = (*Batch)(nil)
@@ -523,6 +544,7 @@ func (b *Batch) refreshMemTableSize() error {
 
 	b.countRangeDels = 0
 	b.countRangeKeys = 0
+	b.countRangeMerges = 0
 	b.minimumFormatMajorVersion = 0
 	for r := b.Reader(); ; {
 		kind, key, value, ok, err := r.Next()
@@ -537,6 +559,11 @@ func (b *Batch) refreshMemTableSize() error {
 			b.countRangeDels++
 		case InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeyDelete:
 			b.countRangeKeys++
+		case InternalKeyKindRangeMerge:
+			b.countRangeMerges++
+			if b.minimumFormatMajorVersion < FormatRangeMerges {
+				b.minimumFormatMajorVersion = FormatRangeMerges
+			}
 		case InternalKeyKindSet, InternalKeyKindDelete, InternalKeyKindMerge, InternalKeyKindSingleDelete, InternalKeyKindSetWithDelete:
 			// fallthrough
 		case InternalKeyKindDeleteSized:
@@ -614,6 +641,11 @@ func (b *Batch) Apply(batch *Batch, _ *WriteOptions) error {
 				b.countRangeDels++
 			case InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeyDelete:
 				b.countRangeKeys++
+			case InternalKeyKindRangeMerge:
+				b.countRangeMerges++
+				if b.minimumFormatMajorVersion < FormatRangeMerges {
+					b.minimumFormatMajorVersion = FormatRangeMerges
+				}
 			case InternalKeyKindIngestSST, InternalKeyKindExcise:
 				panic("pebble: invalid key kind for batch")
 			case InternalKeyKindLogData:
@@ -648,6 +680,8 @@ func (b *Batch) Apply(batch *Batch, _ *WriteOptions) error {
 						b.rangeKeyIndex = batchskl.NewSkiplist(&b.data, b.comparer.Compare, b.comparer.AbbreviatedKey)
 					}
 					err = b.rangeKeyIndex.Add(uint32(offset))
+				case InternalKeyKindRangeMerge:
+					// Range merges are not indexed. See Batch.RangeMerge.
 				default:
 					err = b.index.Add(uint32(offset))
 				}
@@ -658,6 +692,9 @@ func (b *Batch) Apply(batch *Batch, _ *WriteOptions) error {
 			b.memTableSize += memTableEntrySize(len(key), len(value))
 		}
 	}
+	if b.db != nil && b.db.secondaryIndexes != nil {
+		return b.mergeSecondaryIndexEntries(batch)
+	}
 	return nil
 }
 
@@ -786,6 +823,9 @@ func (b *Batch) AddInternalKey(key *base.InternalKey, value []byte, _ *WriteOpti
 		b.prepareDeferredKeyValueRecord(keyLen, len(value), kind)
 		hasValue = true
 		b.incrementRangeKeysCount()
+	case InternalKeyKindRangeMerge:
+		b.rangeMergeDeferred(keyLen, len(value))
+		hasValue = true
 	default:
 		b.prepareDeferredKeyValueRecord(keyLen, len(value), kind)
 		hasValue = true
@@ -810,6 +850,11 @@ func (b *Batch) AddInternalKey(key *base.InternalKey, value []byte, _ *WriteOpti
 //
 // It is safe to modify the contents of the arguments after Set returns.
 func (b *Batch) Set(key, value []byte, _ *WriteOptions) error {
+	if b.db != nil && b.db.secondaryIndexes != nil {
+		if err := b.maintainSecondaryIndexes(key, value, false /* deleted */); err != nil {
+			return err
+		}
+	}
 	deferredOp := b.SetDeferred(len(key), len(value))
 	copy(deferredOp.Key, key)
 	copy(deferredOp.Value, value)
@@ -866,6 +911,11 @@ func (b *Batch) MergeDeferred(keyLen, valueLen int) *DeferredBatchOp {
 //
 // It is safe to modify the contents of the arguments after Delete returns.
 func (b *Batch) Delete(key []byte, _ *WriteOptions) error {
+	if b.db != nil && b.db.secondaryIndexes != nil {
+		if err := b.maintainSecondaryIndexes(key, nil, true /* deleted */); err != nil {
+			return err
+		}
+	}
 	deferredOp := b.DeleteDeferred(len(key))
 	copy(deferredOp.Key, key)
 	// TODO(peter): Manually inline DeferredBatchOp.Finish(). Mid-stack inlining
@@ -902,6 +952,11 @@ func (b *Batch) DeleteDeferred(keyLen int) *DeferredBatchOp {
 // It is safe to modify the contents of the arguments after DeleteSized
 // returns.
 func (b *Batch) DeleteSized(key []byte, deletedValueSize uint32, _ *WriteOptions) error {
+	if b.db != nil && b.db.secondaryIndexes != nil {
+		if err := b.maintainSecondaryIndexes(key, nil, true /* deleted */); err != nil {
+			return err
+		}
+	}
 	deferredOp := b.DeleteSizedDeferred(len(key), deletedValueSize)
 	copy(b.deferredOp.Key, key)
 	// TODO(peter): Manually inline DeferredBatchOp.Finish(). Check if in a
@@ -959,6 +1014,11 @@ func (b *Batch) DeleteSizedDeferred(keyLen int, deletedValueSize uint32) *Deferr
 //
 // It is safe to modify the contents of the arguments after SingleDelete returns.
 func (b *Batch) SingleDelete(key []byte, _ *WriteOptions) error {
+	if b.db != nil && b.db.secondaryIndexes != nil {
+		if err := b.maintainSecondaryIndexes(key, nil, true /* deleted */); err != nil {
+			return err
+		}
+	}
 	deferredOp := b.SingleDeleteDeferred(len(key))
 	copy(deferredOp.Key, key)
 	// TODO(peter): Manually inline DeferredBatchOp.Finish(). Mid-stack inlining
@@ -1022,6 +1082,42 @@ func (b *Batch) DeleteRangeDeferred(startLen, endLen int) *DeferredBatchOp {
 	return &b.deferredOp
 }
 
+// RangeMerge merges operand into the value of every point key in the range
+// [start,end) (inclusive on start, exclusive on end) that exists at the time
+// the batch is committed. The merge is performed using the configured Merger,
+// exactly as if Merge(key, operand) had been called for every such key. Keys
+// that don't exist when the range merge is committed (including keys that are
+// written later in the same batch) are not affected, and RangeMerge never
+// creates keys.
+//
+// Range merges are stored as a single span and applied lazily, so the cost of
+// a RangeMerge does not depend on the number of keys within the range. They
+// require the database's format major version to be at least
+// FormatRangeMerges.
+//
+// Range merges are not visible to reads through an indexed batch until the
+// batch is committed.
+//
+// It is safe to modify the contents of the arguments after RangeMerge
+// returns.
+func (b *Batch) RangeMerge(start, end, operand []byte, _ *WriteOptions) error {
+	deferredOp := b.rangeMergeDeferred(len(start), rangemerge.EncodedValueLen(end, operand))
+	copy(deferredOp.Key, start)
+	rangemerge.EncodeValue(deferredOp.Value[:0], end, operand)
+	return nil
+}
+
+func (b *Batch) rangeMergeDeferred(startLen, internalValueLen int) *DeferredBatchOp {
+	if b.minimumFormatMajorVersion < FormatRangeMerges {
+		b.minimumFormatMajorVersion = FormatRangeMerges
+	}
+	b.prepareDeferredKeyValueRecord(startLen, internalValueLen, InternalKeyKindRangeMerge)
+	b.countRangeMerges++
+	// Range merges are not indexed.
+	b.deferredOp.index = nil
+	return &b.deferredOp
+}
+
 // RangeKeySet sets a range key mapping the key range [start, end) at the MVCC
 // timestamp suffix to value. The suffix is optional. If any portion of the key
 // range [start, end) is already set by a range key with the same suffix value,
@@ -1486,6 +1582,25 @@ func fragmentRangeKeys(frag *keyspan.Fragmenter, it internalIterator, count int)
 	return nil
 }
 
+func fragmentRangeMerges(frag *keyspan.Fragmenter, it internalIterator, count int) error {
+	// As with fragmentRangeKeys, the fragmented spans are slices within
+	// Batch.data.
+	keyBuf := make([]keyspan.Key, 0, count)
+	for kv := it.First(); kv != nil; kv = it.Next() {
+		s, err := rangemerge.Decode(kv.K, kv.InPlaceValue(), keyBuf)
+		if err != nil {
+			return err
+		}
+		keyBuf = s.Keys[len(s.Keys):]
+
+		// Set a fixed capacity to avoid accidental overwriting.
+		s.Keys = s.Keys[:len(s.Keys):len(s.Keys)]
+		frag.Add(s)
+	}
+	frag.Finish()
+	return nil
+}
+
 // Commit applies the batch to its parent writer.
 func (b *Batch) Commit(o *WriteOptions) error {
 	return b.db.Apply(b, o)
@@ -1827,7 +1942,7 @@ func (i *batchIter) value() []byte {
 	switch InternalKeyKind(data[offset]) {
 	case InternalKeyKindSet, InternalKeyKindMerge, InternalKeyKindRangeDelete,
 		InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeyDelete,
-		InternalKeyKindDeleteSized:
+		InternalKeyKindDeleteSized, InternalKeyKindRangeMerge:
 		_, value, ok := batchrepr.DecodeStr(data[keyEnd:])
 		if !ok {
 			return nil
@@ -1898,6 +2013,9 @@ type flushableBatch struct {
 
 	// Fragmented range keys.
 	rangeKeys []keyspan.Span
+
+	// Fragmented range merges.
+	rangeMerges []keyspan.Span
 }
 
 var _ flushable = (*flushableBatch)(nil)
@@ -1922,6 +2040,7 @@ func newFlushableBatch(batch *Batch, comparer *Comparer) (*flushableBatch, error
 	}
 	var rangeDelOffsets []flushableBatchEntry
 	var rangeKeyOffsets []flushableBatchEntry
+	var rangeMergeOffsets []flushableBatchEntry
 	if len(b.data) > batchrepr.HeaderLen {
 		// Non-empty batch.
 		var index uint32
@@ -1953,6 +2072,8 @@ func newFlushableBatch(batch *Batch, comparer *Comparer) (*flushableBatch, error
 				rangeDelOffsets = append(rangeDelOffsets, entry)
 			case InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeyDelete:
 				rangeKeyOffsets = append(rangeKeyOffsets, entry)
+			case InternalKeyKindRangeMerge:
+				rangeMergeOffsets = append(rangeMergeOffsets, entry)
 			case InternalKeyKindLogData:
 				// Skip it; we never want to iterate over LogDatas.
 				continue
@@ -1981,14 +2102,16 @@ func newFlushableBatch(batch *Batch, comparer *Comparer) (*flushableBatch, error
 		}
 	}
 
-	// Sort all of offsets, rangeDelOffsets and rangeKeyOffsets, using *batch's
-	// sort.Interface implementation.
+	// Sort all of offsets, rangeDelOffsets, rangeKeyOffsets and
+	// rangeMergeOffsets, using *batch's sort.Interface implementation.
 	pointOffsets := b.offsets
 	sort.Sort(b)
 	b.offsets = rangeDelOffsets
 	sort.Sort(b)
 	b.offsets = rangeKeyOffsets
 	sort.Sort(b)
+	b.offsets = rangeMergeOffsets
+	sort.Sort(b)
 	b.offsets = pointOffsets
 
 	if len(rangeDelOffsets) > 0 {
@@ -2025,6 +2148,25 @@ func newFlushableBatch(batch *Batch, comparer *Comparer) (*flushableBatch, error
 		}
 		fragmentRangeKeys(frag, it, len(rangeKeyOffsets))
 	}
+	if len(rangeMergeOffsets) > 0 {
+		frag := &keyspan.Fragmenter{
+			Cmp:    b.cmp,
+			Format: b.comparer.FormatKey,
+			Emit: func(s keyspan.Span) {
+				b.rangeMerges = append(b.rangeMerges, s)
+			},
+		}
+		it := &flushableBatchIter{
+			batch:   b,
+			data:    b.data,
+			offsets: rangeMergeOffsets,
+			cmp:     b.cmp,
+			index:   -1,
+		}
+		if err := fragmentRangeMerges(frag, it, len(rangeMergeOffsets)); err != nil {
+			return nil, err
+		}
+	}
 	return b, nil
 }
 
@@ -2049,6 +2191,14 @@ func (b *flushableBatch) setSeqNum(seqNum base.SeqNum) {
 			)
 		}
 	}
+	for i := range b.rangeMerges {
+		for j := range b.rangeMerges[i].Keys {
+			b.rangeMerges[i].Keys[j].Trailer = base.MakeTrailer(
+				b.rangeMerges[i].Keys[j].SeqNum()+seqNum,
+				b.rangeMerges[i].Keys[j].Kind(),
+			)
+		}
+	}
 }
 
 func (b *flushableBatch) Len() int {
@@ -2116,6 +2266,14 @@ func (b *flushableBatch) newRangeKeyIter(o *IterOptions) keyspan.FragmentIterato
 	return keyspan.NewIter(b.cmp, b.rangeKeys)
 }
 
+// newRangeMergeIter is part of the flushable interface.
+func (b *flushableBatch) newRangeMergeIter(o *IterOptions) keyspan.FragmentIterator {
+	if len(b.rangeMerges) == 0 {
+		return nil
+	}
+	return keyspan.NewIter(b.cmp, b.rangeMerges)
+}
+
 // containsRangeKeys is part of the flushable interface.
 func (b *flushableBatch) containsRangeKeys() bool { return len(b.rangeKeys) > 0 }
 
@@ -2332,7 +2490,7 @@ func (i *flushableBatchIter) extractValue() base.LazyValue {
 	switch kind {
 	case InternalKeyKindSet, InternalKeyKindMerge, InternalKeyKindRangeDelete,
 		InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeyDelete,
-		InternalKeyKindDeleteSized:
+		InternalKeyKindDeleteSized, InternalKeyKindRangeMerge:
 		keyEnd := i.offsets[i.index].keyEnd
 		_, value, ok = batchrepr.DecodeStr(i.data[keyEnd:])
 		if !ok {
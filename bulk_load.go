@@ -0,0 +1,375 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// defaultBulkLoadBufferSize is the default BulkLoaderOptions.BufferSize.
+const defaultBulkLoadBufferSize = 64 << 20
+
+// BulkLoaderOptions configures a BulkLoader.
+type BulkLoaderOptions struct {
+	// TempDir is the directory in which the loader writes its sorted runs and
+	// the sstables it ingests. It's created if it doesn't exist. The default
+	// is a "bulkload" directory within the DB's directory.
+	TempDir string
+	// BufferSize is the number of bytes of keys and values buffered in memory
+	// before they're sorted and spilled to an sstable as a sorted run. The
+	// default is 64 MB.
+	BufferSize int
+	// TargetFileSize is the target size of the sstables that are ingested.
+	// The default is the TargetFileSize of the bottommost level.
+	TargetFileSize int64
+	// ExciseSpan, if valid, is excised from the DB as the sstables are
+	// ingested, replacing any existing data within the span with the loaded
+	// data. All loaded keys must be within the span. See DB.IngestAndExcise.
+	ExciseSpan KeyRange
+}
+
+// bulkLoadEntry is a key and value buffered within a BulkLoader's data.
+type bulkLoadEntry struct {
+	offset   int
+	keyLen   int
+	valueLen int
+}
+
+// BulkLoader loads keys written in an arbitrary order into a DB, without
+// writing them through the memtable. It sorts the keys as an external merge
+// sort: keys are buffered in memory and spilled to temporary sstables as
+// sorted runs, which Finish merges into the sstables that it ingests into the
+// DB. If a key is written more than once, the last write wins.
+//
+// The loaded keys are not visible until Finish returns. A BulkLoader is not
+// safe for concurrent use, and must be closed after use whether or not it was
+// finished.
+type BulkLoader struct {
+	d    *DB
+	opts BulkLoaderOptions
+	id   JobID
+
+	data    []byte
+	entries []bulkLoadEntry
+	// runs holds the paths of the sorted runs, from oldest to newest.
+	runs []string
+	// outputs holds the paths of the sstables to ingest.
+	outputs  []string
+	finished bool
+	closed   bool
+}
+
+// NewBulkLoader returns a BulkLoader that loads keys into the DB.
+func (d *DB) NewBulkLoader(opts BulkLoaderOptions) (*BulkLoader, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	if opts.TempDir == "" {
+		opts.TempDir = d.opts.FS.PathJoin(d.dirname, "bulkload")
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultBulkLoadBufferSize
+	}
+	if opts.TargetFileSize <= 0 {
+		// The level options may be changed concurrently by SetOptions.
+		d.mu.Lock()
+		opts.TargetFileSize = d.opts.Level(numLevels - 1).TargetFileSize
+		d.mu.Unlock()
+	}
+	if err := d.opts.FS.MkdirAll(opts.TempDir, 0755); err != nil {
+		return nil, err
+	}
+	return &BulkLoader{d: d, opts: opts, id: d.newJobID()}, nil
+}
+
+// Set adds the key with the provided value to the loader. It is safe to
+// modify the contents of the arguments after Set returns.
+func (l *BulkLoader) Set(key, value []byte) error {
+	if l.finished || l.closed {
+		return errors.New("pebble: bulk loader is finished")
+	}
+	if l.opts.ExciseSpan.Valid() && !l.opts.ExciseSpan.Contains(l.d.cmp, base.MakeInternalKey(key, 0, InternalKeyKindSet)) {
+		return errors.Errorf("pebble: bulk loaded key %s outside of the excise span",
+			l.d.opts.Comparer.FormatKey(key))
+	}
+	l.entries = append(l.entries, bulkLoadEntry{offset: len(l.data), keyLen: len(key), valueLen: len(value)})
+	l.data = append(l.data, key...)
+	l.data = append(l.data, value...)
+	if len(l.data) >= l.opts.BufferSize {
+		return l.spill()
+	}
+	return nil
+}
+
+func (l *BulkLoader) key(e bulkLoadEntry) []byte {
+	return l.data[e.offset : e.offset+e.keyLen]
+}
+
+func (l *BulkLoader) value(e bulkLoadEntry) []byte {
+	return l.data[e.offset+e.keyLen : e.offset+e.keyLen+e.valueLen]
+}
+
+// sortBuffer sorts the buffered entries by key, removing all but the last
+// write of each key.
+func (l *BulkLoader) sortBuffer() {
+	// A stable sort preserves the order in which writes of the same key were
+	// added.
+	slices.SortStableFunc(l.entries, func(a, b bulkLoadEntry) int {
+		return l.d.cmp(l.key(a), l.key(b))
+	})
+	j := 0
+	for i := range l.entries {
+		if i+1 < len(l.entries) && l.d.equal(l.key(l.entries[i]), l.key(l.entries[i+1])) {
+			continue
+		}
+		l.entries[j] = l.entries[i]
+		j++
+	}
+	l.entries = l.entries[:j]
+}
+
+// resetBuffer discards the buffered entries.
+func (l *BulkLoader) resetBuffer() {
+	l.data = l.data[:0]
+	l.entries = l.entries[:0]
+}
+
+// spill writes the buffered entries to a new sorted run.
+func (l *BulkLoader) spill() error {
+	l.sortBuffer()
+	path := l.d.opts.FS.PathJoin(l.opts.TempDir, fmt.Sprintf("%d-run-%06d.sst", l.id, len(l.runs)))
+	f, err := l.d.opts.FS.Create(path, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+	l.runs = append(l.runs, path)
+	// Runs are only read once, by Finish, so there's no benefit to
+	// compressing or filtering them.
+	writerOpts := l.d.makeWriterOptions(0, l.d.FormatMajorVersion().MaxTableFormat())
+	writerOpts.Compression = NoCompression
+	writerOpts.FilterPolicy = nil
+	w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), writerOpts)
+	for _, e := range l.entries {
+		if err := w.Set(l.key(e), l.value(e)); err != nil {
+			_ = w.Close()
+			return err
+		}
+	}
+	l.resetBuffer()
+	return w.Close()
+}
+
+// Finish sorts all of the keys added to the loader, writes them to sstables
+// and ingests them into the DB.
+func (l *BulkLoader) Finish(ctx context.Context) (IngestOperationStats, error) {
+	if l.finished || l.closed {
+		return IngestOperationStats{}, errors.New("pebble: bulk loader is finished")
+	}
+	l.finished = true
+	var err error
+	if len(l.runs) == 0 {
+		// Everything fits in memory, so the ingested sstables can be written
+		// from the buffer directly.
+		l.sortBuffer()
+		err = l.writeOutputs(func(yield func(key, value []byte) error) error {
+			for _, e := range l.entries {
+				if err := yield(l.key(e), l.value(e)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	} else {
+		if len(l.entries) > 0 {
+			err = l.spill()
+		}
+		if err == nil {
+			err = l.writeOutputs(l.mergeRuns)
+		}
+	}
+	l.resetBuffer()
+	if err != nil {
+		return IngestOperationStats{}, err
+	}
+	if len(l.outputs) == 0 && !l.opts.ExciseSpan.Valid() {
+		return IngestOperationStats{}, nil
+	}
+	if l.opts.ExciseSpan.Valid() {
+		return l.d.IngestAndExcise(ctx, l.outputs, nil, nil, l.opts.ExciseSpan)
+	}
+	return l.d.IngestWithStats(ctx, l.outputs)
+}
+
+// writeOutputs writes the keys and values produced by the provided function,
+// in order, to the sstables to ingest.
+func (l *BulkLoader) writeOutputs(produce func(yield func(key, value []byte) error) error) error {
+	// All of the ingested sstables are written with the same options, even
+	// if SetOptions changes them while they're being written.
+	writerOpts := l.d.makeWriterOptions(numLevels-1, l.d.FormatMajorVersion().MaxTableFormat())
+	var w *sstable.Writer
+	closeWriter := func() error {
+		if w == nil {
+			return nil
+		}
+		err := w.Close()
+		w = nil
+		return err
+	}
+	err := produce(func(key, value []byte) error {
+		if w == nil {
+			path := l.d.opts.FS.PathJoin(l.opts.TempDir, fmt.Sprintf("%d-%06d.sst", l.id, len(l.outputs)))
+			f, err := l.d.opts.FS.Create(path, vfs.WriteCategoryUnspecified)
+			if err != nil {
+				return err
+			}
+			l.outputs = append(l.outputs, path)
+			w = sstable.NewWriter(objstorageprovider.NewFileWritable(f), writerOpts)
+		}
+		if err := w.Set(key, value); err != nil {
+			return err
+		}
+		if w.Raw().EstimatedSize() >= uint64(l.opts.TargetFileSize) {
+			return closeWriter()
+		}
+		return nil
+	})
+	return firstError(err, closeWriter())
+}
+
+// bulkLoadRun is a sorted run being merged by BulkLoader.mergeRuns.
+type bulkLoadRun struct {
+	index int
+	iter  sstable.Iterator
+	kv    *base.InternalKV
+}
+
+// bulkLoadRunHeap is a min-heap of runs ordered by their current keys. Runs
+// with equal keys are ordered from newest to oldest.
+type bulkLoadRunHeap struct {
+	cmp  Compare
+	runs []*bulkLoadRun
+}
+
+func (h *bulkLoadRunHeap) Len() int { return len(h.runs) }
+
+func (h *bulkLoadRunHeap) Less(i, j int) bool {
+	if c := h.cmp(h.runs[i].kv.K.UserKey, h.runs[j].kv.K.UserKey); c != 0 {
+		return c < 0
+	}
+	return h.runs[i].index > h.runs[j].index
+}
+
+func (h *bulkLoadRunHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+
+func (h *bulkLoadRunHeap) Push(x any) { h.runs = append(h.runs, x.(*bulkLoadRun)) }
+
+func (h *bulkLoadRunHeap) Pop() any {
+	r := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return r
+}
+
+// mergeRuns merges the sorted runs, yielding the last write of each key in
+// order.
+func (l *BulkLoader) mergeRuns(yield func(key, value []byte) error) (err error) {
+	h := &bulkLoadRunHeap{cmp: l.d.cmp}
+	var readers []*sstable.Reader
+	defer func() {
+		for _, r := range h.runs {
+			err = firstError(err, r.iter.Close())
+		}
+		for _, r := range readers {
+			err = firstError(err, r.Close())
+		}
+	}()
+	for i, path := range l.runs {
+		f, err := l.d.opts.FS.Open(path)
+		if err != nil {
+			return err
+		}
+		readable, err := sstable.NewSimpleReadable(f)
+		if err != nil {
+			return errors.CombineErrors(err, f.Close())
+		}
+		r, err := sstable.NewReader(context.Background(), readable, l.d.opts.MakeReaderOptions())
+		if err != nil {
+			return errors.CombineErrors(err, readable.Close())
+		}
+		readers = append(readers, r)
+		iter, err := r.NewIter(sstable.NoTransforms, nil, nil)
+		if err != nil {
+			return err
+		}
+		run := &bulkLoadRun{index: i, iter: iter, kv: iter.First()}
+		if run.kv == nil {
+			if err := firstError(iter.Error(), iter.Close()); err != nil {
+				return err
+			}
+			continue
+		}
+		h.runs = append(h.runs, run)
+	}
+	heap.Init(h)
+
+	var prevKey []byte
+	var valueBuf []byte
+	first := true
+	for h.Len() > 0 {
+		run := h.runs[0]
+		if first || !l.d.equal(prevKey, run.kv.K.UserKey) {
+			first = false
+			// The run is the newest with the key, so its value wins.
+			value, _, err := run.kv.Value(valueBuf[:0])
+			if err != nil {
+				return err
+			}
+			valueBuf = value
+			prevKey = append(prevKey[:0], run.kv.K.UserKey...)
+			if err := yield(prevKey, value); err != nil {
+				return err
+			}
+		}
+		if run.kv = run.iter.Next(); run.kv != nil {
+			heap.Fix(h, 0)
+			continue
+		}
+		heap.Pop(h)
+		if err := firstError(run.iter.Error(), run.iter.Close()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close releases the loader's resources and removes its temporary files. If
+// the loader wasn't finished, the keys added to it are discarded.
+func (l *BulkLoader) Close() error {
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	l.resetBuffer()
+	var err error
+	for _, path := range append(l.runs, l.outputs...) {
+		if rmErr := l.d.opts.FS.Remove(path); rmErr != nil && !oserror.IsNotExist(rmErr) {
+			err = firstError(err, rmErr)
+		}
+	}
+	l.runs, l.outputs = nil, nil
+	return err
+}
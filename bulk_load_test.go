@@ -0,0 +1,161 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkLoader(t *testing.T) {
+	for _, bufferSize := range []int{0, 512} {
+		t.Run(fmt.Sprintf("buffer=%d", bufferSize), func(t *testing.T) {
+			mem := vfs.NewMem()
+			d, err := Open("", &Options{FS: mem, FormatMajorVersion: FormatNewest})
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+			require.NoError(t, d.Set([]byte("k0000"), []byte("old"), nil))
+			require.NoError(t, d.Set([]byte("z"), []byte("outside"), nil))
+
+			l, err := d.NewBulkLoader(BulkLoaderOptions{
+				BufferSize:     bufferSize,
+				TargetFileSize: 1 << 10,
+				ExciseSpan:     KeyRange{Start: []byte("k"), End: []byte("l")},
+			})
+			require.NoError(t, err)
+
+			// Write the keys in a random order, writing some of them twice.
+			const n = 500
+			rng := rand.New(rand.NewSource(1))
+			want := make(map[string]string)
+			for _, i := range rng.Perm(n) {
+				key := fmt.Sprintf("k%04d", i)
+				require.NoError(t, l.Set([]byte(key), []byte(fmt.Sprint("a", i))))
+				want[key] = fmt.Sprint("a", i)
+				if i%7 == 0 {
+					require.NoError(t, l.Set([]byte(key), []byte(fmt.Sprint("b", i))))
+					want[key] = fmt.Sprint("b", i)
+				}
+			}
+			require.Error(t, l.Set([]byte("z"), nil))
+			if bufferSize > 0 {
+				require.Greater(t, len(l.runs), 1)
+			}
+
+			_, err = l.Finish(context.Background())
+			require.NoError(t, err)
+			require.Greater(t, len(l.outputs), 1)
+			require.NoError(t, l.Close())
+			ls, err := mem.List("bulkload")
+			require.NoError(t, err)
+			require.Empty(t, ls)
+
+			// The loaded keys replace the excised span, and the DB's memtable
+			// remains empty.
+			d.mu.Lock()
+			require.True(t, d.mu.mem.mutable.empty())
+			d.mu.Unlock()
+			iter, _ := d.NewIter(nil)
+			got := make(map[string]string)
+			for valid := iter.First(); valid; valid = iter.Next() {
+				got[string(iter.Key())] = string(iter.Value())
+			}
+			require.NoError(t, iter.Close())
+			want["z"] = "outside"
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestBulkLoaderClose(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("", &Options{FS: mem})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Closing an unfinished loader discards its keys and sorted runs.
+	l, err := d.NewBulkLoader(BulkLoaderOptions{BufferSize: 16})
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, l.Set([]byte(strings.Repeat("x", i+1)), []byte("v")))
+	}
+	ls, err := mem.List("bulkload")
+	require.NoError(t, err)
+	require.NotEmpty(t, ls)
+	require.NoError(t, l.Close())
+	ls, err = mem.List("bulkload")
+	require.NoError(t, err)
+	require.Empty(t, ls)
+	_, err = l.Finish(context.Background())
+	require.Error(t, err)
+
+	iter, _ := d.NewIter(nil)
+	require.False(t, iter.First())
+	require.NoError(t, iter.Close())
+}
+
+func TestBulkLoaderSetOptions(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Change the level options while the loader writes its sorted runs and
+	// the sstables it ingests. Run with -race to detect unsynchronized reads
+	// of the options.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			o := d.DynamicOptions()
+			for j := range o.Levels {
+				o.Levels[j].TargetFileSize = int64(1+i%2) << 20
+				o.Levels[j].Compression = func() Compression {
+					if i%2 == 0 {
+						return NoCompression
+					}
+					return SnappyCompression
+				}
+			}
+			if err := d.SetOptions(o); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	l, err := d.NewBulkLoader(BulkLoaderOptions{BufferSize: 512})
+	require.NoError(t, err)
+	const n = 500
+	for _, i := range rand.New(rand.NewSource(1)).Perm(n) {
+		require.NoError(t, l.Set([]byte(fmt.Sprintf("k%04d", i)), []byte("v")))
+	}
+	_, err = l.Finish(context.Background())
+	close(stop)
+	wg.Wait()
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	iter, _ := d.NewIter(nil)
+	count := 0
+	for valid := iter.First(); valid; valid = iter.Next() {
+		count++
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, n, count)
+}
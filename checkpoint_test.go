@@ -37,7 +37,7 @@ func testCheckpointImpl(t *testing.T, ddFile string, createOnShared bool) {
 	remoteMem := remote.NewInMem()
 	opts := &Options{
 		FS:                          vfs.WithLogging(mem, memLog.Infof),
-		FormatMajorVersion:          internalFormatNewest,
+		FormatMajorVersion:          FormatNewest,
 		L0CompactionThreshold:       10,
 		DisableAutomaticCompactions: true,
 		Logger:                      testLogger{t},
@@ -0,0 +1,196 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/perf/benchfmt"
+	"golang.org/x/perf/benchmath"
+)
+
+var compareConfig struct {
+	alpha      float64
+	confidence float64
+	threshold  float64
+	fail       bool
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <old> <new>",
+	Short: "compare two sets of benchmark results",
+	Long: `
+Compare two sets of benchmark results, as written by the --results flag of the
+bench commands (or any other output in the Go benchmark format). Each
+benchmark's results are summarized by their median and a confidence interval,
+and the old and new results are compared using a Mann-Whitney U-test. A
+difference that is not statistically significant is shown as "~"; at least 4
+samples of each benchmark (eg, from runs appending to the same results file)
+are required to detect significant differences at the default alpha of 0.05.
+
+Statistically significant changes that are worse by more than --threshold
+percent are reported as regressions. Throughput units (eg, ops/sec) regress
+when they decrease; all other units regress when they increase.
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCompare,
+}
+
+func init() {
+	compareCmd.Flags().Float64Var(
+		&compareConfig.alpha, "alpha", benchmath.DefaultThresholds.CompareAlpha,
+		"consider a change significant if p < alpha")
+	compareCmd.Flags().Float64Var(
+		&compareConfig.confidence, "confidence", 0.95, "confidence level for ranges")
+	compareCmd.Flags().Float64Var(
+		&compareConfig.threshold, "threshold", 5, "minimum change, in percent, reported as a regression")
+	compareCmd.Flags().BoolVar(
+		&compareConfig.fail, "fail", false, "exit with an error if any regressions are found")
+}
+
+// benchKey identifies the values of a single unit of a benchmark.
+type benchKey struct {
+	name string
+	unit string
+}
+
+// benchSamples holds the values of benchmark results, grouped by benchmark and
+// unit, in the order the keys were first encountered.
+type benchSamples struct {
+	keys   []benchKey
+	values map[benchKey][]float64
+}
+
+func readBenchSamples(r io.Reader, fileName string) (*benchSamples, error) {
+	s := &benchSamples{values: make(map[benchKey][]float64)}
+	br := benchfmt.NewReader(r, fileName)
+	for br.Scan() {
+		res, ok := br.Result().(*benchfmt.Result)
+		if !ok {
+			// Skip syntax errors and unit metadata; the result files may
+			// contain other output.
+			continue
+		}
+		for _, v := range res.Values {
+			k := benchKey{name: string(res.Name.Full()), unit: v.Unit}
+			if _, ok := s.values[k]; !ok {
+				s.keys = append(s.keys, k)
+			}
+			s.values[k] = append(s.values[k], v.Value)
+		}
+	}
+	return s, br.Err()
+}
+
+func readBenchSamplesFile(path string) (*benchSamples, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readBenchSamples(f, path)
+}
+
+// higherIsBetter returns true if larger values of the unit are improvements.
+func higherIsBetter(unit string) bool {
+	return strings.HasSuffix(unit, "/sec") || strings.HasSuffix(unit, "/s")
+}
+
+// benchComparison is the comparison of the old and new values of a benchmark
+// unit.
+type benchComparison struct {
+	benchKey
+	old, new   benchmath.Summary
+	comparison benchmath.Comparison
+	// delta is the relative change of the new center from the old center, or
+	// zero if the change isn't significant.
+	delta      float64
+	regression bool
+}
+
+func compareBenchSamples(
+	oldSamples, newSamples *benchSamples, alpha, confidence, threshold float64,
+) []benchComparison {
+	thresholds := benchmath.DefaultThresholds
+	thresholds.CompareAlpha = alpha
+	var res []benchComparison
+	for _, k := range oldSamples.keys {
+		newValues, ok := newSamples.values[k]
+		if !ok {
+			continue
+		}
+		oldSample := benchmath.NewSample(oldSamples.values[k], &thresholds)
+		newSample := benchmath.NewSample(newValues, &thresholds)
+		c := benchComparison{
+			benchKey:   k,
+			old:        benchmath.AssumeNothing.Summary(oldSample, confidence),
+			new:        benchmath.AssumeNothing.Summary(newSample, confidence),
+			comparison: benchmath.AssumeNothing.Compare(oldSample, newSample),
+		}
+		if c.comparison.P <= c.comparison.Alpha && c.old.Center != 0 {
+			c.delta = c.new.Center/c.old.Center - 1
+			worse := c.delta
+			if higherIsBetter(k.unit) {
+				worse = -worse
+			}
+			c.regression = worse*100 > threshold
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	oldSamples, err := readBenchSamplesFile(args[0])
+	if err != nil {
+		return err
+	}
+	newSamples, err := readBenchSamplesFile(args[1])
+	if err != nil {
+		return err
+	}
+	comparisons := compareBenchSamples(oldSamples, newSamples,
+		compareConfig.alpha, compareConfig.confidence, compareConfig.threshold)
+	if len(comparisons) == 0 {
+		return errors.Errorf("no benchmarks in common between %s and %s", args[0], args[1])
+	}
+
+	stdout := cmd.OutOrStdout()
+	tw := tabwriter.NewWriter(stdout, 2, 1, 2, ' ', 0)
+	fmt.Fprintf(tw, "name\tunit\told\t\tnew\t\tdelta\t\n")
+	var regressions []benchComparison
+	for _, c := range comparisons {
+		fmt.Fprintf(tw, "%s\t%s\t%.4g\t±%s\t%.4g\t±%s\t%s\t(%s)\n",
+			c.name, c.unit,
+			c.old.Center, c.old.PctRangeString(),
+			c.new.Center, c.new.PctRangeString(),
+			c.comparison.FormatDelta(c.old.Center, c.new.Center), c.comparison)
+		if c.regression {
+			regressions = append(regressions, c)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(regressions) == 0 {
+		fmt.Fprintf(stdout, "\nno regressions larger than %.1f%%\n", compareConfig.threshold)
+		return nil
+	}
+	fmt.Fprintf(stdout, "\n%d regressions larger than %.1f%%:\n", len(regressions), compareConfig.threshold)
+	for _, c := range regressions {
+		fmt.Fprintf(stdout, "  %s %s %+.2f%%\n", c.name, c.unit, 100*c.delta)
+	}
+	if compareConfig.fail {
+		return errors.Errorf("found %d regressions", len(regressions))
+	}
+	return nil
+}
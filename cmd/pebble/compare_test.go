@@ -0,0 +1,72 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareBenchSamples(t *testing.T) {
+	// writeResults appends the results of n runs of a benchmark with the
+	// provided throughput and latency to a results file, and reads them back.
+	dir := t.TempDir()
+	defer func() { resultsPath = "" }()
+	writeResults := func(n int, opsPerSec float64, latency time.Duration) *benchSamples {
+		resultsPath = filepath.Join(dir, fmt.Sprintf("results-%d-%d", n, int(opsPerSec)))
+		for i := 0; i < n; i++ {
+			h := hdrhistogram.New(minLatency.Nanoseconds(), maxLatency.Nanoseconds(), 1)
+			count := int(opsPerSec) + i
+			for j := 0; j < count; j++ {
+				require.NoError(t, h.RecordValue(latency.Nanoseconds()+int64(i*1000)))
+			}
+			r := newBenchResults("test")
+			r.addHistogram("read", h, time.Second)
+			require.NoError(t, r.write())
+		}
+		s, err := readBenchSamplesFile(resultsPath)
+		require.NoError(t, err)
+		return s
+	}
+
+	oldSamples := writeResults(6, 1000, time.Millisecond)
+	require.Len(t, oldSamples.keys, 5)
+	require.Len(t, oldSamples.values[benchKey{name: "test/read/throughput", unit: "ops/sec"}], 6)
+
+	// Identical results aren't significant.
+	for _, c := range compareBenchSamples(oldSamples, oldSamples, 0.05, 0.95, 5) {
+		require.Zero(t, c.delta)
+		require.False(t, c.regression)
+	}
+
+	// Halving the throughput and doubling the latency regresses every unit.
+	newSamples := writeResults(6, 500, 2*time.Millisecond)
+	comparisons := compareBenchSamples(oldSamples, newSamples, 0.05, 0.95, 5)
+	require.Len(t, comparisons, 5)
+	for _, c := range comparisons {
+		require.True(t, c.regression, "%s %s", c.name, c.unit)
+		if c.unit == "ops/sec" {
+			require.Less(t, c.delta, 0.0)
+		} else {
+			require.Greater(t, c.delta, 0.0)
+		}
+	}
+
+	// The reverse is an improvement.
+	for _, c := range compareBenchSamples(newSamples, oldSamples, 0.05, 0.95, 5) {
+		require.False(t, c.regression, "%s %s", c.name, c.unit)
+	}
+
+	// Too few samples can't produce a significant result.
+	for _, c := range compareBenchSamples(writeResults(2, 1000, time.Millisecond),
+		writeResults(2, 500, 2*time.Millisecond), 0.05, 0.95, 5) {
+		require.False(t, c.regression)
+	}
+}
@@ -20,6 +20,7 @@ import (
 type DB interface {
 	NewIter(*pebble.IterOptions) iterator
 	NewBatch() batch
+	NewSnapshot() *pebble.Snapshot
 	Scan(iter iterator, key []byte, count int64, reverse bool) error
 	Metrics() *pebble.Metrics
 	Flush() error
@@ -43,6 +44,10 @@ type batch interface {
 	Commit(opts *pebble.WriteOptions) error
 	Set(key, value []byte, opts *pebble.WriteOptions) error
 	Delete(key []byte, opts *pebble.WriteOptions) error
+	DeleteRange(start, end []byte, opts *pebble.WriteOptions) error
+	RangeKeySet(start, end, suffix, value []byte, opts *pebble.WriteOptions) error
+	RangeKeyUnset(start, end, suffix []byte, opts *pebble.WriteOptions) error
+	RangeKeyDelete(start, end []byte, opts *pebble.WriteOptions) error
 	LogData(data []byte, opts *pebble.WriteOptions) error
 }
 
@@ -140,6 +145,10 @@ func (p pebbleDB) NewBatch() batch {
 	return p.d.NewBatch()
 }
 
+func (p pebbleDB) NewSnapshot() *pebble.Snapshot {
+	return p.d.NewSnapshot()
+}
+
 func (p pebbleDB) Scan(iter iterator, key []byte, count int64, reverse bool) error {
 	var data bytealloc.A
 	if reverse {
@@ -50,18 +50,19 @@ func main() {
 		ycsbCmd,
 		fsBenchCmd,
 		writeBenchCmd,
+		workloadCmd,
 	)
 
 	rootCmd := &cobra.Command{
 		Use:   "pebble [command] (flags)",
 		Short: "pebble benchmarking/introspection tool",
 	}
-	rootCmd.AddCommand(benchCmd)
+	rootCmd.AddCommand(benchCmd, compareCmd)
 
 	t := tool.New(tool.Comparers(&crdbtest.Comparer, testkeys.Comparer), tool.Mergers(fauxMVCCMerger))
 	rootCmd.AddCommand(t.Commands...)
 
-	for _, cmd := range []*cobra.Command{replayCmd, scanCmd, syncCmd, tombstoneCmd, writeBenchCmd, ycsbCmd} {
+	for _, cmd := range []*cobra.Command{replayCmd, scanCmd, syncCmd, tombstoneCmd, workloadCmd, writeBenchCmd, ycsbCmd} {
 		cmd.Flags().BoolVarP(
 			&verbose, "verbose", "v", false, "enable verbose event logging")
 		cmd.Flags().StringVar(
@@ -69,15 +70,15 @@ func main() {
 		cmd.Flags().Int64Var(
 			&secondaryCacheSize, "secondary-cache", 0, "secondary cache size in bytes")
 	}
-	for _, cmd := range []*cobra.Command{scanCmd, syncCmd, tombstoneCmd, ycsbCmd} {
+	for _, cmd := range []*cobra.Command{scanCmd, syncCmd, tombstoneCmd, workloadCmd, ycsbCmd} {
 		cmd.Flags().Int64Var(
 			&cacheSize, "cache", 1<<30, "cache size")
 	}
-	for _, cmd := range []*cobra.Command{scanCmd, syncCmd, tombstoneCmd, ycsbCmd, fsBenchCmd, writeBenchCmd} {
+	for _, cmd := range []*cobra.Command{scanCmd, syncCmd, tombstoneCmd, workloadCmd, ycsbCmd, fsBenchCmd, writeBenchCmd} {
 		cmd.Flags().DurationVarP(
 			&duration, "duration", "d", 10*time.Second, "the duration to run (0, run forever)")
 	}
-	for _, cmd := range []*cobra.Command{scanCmd, syncCmd, tombstoneCmd, ycsbCmd} {
+	for _, cmd := range []*cobra.Command{scanCmd, syncCmd, tombstoneCmd, workloadCmd, ycsbCmd} {
 		cmd.Flags().IntVarP(
 			&concurrency, "concurrency", "c", 1, "number of concurrent workers")
 		cmd.Flags().BoolVar(
@@ -92,6 +93,10 @@ func main() {
 		cmd.Flags().Uint64Var(
 			&maxSize, "max-size", 0, "maximum disk size, in MB (0, run forever)")
 	}
+	for _, cmd := range []*cobra.Command{scanCmd, syncCmd, tombstoneCmd, workloadCmd, writeBenchCmd, ycsbCmd} {
+		cmd.Flags().StringVar(
+			&resultsPath, "results", "", "append machine-readable results to the given file")
+	}
 
 	if err := rootCmd.Execute(); err != nil {
 		// Cobra has already printed the error message.
@@ -40,7 +40,7 @@ func initReplayCmd() *cobra.Command {
 	}
 	cmd := &cobra.Command{
 		Use:   "replay <workload>",
-		Short: "run the provided captured write workload",
+		Short: "run the provided captured workload",
 		Args:  cobra.ExactArgs(1),
 		RunE:  c.runE,
 	}
@@ -64,6 +64,8 @@ func initReplayCmd() *cobra.Command {
 		&c.ignoreCheckpoint, "ignore-checkpoint", c.ignoreCheckpoint, "ignore the workload's initial checkpoint")
 	cmd.Flags().StringVar(
 		&c.checkpointDir, "checkpoint-dir", c.checkpointDir, "path to the checkpoint to use if not <WORKLOAD_DIR>/checkpoint")
+	cmd.Flags().IntVar(
+		&c.readConcurrency, "read-concurrency", 0, "the number of goroutines replaying the workload's captured reads, with 0 disabling read replay")
 	return cmd
 }
 
@@ -78,6 +80,7 @@ type replayConfig struct {
 	ignoreCheckpoint bool
 	optionsString    string
 	maxCacheSize     int64
+	readConcurrency  int
 
 	cleanUpFuncs []func() error
 }
@@ -113,6 +116,9 @@ func (c *replayConfig) args() (args []string) {
 	if c.optionsString != "" {
 		args = append(args, "--options", c.optionsString)
 	}
+	if c.readConcurrency != 0 {
+		args = append(args, "--read-concurrency", fmt.Sprint(c.readConcurrency))
+	}
 	return args
 }
 
@@ -154,11 +160,12 @@ func (c *replayConfig) runOnce(stdout io.Writer, workloadPath string) error {
 	}
 
 	r := &replay.Runner{
-		RunDir:       c.runDir,
-		WorkloadFS:   vfs.Default,
-		WorkloadPath: workloadPath,
-		Pacer:        c.pacer,
-		Opts:         &pebble.Options{},
+		RunDir:          c.runDir,
+		WorkloadFS:      vfs.Default,
+		WorkloadPath:    workloadPath,
+		Pacer:           c.pacer,
+		Opts:            &pebble.Options{},
+		ReadConcurrency: c.readConcurrency,
 	}
 	if c.maxWritesMB > 0 {
 		r.MaxWriteBytes = c.maxWritesMB * (1 << 20)
@@ -0,0 +1,92 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/cockroachdb/pebble"
+	"golang.org/x/perf/benchfmt"
+)
+
+// resultsPath is the path of the file to which machine-readable benchmark
+// results are appended, as specified by the --results flag.
+var resultsPath string
+
+// benchResults accumulates the machine-readable results of a benchmark run.
+// The results are written in the Go benchmark format, one result per line, so
+// that they can be compared across runs using the compare command or
+// benchstat.
+type benchResults struct {
+	name    string
+	results []*benchfmt.Result
+}
+
+func newBenchResults(name string) *benchResults {
+	return &benchResults{name: name}
+}
+
+func (r *benchResults) add(label string, values ...benchfmt.Value) {
+	r.results = append(r.results, &benchfmt.Result{
+		Name:   benchfmt.Name(r.name + "/" + label),
+		Iters:  1,
+		Values: values,
+	})
+}
+
+// addHistogram records the throughput and latency percentiles of the
+// operations recorded in h over the elapsed duration.
+func (r *benchResults) addHistogram(
+	label string, h *hdrhistogram.Histogram, elapsed time.Duration,
+) {
+	r.add(label+"/throughput", benchfmt.Value{
+		Value: float64(h.TotalCount()) / elapsed.Seconds(), Unit: "ops/sec",
+	})
+	for _, q := range []float64{50, 95, 99, 100} {
+		qLabel := fmt.Sprintf("p%d", int(q))
+		if q == 100 {
+			qLabel = "pMax"
+		}
+		r.add(label+"/"+qLabel, benchfmt.Value{
+			Value: time.Duration(h.ValueAtQuantile(q)).Seconds(), Unit: "sec/op",
+		})
+	}
+}
+
+// addMetrics records the read and write amplification and the bytes read and
+// written by the LSM.
+func (r *benchResults) addMetrics(m *pebble.Metrics) {
+	total := m.Total()
+	r.add("ReadAmp", benchfmt.Value{Value: float64(m.ReadAmp()), Unit: "files"})
+	r.add("WriteAmp", benchfmt.Value{Value: total.WriteAmp(), Unit: "wamp"})
+	r.add("BytesRead", benchfmt.Value{Value: float64(total.BytesRead), Unit: "B"})
+	r.add("BytesWritten", benchfmt.Value{
+		Value: float64(total.BytesFlushed + total.BytesCompacted), Unit: "B",
+	})
+}
+
+// write appends the accumulated results to the file specified by --results,
+// if any. Appending allows repeated runs of the same benchmark to accumulate
+// the samples needed to compare results with statistical significance.
+func (r *benchResults) write() error {
+	if resultsPath == "" {
+		return nil
+	}
+	f, err := os.OpenFile(resultsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := benchfmt.NewWriter(f)
+	for _, res := range r.results {
+		if err := w.Write(res); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
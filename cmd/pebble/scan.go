@@ -17,6 +17,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/randvar"
 	"github.com/spf13/cobra"
 	"golang.org/x/exp/rand"
+	"golang.org/x/perf/benchfmt"
 )
 
 var scanConfig struct {
@@ -61,8 +62,10 @@ func runScan(cmd *cobra.Command, args []string) {
 
 	rowDist := scanConfig.rows
 
+	var db DB
 	runTest(args[0], test{
 		init: func(d DB, wg *sync.WaitGroup) {
+			db = d
 			const count = 100000
 			const batch = 1000
 
@@ -156,6 +159,17 @@ func runScan(cmd *cobra.Command, args []string) {
 				float64(curBytes)/(elapsed.Seconds()*(1<<20)),
 				float64(elapsed)/float64(curScanned),
 			)
+
+			results := newBenchResults(fmt.Sprintf("scan/rows=%s/reverse=%t", rowDist, scanConfig.reverse))
+			results.add("throughput", benchfmt.Value{
+				Value: float64(curScanned) / elapsed.Seconds(), Unit: "rows/sec",
+			}, benchfmt.Value{
+				Value: float64(curBytes) / elapsed.Seconds(), Unit: "B/sec",
+			})
+			results.addMetrics(db.Metrics())
+			if err := results.write(); err != nil {
+				log.Fatal(err)
+			}
 		},
 	})
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/randvar"
 	"github.com/spf13/cobra"
 	"golang.org/x/exp/rand"
+	"golang.org/x/perf/benchfmt"
 )
 
 var syncConfig struct {
@@ -57,8 +58,10 @@ func runSync(cmd *cobra.Command, args []string) {
 
 	batchDist := syncConfig.batch
 
+	var db DB
 	runTest(args[0], test{
 		init: func(d DB, wg *sync.WaitGroup) {
+			db = d
 			limiter := maxOpsPerSec.newRateLimiter()
 
 			wg.Add(concurrency)
@@ -127,8 +130,10 @@ func runSync(cmd *cobra.Command, args []string) {
 
 		done: func(elapsed time.Duration) {
 			fmt.Println("\n_elapsed___ops(total)_ops/sec(cum)_mb/sec(cum)__avg(ms)__p50(ms)__p95(ms)__p99(ms)_pMax(ms)")
+			results := newBenchResults(fmt.Sprintf("sync/wal-only=%t/values=%s", syncConfig.walOnly, syncConfig.values))
 			reg.Tick(func(tick histogramTick) {
 				h := tick.Cumulative
+				results.addHistogram(tick.Name, h, elapsed)
 				fmt.Printf("%7.1fs %12d %12.1f %11.1f %8.1f %8.1f %8.1f %8.1f %8.1f\n\n",
 					elapsed.Seconds(), h.TotalCount(),
 					float64(h.TotalCount())/elapsed.Seconds(),
@@ -139,6 +144,13 @@ func runSync(cmd *cobra.Command, args []string) {
 					time.Duration(h.ValueAtQuantile(99)).Seconds()*1000,
 					time.Duration(h.ValueAtQuantile(100)).Seconds()*1000)
 			})
+			results.add("bytes", benchfmt.Value{
+				Value: float64(bytes.Load()) / elapsed.Seconds(), Unit: "B/sec",
+			})
+			results.addMetrics(db.Metrics())
+			if err := results.write(); err != nil {
+				log.Fatal(err)
+			}
 		},
 	})
 }
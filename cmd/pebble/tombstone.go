@@ -13,6 +13,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/humanize"
 	"github.com/spf13/cobra"
+	"golang.org/x/perf/benchfmt"
 )
 
 func init() {
@@ -128,6 +129,19 @@ func runTombstoneCmd(cmd *cobra.Command, args []string) error {
 				log.Fatal(err)
 			}
 			fmt.Printf("%15s %15s\n", elapsed.Truncate(time.Second), humanize.Bytes.Uint64(queueSize))
+
+			results := newBenchResults(fmt.Sprintf("tombstone/%s/values=%s", ycsbConfig.workload, ycsbConfig.values))
+			results.add("queue/throughput", benchfmt.Value{
+				Value: float64(queueOps.Load()) / elapsed.Seconds(), Unit: "ops/sec",
+			})
+			results.add("queue/size", benchfmt.Value{Value: float64(queueSize), Unit: "B"})
+			y.reg.Tick(func(tick histogramTick) {
+				results.addHistogram("ycsb/"+tick.Name, tick.Cumulative, elapsed)
+			})
+			results.addMetrics(pdb.Metrics())
+			if err := results.write(); err != nil {
+				log.Fatal(err)
+			}
 		},
 	})
 	return nil
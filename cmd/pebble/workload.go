@@ -0,0 +1,638 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/internal/crdbtest"
+	"github.com/cockroachdb/pebble/internal/randvar"
+	"github.com/cockroachdb/pebble/internal/rate"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/rand"
+)
+
+var workloadConfig struct {
+	spec string
+}
+
+var workloadCmd = &cobra.Command{
+	Use:   "workload <dir>",
+	Short: "run a synthetic workload described by a spec file",
+	Long: `
+Run a synthetic workload described by the spec file provided with --spec. The
+spec is composed of one or more phases which are run in order. Each phase
+begins with a "[phase <name>]" header and is followed by key=value lines
+configuring it. Blank lines and lines beginning with '#' are ignored.
+
+  # Load an initial data set.
+  [phase load]
+  ops=1000000
+  mix=set=100
+  batch=100
+
+  # A mixed workload with range deletions, range keys and snapshots.
+  [phase mixed]
+  duration=5m
+  concurrency=8
+  mix=seek=50,scan=10,set=30,delete=5,range-delete=1,range-key-set=2,snapshot=2
+  keys=zipf:1-1000000
+  values=1000/2
+  snapshot-lifetime=100-5000
+
+The supported phase keys are:
+
+  duration           the duration of the phase (0, no limit)
+  ops                the number of operations to perform (0, no limit)
+  concurrency        the number of concurrent workers (default: --concurrency)
+  rate               max ops per second (default: --rate)
+  mix                the operation mix, as a comma separated list of op=weight
+                     pairs. The supported ops are: seek, scan, reverse-scan,
+                     set, delete, range-delete, range-key-set, range-key-unset,
+                     range-key-delete and snapshot. A seek reads the newest
+                     version of a key and a delete deletes it.
+  keys               the distribution of key numbers (default: uniform:1-1000000)
+  values             the distribution of value sizes (default: 1000)
+  batch              the number of keys written per batch (default: 1)
+  scan-length        the number of keys read by a scan (default: 1-100)
+  range-width        the number of keys covered by range operations
+                     (default: 1-100)
+  iterator-lifetime  how long a scan's iterator is held open after the scan,
+                     in milliseconds (default: 0)
+  snapshot-lifetime  how long a snapshot is held open, in milliseconds
+                     (default: 1000)
+
+With the exception of mix, duration and ops, the values are specifications for
+a random variable as accepted by the ycsb command: [<type>:]<min>[-max]. Every
+phase but the last must specify a duration or a number of ops; a phase with
+neither runs until the --duration flag expires.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorkload,
+}
+
+func init() {
+	workloadCmd.Flags().StringVar(
+		&workloadConfig.spec, "spec", "", "path to the workload spec file")
+	_ = workloadCmd.MarkFlagRequired("spec")
+}
+
+const (
+	workloadSeek = iota
+	workloadScan
+	workloadReverseScan
+	workloadSet
+	workloadDelete
+	workloadRangeDelete
+	workloadRangeKeySet
+	workloadRangeKeyUnset
+	workloadRangeKeyDelete
+	workloadSnapshot
+	workloadNumOps
+)
+
+var workloadOpNames = [workloadNumOps]string{
+	workloadSeek:           "seek",
+	workloadScan:           "scan",
+	workloadReverseScan:    "reverse-scan",
+	workloadSet:            "set",
+	workloadDelete:         "delete",
+	workloadRangeDelete:    "range-delete",
+	workloadRangeKeySet:    "range-key-set",
+	workloadRangeKeyUnset:  "range-key-unset",
+	workloadRangeKeyDelete: "range-key-delete",
+	workloadSnapshot:       "snapshot",
+}
+
+// workloadPhase is a single phase of a workload spec.
+type workloadPhase struct {
+	name        string
+	duration    time.Duration
+	ops         uint64
+	concurrency int
+	rate        *rateFlag
+	weights     [workloadNumOps]float64
+
+	keys             *randvar.Flag
+	values           *randvar.BytesFlag
+	batch            *randvar.Flag
+	scanLength       *randvar.Flag
+	rangeWidth       *randvar.Flag
+	iterLifetime     *randvar.Flag
+	snapshotLifetime *randvar.Flag
+}
+
+func newWorkloadPhase(name string) *workloadPhase {
+	return &workloadPhase{
+		name:             name,
+		concurrency:      concurrency,
+		rate:             maxOpsPerSec,
+		keys:             randvar.NewFlag("uniform:1-1000000"),
+		values:           randvar.NewBytesFlag("1000"),
+		batch:            randvar.NewFlag("1"),
+		scanLength:       randvar.NewFlag("1-100"),
+		rangeWidth:       randvar.NewFlag("1-100"),
+		iterLifetime:     randvar.NewFlag("0"),
+		snapshotLifetime: randvar.NewFlag("1000"),
+	}
+}
+
+func (p *workloadPhase) set(key, value string) error {
+	var err error
+	switch key {
+	case "duration":
+		p.duration, err = time.ParseDuration(value)
+	case "ops":
+		p.ops, err = strconv.ParseUint(value, 10, 64)
+	case "concurrency":
+		p.concurrency, err = strconv.Atoi(value)
+		if err == nil && p.concurrency <= 0 {
+			err = errors.Errorf("concurrency must be positive")
+		}
+	case "rate":
+		p.rate = &rateFlag{}
+		err = p.rate.Set(value)
+	case "mix":
+		err = p.parseMix(value)
+	case "keys":
+		err = p.keys.Set(value)
+	case "values":
+		err = p.values.Set(value)
+	case "batch":
+		err = p.batch.Set(value)
+	case "scan-length":
+		err = p.scanLength.Set(value)
+	case "range-width":
+		err = p.rangeWidth.Set(value)
+	case "iterator-lifetime":
+		err = p.iterLifetime.Set(value)
+	case "snapshot-lifetime":
+		err = p.snapshotLifetime.Set(value)
+	default:
+		return errors.Errorf("unknown key %q", key)
+	}
+	return err
+}
+
+func (p *workloadPhase) parseMix(mix string) error {
+	p.weights = [workloadNumOps]float64{}
+	var sum float64
+	for _, field := range strings.Split(mix, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, weight, ok := strings.Cut(field, "=")
+		if !ok {
+			return errors.Errorf("malformed op weight %q", field)
+		}
+		op := -1
+		for i := range workloadOpNames {
+			if workloadOpNames[i] == strings.TrimSpace(name) {
+				op = i
+			}
+		}
+		if op < 0 {
+			return errors.Errorf("unknown op %q", name)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil {
+			return err
+		}
+		if w < 0 {
+			return errors.Errorf("negative weight for op %q", name)
+		}
+		p.weights[op] = w
+		sum += w
+	}
+	if sum == 0 {
+		return errors.Errorf("empty op mix %q", mix)
+	}
+	for i := range p.weights {
+		p.weights[i] /= sum
+	}
+	return nil
+}
+
+// parseWorkloadSpec parses a workload spec. See workloadCmd for the format.
+func parseWorkloadSpec(r io.Reader) ([]*workloadPhase, error) {
+	var phases []*workloadPhase
+	var phase *workloadPhase
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			header, ok := strings.CutSuffix(line, "]")
+			name, isPhase := strings.CutPrefix(strings.TrimSpace(header[1:]), "phase ")
+			name = strings.TrimSpace(name)
+			if !ok || !isPhase || name == "" {
+				return nil, errors.Errorf("line %d: malformed phase header %q", lineNum, line)
+			}
+			for _, p := range phases {
+				if p.name == name {
+					return nil, errors.Errorf("line %d: duplicate phase %q", lineNum, name)
+				}
+			}
+			phase = newWorkloadPhase(name)
+			phases = append(phases, phase)
+			continue
+		}
+		if phase == nil {
+			return nil, errors.Errorf("line %d: %q does not belong to a phase", lineNum, line)
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, errors.Errorf("line %d: expected key=value: %q", lineNum, line)
+		}
+		if err := phase.set(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNum)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(phases) == 0 {
+		return nil, errors.New("workload spec contains no phases")
+	}
+	for i, p := range phases {
+		var sum float64
+		for _, w := range p.weights {
+			sum += w
+		}
+		if sum == 0 {
+			return nil, errors.Errorf("phase %q: no op mix specified", p.name)
+		}
+		if i < len(phases)-1 && p.duration == 0 && p.ops == 0 {
+			return nil, errors.Errorf("phase %q: duration or ops must be specified for all but the last phase", p.name)
+		}
+	}
+	return phases, nil
+}
+
+func runWorkload(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(workloadConfig.spec)
+	if err != nil {
+		return err
+	}
+	phases, err := parseWorkloadSpec(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	w := &workload{
+		name:   strings.TrimSuffix(filepath.Base(workloadConfig.spec), filepath.Ext(workloadConfig.spec)),
+		phases: phases,
+	}
+	w.writeOpts = pebble.Sync
+	if disableWAL {
+		w.writeOpts = pebble.NoSync
+	}
+	runTest(args[0], test{
+		init: w.init,
+		tick: w.tick,
+		done: w.done,
+	})
+	return nil
+}
+
+// workloadPhaseRun holds the state of a phase that is running or has run.
+type workloadPhaseRun struct {
+	*workloadPhase
+	reg   *histogramRegistry
+	start time.Time
+	// end is set once all of the phase's workers have exited.
+	end    atomic.Pointer[time.Time]
+	numOps atomic.Uint64
+}
+
+// elapsed returns the time spent running the phase so far.
+func (r *workloadPhaseRun) elapsed() time.Duration {
+	if end := r.end.Load(); end != nil {
+		return end.Sub(r.start)
+	}
+	return time.Since(r.start)
+}
+
+type workload struct {
+	name      string
+	phases    []*workloadPhase
+	db        DB
+	writeOpts *pebble.WriteOptions
+	// timestamp is the MVCC walltime of the most recent write.
+	timestamp atomic.Uint64
+
+	mu struct {
+		sync.Mutex
+		runs []*workloadPhaseRun
+	}
+}
+
+func (w *workload) init(db DB, wg *sync.WaitGroup) {
+	w.db = db
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, p := range w.phases {
+			w.runPhase(p)
+		}
+	}()
+}
+
+// runPhase runs a single phase to completion.
+func (w *workload) runPhase(p *workloadPhase) {
+	r := &workloadPhaseRun{
+		workloadPhase: p,
+		reg:           newHistogramRegistry(),
+		start:         time.Now(),
+	}
+	w.mu.Lock()
+	w.mu.runs = append(w.mu.runs, r)
+	w.mu.Unlock()
+	fmt.Printf("starting phase %s\n", p.name)
+
+	limiter := p.rate.newRateLimiter()
+	var phaseWG sync.WaitGroup
+	phaseWG.Add(p.concurrency)
+	for i := 0; i < p.concurrency; i++ {
+		go func() {
+			defer phaseWG.Done()
+			w.runWorker(r, limiter)
+		}()
+	}
+	phaseWG.Wait()
+	end := time.Now()
+	r.end.Store(&end)
+}
+
+// workloadBuf holds per-worker state.
+type workloadBuf struct {
+	rng      *rand.Rand
+	keyBuf   []byte
+	endBuf   []byte
+	valueBuf []byte
+}
+
+func (w *workload) runWorker(r *workloadPhaseRun, limiter *rate.Limiter) {
+	var latency [workloadNumOps]*namedHistogram
+	for op, weight := range r.weights {
+		if weight > 0 {
+			latency[op] = r.reg.Register(workloadOpNames[op])
+		}
+	}
+	buf := &workloadBuf{rng: randvar.NewRand()}
+	ops := randvar.NewWeighted(nil, r.weights[:]...)
+	for {
+		if r.duration > 0 && time.Since(r.start) >= r.duration {
+			return
+		}
+		if r.ops > 0 && r.numOps.Add(1) > r.ops {
+			return
+		}
+		wait(limiter)
+
+		start := time.Now()
+		op := ops.Int()
+		switch op {
+		case workloadSeek:
+			w.seek(r, buf)
+		case workloadScan:
+			w.scan(r, buf, false /* reverse */)
+		case workloadReverseScan:
+			w.scan(r, buf, true /* reverse */)
+		case workloadSet, workloadDelete:
+			w.write(r, buf, op)
+		case workloadRangeDelete, workloadRangeKeySet, workloadRangeKeyUnset, workloadRangeKeyDelete:
+			w.writeRange(r, buf, op)
+		case workloadSnapshot:
+			w.snapshot(r, buf)
+		default:
+			panic("not reached")
+		}
+		latency[op].Record(time.Since(start))
+	}
+}
+
+// makeWorkloadKey returns the MVCC key for the provided key number and walltime. A
+// walltime of zero produces a bare key without a version.
+func makeWorkloadKey(dst []byte, keyNum, walltime uint64) []byte {
+	var prefix [16]byte
+	p := append(prefix[:0], 'k')
+	p = fmt.Appendf(p, "%012d", keyNum)
+	return crdbtest.EncodeMVCCKey(dst, p, walltime, 0)
+}
+
+// seek reads the newest version of a key.
+func (w *workload) seek(r *workloadPhaseRun, buf *workloadBuf) {
+	buf.keyBuf = makeWorkloadKey(buf.keyBuf, r.keys.Uint64(buf.rng), 0)
+	iter := w.db.NewIter(nil)
+	iter.SeekGE(buf.keyBuf)
+	if iter.Valid() {
+		_ = iter.Key()
+		_ = iter.Value()
+	}
+	if err := iter.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (w *workload) scan(r *workloadPhaseRun, buf *workloadBuf, reverse bool) {
+	buf.keyBuf = makeWorkloadKey(buf.keyBuf, r.keys.Uint64(buf.rng), 0)
+	iter := w.db.NewIter(&pebble.IterOptions{KeyTypes: pebble.IterKeyTypePointsAndRanges})
+	if err := w.db.Scan(iter, buf.keyBuf, int64(r.scanLength.Uint64(buf.rng)), reverse); err != nil {
+		log.Fatal(err)
+	}
+	closeIter := func() {
+		if err := iter.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if lifetime := time.Duration(r.iterLifetime.Uint64(buf.rng)) * time.Millisecond; lifetime > 0 {
+		// Hold the iterator open, pinning the state of the LSM it observed.
+		time.AfterFunc(lifetime, closeIter)
+		return
+	}
+	closeIter()
+}
+
+func (w *workload) write(r *workloadPhaseRun, buf *workloadBuf, op int) {
+	count := int(r.batch.Uint64(buf.rng))
+	b := w.db.NewBatch()
+	var iter iterator
+	if op == workloadDelete {
+		// Deletes target the newest version of each key, which must be looked
+		// up since every write uses a new version.
+		iter = w.db.NewIter(nil)
+	}
+	for i := 0; i < count; i++ {
+		keyNum := r.keys.Uint64(buf.rng)
+		var err error
+		if op == workloadSet {
+			buf.keyBuf = makeWorkloadKey(buf.keyBuf, keyNum, w.timestamp.Add(1))
+			buf.valueBuf = r.values.Bytes(buf.rng, buf.valueBuf)
+			err = b.Set(buf.keyBuf, buf.valueBuf, nil)
+		} else {
+			buf.keyBuf = makeWorkloadKey(buf.keyBuf, keyNum, 0)
+			prefix := buf.keyBuf[:crdbtest.Split(buf.keyBuf)]
+			if !iter.SeekGE(buf.keyBuf) || !bytes.HasPrefix(iter.Key(), prefix) ||
+				crdbtest.Split(iter.Key()) != len(prefix) {
+				// The key has no live versions.
+				continue
+			}
+			err = b.Delete(iter.Key(), nil)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if iter != nil {
+		if err := iter.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := b.Commit(w.writeOpts); err != nil {
+		log.Fatal(err)
+	}
+	_ = b.Close()
+}
+
+func (w *workload) writeRange(r *workloadPhaseRun, buf *workloadBuf, op int) {
+	start := r.keys.Uint64(buf.rng)
+	end := start + max(r.rangeWidth.Uint64(buf.rng), 1)
+	buf.keyBuf = makeWorkloadKey(buf.keyBuf, start, 0)
+	buf.endBuf = makeWorkloadKey(buf.endBuf, end, 0)
+
+	b := w.db.NewBatch()
+	var err error
+	switch op {
+	case workloadRangeDelete:
+		err = b.DeleteRange(buf.keyBuf, buf.endBuf, nil)
+	case workloadRangeKeySet, workloadRangeKeyUnset:
+		// Range keys use the same MVCC timestamps as point keys; the suffix is
+		// the encoded version of a key at the chosen timestamp.
+		ts := w.timestamp.Add(1)
+		if op == workloadRangeKeyUnset {
+			ts = max(ts-1, 1)
+		}
+		var suffixBuf [32]byte
+		k := crdbtest.EncodeMVCCKey(suffixBuf[:0], nil, ts, 0)
+		suffix := k[crdbtest.Split(k):]
+		if op == workloadRangeKeySet {
+			buf.valueBuf = r.values.Bytes(buf.rng, buf.valueBuf)
+			err = b.RangeKeySet(buf.keyBuf, buf.endBuf, suffix, buf.valueBuf, nil)
+		} else {
+			err = b.RangeKeyUnset(buf.keyBuf, buf.endBuf, suffix, nil)
+		}
+	case workloadRangeKeyDelete:
+		err = b.RangeKeyDelete(buf.keyBuf, buf.endBuf, nil)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := b.Commit(w.writeOpts); err != nil {
+		log.Fatal(err)
+	}
+	_ = b.Close()
+}
+
+func (w *workload) snapshot(r *workloadPhaseRun, buf *workloadBuf) {
+	s := w.db.NewSnapshot()
+	lifetime := time.Duration(r.snapshotLifetime.Uint64(buf.rng)) * time.Millisecond
+	time.AfterFunc(lifetime, func() {
+		if err := s.Close(); err != nil {
+			log.Fatal(err)
+		}
+	})
+}
+
+func (w *workload) tick(elapsed time.Duration, i int) {
+	w.mu.Lock()
+	if len(w.mu.runs) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	r := w.mu.runs[len(w.mu.runs)-1]
+	w.mu.Unlock()
+
+	if i%20 == 0 {
+		fmt.Println("_____________phase/optype__elapsed__ops/sec(inst)___ops/sec(cum)__p50(ms)__p95(ms)__p99(ms)_pMax(ms)")
+	}
+	phaseElapsed := r.elapsed()
+	r.reg.Tick(func(tick histogramTick) {
+		h := tick.Hist
+		fmt.Printf("%25s %8s %14.1f %14.1f %8.1f %8.1f %8.1f %8.1f\n",
+			r.name+"/"+tick.Name,
+			time.Duration(elapsed.Seconds()+0.5)*time.Second,
+			float64(h.TotalCount())/tick.Elapsed.Seconds(),
+			float64(tick.Cumulative.TotalCount())/phaseElapsed.Seconds(),
+			time.Duration(h.ValueAtQuantile(50)).Seconds()*1000,
+			time.Duration(h.ValueAtQuantile(95)).Seconds()*1000,
+			time.Duration(h.ValueAtQuantile(99)).Seconds()*1000,
+			time.Duration(h.ValueAtQuantile(100)).Seconds()*1000,
+		)
+	})
+}
+
+func (w *workload) done(elapsed time.Duration) {
+	w.mu.Lock()
+	runs := append([]*workloadPhaseRun(nil), w.mu.runs...)
+	w.mu.Unlock()
+
+	fmt.Println("\n_____________phase/optype__elapsed_____ops(total)___ops/sec(cum)__avg(ms)__p50(ms)__p95(ms)__p99(ms)_pMax(ms)")
+	results := newBenchResults("workload/" + w.name)
+	var totalOps int64
+	var summaries []string
+	for _, r := range runs {
+		phaseElapsed := r.elapsed()
+		var phaseOps int64
+		r.reg.Tick(func(tick histogramTick) {
+			h := tick.Cumulative
+			phaseOps += h.TotalCount()
+			results.addHistogram(r.name+"/"+tick.Name, h, phaseElapsed)
+			fmt.Printf("%25s %7.1fs %14d %14.1f %8.1f %8.1f %8.1f %8.1f %8.1f\n",
+				r.name+"/"+tick.Name, phaseElapsed.Seconds(), h.TotalCount(),
+				float64(h.TotalCount())/phaseElapsed.Seconds(),
+				time.Duration(h.Mean()).Seconds()*1000,
+				time.Duration(h.ValueAtQuantile(50)).Seconds()*1000,
+				time.Duration(h.ValueAtQuantile(95)).Seconds()*1000,
+				time.Duration(h.ValueAtQuantile(99)).Seconds()*1000,
+				time.Duration(h.ValueAtQuantile(100)).Seconds()*1000)
+		})
+		totalOps += phaseOps
+		summaries = append(summaries, fmt.Sprintf("Benchmarkworkload/%s/%s %d  %0.1f ops/sec\n",
+			w.name, r.name, phaseOps, float64(phaseOps)/phaseElapsed.Seconds()))
+	}
+	fmt.Println()
+	for _, s := range summaries {
+		fmt.Print(s)
+	}
+
+	m := w.db.Metrics()
+	total := m.Total()
+	fmt.Printf("Benchmarkworkload/%s %d  %0.1f ops/sec  %d read  %d write  %0.2f w-amp\n\n",
+		w.name, totalOps, float64(totalOps)/elapsed.Seconds(),
+		total.BytesRead, total.BytesFlushed+total.BytesCompacted, total.WriteAmp())
+
+	results.addMetrics(m)
+	if err := results.write(); err != nil {
+		log.Fatal(err)
+	}
+}
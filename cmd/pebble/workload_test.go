@@ -0,0 +1,136 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/internal/crdbtest"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkloadSpec(t *testing.T) {
+	phases, err := parseWorkloadSpec(strings.NewReader(`
+# Load phase.
+[phase load]
+ops=1000
+mix=set=1
+batch=100
+
+[phase mixed]
+duration=1m
+concurrency=4
+rate=500
+mix=seek=50, scan=20, range-delete=10, range-key-set=10, snapshot=10
+keys=zipf:1-1000
+snapshot-lifetime=10-100
+`))
+	require.NoError(t, err)
+	require.Len(t, phases, 2)
+
+	load := phases[0]
+	require.Equal(t, "load", load.name)
+	require.Equal(t, uint64(1000), load.ops)
+	require.Equal(t, 1.0, load.weights[workloadSet])
+	require.Equal(t, "100", load.batch.String())
+
+	mixed := phases[1]
+	require.Equal(t, "mixed", mixed.name)
+	require.Equal(t, time.Minute, mixed.duration)
+	require.Equal(t, 4, mixed.concurrency)
+	require.Equal(t, "500", mixed.rate.String())
+	require.Equal(t, 0.5, mixed.weights[workloadSeek])
+	require.Equal(t, 0.1, mixed.weights[workloadSnapshot])
+	require.Equal(t, "zipf:1-1000", mixed.keys.String())
+	require.Equal(t, "10-100", mixed.snapshotLifetime.String())
+
+	for _, tc := range []struct {
+		spec string
+		err  string
+	}{
+		{spec: ``, err: "no phases"},
+		{spec: "mix=seek=1", err: "does not belong to a phase"},
+		{spec: "[load]", err: "malformed phase header"},
+		{spec: "[phase a]\nmix=seek=1\n[phase a]\nmix=seek=1", err: "duplicate phase"},
+		{spec: "[phase a]\nmix=foo=1", err: `unknown op "foo"`},
+		{spec: "[phase a]\nfoo=1", err: `unknown key "foo"`},
+		{spec: "[phase a]\nops=10", err: "no op mix"},
+		{spec: "[phase a]\nmix=seek=1\n[phase b]\nmix=seek=1", err: "duration or ops must be specified"},
+	} {
+		_, err := parseWorkloadSpec(strings.NewReader(tc.spec))
+		require.ErrorContains(t, err, tc.err)
+	}
+}
+
+func TestRunWorkload(t *testing.T) {
+	phases, err := parseWorkloadSpec(strings.NewReader(`
+[phase load]
+ops=10
+concurrency=1
+mix=set=1
+keys=1
+
+[phase delete]
+ops=4
+concurrency=1
+mix=delete=1
+keys=1
+
+[phase mixed]
+ops=400
+concurrency=4
+mix=seek=20,scan=20,reverse-scan=10,set=20,delete=10,range-delete=5,range-key-set=5,range-key-unset=5,range-key-delete=5
+keys=uniform:1-100
+values=10
+scan-length=1-10
+`))
+	require.NoError(t, err)
+
+	d, err := pebble.Open("", &pebble.Options{
+		FS:                 vfs.NewMem(),
+		Comparer:           &crdbtest.Comparer,
+		FormatMajorVersion: pebble.FormatNewest,
+	})
+	require.NoError(t, err)
+	defer d.Close()
+
+	w := &workload{name: "test", phases: phases[:2], writeOpts: pebble.NoSync}
+	var wg sync.WaitGroup
+	w.init(pebbleDB{d: d}, &wg)
+	wg.Wait()
+
+	// Each delete removes the newest version of the single key written by
+	// the load phase.
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	var versions int
+	for valid := iter.First(); valid; valid = iter.Next() {
+		versions++
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, 6, versions)
+
+	w = &workload{name: "test", phases: phases[2:], writeOpts: pebble.NoSync}
+	w.init(pebbleDB{d: d}, &wg)
+	wg.Wait()
+
+	ops := make(map[string]int64)
+	var total int64
+	w.mu.runs[0].reg.Tick(func(tick histogramTick) {
+		ops[tick.Name] = tick.Cumulative.TotalCount()
+		total += tick.Cumulative.TotalCount()
+	})
+	require.Equal(t, int64(400), total)
+	for op, weight := range phases[2].weights {
+		if weight > 0 {
+			require.Greater(t, ops[workloadOpNames[op]], int64(0), workloadOpNames[op])
+		}
+	}
+}
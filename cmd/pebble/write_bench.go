@@ -7,6 +7,8 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
+	"slices"
 	"sync"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/randvar"
 	"github.com/cockroachdb/pebble/internal/rate"
 	"github.com/spf13/cobra"
+	"golang.org/x/perf/benchfmt"
 )
 
 // The following constants match the values that Cockroach uses in Admission
@@ -401,11 +404,25 @@ func runWriteBenchmark(_ *cobra.Command, args []string) error {
 		done: func(elapsed time.Duration) {
 			// Print final analysis.
 			var total int64
+			results := newBenchResults(fmt.Sprintf("write/values=%s", writeBenchConfig.values))
 			y.reg.Tick(func(tick histogramTick) {
 				total = tick.Cumulative.TotalCount()
+				results.addHistogram(tick.Name, tick.Cumulative, elapsed)
 			})
 			fmt.Println("___elapsed___ops(total)")
 			fmt.Printf("%10s %12d\n", elapsed.Truncate(time.Second), total)
+
+			// The highest write load that passed is the benchmark's headline
+			// result.
+			if len(pass) > 0 {
+				results.add("max-passing-rate", benchfmt.Value{
+					Value: float64(slices.Max(pass)), Unit: "ops/sec",
+				})
+			}
+			results.addMetrics(y.db.Metrics())
+			if err := results.write(); err != nil {
+				log.Fatal(err)
+			}
 		},
 	})
 
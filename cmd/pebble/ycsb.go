@@ -565,9 +565,11 @@ func (y *ycsb) tick(elapsed time.Duration, i int) {
 func (y *ycsb) done(elapsed time.Duration) {
 	fmt.Println("\n____optype__elapsed_____ops(total)___ops/sec(cum)__avg(ms)__p50(ms)__p95(ms)__p99(ms)_pMax(ms)")
 
+	results := newBenchResults(fmt.Sprintf("ycsb/%s/values=%s", ycsbConfig.workload, ycsbConfig.values))
 	resultTick := histogramTick{}
 	y.reg.Tick(func(tick histogramTick) {
 		h := tick.Cumulative
+		results.addHistogram(tick.Name, h, elapsed)
 		if resultTick.Cumulative == nil {
 			resultTick.Now = tick.Now
 			resultTick.Cumulative = h
@@ -606,4 +608,10 @@ func (y *ycsb) done(elapsed time.Duration) {
 		float64(readAmpSum)/float64(readAmpCount),
 		total.WriteAmp(),
 	)
+
+	results.addHistogram("total", resultHist, elapsed)
+	results.addMetrics(m)
+	if err := results.write(); err != nil {
+		log.Fatal(err)
+	}
 }
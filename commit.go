@@ -140,6 +140,9 @@ type commitEnv struct {
 	// the memtable the batch should be applied to. Serial execution enforced by
 	// commitPipeline.mu.
 	write func(b *Batch, wg *sync.WaitGroup, err *error) (*memTable, error)
+	// pipelined, if set, makes commits write batches to the WAL in groups.
+	// See Options.Experimental.PipelinedWrites.
+	pipelined bool
 }
 
 // A commitPipeline manages the stages of committing a set of mutations
@@ -190,9 +193,19 @@ type commitEnv struct {
 //
 // As soon as a batch has been written to the WAL, the commitPipeline mutex is
 // released allowing another batch to write to the WAL. Each commit operation
-// individually applies its batch to the memtable providing concurrency. The
-// WAL sync happens concurrently with applying to the memtable (see
-// commitPipeline.syncLoop).
+// individually applies its batch to the memtable providing concurrency, and a
+// large batch may itself be applied by several goroutines (see
+// Options.Experimental.MemTableApplyParallelism). The WAL sync happens
+// concurrently with applying to the memtable (see commitPipeline.syncLoop).
+//
+// With pipelined writes (see Options.Experimental.PipelinedWrites), committing
+// batches instead queue up to be written to the WAL in groups. The first batch
+// of a group is its leader, which locks the commitPipeline mutex once to
+// sequence and write every batch of the group. Before applying its own batch
+// the leader hands the WAL to the leader of the next group, so that group N+1
+// is written to the WAL while the batches of group N are applied to the
+// memtable, and the mutex changes hands once per group rather than once per
+// batch. See commitPipeline.prepareGroup.
 //
 // The "waits for earlier batches to apply" work is more complicated than might
 // be expected. The obvious approach would be to keep a queue of pending
@@ -248,6 +261,36 @@ type commitPipeline struct {
 	// The mutex to use for synchronizing access to logSeqNum and serializing
 	// calls to commitEnv.write().
 	mu sync.Mutex
+	// writeGroup holds the batches waiting to be written to the WAL when
+	// writes are pipelined.
+	writeGroup struct {
+		sync.Mutex
+		// queue holds the batches of the next group, in arrival order.
+		queue []*pipelinedWrite
+		// writing is set while a leader is writing a group to the WAL.
+		// Batches that arrive while it's set wait for a leader to write them.
+		writing bool
+	}
+}
+
+// pipelinedWrite is a batch waiting to be written to the WAL as part of a
+// write group.
+type pipelinedWrite struct {
+	b       *Batch
+	syncWG  *sync.WaitGroup
+	syncErr *error
+	// written is done once the batch has been written to the WAL, or once
+	// the batch has been made the leader of the next group.
+	written sync.WaitGroup
+	leader  bool
+	mem     *memTable
+	err     error
+}
+
+var pipelinedWritePool = sync.Pool{
+	New: func() interface{} {
+		return &pipelinedWrite{}
+	},
 }
 
 func newCommitPipeline(env commitEnv) *commitPipeline {
@@ -314,7 +357,13 @@ func (p *commitPipeline) Commit(b *Batch, syncWAL bool, noSyncWait bool) error {
 	//
 	// NB: We set Batch.commitErr on error so that the batch won't be a candidate
 	// for reuse. See Batch.release().
-	mem, err := p.prepare(b, syncWAL, noSyncWait)
+	var mem *memTable
+	var err error
+	if p.env.pipelined {
+		mem, err = p.prepareGroup(b, syncWAL, noSyncWait)
+	} else {
+		mem, err = p.prepare(b, syncWAL, noSyncWait)
+	}
 	if err != nil {
 		b.db = nil // prevent batch reuse on error
 		// NB: we are not doing <-p.commitQueueSem since the batch is still
@@ -353,6 +402,47 @@ func (p *commitPipeline) Commit(b *Batch, syncWAL bool, noSyncWait bool) error {
 	return err
 }
 
+// CommitIfUnchanged commits the specified batch if no batch has been sequenced
+// since seqNum, returning whether the batch was committed. That is, the batch
+// is committed only if the batch receives the sequence number seqNum. The
+// batch is written to the WAL without syncing it. The check only compares
+// sequence numbers, so commitPipeline.mu is held only briefly; callers are
+// expected to read whatever the batch depends on at seqNum beforehand.
+func (p *commitPipeline) CommitIfUnchanged(b *Batch, seqNum base.SeqNum) (bool, error) {
+	if b.Empty() {
+		return false, nil
+	}
+
+	p.commitQueueSem <- struct{}{}
+	p.mu.Lock()
+	if p.env.logSeqNum.Load() != seqNum {
+		p.mu.Unlock()
+		<-p.commitQueueSem
+		return false, nil
+	}
+
+	// Only need to wait for the publish.
+	b.commit.Add(1)
+	p.pending.enqueue(b)
+	n := base.SeqNum(b.Count())
+	b.setSeqNum(p.env.logSeqNum.Add(n) - n)
+	mem, err := p.env.write(b, nil /* syncWG */, nil /* syncErr */)
+	p.mu.Unlock()
+	if err != nil {
+		// NB: As in Commit, we are not doing <-p.commitQueueSem since the batch
+		// is still sitting in the pending queue.
+		b.db = nil // prevent batch reuse on error
+		return false, err
+	}
+	if err := p.env.apply(b, mem); err != nil {
+		b.db = nil // prevent batch reuse on error
+		return false, err
+	}
+	p.publish(b)
+	<-p.commitQueueSem
+	return true, nil
+}
+
 // AllocateSeqNum allocates count sequence numbers, invokes the prepare
 // callback, then the apply callback, and then publishes the sequence
 // numbers. AllocateSeqNum does not write to the WAL or add entries to the
@@ -431,8 +521,33 @@ func (p *commitPipeline) prepare(b *Batch, syncWAL bool, noSyncWait bool) (*memT
 	if n == invalidBatchCount {
 		return nil, ErrInvalidBatch
 	}
-	var syncWG *sync.WaitGroup
-	var syncErr *error
+	syncWG, syncErr := prepareCommitWait(b, syncWAL, noSyncWait)
+
+	p.mu.Lock()
+
+	// Enqueue the batch in the pending queue. Note that while the pending queue
+	// is lock-free, we want the order of batches to be the same as the sequence
+	// number order.
+	p.pending.enqueue(b)
+
+	// Assign the batch a sequence number. Note that we use atomic operations
+	// here to handle concurrent reads of logSeqNum. commitPipeline.mu provides
+	// mutual exclusion for other goroutines writing to logSeqNum.
+	b.setSeqNum(p.env.logSeqNum.Add(base.SeqNum(n)) - base.SeqNum(n))
+
+	// Write the data to the WAL.
+	mem, err := p.env.write(b, syncWG, syncErr)
+
+	p.mu.Unlock()
+
+	return mem, err
+}
+
+// prepareCommitWait sets up the batch's wait groups for the commit, returning
+// the wait group and error the WAL sync should signal, if any.
+func prepareCommitWait(
+	b *Batch, syncWAL bool, noSyncWait bool,
+) (syncWG *sync.WaitGroup, syncErr *error) {
 	switch {
 	case !syncWAL:
 		// Only need to wait for the publish.
@@ -451,25 +566,82 @@ func (p *commitPipeline) prepare(b *Batch, syncWAL bool, noSyncWait bool) (*memT
 		// Must wait for both the publish and the WAL fsync.
 		b.commit.Add(2)
 	}
+	return syncWG, syncErr
+}
 
-	p.mu.Lock()
-
-	// Enqueue the batch in the pending queue. Note that while the pending queue
-	// is lock-free, we want the order of batches to be the same as the sequence
-	// number order.
-	p.pending.enqueue(b)
-
-	// Assign the batch a sequence number. Note that we use atomic operations
-	// here to handle concurrent reads of logSeqNum. commitPipeline.mu provides
-	// mutual exclusion for other goroutines writing to logSeqNum.
-	b.setSeqNum(p.env.logSeqNum.Add(base.SeqNum(n)) - base.SeqNum(n))
+// prepareGroup is the pipelined counterpart of prepare. The batch joins the
+// next write group, and is written to the WAL either by the leader of that
+// group or, if it's the group's leader, by the calling goroutine.
+func (p *commitPipeline) prepareGroup(b *Batch, syncWAL bool, noSyncWait bool) (*memTable, error) {
+	if uint64(b.Count()) == invalidBatchCount {
+		return nil, ErrInvalidBatch
+	}
+	w := pipelinedWritePool.Get().(*pipelinedWrite)
+	w.b = b
+	w.syncWG, w.syncErr = prepareCommitWait(b, syncWAL, noSyncWait)
+	w.written.Add(1)
+
+	p.writeGroup.Lock()
+	p.writeGroup.queue = append(p.writeGroup.queue, w)
+	leader := !p.writeGroup.writing
+	p.writeGroup.writing = true
+	p.writeGroup.Unlock()
+
+	if leader {
+		// The batch leads its group right away, so nothing else will signal
+		// it.
+		w.written.Done()
+	} else {
+		w.written.Wait()
+		leader = w.leader
+	}
+	if leader {
+		p.writeGroupToWAL(w)
+	}
+	mem, err := w.mem, w.err
+	w.b, w.syncWG, w.syncErr, w.leader, w.mem, w.err = nil, nil, nil, false, nil, nil
+	pipelinedWritePool.Put(w)
+	return mem, err
+}
 
-	// Write the data to the WAL.
-	mem, err := p.env.write(b, syncWG, syncErr)
+// writeGroupToWAL sequences and writes the queued write group, which leader
+// leads, to the WAL. Before returning, it hands the WAL to the leader of the
+// next group and wakes the other batches of the group so that they can apply
+// themselves to the memtable.
+func (p *commitPipeline) writeGroupToWAL(leader *pipelinedWrite) {
+	p.writeGroup.Lock()
+	group := p.writeGroup.queue
+	p.writeGroup.queue = nil
+	p.writeGroup.Unlock()
 
+	p.mu.Lock()
+	for _, w := range group {
+		// As in prepare, the pending queue and the sequence numbers must have
+		// the same order as the WAL.
+		p.pending.enqueue(w.b)
+		n := base.SeqNum(w.b.Count())
+		w.b.setSeqNum(p.env.logSeqNum.Add(n) - n)
+		w.mem, w.err = p.env.write(w.b, w.syncWG, w.syncErr)
+	}
 	p.mu.Unlock()
 
-	return mem, err
+	p.writeGroup.Lock()
+	if len(p.writeGroup.queue) > 0 {
+		next := p.writeGroup.queue[0]
+		next.leader = true
+		next.written.Done()
+	} else {
+		p.writeGroup.writing = false
+	}
+	p.writeGroup.Unlock()
+
+	// NB: A batch's goroutine may release its pipelinedWrite as soon as it's
+	// woken, so it must not be accessed afterwards.
+	for _, w := range group {
+		if w != leader {
+			w.written.Done()
+		}
+	}
 }
 
 func (p *commitPipeline) publish(b *Batch) {
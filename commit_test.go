@@ -32,6 +32,7 @@ type testCommitEnv struct {
 		buf []uint64
 	}
 	queueSemChan chan struct{}
+	pipelined    bool
 }
 
 func (e *testCommitEnv) env() commitEnv {
@@ -40,6 +41,7 @@ func (e *testCommitEnv) env() commitEnv {
 		visibleSeqNum: &e.visibleSeqNum,
 		apply:         e.apply,
 		write:         e.write,
+		pipelined:     e.pipelined,
 	}
 }
 
@@ -84,42 +86,47 @@ func TestCommitQueue(t *testing.T) {
 }
 
 func TestCommitPipeline(t *testing.T) {
-	var e testCommitEnv
-	p := newCommitPipeline(e.env())
+	for _, pipelined := range []bool{false, true} {
+		t.Run(fmt.Sprintf("pipelined=%t", pipelined), func(t *testing.T) {
+			var e testCommitEnv
+			e.pipelined = pipelined
+			p := newCommitPipeline(e.env())
 
-	n := 10000
-	if invariants.RaceEnabled {
-		// Under race builds we have to limit the concurrency or we hit the
-		// following error:
-		//
-		//   race: limit on 8128 simultaneously alive goroutines is exceeded, dying
-		n = 1000
-	}
+			n := 10000
+			if invariants.RaceEnabled {
+				// Under race builds we have to limit the concurrency or we hit the
+				// following error:
+				//
+				//   race: limit on 8128 simultaneously alive goroutines is exceeded, dying
+				n = 1000
+			}
 
-	var wg sync.WaitGroup
-	wg.Add(n)
-	for i := 0; i < n; i++ {
-		go func(i int) {
-			defer wg.Done()
-			var b Batch
-			_ = b.Set([]byte(fmt.Sprint(i)), nil, nil)
-			_ = p.Commit(&b, false, false)
-		}(i)
-	}
-	wg.Wait()
+			var wg sync.WaitGroup
+			wg.Add(n)
+			for i := 0; i < n; i++ {
+				go func(i int) {
+					defer wg.Done()
+					var b Batch
+					_ = b.Set([]byte(fmt.Sprint(i)), nil, nil)
+					_ = p.Commit(&b, false, false)
+				}(i)
+			}
+			wg.Wait()
 
-	if s := e.writeCount.Load(); uint64(n) != s {
-		t.Fatalf("expected %d written batches, but found %d", n, s)
-	}
-	if n != len(e.applyBuf.buf) {
-		t.Fatalf("expected %d written batches, but found %d",
-			n, len(e.applyBuf.buf))
-	}
-	if s := e.logSeqNum.Load(); base.SeqNum(n) != s {
-		t.Fatalf("expected %d, but found %d", n, s)
-	}
-	if s := e.visibleSeqNum.Load(); base.SeqNum(n) != s {
-		t.Fatalf("expected %d, but found %d", n, s)
+			if s := e.writeCount.Load(); uint64(n) != s {
+				t.Fatalf("expected %d written batches, but found %d", n, s)
+			}
+			if n != len(e.applyBuf.buf) {
+				t.Fatalf("expected %d written batches, but found %d",
+					n, len(e.applyBuf.buf))
+			}
+			if s := e.logSeqNum.Load(); base.SeqNum(n) != s {
+				t.Fatalf("expected %d, but found %d", n, s)
+			}
+			if s := e.visibleSeqNum.Load(); base.SeqNum(n) != s {
+				t.Fatalf("expected %d, but found %d", n, s)
+			}
+		})
 	}
 }
 
@@ -133,9 +140,12 @@ func TestCommitPipelineSync(t *testing.T) {
 		n = 1000
 	}
 
-	for _, noSyncWait := range []bool{false, true} {
-		t.Run(fmt.Sprintf("no-sync-wait=%t", noSyncWait), func(t *testing.T) {
-			var e testCommitEnv
+	for _, c := range []struct{ noSyncWait, pipelined bool }{
+		{false, false}, {true, false}, {false, true}, {true, true},
+	} {
+		noSyncWait := c.noSyncWait
+		t.Run(fmt.Sprintf("no-sync-wait=%t/pipelined=%t", noSyncWait, c.pipelined), func(t *testing.T) {
+			e := testCommitEnv{pipelined: c.pipelined}
 			p := newCommitPipeline(e.env())
 			e.queueSemChan = p.logSyncQSem
 
@@ -170,6 +180,84 @@ func TestCommitPipelineSync(t *testing.T) {
 	}
 }
 
+func TestCommitPipelineWriteGroups(t *testing.T) {
+	var logSeqNum, visibleSeqNum base.AtomicSeqNum
+	var mu sync.Mutex
+	var written []base.SeqNum
+	writeStarted := make(chan struct{}, 1)
+	releaseWrite := make(chan struct{})
+	releaseApply := make(chan struct{})
+	p := newCommitPipeline(commitEnv{
+		logSeqNum:     &logSeqNum,
+		visibleSeqNum: &visibleSeqNum,
+		apply: func(b *Batch, mem *memTable) error {
+			if b.SeqNum() == 0 {
+				<-releaseApply
+			}
+			return nil
+		},
+		write: func(b *Batch, wg *sync.WaitGroup, err *error) (*memTable, error) {
+			mu.Lock()
+			written = append(written, b.SeqNum())
+			first := len(written) == 1
+			mu.Unlock()
+			if first {
+				writeStarted <- struct{}{}
+				<-releaseWrite
+			}
+			return nil, nil
+		},
+		pipelined: true,
+	})
+	numWritten := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(written)
+	}
+
+	var wg sync.WaitGroup
+	commit := func(i int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var b Batch
+			require.NoError(t, b.Set([]byte(fmt.Sprint(i)), nil, nil))
+			require.NoError(t, p.Commit(&b, false, false))
+		}()
+	}
+
+	// The first batch leads its group. While it's writing to the WAL, the
+	// batches that arrive queue up as the next group.
+	commit(0)
+	<-writeStarted
+	const n = 10
+	for i := 1; i <= n; i++ {
+		commit(i)
+	}
+	require.Eventually(t, func() bool {
+		p.writeGroup.Lock()
+		defer p.writeGroup.Unlock()
+		return len(p.writeGroup.queue) == n
+	}, 10*time.Second, time.Millisecond)
+	require.Equal(t, 1, numWritten())
+
+	// The next group is written to the WAL while the first batch is still
+	// being applied to the memtable.
+	close(releaseWrite)
+	require.Eventually(t, func() bool { return numWritten() == n+1 }, 10*time.Second, time.Millisecond)
+	require.Equal(t, base.SeqNum(0), visibleSeqNum.Load())
+
+	close(releaseApply)
+	wg.Wait()
+	require.Equal(t, base.SeqNum(n+1), visibleSeqNum.Load())
+	for i, seqNum := range written {
+		require.Equal(t, base.SeqNum(i), seqNum)
+	}
+	p.writeGroup.Lock()
+	defer p.writeGroup.Unlock()
+	require.False(t, p.writeGroup.writing)
+}
+
 func TestCommitPipelineAllocateSeqNum(t *testing.T) {
 	var e testCommitEnv
 	p := newCommitPipeline(e.env())
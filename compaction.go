@@ -11,6 +11,7 @@ import (
 	"math"
 	"runtime/pprof"
 	"slices"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -82,8 +83,10 @@ type compactionLevel struct {
 	level int
 	files manifest.LevelSlice
 	// l0SublevelInfo contains information about L0 sublevels being compacted.
-	// It's only set for the start level of a compaction starting out of L0 and
-	// is nil for all other compactions.
+	// It's only set for the start level of a compaction starting out of L0, or
+	// out of a level holding multiple sorted runs under CompactionStyleTiered,
+	// in which case it holds the level's runs. It's nil for all other
+	// compactions.
 	l0SublevelInfo []sublevelInfo
 }
 
@@ -105,7 +108,7 @@ type compactionWritable struct {
 	objstorage.Writable
 
 	versions *versionSet
-	written  *int64
+	written  *atomic.Int64
 }
 
 // Write is part of the objstorage.Writable interface.
@@ -114,7 +117,7 @@ func (c *compactionWritable) Write(p []byte) error {
 		return err
 	}
 
-	*c.written += int64(len(p))
+	c.written.Add(int64(len(p)))
 	c.versions.incrementCompactionBytes(int64(len(p)))
 	return nil
 }
@@ -138,6 +141,15 @@ const (
 	compactionKindTombstoneDensity
 	compactionKindRewrite
 	compactionKindIngestedFlushable
+	// compactionKindFIFO denotes a compaction that deletes the oldest tables
+	// under CompactionStyleFIFO, because the tables' total size or age exceeds
+	// the configured retention limits. Like compactionKindDeleteOnly, it only
+	// deletes input files.
+	compactionKindFIFO
+	// compactionKindPeriodic denotes a compaction that rewrites a file in place
+	// because it was created longer than Options.Experimental.PeriodicCompactionAge
+	// ago.
+	compactionKindPeriodic
 )
 
 func (k compactionKind) String() string {
@@ -162,6 +174,10 @@ func (k compactionKind) String() string {
 		return "ingested-flushable"
 	case compactionKindCopy:
 		return "copy"
+	case compactionKindFIFO:
+		return "fifo"
+	case compactionKindPeriodic:
+		return "periodic"
 	}
 	return "?"
 }
@@ -186,13 +202,11 @@ type compaction struct {
 	formatKey base.FormatKey
 	logger    Logger
 	version   *version
-	stats     base.InternalIteratorStats
 	beganAt   time.Time
 	// versionEditApplied is set to true when a compaction has completed and the
 	// resulting version has been installed (if successful), but the compaction
 	// goroutine is still cleaning up (eg, deleting obsolete files).
 	versionEditApplied bool
-	bufferPool         sstable.BufferPool
 
 	// startLevel is the level that is being compacted. Inputs from startLevel
 	// and outputLevel will be merged to produce a set of outputLevel files.
@@ -221,18 +235,14 @@ type compaction struct {
 
 	// flushing contains the flushables (aka memtables) that are being flushed.
 	flushing flushableList
-	// bytesWritten contains the number of bytes that have been written to outputs.
-	bytesWritten int64
+	// bytesWritten contains the number of bytes that have been written to
+	// outputs. It's updated concurrently by the subcompactions.
+	bytesWritten atomic.Int64
 
 	// The boundaries of the input data.
 	smallest InternalKey
 	largest  InternalKey
 
-	// A list of fragment iterators to close when the compaction finishes. Used by
-	// input iteration to keep rangeDelIters open for the lifetime of the
-	// compaction, and only close them when the compaction finishes.
-	closers []*noCloseIter
-
 	// grandparents are the tables in level+2 that overlap with the files being
 	// compacted. Used to determine output table boundaries. Do not assume that the actual files
 	// in the grandparent when this compaction finishes will be the same.
@@ -320,6 +330,12 @@ func (c *compaction) userKeyBounds() base.UserKeyBounds {
 func newCompaction(
 	pc *pickedCompaction, opts *Options, beganAt time.Time, provider objstorage.Provider,
 ) *compaction {
+	if pc.kind == compactionKindFIFO {
+		// FIFO compactions only delete their inputs, and have no output level.
+		c := newDeleteOnlyCompaction(opts, pc.version, pc.inputs, beganAt)
+		c.kind = compactionKindFIFO
+		return c
+	}
 	c := &compaction{
 		kind:              compactionKindDefault,
 		cmp:               pc.cmp,
@@ -351,9 +367,7 @@ func newCompaction(
 	if c.outputLevel.level+1 < numLevels {
 		c.grandparents = c.version.Overlaps(c.outputLevel.level+1, c.userKeyBounds())
 	}
-	c.delElision, c.rangeKeyElision = compact.SetupTombstoneElision(
-		c.cmp, c.version, c.outputLevel.level, base.UserKeyBoundsFromInternal(c.smallest, c.largest),
-	)
+	c.setupTombstoneElision(opts)
 	c.kind = pc.kind
 
 	if c.kind == compactionKindDefault && c.outputLevel.files.Empty() && !c.hasExtraLevelData() &&
@@ -579,6 +593,11 @@ func newFlush(
 				return nil, err
 			}
 		}
+		if rangeMergeIter := f.newRangeMergeIter(nil); rangeMergeIter != nil {
+			if err := updateRangeBounds(rangeMergeIter); err != nil {
+				return nil, err
+			}
+		}
 		flushingBytes += f.inuseBytes()
 	}
 
@@ -595,16 +614,16 @@ func newFlush(
 }
 
 func (c *compaction) hasExtraLevelData() bool {
-	if len(c.extraLevels) == 0 {
-		// not a multi level compaction
-		return false
-	} else if c.extraLevels[0].files.Empty() {
-		// a multi level compaction without data in the intermediate input level;
-		// e.g. for a multi level compaction with levels 4,5, and 6, this could
-		// occur if there is no files to compact in 5, or in 5 and 6 (i.e. a move).
-		return false
+	// A multi level compaction may have no data in the intermediate input
+	// levels; e.g. for a multi level compaction with levels 4,5, and 6, this
+	// could occur if there is no files to compact in 5, or in 5 and 6 (i.e. a
+	// move).
+	for _, cl := range c.extraLevels {
+		if !cl.files.Empty() {
+			return true
+		}
 	}
-	return true
+	return false
 }
 
 // errorOnUserKeyOverlap returns an error if the last two written sstables in
@@ -625,6 +644,36 @@ func (c *compaction) errorOnUserKeyOverlap(ve *versionEdit) error {
 	return nil
 }
 
+// setupTombstoneElision sets up the compaction's TombstoneElision policies
+// from the in-use key ranges of the levels below the output level.
+//
+// Under CompactionStyleTiered, the output level may also hold older sorted
+// runs that overlap the compaction but aren't among its inputs. The keys
+// they hold may be shadowed by the compaction's tombstones, so their key
+// ranges are in use too.
+func (c *compaction) setupTombstoneElision(opts *Options) {
+	bounds := base.UserKeyBoundsFromInternal(c.smallest, c.largest)
+	if opts.Experimental.CompactionStyle != CompactionStyleTiered || c.outputLevel.level == 0 {
+		c.delElision, c.rangeKeyElision = compact.SetupTombstoneElision(
+			c.cmp, c.version, c.outputLevel.level, bounds,
+		)
+		return
+	}
+	var inputFiles int
+	for i := range c.inputs {
+		if c.inputs[i].level == c.outputLevel.level {
+			inputFiles += c.inputs[i].files.Len()
+		}
+	}
+	startLevel := c.outputLevel.level + 1
+	if overlaps := c.version.Overlaps(c.outputLevel.level, bounds); overlaps.Len() > inputFiles {
+		startLevel = c.outputLevel.level
+	}
+	c.delElision, c.rangeKeyElision = compact.SetupTombstoneElisionFromLevel(
+		c.cmp, c.version, startLevel, bounds,
+	)
+}
+
 // allowZeroSeqNum returns true if seqnum's can be zeroed if there are no
 // snapshots requiring them to be kept. It performs this determination by
 // looking at the TombstoneElision values which are set up based on sstables
@@ -641,56 +690,54 @@ func (c *compaction) allowZeroSeqNum() bool {
 	return len(c.flushing) == 0 && c.delElision.ElidesEverything() && c.rangeKeyElision.ElidesEverything()
 }
 
-// newInputIters returns an iterator over all the input tables in a compaction.
-func (c *compaction) newInputIters(
+// newInputIters returns an iterator over all the input tables in a compaction,
+// restricted to the bounds of the subcompaction.
+func (s *subcompaction) newInputIters(
 	newIters tableNewIters, newRangeKeyIter keyspanimpl.TableNewSpanIter,
 ) (
 	pointIter internalIterator,
-	rangeDelIter, rangeKeyIter keyspan.FragmentIterator,
+	rangeDelIter, rangeKeyIter, rangeMergeIter keyspan.FragmentIterator,
 	retErr error,
 ) {
+	c := s.c
 	// Validate the ordering of compaction input files for defense in depth.
 	if len(c.flushing) == 0 {
-		if c.startLevel.level >= 0 {
+		// The runs of a start level below L0 holding multiple sorted runs may
+		// overlap one another, so they're checked one by one below.
+		if c.startLevel.level == 0 || (c.startLevel.level > 0 && c.startLevel.l0SublevelInfo == nil) {
 			err := manifest.CheckOrdering(c.cmp, c.formatKey,
 				manifest.Level(c.startLevel.level), c.startLevel.files.Iter())
 			if err != nil {
-				return nil, nil, nil, err
+				return nil, nil, nil, nil, err
 			}
 		}
 		err := manifest.CheckOrdering(c.cmp, c.formatKey,
 			manifest.Level(c.outputLevel.level), c.outputLevel.files.Iter())
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, err
 		}
-		if c.startLevel.level == 0 {
-			if c.startLevel.l0SublevelInfo == nil {
-				panic("l0SublevelInfo not created for compaction out of L0")
-			}
-			for _, info := range c.startLevel.l0SublevelInfo {
-				err := manifest.CheckOrdering(c.cmp, c.formatKey,
-					info.sublevel, info.Iter())
-				if err != nil {
-					return nil, nil, nil, err
-				}
-			}
+		if c.startLevel.level == 0 && c.startLevel.l0SublevelInfo == nil {
+			panic("l0SublevelInfo not created for compaction out of L0")
 		}
-		if len(c.extraLevels) > 0 {
-			if len(c.extraLevels) > 1 {
-				panic("n>2 multi level compaction not implemented yet")
+		for _, info := range c.startLevel.l0SublevelInfo {
+			err := manifest.CheckOrdering(c.cmp, c.formatKey,
+				info.sublevel, info.Iter())
+			if err != nil {
+				return nil, nil, nil, nil, err
 			}
-			interLevel := c.extraLevels[0]
+		}
+		for _, interLevel := range c.extraLevels {
 			err := manifest.CheckOrdering(c.cmp, c.formatKey,
 				manifest.Level(interLevel.level), interLevel.files.Iter())
 			if err != nil {
-				return nil, nil, nil, err
+				return nil, nil, nil, nil, err
 			}
 		}
 	}
 
-	// There are three classes of keys that a compaction needs to process: point
-	// keys, range deletion tombstones and range keys. Collect all iterators for
-	// all these classes of keys from all the levels. We'll aggregate them
+	// There are four classes of keys that a compaction needs to process: point
+	// keys, range deletion tombstones, range merges and range keys. Collect all
+	// iterators for all these classes of keys from all the levels. We'll aggregate them
 	// together farther below.
 	//
 	// numInputLevels is an approximation of the number of iterator levels. Due
@@ -700,6 +747,7 @@ func (c *compaction) newInputIters(
 	iters := make([]internalIterator, 0, numInputLevels)
 	rangeDelIters := make([]keyspan.FragmentIterator, 0, numInputLevels)
 	rangeKeyIters := make([]keyspan.FragmentIterator, 0, numInputLevels)
+	var rangeMergeIters []keyspan.FragmentIterator
 
 	// If construction of the iterator inputs fails, ensure that we close all
 	// the consitutent iterators.
@@ -713,6 +761,9 @@ func (c *compaction) newInputIters(
 			for _, rangeDelIter := range rangeDelIters {
 				rangeDelIter.Close()
 			}
+			for _, rangeMergeIter := range rangeMergeIters {
+				rangeMergeIter.Close()
+			}
 		}
 	}()
 	iterOpts := IterOptions{
@@ -739,6 +790,9 @@ func (c *compaction) newInputIters(
 			if rangeKeyIter := f.newRangeKeyIter(nil); rangeKeyIter != nil {
 				rangeKeyIters = append(rangeKeyIters, rangeKeyIter)
 			}
+			if rangeMergeIter := f.newRangeMergeIter(nil); rangeMergeIter != nil {
+				rangeMergeIters = append(rangeMergeIters, rangeMergeIter)
+			}
 		}
 	} else {
 		addItersForLevel := func(level *compactionLevel, l manifest.Layer) error {
@@ -749,7 +803,7 @@ func (c *compaction) newInputIters(
 			iters = append(iters, newLevelIter(context.Background(),
 				iterOpts, c.comparer, newIters, level.files.Iter(), l, internalIterOpts{
 					compaction: true,
-					bufferPool: &c.bufferPool,
+					bufferPool: &s.bufferPool,
 				}))
 			// TODO(jackson): Use keyspanimpl.LevelIter to avoid loading all the range
 			// deletions into memory upfront. (See #2015, which reverted this.) There
@@ -788,7 +842,7 @@ func (c *compaction) newInputIters(
 			// mergingIter.
 			iter := level.files.Iter()
 			for f := iter.First(); f != nil; f = iter.Next() {
-				rangeDelIter, err := c.newRangeDelIter(newIters, iter.Take(), iterOpts, l)
+				rangeDelIter, err := s.newRangeDelIter(newIters, iter.Take(), iterOpts, l)
 				if err != nil {
 					// The error will already be annotated with the BackingFileNum, so
 					// we annotate it with the FileNum.
@@ -798,7 +852,23 @@ func (c *compaction) newInputIters(
 					continue
 				}
 				rangeDelIters = append(rangeDelIters, rangeDelIter)
-				c.closers = append(c.closers, rangeDelIter)
+				s.closers = append(s.closers, rangeDelIter)
+			}
+
+			// Add the range merge iterator for each file that has range
+			// merges. The compaction iterator reads all of the range merges
+			// upfront, so unlike range deletions they need not be kept open.
+			for f := iter.First(); f != nil; f = iter.Next() {
+				if !f.HasRangeMerges {
+					continue
+				}
+				rangeMergeIter, err := s.newRangeMergeIter(newIters, iter.Take(), iterOpts, l)
+				if err != nil {
+					return errors.Wrapf(err, "pebble: could not open table %s", errors.Safe(f.FileNum))
+				}
+				if rangeMergeIter != nil {
+					rangeMergeIters = append(rangeMergeIters, rangeMergeIter)
+				}
 			}
 
 			// Check if this level has any range keys.
@@ -822,7 +892,7 @@ func (c *compaction) newInputIters(
 					// requires the range keys to be held in memory for up to the
 					// lifetime of the compaction.
 					noCloseIter := &noCloseIter{rangeKeyIter}
-					c.closers = append(c.closers, noCloseIter)
+					s.closers = append(s.closers, noCloseIter)
 
 					// We do not need to truncate range keys to sstable boundaries, or
 					// only read within the file's atomic compaction units, unlike with
@@ -842,20 +912,20 @@ func (c *compaction) newInputIters(
 
 		for i := range c.inputs {
 			// If the level is annotated with l0SublevelInfo, expand it into one
-			// level per sublevel.
+			// level per sublevel (or sorted run).
 			// TODO(jackson): Perform this expansion even earlier when we pick the
 			// compaction?
 			if len(c.inputs[i].l0SublevelInfo) > 0 {
-				for _, info := range c.startLevel.l0SublevelInfo {
-					sublevelCompactionLevel := &compactionLevel{0, info.LevelSlice, nil}
+				for _, info := range c.inputs[i].l0SublevelInfo {
+					sublevelCompactionLevel := &compactionLevel{c.inputs[i].level, info.LevelSlice, nil}
 					if err := addItersForLevel(sublevelCompactionLevel, info.sublevel); err != nil {
-						return nil, nil, nil, err
+						return nil, nil, nil, nil, err
 					}
 				}
 				continue
 			}
 			if err := addItersForLevel(&c.inputs[i], manifest.Level(c.inputs[i].level)); err != nil {
-				return nil, nil, nil, err
+				return nil, nil, nil, nil, err
 			}
 		}
 	}
@@ -866,7 +936,7 @@ func (c *compaction) newInputIters(
 	// iter.
 	pointIter = iters[0]
 	if len(iters) > 1 {
-		pointIter = newMergingIter(c.logger, &c.stats, c.cmp, nil, iters...)
+		pointIter = newMergingIter(c.logger, &s.stats, c.cmp, nil, iters...)
 	}
 
 	// In normal operation, levelIter iterates over the point operations in a
@@ -898,17 +968,37 @@ func (c *compaction) newInputIters(
 		di.Init(c.comparer, mi, keyspan.DefragmentInternal, keyspan.StaticDefragmentReducer, new(keyspan.DefragmentingBuffers))
 		rangeKeyIter = di
 	}
-	return pointIter, rangeDelIter, rangeKeyIter, nil
+
+	// The range merge levels are combined with a keyspanimpl.MergingIter, like
+	// range deletions.
+	if len(rangeMergeIters) > 0 {
+		mi := &keyspanimpl.MergingIter{}
+		mi.Init(c.comparer, keyspan.NoopTransform, new(keyspanimpl.MergingBuffers), rangeMergeIters...)
+		rangeMergeIter = mi
+	}
+	if s.lower != nil || s.upper != nil {
+		pointIter = &subcompactionIter{internalIterator: pointIter, cmp: c.cmp, lower: s.lower, upper: s.upper}
+		if rangeDelIter != nil {
+			rangeDelIter = keyspan.Truncate(c.cmp, rangeDelIter, s.bounds)
+		}
+		if rangeKeyIter != nil {
+			rangeKeyIter = keyspan.Truncate(c.cmp, rangeKeyIter, s.bounds)
+		}
+		if rangeMergeIter != nil {
+			rangeMergeIter = keyspan.Truncate(c.cmp, rangeMergeIter, s.bounds)
+		}
+	}
+	return pointIter, rangeDelIter, rangeKeyIter, rangeMergeIter, nil
 }
 
-func (c *compaction) newRangeDelIter(
+func (s *subcompaction) newRangeDelIter(
 	newIters tableNewIters, f manifest.LevelFile, opts IterOptions, l manifest.Layer,
 ) (*noCloseIter, error) {
 	opts.layer = l
 	iterSet, err := newIters(context.Background(), f.FileMetadata, &opts,
 		internalIterOpts{
 			compaction: true,
-			bufferPool: &c.bufferPool,
+			bufferPool: &s.bufferPool,
 		}, iterRangeDeletions)
 	if err != nil {
 		return nil, err
@@ -923,6 +1013,21 @@ func (c *compaction) newRangeDelIter(
 	return &noCloseIter{iterSet.rangeDeletion}, nil
 }
 
+func (s *subcompaction) newRangeMergeIter(
+	newIters tableNewIters, f manifest.LevelFile, opts IterOptions, l manifest.Layer,
+) (keyspan.FragmentIterator, error) {
+	opts.layer = l
+	iterSet, err := newIters(context.Background(), f.FileMetadata, &opts,
+		internalIterOpts{
+			compaction: true,
+			bufferPool: &s.bufferPool,
+		}, iterRangeMerges)
+	if err != nil {
+		return nil, err
+	}
+	return iterSet.rangeMerge, nil
+}
+
 func (c *compaction) String() string {
 	if len(c.flushing) != 0 {
 		return "flush\n"
@@ -1244,6 +1349,10 @@ func (d *DB) runIngestFlush(c *compaction) (*manifest.VersionEdit, error) {
 			ingestFlushable.exciseSpan.Contains(d.cmp, file.FileMetadata.Smallest) &&
 			ingestFlushable.exciseSpan.Contains(d.cmp, file.FileMetadata.Largest) {
 			level = 6
+		} else if d.opts.Experimental.CompactionStyle == CompactionStyleTiered {
+			// Under CompactionStyleTiered, ingested sstables are always added to
+			// L0. See ingestApply.
+			level = 0
 		} else {
 			// TODO(radu): this can perform I/O; we should not do this while holding DB.mu.
 			lsmOverlap, err := overlapChecker.DetermineLSMOverlap(ctx, file.UserKeyBounds())
@@ -1722,6 +1831,9 @@ func (d *DB) maybeScheduleCompactionPicker(
 		diskAvailBytes:          d.diskAvailBytes.Load(),
 		earliestSnapshotSeqNum:  d.mu.snapshots.earliest(),
 		earliestUnflushedSeqNum: d.getEarliestUnflushedSeqNumLocked(),
+		levelMetrics:            &d.mu.versions.metrics.Levels,
+		now:                     d.timeNow(),
+		priorities:              d.mu.compact.priorities,
 	}
 
 	if d.mu.compact.compactingCount < maxCompactions {
@@ -1745,6 +1857,9 @@ func (d *DB) maybeScheduleCompactionPicker(
 		for !d.opts.DisableAutomaticCompactions && d.mu.compact.compactingCount < maxCompactions &&
 			d.tryScheduleAutoCompaction(env, pickFunc) {
 		}
+		if !d.opts.DisableAutomaticCompactions {
+			d.maybeScheduleFIFOAgeCheck(env.now)
+		}
 	}
 
 	for len(d.mu.compact.downloads) > 0 && d.mu.compact.downloadingCount < maxDownloads &&
@@ -2133,13 +2248,18 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 		d.mu.snapshots.cumulativePinnedCount += stats.CumulativePinnedKeys
 		d.mu.snapshots.cumulativePinnedSize += stats.CumulativePinnedSize
 		d.mu.versions.metrics.Keys.MissizedTombstonesCount += stats.CountMissizedDels
+		if c.kind == compactionKindFIFO {
+			for _, cl := range c.inputs {
+				d.mu.versions.metrics.Compact.FIFOBytesDropped += cl.files.SizeSum()
+			}
+		}
 	}
 
 	// NB: clearing compacting state must occur before updating the read state;
 	// L0Sublevels initialization depends on it.
 	d.clearCompactingState(c, err != nil)
 	d.mu.versions.incrementCompactions(c.kind, c.extraLevels, c.pickerMetrics)
-	d.mu.versions.incrementCompactionBytes(-c.bytesWritten)
+	d.mu.versions.incrementCompactionBytes(-c.bytesWritten.Load())
 
 	info.TotalDuration = d.timeNow().Sub(c.beganAt)
 	d.opts.EventListener.CompactionEnd(info)
@@ -2215,6 +2335,7 @@ func (d *DB) runCopyCompaction(
 		Virtual:               inputMeta.Virtual,
 		SyntheticPrefix:       inputMeta.SyntheticPrefix,
 		SyntheticSuffix:       inputMeta.SyntheticSuffix,
+		HasRangeMerges:        inputMeta.HasRangeMerges,
 	}
 	if inputMeta.HasPointKeys {
 		newMeta.ExtendPointKeyBounds(c.cmp, inputMeta.SmallestPointKey, inputMeta.LargestPointKey)
@@ -2296,7 +2417,7 @@ func (d *DB) runCopyCompaction(
 			var err error
 			wrote, err = sstable.CopySpan(ctx,
 				src, r.UnsafeReader(), d.opts.MakeReaderOptions(),
-				w, d.opts.MakeWriterOptions(c.outputLevel.level, d.FormatMajorVersion().MaxTableFormat()),
+				w, d.makeWriterOptions(c.outputLevel.level, d.FormatMajorVersion().MaxTableFormat()),
 				start, end,
 			)
 			return err
@@ -2410,7 +2531,7 @@ func (d *DB) runCompaction(
 	jobID JobID, c *compaction,
 ) (ve *versionEdit, stats compact.Stats, retErr error) {
 	switch c.kind {
-	case compactionKindDeleteOnly:
+	case compactionKindDeleteOnly, compactionKindFIFO:
 		return d.runDeleteOnlyCompaction(jobID, c)
 	case compactionKindMove:
 		return d.runMoveCompaction(jobID, c)
@@ -2450,12 +2571,23 @@ func (d *DB) runCompaction(
 		tableFormat = sstable.TableFormatPebblev2
 	}
 
+	// A large compaction may be split into subcompactions that run
+	// concurrently. Each subcompaction beyond the first occupies one of the
+	// compaction concurrency slots until the compaction completes. Note that
+	// this deferred function runs after the deferred d.mu.Lock() below.
+	splitKeys := d.subcompactionSplitKeys(c)
+	if n := len(splitKeys); n > 0 {
+		d.mu.compact.compactingCount += n
+		d.mu.versions.metrics.Compact.SubcompactionCount += int64(n + 1)
+		defer func() { d.mu.compact.compactingCount -= n }()
+	}
+
 	// Release the d.mu lock while doing I/O.
 	// Note the unusual order: Unlock and then Lock.
 	d.mu.Unlock()
 	defer d.mu.Lock()
 
-	result := d.compactAndWrite(jobID, c, snapshots, tableFormat)
+	result := d.compactAndWrite(jobID, c, snapshots, tableFormat, splitKeys)
 	if result.Err == nil {
 		ve, result.Err = c.makeVersionEdit(result)
 	}
@@ -2472,10 +2604,47 @@ func (d *DB) runCompaction(
 }
 
 // compactAndWrite runs the data part of a compaction, where we set up a
-// compaction iterator and use it to write output tables.
+// compaction iterator and use it to write output tables. If splitKeys is
+// non-empty, the compaction is split into subcompactions at the split keys,
+// which are run concurrently.
 func (d *DB) compactAndWrite(
-	jobID JobID, c *compaction, snapshots compact.Snapshots, tableFormat sstable.TableFormat,
+	jobID JobID,
+	c *compaction,
+	snapshots compact.Snapshots,
+	tableFormat sstable.TableFormat,
+	splitKeys [][]byte,
 ) (result compact.Result) {
+	c.allowedZeroSeqNum = c.allowZeroSeqNum()
+	subs := c.newSubcompactions(splitKeys)
+	if len(subs) == 1 {
+		result = d.runSubcompaction(jobID, subs[0], snapshots, tableFormat)
+	} else {
+		results := make([]compact.Result, len(subs))
+		var wg sync.WaitGroup
+		for i := range subs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				pprof.Do(context.Background(), compactLabels, func(context.Context) {
+					results[i] = d.runSubcompaction(jobID, subs[i], snapshots, tableFormat)
+				})
+			}(i)
+		}
+		wg.Wait()
+		result = mergeSubcompactionResults(results)
+	}
+	if result.Err == nil {
+		result.Err = d.objProvider.Sync()
+	}
+	return result
+}
+
+// runSubcompaction sets up a compaction iterator over the key range of a
+// subcompaction and uses it to write output tables.
+func (d *DB) runSubcompaction(
+	jobID JobID, s *subcompaction, snapshots compact.Snapshots, tableFormat sstable.TableFormat,
+) compact.Result {
+	c := s.c
 	// Compactions use a pool of buffers to read blocks, avoiding polluting the
 	// block cache with blocks that will not be read again. We initialize the
 	// buffer pool with a size 12. This initial size does not need to be
@@ -2499,19 +2668,18 @@ func (d *DB) compactAndWrite(
 	// a 12-buffer pool is expected to be within reason, even if all the buffers
 	// grow to the typical size of an index block (256 KiB) which would
 	// translate to 3 MiB per compaction.
-	c.bufferPool.Init(12)
-	defer c.bufferPool.Release()
+	s.bufferPool.Init(12)
+	defer s.bufferPool.Release()
 
-	pointIter, rangeDelIter, rangeKeyIter, err := c.newInputIters(d.newIters, d.tableNewRangeKeyIter)
+	pointIter, rangeDelIter, rangeKeyIter, rangeMergeIter, err := s.newInputIters(d.newIters, d.tableNewRangeKeyIter)
 	defer func() {
-		for _, closer := range c.closers {
+		for _, closer := range s.closers {
 			closer.FragmentIterator.Close()
 		}
 	}()
 	if err != nil {
 		return compact.Result{Err: err}
 	}
-	c.allowedZeroSeqNum = c.allowZeroSeqNum()
 	cfg := compact.IterConfig{
 		Comparer:                               c.comparer,
 		Merge:                                  d.merge,
@@ -2522,10 +2690,10 @@ func (d *DB) compactAndWrite(
 		IneffectualSingleDeleteCallback:        d.opts.Experimental.IneffectualSingleDeleteCallback,
 		SingleDeleteInvariantViolationCallback: d.opts.Experimental.SingleDeleteInvariantViolationCallback,
 	}
-	iter := compact.NewIter(cfg, pointIter, rangeDelIter, rangeKeyIter)
+	iter := compact.NewIter(cfg, pointIter, rangeDelIter, rangeKeyIter, rangeMergeIter)
 
 	runnerCfg := compact.RunnerConfig{
-		CompactionBounds:           base.UserKeyBoundsFromInternal(c.smallest, c.largest),
+		CompactionBounds:           s.bounds,
 		L0SplitKeys:                c.l0Limits,
 		Grandparents:               c.grandparents,
 		MaxGrandparentOverlapBytes: c.maxOverlapBytes,
@@ -2537,7 +2705,7 @@ func (d *DB) compactAndWrite(
 			return runner.Finish().WithError(ErrCancelledCompaction)
 		}
 		// Create a new table.
-		writerOpts := d.opts.MakeWriterOptions(c.outputLevel.level, tableFormat)
+		writerOpts := d.makeWriterOptions(c.outputLevel.level, tableFormat)
 		objMeta, tw, cpuWorkHandle, err := d.newCompactionOutput(jobID, c, writerOpts)
 		if err != nil {
 			return runner.Finish().WithError(err)
@@ -2545,11 +2713,7 @@ func (d *DB) compactAndWrite(
 		runner.WriteTable(objMeta, tw)
 		d.opts.Experimental.CPUWorkPermissionGranter.CPUWorkDone(cpuWorkHandle)
 	}
-	result = runner.Finish()
-	if result.Err == nil {
-		result.Err = d.objProvider.Sync()
-	}
-	return result
+	return runner.Finish()
 }
 
 // makeVersionEdit creates the version edit for a compaction, based on the
@@ -2573,8 +2737,8 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 		BytesIn:   startLevelBytes,
 		BytesRead: c.outputLevel.files.SizeSum(),
 	}
-	if len(c.extraLevels) > 0 {
-		outputMetrics.BytesIn += c.extraLevels[0].files.SizeSum()
+	for _, cl := range c.extraLevels {
+		outputMetrics.BytesIn += cl.files.SizeSum()
 	}
 	outputMetrics.BytesRead += outputMetrics.BytesIn
 
@@ -2584,8 +2748,10 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 	if len(c.flushing) == 0 && c.metrics[c.startLevel.level] == nil {
 		c.metrics[c.startLevel.level] = &LevelMetrics{}
 	}
+	for _, cl := range c.extraLevels {
+		c.metrics[cl.level] = &LevelMetrics{}
+	}
 	if len(c.extraLevels) > 0 {
-		c.metrics[c.extraLevels[0].level] = &LevelMetrics{}
 		outputMetrics.MultiLevel.BytesInTop = startLevelBytes
 		outputMetrics.MultiLevel.BytesIn = outputMetrics.BytesIn
 		outputMetrics.MultiLevel.BytesRead = outputMetrics.BytesRead
@@ -2626,6 +2792,10 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 		if t.WriterMeta.HasRangeDelKeys {
 			fileMeta.ExtendPointKeyBounds(c.cmp, t.WriterMeta.SmallestRangeDel, t.WriterMeta.LargestRangeDel)
 		}
+		if t.WriterMeta.HasRangeMergeKeys {
+			fileMeta.HasRangeMerges = true
+			fileMeta.ExtendPointKeyBounds(c.cmp, t.WriterMeta.SmallestRangeMerge, t.WriterMeta.LargestRangeMerge)
+		}
 		if t.WriterMeta.HasRangeKeys {
 			fileMeta.ExtendRangeKeyBounds(c.cmp, t.WriterMeta.SmallestRangeKey, t.WriterMeta.LargestRangeKey)
 		}
@@ -10,6 +10,7 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
@@ -38,6 +39,14 @@ type compactionEnv struct {
 	earliestSnapshotSeqNum  base.SeqNum
 	inProgressCompactions   []compactionInfo
 	readCompactionEnv       readCompactionEnv
+	// levelMetrics holds the per-level metrics of the DB, if available. It's
+	// exposed to a custom CompactionPicker.
+	levelMetrics *[numLevels]LevelMetrics
+	// now is the current time, used to determine the age of tables.
+	now time.Time
+	// priorities holds the compaction priorities registered through
+	// DB.SetCompactionPriority.
+	priorities compactionPriorities
 }
 
 type compactionPicker interface {
@@ -48,6 +57,7 @@ type compactionPicker interface {
 	pickElisionOnlyCompaction(env compactionEnv) (pc *pickedCompaction)
 	pickRewriteCompaction(env compactionEnv) (pc *pickedCompaction)
 	pickReadTriggeredCompaction(env compactionEnv) (pc *pickedCompaction)
+	nextFIFOExpiry() (time.Time, bool)
 	forceBaseLevel1()
 }
 
@@ -586,6 +596,10 @@ func newCompactionPickerByScore(
 		virtualBackings: virtualBackings,
 	}
 	p.initLevelMaxBytes(inProgressCompactions)
+	if opts.Experimental.CompactionStyle == CompactionStyleTiered {
+		// Every level holds sorted runs, so L0 is always merged into L1.
+		p.baseLevel = 1
+	}
 	p.initTombstoneDensityAnnotator(opts)
 	return p
 }
@@ -1003,15 +1017,16 @@ func calculateL0UncompensatedScore(
 
 // pickCompactionSeedFile picks a file from `level` in the `vers` to build a
 // compaction around. Currently, this function implements a heuristic similar to
-// RocksDB's kMinOverlappingRatio, seeking to minimize write amplification. This
-// function is linear with respect to the number of files in `level` and
-// `outputLevel`.
+// RocksDB's kMinOverlappingRatio, seeking to minimize write amplification,
+// among the files with the highest compaction priority. This function is
+// linear with respect to the number of files in `level` and `outputLevel`.
 func pickCompactionSeedFile(
 	vers *version,
 	virtualBackings *manifest.VirtualBackings,
 	opts *Options,
 	level, outputLevel int,
 	earliestSnapshotSeqNum base.SeqNum,
+	priorities compactionPriorities,
 ) (manifest.LevelFile, bool) {
 	// Select the file within the level to compact. We want to minimize write
 	// amplification, but also ensure that (a) deletes are propagated to the
@@ -1031,6 +1046,10 @@ func pickCompactionSeedFile(
 	// through compaction, and (b) a fraction of the amount of garbage in the
 	// backing sstable pinned by this (virtual) sstable.
 	//
+	// Files are first ordered by the compaction priority of their key ranges,
+	// as registered through DB.SetCompactionPriority, so the heuristic only
+	// chooses between the files with the highest priority.
+	//
 	// TODO(peter): For concurrent compactions, we may want to try harder to
 	// pick a seed file whose resulting compaction bounds do not overlap with
 	// an in-progress compaction.
@@ -1041,6 +1060,7 @@ func pickCompactionSeedFile(
 
 	var file manifest.LevelFile
 	smallestRatio := uint64(math.MaxUint64)
+	highestPriority := CompactionPriorityLow
 
 	outputFile := outputIter.First()
 
@@ -1116,9 +1136,14 @@ func pickCompactionSeedFile(
 			continue
 		}
 
+		priority := priorities.forBounds(cmp, f.UserKeyBounds())
+		if priority < highestPriority {
+			continue
+		}
 		compSz := compensatedSize(f) + responsibleForGarbageBytes(virtualBackings, f)
 		scaledRatio := overlappingBytes * 1024 / compSz
-		if scaledRatio < smallestRatio {
+		if priority > highestPriority || scaledRatio < smallestRatio {
+			highestPriority = priority
 			smallestRatio = scaledRatio
 			file = startIter.Take()
 		}
@@ -1165,7 +1190,30 @@ func responsibleForGarbageBytes(virtualBackings *manifest.VirtualBackings, m *fi
 //
 // If a score-based compaction cannot be found, pickAuto falls back to looking
 // for an elision-only compaction to remove obsolete keys.
+//
+// If Options.Experimental.CompactionPicker is set, it's consulted instead.
 func (p *compactionPickerByScore) pickAuto(env compactionEnv) (pc *pickedCompaction) {
+	if p.opts.Experimental.CompactionPicker != nil {
+		return p.pickCustom(env)
+	}
+	return p.pickDefault(env)
+}
+
+// pickDefault picks an automatic compaction using the built-in policy of the
+// configured compaction style.
+func (p *compactionPickerByScore) pickDefault(env compactionEnv) *pickedCompaction {
+	switch p.opts.Experimental.CompactionStyle {
+	case CompactionStyleTiered:
+		return p.pickTiered(env)
+	case CompactionStyleFIFO:
+		return p.pickFIFO(env)
+	}
+	return p.pickAutoByScore(env)
+}
+
+// pickAutoByScore implements the built-in automatic compaction picking policy
+// described in pickAuto.
+func (p *compactionPickerByScore) pickAutoByScore(env compactionEnv) (pc *pickedCompaction) {
 	// Compaction concurrency is controlled by L0 read-amp. We allow one
 	// additional compaction per L0CompactionConcurrency sublevels, as well as
 	// one additional compaction per CompactionDebtConcurrency bytes of
@@ -1269,7 +1317,7 @@ func (p *compactionPickerByScore) pickAuto(env compactionEnv) (pc *pickedCompact
 
 		// info.level > 0
 		var ok bool
-		info.file, ok = pickCompactionSeedFile(p.vers, p.virtualBackings, p.opts, info.level, info.outputLevel, env.earliestSnapshotSeqNum)
+		info.file, ok = pickCompactionSeedFile(p.vers, p.virtualBackings, p.opts, info.level, info.outputLevel, env.earliestSnapshotSeqNum, env.priorities)
 		if !ok {
 			continue
 		}
@@ -1343,6 +1391,12 @@ func (p *compactionPickerByScore) pickAuto(env compactionEnv) (pc *pickedCompact
 		}
 	}
 
+	// Finally, rewrite files that haven't been compacted for longer than
+	// Options.Experimental.PeriodicCompactionAge.
+	if pc := p.pickPeriodicCompaction(env); pc != nil {
+		return pc
+	}
+
 	return nil
 }
 
@@ -1506,6 +1560,68 @@ func (p *compactionPickerByScore) pickRewriteCompaction(env compactionEnv) (pc *
 	return nil
 }
 
+// periodicCompactionAnnotator is a manifest.Annotator that annotates B-Tree
+// nodes with the *fileMetadata of the file with the earliest creation time
+// within the subtree that isn't compacting. Files with an unknown creation time
+// are never picked.
+var periodicCompactionAnnotator = &manifest.Annotator[fileMetadata]{
+	Aggregator: manifest.PickFileAggregator{
+		Filter: func(f *fileMetadata) (eligible bool, cacheOK bool) {
+			if f.IsCompacting() {
+				// The file will be eligible again if the compaction fails.
+				return false, false
+			}
+			// Files written by versions of Pebble that didn't record the
+			// creation time of files have a zero creation time.
+			return f.CreationTime != 0, true
+		},
+		Compare: func(f1 *fileMetadata, f2 *fileMetadata) bool {
+			return f1.CreationTime < f2.CreationTime
+		},
+	},
+}
+
+// pickPeriodicCompaction attempts to construct a compaction that rewrites the
+// oldest file that was created longer than
+// Options.Experimental.PeriodicCompactionAge ago and can be compacted. Like a rewrite compaction,
+// a periodic compaction outputs files to the same level as the input level,
+// so that files that would otherwise never be compacted drop obsolete keys and
+// are rewritten with the current table format and compression settings.
+func (p *compactionPickerByScore) pickPeriodicCompaction(env compactionEnv) (pc *pickedCompaction) {
+	age := p.opts.Experimental.PeriodicCompactionAge
+	if age <= 0 {
+		return nil
+	}
+	type candidate struct {
+		f     *fileMetadata
+		level int
+	}
+	var candidates []candidate
+	for l := numLevels - 1; l >= 0; l-- {
+		f := periodicCompactionAnnotator.LevelAnnotation(p.vers.Levels[l])
+		if f != nil && f.IsCompacting() {
+			// The file started compacting since the annotation was cached.
+			periodicCompactionAnnotator.InvalidateLevelAnnotation(p.vers.Levels[l])
+			f = periodicCompactionAnnotator.LevelAnnotation(p.vers.Levels[l])
+		}
+		if f != nil && env.now.Sub(time.Unix(f.CreationTime, 0)) > age {
+			candidates = append(candidates, candidate{f: f, level: l})
+		}
+	}
+	// It may not be possible to set up a compaction including the oldest file,
+	// so the oldest files of each level are tried in order of age until a
+	// compaction is picked.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].f.CreationTime < candidates[j].f.CreationTime
+	})
+	for _, c := range candidates {
+		if pc := p.pickedCompactionFromCandidateFile(c.f, env, c.level, c.level, compactionKindPeriodic); pc != nil {
+			return pc
+		}
+	}
+	return nil
+}
+
 func (p *compactionPickerByScore) initTombstoneDensityAnnotator(opts *Options) {
 	p.tombstoneDensityAnnotator = &manifest.Annotator[fileMetadata]{
 		Aggregator: manifest.PickFileAggregator{
@@ -1718,7 +1834,8 @@ func pickL0(env compactionEnv, opts *Options, vers *version, baseLevel int) (pc
 	//
 	// TODO(bilal) Remove the minCompactionDepth parameter once fixing it at 1
 	// has been shown to not cause a performance regression.
-	lcf, err := vers.L0Sublevels.PickBaseCompaction(1, vers.Levels[baseLevel].Slice())
+	intervalPriority := env.priorities.intervalPriority(opts.Comparer.Compare)
+	lcf, err := vers.L0Sublevels.PickBaseCompaction(1, vers.Levels[baseLevel].Slice(), intervalPriority)
 	if err != nil {
 		opts.Logger.Errorf("error when picking base compaction: %s", err)
 		return
@@ -1736,7 +1853,7 @@ func pickL0(env compactionEnv, opts *Options, vers *version, baseLevel int) (pc
 	// compaction. Note that we pass in L0CompactionThreshold here as opposed to
 	// 1, since choosing a single sublevel intra-L0 compaction is
 	// counterproductive.
-	lcf, err = vers.L0Sublevels.PickIntraL0Compaction(env.earliestUnflushedSeqNum, minIntraL0Count)
+	lcf, err = vers.L0Sublevels.PickIntraL0Compaction(env.earliestUnflushedSeqNum, minIntraL0Count, intervalPriority)
 	if err != nil {
 		opts.Logger.Errorf("error when picking intra-L0 compaction: %s", err)
 		return
@@ -1765,6 +1882,9 @@ func pickL0(env compactionEnv, opts *Options, vers *version, baseLevel int) (pc
 func pickManualCompaction(
 	vers *version, opts *Options, env compactionEnv, baseLevel int, manual *manualCompaction,
 ) (pc *pickedCompaction, retryLater bool) {
+	if opts.Experimental.CompactionStyle == CompactionStyleTiered {
+		return pickTieredManualCompaction(vers, opts, env, manual)
+	}
 	outputLevel := manual.level + 1
 	if manual.level == 0 {
 		outputLevel = baseLevel
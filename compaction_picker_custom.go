@@ -0,0 +1,249 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+)
+
+// CompactionPicker chooses the automatic compactions run by a DB. It's
+// configured through Options.Experimental.CompactionPicker and replaces the
+// built-in score-based policy, allowing alternative policies to be evaluated
+// without modifying Pebble (for example, by replaying a captured workload
+// with the replay package).
+//
+// Pick is called with the DB mutex held whenever the DB has capacity for
+// another compaction (as determined by Options.MaxConcurrentCompactions),
+// typically after a flush or compaction completes. It must not block, and must
+// not call into the DB. It's called repeatedly until it returns nil or the
+// proposed compaction can't be run.
+type CompactionPicker interface {
+	// Pick returns the compaction to run, or nil if no compaction should be
+	// run. The view is only valid for the duration of the call.
+	Pick(view *CompactionPickerView) *CompactionProposal
+}
+
+// CompactionProposal describes a compaction proposed by a CompactionPicker.
+//
+// The compaction compacts tables in StartLevel into the next level of the LSM
+// (or into the base level, if StartLevel is L0). Pebble expands the proposal
+// to include all the tables within the key range of the proposed tables in
+// StartLevel, all overlapping tables in the output level, and any additional
+// tables in StartLevel that can be included without pulling in more tables
+// from the output level. A proposal that conflicts with an in-progress
+// compaction is not run.
+type CompactionProposal struct {
+	// StartLevel is the level containing Files. It must be L0 or a level in
+	// [BaseLevel, NumLevels-2].
+	StartLevel int
+	// Files holds the file numbers of the tables in StartLevel to compact.
+	Files []FileNum
+
+	// picked is the compaction chosen by the built-in policy, for proposals
+	// returned by CompactionPickerView.DefaultProposal.
+	picked *pickedCompaction
+}
+
+// CompactionPickerTable describes a table in a CompactionPickerView.
+type CompactionPickerTable struct {
+	TableInfo
+	// Compacting is true if the table is an input to an in-progress
+	// compaction. Proposals including tables that are compacting are not run.
+	Compacting bool
+	// SubLevel is the L0 sublevel of the table, for tables in L0.
+	SubLevel int
+}
+
+// CompactionPickerInProgress describes an in-progress compaction in a
+// CompactionPickerView.
+type CompactionPickerInProgress struct {
+	// InputLevels holds the levels of the compaction's inputs.
+	InputLevels []int
+	// OutputLevel is the level into which the compaction writes its output.
+	OutputLevel int
+	// Smallest and Largest are the bounds of the compaction.
+	Smallest InternalKey
+	Largest  InternalKey
+}
+
+// CompactionPickerView is a read-only view of the state of a DB provided to a
+// CompactionPicker: the current version of the LSM, the in-progress
+// compactions and the per-level metrics.
+type CompactionPickerView struct {
+	p   *compactionPickerByScore
+	env compactionEnv
+
+	defaultPicked   bool
+	defaultProposal *CompactionProposal
+}
+
+// BaseLevel returns the level into which L0 is compacted.
+func (v *CompactionPickerView) BaseLevel() int {
+	return v.p.getBaseLevel()
+}
+
+// Tables returns the tables in the specified level, ordered by key (or, for
+// L0, by sublevel and key).
+func (v *CompactionPickerView) Tables(level int) []CompactionPickerTable {
+	var tables []CompactionPickerTable
+	add := func(iter manifest.LevelIterator) {
+		for f := iter.First(); f != nil; f = iter.Next() {
+			tables = append(tables, CompactionPickerTable{
+				TableInfo:  f.TableInfo(),
+				Compacting: f.IsCompacting(),
+				SubLevel:   f.SubLevel,
+			})
+		}
+	}
+	if level == 0 {
+		for i := range v.p.vers.L0SublevelFiles {
+			add(v.p.vers.L0SublevelFiles[i].Iter())
+		}
+		return tables
+	}
+	add(v.p.vers.Levels[level].Iter())
+	return tables
+}
+
+// L0Sublevels returns the number of L0 sublevels.
+func (v *CompactionPickerView) L0Sublevels() int {
+	return len(v.p.vers.L0SublevelFiles)
+}
+
+// InProgress returns the in-progress compactions.
+func (v *CompactionPickerView) InProgress() []CompactionPickerInProgress {
+	inProgress := make([]CompactionPickerInProgress, len(v.env.inProgressCompactions))
+	for i, info := range v.env.inProgressCompactions {
+		inProgress[i] = CompactionPickerInProgress{
+			OutputLevel: info.outputLevel,
+			Smallest:    info.smallest,
+			Largest:     info.largest,
+		}
+		for _, cl := range info.inputs {
+			inProgress[i].InputLevels = append(inProgress[i].InputLevels, cl.level)
+		}
+	}
+	return inProgress
+}
+
+// Scores returns the compaction scores computed by the built-in policy for
+// each level, taking the in-progress compactions into account. The built-in
+// policy compacts levels with a score of at least 1, highest score first.
+func (v *CompactionPickerView) Scores() [numLevels]float64 {
+	return v.p.getScores(v.env.inProgressCompactions)
+}
+
+// CompactionDebt returns the estimated number of bytes that need to be
+// compacted before the LSM reaches a stable state.
+func (v *CompactionPickerView) CompactionDebt() uint64 {
+	return v.p.estimatedCompactionDebt(0)
+}
+
+// LevelMetrics returns the per-level metrics of the DB.
+func (v *CompactionPickerView) LevelMetrics() [numLevels]LevelMetrics {
+	if v.env.levelMetrics == nil {
+		return [numLevels]LevelMetrics{}
+	}
+	return *v.env.levelMetrics
+}
+
+// DiskAvailBytes returns the number of bytes available on disk.
+func (v *CompactionPickerView) DiskAvailBytes() uint64 {
+	return v.env.diskAvailBytes
+}
+
+// DefaultProposal returns the compaction the built-in policy of the configured
+// CompactionStyle would pick, or nil if it wouldn't pick a compaction. It allows a CompactionPicker
+// to fall back to the built-in policy. The returned proposal must not be
+// modified, and may only be returned from the same call to Pick.
+func (v *CompactionPickerView) DefaultProposal() *CompactionProposal {
+	if !v.defaultPicked {
+		v.defaultPicked = true
+		if pc := v.p.pickDefault(v.env); pc != nil {
+			// NB: FIFO compactions have no start level, so the first input level
+			// is used instead.
+			v.defaultProposal = &CompactionProposal{
+				StartLevel: pc.inputs[0].level,
+				picked:     pc,
+			}
+			pc.inputs[0].files.Each(func(f *fileMetadata) {
+				v.defaultProposal.Files = append(v.defaultProposal.Files, f.FileNum)
+			})
+		}
+	}
+	return v.defaultProposal
+}
+
+// pickCustom picks an automatic compaction using the CompactionPicker
+// configured in the options.
+func (p *compactionPickerByScore) pickCustom(env compactionEnv) *pickedCompaction {
+	view := &CompactionPickerView{p: p, env: env}
+	proposal := p.opts.Experimental.CompactionPicker.Pick(view)
+	if proposal == nil {
+		return nil
+	}
+	if proposal.picked != nil {
+		return proposal.picked
+	}
+	pc, err := p.pickedCompactionFromProposal(env, proposal)
+	if err != nil {
+		p.opts.Logger.Infof("pebble: ignoring invalid compaction proposal: %s", err)
+		return nil
+	}
+	return pc
+}
+
+// pickedCompactionFromProposal validates a compaction proposed by a
+// CompactionPicker and sets up the compaction. It returns a nil compaction
+// and no error if the proposal is valid but conflicts with an in-progress
+// compaction.
+func (p *compactionPickerByScore) pickedCompactionFromProposal(
+	env compactionEnv, proposal *CompactionProposal,
+) (*pickedCompaction, error) {
+	level := proposal.StartLevel
+	if level < 0 || level >= numLevels-1 || (level > 0 && level < p.baseLevel) {
+		return nil, errors.Errorf("invalid start level L%d (base level L%d)", level, p.baseLevel)
+	}
+	if len(proposal.Files) == 0 {
+		return nil, errors.Errorf("no files proposed in L%d", level)
+	}
+	var files []*fileMetadata
+	iter := p.vers.Levels[level].Iter()
+	for f := iter.First(); f != nil; f = iter.Next() {
+		if slices.Contains(proposal.Files, f.FileNum) {
+			files = append(files, f)
+		}
+	}
+	if len(files) != len(proposal.Files) {
+		return nil, errors.Errorf("proposed files %s not all found in L%d", proposal.Files, level)
+	}
+
+	// The proposal is expanded to include every table within its key range.
+	// In L0 this also pulls in any older overlapping tables, which must be
+	// compacted along with newer ones.
+	smallest, largest := files[0].Smallest, files[0].Largest
+	for _, f := range files[1:] {
+		if base.InternalCompare(p.opts.Comparer.Compare, f.Smallest, smallest) < 0 {
+			smallest = f.Smallest
+		}
+		if base.InternalCompare(p.opts.Comparer.Compare, f.Largest, largest) > 0 {
+			largest = f.Largest
+		}
+	}
+	pc := newPickedCompaction(p.opts, p.vers, level, defaultOutputLevel(level, p.baseLevel), p.baseLevel)
+	pc.startLevel.files = p.vers.Overlaps(level, base.UserKeyBoundsFromInternal(smallest, largest))
+	if !pc.setupInputs(p.opts, env.diskAvailBytes, pc.startLevel) {
+		return nil, nil
+	}
+	// Fail-safe to protect against compacting the same sstable concurrently.
+	if inputRangeAlreadyCompacting(env, pc) {
+		return nil, nil
+	}
+	return pc, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+type funcCompactionPicker func(view *CompactionPickerView) *CompactionProposal
+
+func (f funcCompactionPicker) Pick(view *CompactionPickerView) *CompactionProposal {
+	return f(view)
+}
+
+func TestCustomCompactionPicker(t *testing.T) {
+	pick := funcCompactionPicker(func(*CompactionPickerView) *CompactionProposal { return nil })
+	var log []string
+	opts := &Options{
+		FS:                    vfs.NewMem(),
+		L0CompactionThreshold: 100,
+		L0StopWritesThreshold: 1000,
+		Logger:                testLogger{t: t},
+	}
+	opts.Experimental.CompactionPicker = funcCompactionPicker(func(view *CompactionPickerView) *CompactionProposal {
+		return pick(view)
+	})
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	flush := func(keys ...string) {
+		for _, k := range keys {
+			require.NoError(t, d.Set([]byte(k), []byte(k), nil))
+		}
+		require.NoError(t, d.Flush())
+		waitForCompactionsAndTableStats(d)
+	}
+	levelFiles := func() string {
+		m := d.Metrics()
+		var s string
+		for level := range m.Levels {
+			if n := m.Levels[level].NumFiles; n > 0 {
+				s += fmt.Sprintf("L%d:%d ", level, n)
+			}
+		}
+		return s
+	}
+
+	// A picker that compacts L0 once it contains three tables. Proposing a
+	// single table compacts all the L0 tables overlapping it.
+	pick = func(view *CompactionPickerView) *CompactionProposal {
+		tables := view.Tables(0)
+		require.Equal(t, int64(len(tables)), view.LevelMetrics()[0].NumFiles)
+		log = append(log, fmt.Sprintf("L0 tables=%d sublevels=%d in-progress=%d",
+			len(tables), view.L0Sublevels(), len(view.InProgress())))
+		if len(tables) < 3 || tables[0].Compacting {
+			return nil
+		}
+		return &CompactionProposal{StartLevel: 0, Files: []FileNum{tables[0].FileNum}}
+	}
+	flush("a", "z")
+	flush("b", "y")
+	require.Equal(t, "L0:2 ", levelFiles())
+	flush("c", "x")
+	require.Equal(t, "L6:1 ", levelFiles())
+	require.Contains(t, log, "L0 tables=3 sublevels=3 in-progress=0")
+
+	// Invalid proposals are ignored.
+	for _, proposal := range []*CompactionProposal{
+		{StartLevel: 0, Files: []FileNum{999}},
+		{StartLevel: 0},
+		{StartLevel: 1, Files: []FileNum{1}},
+		{StartLevel: 6, Files: []FileNum{1}},
+	} {
+		pick = func(view *CompactionPickerView) *CompactionProposal {
+			if view.L0Sublevels() == 0 {
+				return nil
+			}
+			return proposal
+		}
+		flush("a")
+		require.Equal(t, "L0:1 L6:1 ", levelFiles())
+		require.NoError(t, d.Compact([]byte("a"), []byte("b"), false))
+	}
+
+	// A picker can fall back to the built-in policy, which doesn't compact L0
+	// until it reaches the L0CompactionThreshold.
+	pick = func(view *CompactionPickerView) *CompactionProposal {
+		return view.DefaultProposal()
+	}
+	flush("a")
+	flush("b")
+	require.Equal(t, "L0:2 L6:1 ", levelFiles())
+	o := d.DynamicOptions()
+	o.L0CompactionThreshold = 1
+	require.NoError(t, d.SetOptions(o))
+	waitForCompactionsAndTableStats(d)
+	require.Equal(t, "L6:1 ", levelFiles())
+}
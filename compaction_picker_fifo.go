@@ -0,0 +1,158 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/manifest"
+)
+
+// FIFOCompactionOptions configures the retention limits of
+// CompactionStyleFIFO. Tables are deleted oldest first, in the order in which
+// their data was written, while either limit is exceeded. A zero value
+// disables the corresponding limit.
+//
+// The limits are enforced whenever the DB schedules compactions, such as after
+// each flush. In addition, the DB schedules compactions once the oldest table
+// exceeds MaxAge, so tables expire while the DB isn't being written.
+type FIFOCompactionOptions struct {
+	// MaxSize is the maximum total size of the tables in the DB, in bytes.
+	MaxSize uint64
+	// MaxAge is the maximum age of a table, measured from the time it was
+	// created.
+	MaxAge time.Duration
+}
+
+// pickFIFO picks a compaction deleting the oldest tables under the
+// CompactionStyleFIFO, if the tables exceed the retention limits. Tables are
+// deleted strictly oldest first, so no tables are picked at or after the
+// oldest table that's being compacted, such as one being deleted by an
+// in-progress FIFO compaction.
+func (p *compactionPickerByScore) pickFIFO(env compactionEnv) *pickedCompaction {
+	o := &p.opts.Experimental.FIFOCompaction
+	if o.MaxSize == 0 && o.MaxAge == 0 {
+		return nil
+	}
+	type levelFile struct {
+		level int
+		f     *fileMetadata
+	}
+	var files []levelFile
+	var totalSize uint64
+	for level := 0; level < numLevels; level++ {
+		iter := p.vers.Levels[level].Iter()
+		for f := iter.First(); f != nil; f = iter.Next() {
+			files = append(files, levelFile{level: level, f: f})
+			totalSize += f.Size
+		}
+	}
+	slices.SortFunc(files, func(a, b levelFile) int {
+		if c := cmp.Compare(a.f.LargestSeqNum, b.f.LargestSeqNum); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.f.FileNum, b.f.FileNum)
+	})
+
+	expired := func(f *fileMetadata) bool {
+		return o.MaxAge > 0 && env.now.Sub(time.Unix(f.CreationTime, 0)) > o.MaxAge
+	}
+	var dropped [numLevels][]*fileMetadata
+	n := 0
+	for _, lf := range files {
+		if (o.MaxSize == 0 || totalSize <= o.MaxSize) && !expired(lf.f) {
+			break
+		}
+		if lf.f.IsCompacting() {
+			// Tables must be deleted in order, so no newer table may be
+			// deleted until the compaction of this one completes. If it's being
+			// deleted by an in-progress FIFO compaction, another FIFO
+			// compaction will be picked once it completes if the limits are
+			// still exceeded.
+			break
+		}
+		dropped[lf.level] = append(dropped[lf.level], lf.f)
+		totalSize -= lf.f.Size
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+
+	pc := &pickedCompaction{
+		cmp:       p.opts.Comparer.Compare,
+		version:   p.vers,
+		baseLevel: p.baseLevel,
+		kind:      compactionKindFIFO,
+	}
+	for level := range dropped {
+		if len(dropped[level]) == 0 {
+			continue
+		}
+		pc.inputs = append(pc.inputs, compactionLevel{
+			level: level,
+			files: manifest.NewLevelSliceKeySorted(pc.cmp, dropped[level]),
+		})
+	}
+	return pc
+}
+
+// nextFIFOExpiry returns the time at which the oldest table that isn't being
+// compacted exceeds FIFOCompactionOptions.MaxAge, if MaxAge is enforced and
+// there is such a table.
+func (p *compactionPickerByScore) nextFIFOExpiry() (time.Time, bool) {
+	maxAge := p.opts.Experimental.FIFOCompaction.MaxAge
+	if p.opts.Experimental.CompactionStyle != CompactionStyleFIFO || maxAge == 0 {
+		return time.Time{}, false
+	}
+	// Tables with an unknown creation time have already expired, and are
+	// deleted by the compaction picked along with this check.
+	var oldest *fileMetadata
+	for l := range p.vers.Levels {
+		f := periodicCompactionAnnotator.LevelAnnotation(p.vers.Levels[l])
+		if f != nil && (oldest == nil || f.CreationTime < oldest.CreationTime) {
+			oldest = f
+		}
+	}
+	if oldest == nil {
+		return time.Time{}, false
+	}
+	// A table expires once its age exceeds MaxAge, and creation times are
+	// truncated to seconds.
+	return time.Unix(oldest.CreationTime, 0).Add(maxAge + time.Second), true
+}
+
+// maybeScheduleFIFOAgeCheck arms a timer that schedules compactions once the
+// oldest table expires under CompactionStyleFIFO, unless an earlier check is
+// already scheduled.
+//
+// d.mu must be held when calling this.
+func (d *DB) maybeScheduleFIFOAgeCheck(now time.Time) {
+	due, ok := d.mu.versions.picker.nextFIFOExpiry()
+	if !ok || !due.After(now) {
+		// If the oldest table has already expired but couldn't be deleted,
+		// compactions are scheduled again once a running compaction
+		// completes.
+		return
+	}
+	check := &d.mu.compact.fifoAgeCheck
+	if check.timer != nil && !check.due.After(due) {
+		return
+	}
+	if check.timer != nil {
+		check.timer.Stop()
+	}
+	check.due = due
+	check.timer = time.AfterFunc(due.Sub(now), func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.mu.compact.fifoAgeCheck.due.Equal(due) {
+			d.mu.compact.fifoAgeCheck.timer = nil
+		}
+		d.maybeScheduleCompaction()
+	})
+}
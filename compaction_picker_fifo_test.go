@@ -0,0 +1,199 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIFOCompaction(t *testing.T) {
+	open := func(fifo FIFOCompactionOptions) *DB {
+		opts := &Options{
+			FS:                    vfs.NewMem(),
+			L0CompactionThreshold: 1,
+			L0StopWritesThreshold: 1000,
+		}
+		opts.Experimental.CompactionStyle = CompactionStyleFIFO
+		opts.Experimental.FIFOCompaction = fifo
+		d, err := Open("", opts)
+		require.NoError(t, err)
+		return d
+	}
+	key := func(i int) string { return fmt.Sprintf("key%06d", i) }
+	// writeTable writes and flushes a table of n keys starting at start.
+	rng := rand.New(rand.NewPCG(0, 1))
+	writeTable := func(d *DB, start, n int) {
+		for i := start; i < start+n; i++ {
+			// Use incompressible values, so the tables' sizes are predictable.
+			v := make([]byte, 100)
+			for j := range v {
+				v[j] = byte(rng.Uint32())
+			}
+			require.NoError(t, d.Set([]byte(key(i)), v, nil))
+		}
+		require.NoError(t, d.Flush())
+		waitForCompactionsAndTableStats(d)
+	}
+	// keyRange returns the first and last keys in the DB.
+	keyRange := func(d *DB) string {
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, iter.Close()) }()
+		if !iter.First() {
+			return "empty"
+		}
+		first := string(iter.Key())
+		count := 1
+		for iter.Next() {
+			count++
+		}
+		require.True(t, iter.Last())
+		return fmt.Sprintf("%s-%s (%d keys)", first, iter.Key(), count)
+	}
+
+	t.Run("size", func(t *testing.T) {
+		d := open(FIFOCompactionOptions{MaxSize: 40 << 10})
+		defer func() { require.NoError(t, d.Close()) }()
+		for i := 0; i < 20; i++ {
+			writeTable(d, i*100, 100)
+			m := d.Metrics()
+			require.LessOrEqual(t, uint64(m.Total().Size), uint64(40<<10))
+		}
+		// Only the most recently written tables remain, and the data was never
+		// rewritten.
+		m := d.Metrics()
+		require.Equal(t, "key001700-key001999 (300 keys)", keyRange(d))
+		require.Greater(t, m.Compact.FIFOCount, int64(0))
+		require.Greater(t, m.Compact.FIFOBytesDropped, uint64(0))
+		require.Zero(t, m.Compact.DefaultCount)
+		require.Zero(t, m.Total().BytesCompacted)
+	})
+
+	t.Run("age", func(t *testing.T) {
+		d := open(FIFOCompactionOptions{MaxAge: time.Hour})
+		defer func() { require.NoError(t, d.Close()) }()
+		for i := 0; i < 3; i++ {
+			writeTable(d, i*100, 100)
+		}
+		require.Equal(t, "key000000-key000299 (300 keys)", keyRange(d))
+
+		// Once the tables have expired, they're deleted the next time
+		// compactions are scheduled.
+		d.mu.Lock()
+		d.timeNow = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		d.maybeScheduleCompaction()
+		d.mu.Unlock()
+		waitForCompactionsAndTableStats(d)
+		require.Equal(t, "empty", keyRange(d))
+
+		d.mu.Lock()
+		d.timeNow = time.Now
+		d.mu.Unlock()
+		writeTable(d, 300, 100)
+		require.Equal(t, "key000300-key000399 (100 keys)", keyRange(d))
+		require.Equal(t, int64(1), d.Metrics().Compact.FIFOCount)
+	})
+
+	t.Run("age-timer", func(t *testing.T) {
+		d := open(FIFOCompactionOptions{MaxAge: time.Second})
+		defer func() { require.NoError(t, d.Close()) }()
+		writeTable(d, 0, 100)
+
+		// A check is scheduled for when the table expires, which deletes it
+		// even though nothing else schedules compactions.
+		d.mu.Lock()
+		due := d.mu.compact.fifoAgeCheck.due
+		d.mu.Unlock()
+		require.False(t, due.IsZero())
+		require.Eventually(t, func() bool { return keyRange(d) == "empty" }, 10*time.Second, 10*time.Millisecond)
+		require.Equal(t, int64(1), d.Metrics().Compact.FIFOCount)
+	})
+
+	t.Run("l0-read-amp", func(t *testing.T) {
+		// Overlapping flushes raise the L0 read amplification well past the
+		// default L0StopWritesThreshold, which must not stall writes.
+		opts := &Options{FS: vfs.NewMem()}
+		opts.Experimental.CompactionStyle = CompactionStyleFIFO
+		opts.Experimental.FIFOCompaction = FIFOCompactionOptions{MaxAge: time.Hour}
+		opts.Experimental.WriteAdmission.Enabled = true
+		d, err := Open("", opts)
+		require.NoError(t, err)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 2*d.opts.L0StopWritesThreshold; i++ {
+				writeTable(d, 0, 10)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(30 * time.Second):
+			// NB: Closing the DB would wait for the stalled writes.
+			t.Fatal("writes stalled")
+		}
+		d.mu.Lock()
+		readAmp := d.mu.versions.currentVersion().L0Sublevels.ReadAmplification()
+		d.mu.Unlock()
+		require.GreaterOrEqual(t, readAmp, 2*d.opts.L0StopWritesThreshold)
+		require.False(t, d.writeStalled.Load())
+		d.writeAdmission.mu.Lock()
+		l0Sublevels := d.writeAdmission.mu.signals.l0Sublevels
+		d.writeAdmission.mu.Unlock()
+		require.Zero(t, l0Sublevels)
+		require.NoError(t, d.Close())
+	})
+
+	t.Run("compacting", func(t *testing.T) {
+		d := open(FIFOCompactionOptions{MaxSize: 1 << 30})
+		defer func() { require.NoError(t, d.Close()) }()
+		for i := 0; i < 3; i++ {
+			writeTable(d, i*100, 100)
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.opts.Experimental.FIFOCompaction.MaxSize = 1
+		p := d.mu.versions.picker.(*compactionPickerByScore)
+		var oldest *fileMetadata
+		iter := p.vers.Levels[0].Iter()
+		for f := iter.First(); f != nil; f = iter.Next() {
+			if oldest == nil || f.LargestSeqNum < oldest.LargestSeqNum {
+				oldest = f
+			}
+		}
+		// No table newer than one that's being compacted may be deleted.
+		oldest.CompactionState = manifest.CompactionStateCompacting
+		require.Nil(t, p.pickFIFO(compactionEnv{now: time.Now()}))
+		oldest.CompactionState = manifest.CompactionStateNotCompacting
+		pc := p.pickFIFO(compactionEnv{now: time.Now()})
+		require.NotNil(t, pc)
+		require.Equal(t, 3, pc.inputs[0].files.Len())
+	})
+}
+
+func TestFIFOCompactionOptions(t *testing.T) {
+	opts := &Options{}
+	opts.Experimental.CompactionStyle = CompactionStyleFIFO
+	opts.Experimental.FIFOCompaction = FIFOCompactionOptions{MaxSize: 1 << 30, MaxAge: 24 * time.Hour}
+	opts.EnsureDefaults()
+	require.NoError(t, opts.Validate())
+	require.Contains(t, opts.String(), "compaction_style=fifo\n")
+
+	var parsed Options
+	require.NoError(t, parsed.Parse(opts.String(), nil))
+	require.Equal(t, CompactionStyleFIFO, parsed.Experimental.CompactionStyle)
+	require.Equal(t, opts.Experimental.FIFOCompaction, parsed.Experimental.FIFOCompaction)
+
+	opts.Experimental.FIFOCompaction = FIFOCompactionOptions{}
+	require.ErrorContains(t, opts.Validate(), "FIFOCompaction.MaxSize or FIFOCompaction.MaxAge must be set")
+}
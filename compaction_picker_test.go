@@ -1399,6 +1399,21 @@ func TestCompactionPickerPickFile(t *testing.T) {
 			d.mu.Unlock()
 			return s
 
+		case "set-compaction-priority":
+			var start, end, priority string
+			td.ScanArgs(t, "start", &start)
+			td.ScanArgs(t, "end", &end)
+			td.ScanArgs(t, "priority", &priority)
+			p := map[string]CompactionPriority{
+				"low":     CompactionPriorityLow,
+				"default": CompactionPriorityDefault,
+				"high":    CompactionPriorityHigh,
+			}[priority]
+			if err := d.SetCompactionPriority([]byte(start), []byte(end), p); err != nil {
+				return err.Error()
+			}
+			return ""
+
 		case "pick-file":
 			s := strings.TrimPrefix(td.CmdArgs[0].String(), "L")
 			level, err := strconv.Atoi(s)
@@ -1419,7 +1434,7 @@ func TestCompactionPickerPickFile(t *testing.T) {
 			var ok bool
 			d.maybeScheduleCompactionPicker(func(untypedPicker compactionPicker, env compactionEnv) *pickedCompaction {
 				p := untypedPicker.(*compactionPickerByScore)
-				lf, ok = pickCompactionSeedFile(p.vers, p.virtualBackings, opts, level, level+1, env.earliestSnapshotSeqNum)
+				lf, ok = pickCompactionSeedFile(p.vers, p.virtualBackings, opts, level, level+1, env.earliestSnapshotSeqNum, env.priorities)
 				return nil
 			})
 			if !ok {
@@ -1636,3 +1651,84 @@ func checkTableBoundary(a, b *fileMetadata, cmp base.Compare) (ok bool) {
 	c := cmp(a.LargestPointKey.UserKey, b.SmallestPointKey.UserKey)
 	return c < 0 || (c == 0 && a.LargestPointKey.IsExclusiveSentinel())
 }
+
+func TestPeriodicCompaction(t *testing.T) {
+	opts := &Options{FS: vfs.NewMem()}
+	opts.Experimental.PeriodicCompactionAge = time.Hour
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("key%02d", i)), []byte("value"), nil))
+	}
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false))
+	fileNums := func() string {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return fileNums(d.mu.versions.currentVersion().Levels[numLevels-1].Slice())
+	}
+	before := fileNums()
+	require.NotEmpty(t, before)
+
+	// The tables are young, so nothing is compacted.
+	d.mu.Lock()
+	d.maybeScheduleCompaction()
+	d.mu.Unlock()
+	waitForCompactionsAndTableStats(d)
+	require.Equal(t, before, fileNums())
+	require.Zero(t, d.Metrics().Compact.PeriodicCount)
+
+	// Advance the clock once, so that the tables are considered old. The
+	// rewritten tables are new, so they aren't compacted again.
+	d.mu.Lock()
+	advanced := false
+	d.timeNow = func() time.Time {
+		if !advanced {
+			advanced = true
+			return time.Now().Add(2 * time.Hour)
+		}
+		return time.Now()
+	}
+	d.maybeScheduleCompaction()
+	d.mu.Unlock()
+	waitForCompactionsAndTableStats(d)
+	require.NotEqual(t, before, fileNums())
+	require.Equal(t, int64(1), d.Metrics().Compact.PeriodicCount)
+
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	count := 0
+	for valid := iter.First(); valid; valid = iter.Next() {
+		count++
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, 10, count)
+
+	// If the oldest table is already compacting, another old table is picked.
+	for i := 0; i < 10; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("other%02d", i)), []byte("value"), nil))
+	}
+	require.NoError(t, d.Compact([]byte("o"), []byte("p"), false))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p := d.mu.versions.picker.(*compactionPickerByScore)
+	require.Greater(t, p.vers.Levels[numLevels-1].Len(), 1)
+	oldest := periodicCompactionAnnotator.LevelAnnotation(p.vers.Levels[numLevels-1])
+	oldest.CompactionState = manifest.CompactionStateCompacting
+	pc := p.pickPeriodicCompaction(compactionEnv{now: time.Now().Add(2 * time.Hour)})
+	require.NotNil(t, pc)
+	require.Equal(t, compactionKindPeriodic, pc.kind)
+	pc.startLevel.files.Each(func(f *fileMetadata) {
+		require.NotEqual(t, oldest.FileNum, f.FileNum)
+	})
+	oldest.CompactionState = manifest.CompactionStateNotCompacting
+
+	// Tables with an unknown creation time aren't considered old.
+	levelIter := p.vers.Levels[numLevels-1].Iter()
+	for f := levelIter.First(); f != nil; f = levelIter.Next() {
+		f.CreationTime = 0
+	}
+	periodicCompactionAnnotator.InvalidateLevelAnnotation(p.vers.Levels[numLevels-1])
+	require.Nil(t, p.pickPeriodicCompaction(compactionEnv{now: time.Now().Add(2 * time.Hour)}))
+}
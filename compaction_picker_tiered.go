@@ -0,0 +1,127 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import "github.com/cockroachdb/pebble/internal/manifest"
+
+// TieredCompactionOptions configures CompactionStyleTiered.
+type TieredCompactionOptions struct {
+	// RunsPerLevel is the number of sorted runs a level below L0 accumulates
+	// before they're merged into a single new run in the next level. In the
+	// bottommost level, the runs are merged in place. Defaults to 4.
+	RunsPerLevel int
+}
+
+// EnsureDefaults ensures that the default values for all of the options have
+// been initialized. It is valid to call EnsureDefaults on a nil receiver. A
+// non-nil result will always be returned.
+func (o *TieredCompactionOptions) EnsureDefaults() *TieredCompactionOptions {
+	if o == nil {
+		o = &TieredCompactionOptions{}
+	}
+	if o.RunsPerLevel <= 0 {
+		o.RunsPerLevel = 4
+	}
+	return o
+}
+
+// pickTiered picks an automatic compaction under the tiered compaction style,
+// described at CompactionStyleTiered. If no level has accumulated enough
+// sorted runs, it falls back to elision-only, rewrite and periodic
+// compactions, which rewrite tables within a level.
+func (p *compactionPickerByScore) pickTiered(env compactionEnv) *pickedCompaction {
+	if pc := p.pickTieredMerge(env); pc != nil {
+		return pc
+	}
+	if pc := p.pickElisionOnlyCompaction(env); pc != nil {
+		return pc
+	}
+	if p.vers.Stats.MarkedForCompaction > 0 {
+		if pc := p.pickRewriteCompaction(env); pc != nil {
+			return pc
+		}
+	}
+	return p.pickPeriodicCompaction(env)
+}
+
+// pickTieredMerge picks a compaction merging all the sorted runs of the level
+// with the most runs relative to its limit, if any level has reached it. The
+// limit is Options.L0CompactionThreshold sublevels for L0, and
+// TieredCompactionOptions.RunsPerLevel runs for the other levels.
+func (p *compactionPickerByScore) pickTieredMerge(env compactionEnv) *pickedCompaction {
+	bestLevel, bestScore := -1, 1.0
+	for level := 0; level < numLevels; level++ {
+		runs := p.vers.NumRuns(level)
+		if level == numLevels-1 && runs < 2 {
+			// A single run in the bottommost level has nowhere to go.
+			continue
+		}
+		limit := p.opts.Experimental.TieredCompaction.RunsPerLevel
+		if level == 0 {
+			limit = p.opts.L0CompactionThreshold
+		}
+		score := float64(runs) / float64(limit)
+		if score < bestScore || anyTablesCompacting(p.vers.Levels[level].Slice()) {
+			continue
+		}
+		bestLevel, bestScore = level, score
+	}
+	if bestLevel < 0 {
+		return nil
+	}
+	return newTieredCompaction(p.opts, p.vers, env, bestLevel)
+}
+
+// pickTieredManualCompaction picks a manual compaction under the tiered
+// compaction style. Like an automatic one, it merges all the sorted runs of
+// the level, regardless of the number of runs and the compaction's key range.
+func pickTieredManualCompaction(
+	vers *version, opts *Options, env compactionEnv, manual *manualCompaction,
+) (pc *pickedCompaction, retryLater bool) {
+	if vers.Levels[manual.level].Empty() ||
+		(manual.level == numLevels-1 && vers.NumRuns(manual.level) < 2) {
+		// Nothing to do
+		return nil, false
+	}
+	if anyTablesCompacting(vers.Levels[manual.level].Slice()) {
+		return nil, true
+	}
+	if pc = newTieredCompaction(opts, vers, env, manual.level); pc == nil {
+		return nil, true
+	}
+	manual.outputLevel = pc.outputLevel.level
+	return pc, false
+}
+
+// newTieredCompaction returns a compaction merging all the sorted runs of the
+// specified level into a single new run in the next level, or in the same
+// level if it's the bottommost, or nil if the level's key range overlaps an
+// in-progress compaction into the output level.
+//
+// The runs already in the output level aren't inputs to the compaction. A
+// level is only ever merged as a whole, and only by one compaction at a time,
+// so the data in each level is newer than the data in the levels below it,
+// and the new run is newer than the runs already in the output level.
+func newTieredCompaction(
+	opts *Options, vers *version, env compactionEnv, level int,
+) *pickedCompaction {
+	outputLevel := min(level+1, numLevels-1)
+	pc := newPickedCompaction(opts, vers, level, outputLevel, 1)
+	pc.startLevel.files = vers.Levels[level].Slice()
+	if level == 0 {
+		pc.startLevel.l0SublevelInfo = generateSublevelInfo(pc.cmp, pc.startLevel.files)
+	} else {
+		for r, run := range vers.Runs[level] {
+			pc.startLevel.l0SublevelInfo = append(pc.startLevel.l0SublevelInfo,
+				sublevelInfo{run, manifest.LevelRun(level, r)})
+		}
+	}
+	pc.smallest, pc.largest = manifest.KeyRange(pc.cmp, pc.startLevel.files.Iter())
+	// Fail-safe to protect against compacting the same sstable concurrently.
+	if inputRangeAlreadyCompacting(env, pc) {
+		return nil
+	}
+	return pc
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTieredCompaction(t *testing.T) {
+	newOpts := func(fs vfs.FS, style CompactionStyle) *Options {
+		opts := &Options{
+			FS:                    fs,
+			L0CompactionThreshold: 4,
+			L0StopWritesThreshold: 1000,
+			LBaseMaxBytes:         64 << 10,
+			Levels:                []LevelOptions{{TargetFileSize: 32 << 10}},
+		}
+		opts.Experimental.CompactionStyle = style
+		opts.Experimental.TieredCompaction.RunsPerLevel = 3
+		return opts
+	}
+	verify := func(d *DB, expected map[string]string) {
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		count := 0
+		for valid := iter.First(); valid; valid = iter.Next() {
+			require.Equal(t, expected[string(iter.Key())], string(iter.Value()), "key %s", iter.Key())
+			count++
+		}
+		require.NoError(t, iter.Close())
+		require.Equal(t, len(expected), count)
+		for k, v := range expected {
+			got, closer, err := d.Get([]byte(k))
+			require.NoError(t, err)
+			require.Equal(t, v, string(got))
+			require.NoError(t, closer.Close())
+		}
+	}
+
+	run := func(style CompactionStyle) (bytesCompacted uint64, maxRuns int32) {
+		fs := vfs.NewMem()
+		d, err := Open("", newOpts(fs, style))
+		require.NoError(t, err)
+
+		// Write batches of random keys across the keyspace, including point and
+		// range deletions, flushing each one so that every flush adds an L0
+		// sublevel.
+		rng := rand.New(rand.NewPCG(0, 1))
+		expected := make(map[string]string)
+		for i := 0; i < 60; i++ {
+			for j := 0; j < 200; j++ {
+				k := fmt.Sprintf("key%04d", rng.IntN(4000))
+				switch rng.IntN(20) {
+				case 0:
+					require.NoError(t, d.Delete([]byte(k), nil))
+					delete(expected, k)
+				case 1:
+					end := fmt.Sprintf("key%04d", rng.IntN(4000))
+					if end <= k {
+						continue
+					}
+					require.NoError(t, d.DeleteRange([]byte(k), []byte(end), nil))
+					for ek := range expected {
+						if ek >= k && ek < end {
+							delete(expected, ek)
+						}
+					}
+				default:
+					v := fmt.Sprintf("%s-%d-%0100d", k, i, j)
+					require.NoError(t, d.Set([]byte(k), []byte(v), nil))
+					expected[k] = v
+				}
+			}
+			require.NoError(t, d.Flush())
+			waitForCompactionsAndTableStats(d)
+
+			m := d.Metrics()
+			for level := 1; level < numLevels; level++ {
+				maxRuns = max(maxRuns, m.Levels[level].Sublevels)
+			}
+		}
+		verify(d, expected)
+
+		m := d.Metrics()
+		for level := 1; level < numLevels; level++ {
+			bytesCompacted += m.Levels[level].BytesCompacted
+		}
+		if style != CompactionStyleTiered {
+			require.NoError(t, d.Close())
+			return bytesCompacted, maxRuns
+		}
+
+		// A manual compaction of the whole keyspace leaves a single run.
+		require.NoError(t, d.Compact([]byte("a"), []byte("z"), false))
+		m = d.Metrics()
+		for level := 0; level < numLevels-1; level++ {
+			require.Zero(t, m.Levels[level].NumFiles, "L%d", level)
+		}
+		require.Equal(t, int32(1), m.Levels[numLevels-1].Sublevels)
+		verify(d, expected)
+		require.NoError(t, d.Close())
+
+		// The runs are rebuilt when the DB is reopened.
+		d, err = Open("", newOpts(fs, style))
+		require.NoError(t, err)
+		verify(d, expected)
+		require.NoError(t, d.Close())
+		return bytesCompacted, maxRuns
+	}
+
+	leveledBytes, _ := run(CompactionStyleLeveled)
+	tieredBytes, maxRuns := run(CompactionStyleTiered)
+	t.Logf("bytes compacted: leveled %d, tiered %d", leveledBytes, tieredBytes)
+	require.Less(t, tieredBytes, leveledBytes)
+	// The levels below L0 held multiple sorted runs, but never more than the
+	// configured limit.
+	require.Greater(t, maxRuns, int32(1))
+	require.LessOrEqual(t, maxRuns, int32(3))
+}
+
+func TestTieredCompactionIngest(t *testing.T) {
+	fs := vfs.NewMem()
+	opts := &Options{FS: fs, FormatMajorVersion: internalFormatNewest}
+	opts.Experimental.CompactionStyle = CompactionStyleTiered
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	writeTable := func(path string, keys ...string) {
+		f, err := fs.Create(path, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), d.opts.MakeWriterOptions(0, d.opts.FormatMajorVersion.MaxTableFormat()))
+		for _, k := range keys {
+			require.NoError(t, w.Set([]byte(k), []byte("ingested")))
+		}
+		require.NoError(t, w.Close())
+	}
+
+	// The ingested table doesn't overlap the memtable, but is still added to
+	// L0 after the memtable is flushed.
+	require.NoError(t, d.Set([]byte("a"), []byte("written"), nil))
+	writeTable("ext1", "b", "c")
+	require.NoError(t, d.Ingest(context.Background(), []string{"ext1"}))
+	require.NoError(t, d.Flush())
+	m := d.Metrics()
+	require.Equal(t, uint64(1), m.Levels[0].TablesIngested)
+	for level := 1; level < numLevels; level++ {
+		require.Zero(t, m.Levels[level].TablesIngested)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		v, closer, err := d.Get([]byte(k))
+		require.NoError(t, err)
+		require.NotEmpty(t, v)
+		require.NoError(t, closer.Close())
+	}
+
+	// Excises aren't supported.
+	writeTable("ext2", "d")
+	_, err = d.IngestAndExcise(context.Background(), []string{"ext2"}, nil, nil, KeyRange{Start: []byte("d"), End: []byte("e")})
+	require.ErrorContains(t, err, "not supported with the tiered compaction style")
+}
+
+func TestTieredCompactionOptions(t *testing.T) {
+	opts := &Options{}
+	opts.Experimental.CompactionStyle = CompactionStyleTiered
+	opts.Experimental.TieredCompaction.RunsPerLevel = 6
+	opts.EnsureDefaults()
+	require.Contains(t, opts.String(), "compaction_style=tiered\n")
+	require.Contains(t, opts.String(), "tiered_runs_per_level=6\n")
+
+	var parsed Options
+	require.NoError(t, parsed.Parse(opts.String(), nil))
+	require.Equal(t, CompactionStyleTiered, parsed.Experimental.CompactionStyle)
+	require.Equal(t, opts.Experimental.TieredCompaction, parsed.Experimental.TieredCompaction)
+
+	opts.Experimental.TieredCompaction.RunsPerLevel = 1
+	require.ErrorContains(t, opts.Validate(), "TieredCompaction.RunsPerLevel (1) must be >= 2")
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// CompactionPriority is a hint about how urgently the automatic compactions of
+// a key range should be performed, relative to the rest of the keyspace. It's
+// registered through DB.SetCompactionPriority.
+type CompactionPriority int8
+
+const (
+	// CompactionPriorityLow defers the compaction of a key range, such as a
+	// range that's rarely read. Tables within the range are only chosen by the
+	// score-based compaction picker if no other tables in the same level can
+	// be compacted, and L0 compactions within the range are disfavored relative
+	// to those of similar depth elsewhere.
+	CompactionPriorityLow CompactionPriority = -1
+	// CompactionPriorityDefault is the priority of key ranges without a
+	// registered priority.
+	CompactionPriorityDefault CompactionPriority = 0
+	// CompactionPriorityHigh keeps the read amplification of a key range low,
+	// such as a range that's read heavily. Tables overlapping the range are
+	// chosen by the score-based compaction picker before other tables in the
+	// same level, and L0 compactions within the range are favored over those
+	// of similar depth elsewhere.
+	CompactionPriorityHigh CompactionPriority = 1
+)
+
+// String implements fmt.Stringer.
+func (p CompactionPriority) String() string {
+	switch p {
+	case CompactionPriorityLow:
+		return "low"
+	case CompactionPriorityDefault:
+		return "default"
+	case CompactionPriorityHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// compactionPriorityRange is a key range [start, end) with a registered
+// compaction priority.
+type compactionPriorityRange struct {
+	start, end []byte
+	priority   CompactionPriority
+}
+
+// compactionPriorities holds the registered compaction priorities, as a list of
+// non-overlapping key ranges sorted by start key. Key ranges with the default
+// priority are omitted. A compactionPriorities is never modified once
+// constructed, so it can be used by the compaction picker without copying.
+type compactionPriorities []compactionPriorityRange
+
+// set returns a copy of the priorities with the priority of the key range
+// [start, end) replaced by the given priority.
+func (ps compactionPriorities) set(
+	cmp base.Compare, start, end []byte, priority CompactionPriority,
+) compactionPriorities {
+	result := make(compactionPriorities, 0, len(ps)+2)
+	for _, r := range ps {
+		if cmp(r.end, start) <= 0 || cmp(r.start, end) >= 0 {
+			result = append(result, r)
+			continue
+		}
+		// Retain the parts of r outside [start, end).
+		if cmp(r.start, start) < 0 {
+			result = append(result, compactionPriorityRange{start: r.start, end: start, priority: r.priority})
+		}
+		if cmp(r.end, end) > 0 {
+			result = append(result, compactionPriorityRange{start: end, end: r.end, priority: r.priority})
+		}
+	}
+	if priority != CompactionPriorityDefault {
+		result = append(result, compactionPriorityRange{start: start, end: end, priority: priority})
+	}
+	slices.SortFunc(result, func(a, b compactionPriorityRange) int {
+		return cmp(a.start, b.start)
+	})
+	return result
+}
+
+// forBounds returns the priority of compacting the keys within the bounds: high
+// if the bounds overlap any range with a high priority, low if the bounds are
+// entirely covered by ranges with a low priority, and the default priority
+// otherwise.
+func (ps compactionPriorities) forBounds(
+	cmp base.Compare, bounds base.UserKeyBounds,
+) CompactionPriority {
+	if len(ps) == 0 {
+		return CompactionPriorityDefault
+	}
+	// covered is the exclusive end of the prefix of the bounds covered by
+	// contiguous low priority ranges, if any.
+	covered := bounds.Start
+	coveredLow := true
+	for _, r := range ps {
+		if cmp(r.start, bounds.End.Key) > 0 || (cmp(r.start, bounds.End.Key) == 0 && bounds.End.Kind == base.Exclusive) {
+			break
+		}
+		if cmp(r.end, bounds.Start) <= 0 {
+			continue
+		}
+		if r.priority == CompactionPriorityHigh {
+			return CompactionPriorityHigh
+		}
+		if coveredLow && cmp(r.start, covered) <= 0 {
+			covered = r.end
+		} else {
+			coveredLow = false
+		}
+	}
+	if coveredLow && !bounds.End.IsUpperBoundFor(cmp, covered) {
+		return CompactionPriorityLow
+	}
+	return CompactionPriorityDefault
+}
+
+// intervalPriority returns a manifest.IntervalPriority that weighs L0
+// compactions by the priorities, or nil if there are none.
+func (ps compactionPriorities) intervalPriority(cmp base.Compare) func(base.UserKeyBounds) int {
+	if len(ps) == 0 {
+		return nil
+	}
+	return func(bounds base.UserKeyBounds) int {
+		return int(ps.forBounds(cmp, bounds))
+	}
+}
+
+// SetCompactionPriority registers a compaction priority for the keys in the
+// range [start, end), replacing any priority previously registered for keys
+// within the range. Setting CompactionPriorityDefault clears the priority of
+// the range.
+//
+// The priorities are taken into account by the default, score-based
+// compaction picker when it chooses the tables to compact within a level and
+// when it chooses L0 compactions. They don't affect which level is compacted.
+// Priorities are not persisted, and must be registered again after the DB is
+// reopened.
+func (d *DB) SetCompactionPriority(start, end []byte, priority CompactionPriority) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.cmp(start, end) >= 0 {
+		return errors.Errorf("SetCompactionPriority start %s is not less than end %s",
+			d.opts.Comparer.FormatKey(start), d.opts.Comparer.FormatKey(end))
+	}
+	switch priority {
+	case CompactionPriorityLow, CompactionPriorityDefault, CompactionPriorityHigh:
+	default:
+		return errors.Errorf("unknown compaction priority %d", priority)
+	}
+	start, end = slices.Clone(start), slices.Clone(end)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mu.compact.priorities = d.mu.compact.priorities.set(d.cmp, start, end, priority)
+	d.maybeScheduleCompaction()
+	return nil
+}
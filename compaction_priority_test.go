@@ -0,0 +1,58 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactionPriorities(t *testing.T) {
+	cmp := base.DefaultComparer.Compare
+	format := func(ps compactionPriorities) string {
+		var parts []string
+		for _, r := range ps {
+			parts = append(parts, fmt.Sprintf("[%s,%s):%s", r.start, r.end, r.priority))
+		}
+		return strings.Join(parts, " ")
+	}
+
+	var ps compactionPriorities
+	ps = ps.set(cmp, []byte("c"), []byte("g"), CompactionPriorityLow)
+	ps = ps.set(cmp, []byte("m"), []byte("p"), CompactionPriorityHigh)
+	require.Equal(t, "[c,g):low [m,p):high", format(ps))
+
+	// Setting a priority replaces the priorities of the overlapping ranges.
+	ps2 := ps.set(cmp, []byte("e"), []byte("n"), CompactionPriorityLow)
+	require.Equal(t, "[c,e):low [e,n):low [n,p):high", format(ps2))
+	ps2 = ps2.set(cmp, []byte("d"), []byte("f"), CompactionPriorityDefault)
+	require.Equal(t, "[c,d):low [f,n):low [n,p):high", format(ps2))
+	// The original priorities are unmodified.
+	require.Equal(t, "[c,g):low [m,p):high", format(ps))
+
+	testCases := []struct {
+		bounds base.UserKeyBounds
+		want   CompactionPriority
+	}{
+		{base.UserKeyBoundsInclusive([]byte("a"), []byte("b")), CompactionPriorityDefault},
+		{base.UserKeyBoundsInclusive([]byte("c"), []byte("f")), CompactionPriorityLow},
+		{base.UserKeyBoundsInclusive([]byte("c"), []byte("g")), CompactionPriorityDefault},
+		{base.UserKeyBoundsEndExclusive([]byte("c"), []byte("g")), CompactionPriorityLow},
+		{base.UserKeyBoundsInclusive([]byte("b"), []byte("d")), CompactionPriorityDefault},
+		{base.UserKeyBoundsInclusive([]byte("e"), []byte("m")), CompactionPriorityHigh},
+		{base.UserKeyBoundsEndExclusive([]byte("e"), []byte("m")), CompactionPriorityDefault},
+		{base.UserKeyBoundsInclusive([]byte("o"), []byte("z")), CompactionPriorityHigh},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, ps.forBounds(cmp, tc.bounds), "%s", tc.bounds)
+	}
+	// Adjacent low priority ranges cover the bounds together.
+	require.Equal(t, CompactionPriorityLow,
+		ps2.set(cmp, []byte("d"), []byte("f"), CompactionPriorityLow).forBounds(cmp, base.UserKeyBoundsInclusive([]byte("c"), []byte("m"))))
+}
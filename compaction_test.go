@@ -72,6 +72,10 @@ func (p *compactionPickerForTesting) estimatedCompactionDebt(l0ExtraSize uint64)
 
 func (p *compactionPickerForTesting) forceBaseLevel1() {}
 
+func (p *compactionPickerForTesting) nextFIFOExpiry() (time.Time, bool) {
+	return time.Time{}, false
+}
+
 func (p *compactionPickerForTesting) pickAuto(env compactionEnv) (pc *pickedCompaction) {
 	if p.score < 1 {
 		return nil
@@ -2239,7 +2243,7 @@ func TestCompactionCheckOrdering(t *testing.T) {
 					return iterSet{point: &errorIter{}}, nil
 				}
 				result := "OK"
-				_, _, _, err := c.newInputIters(newIters, nil)
+				_, _, _, _, err := c.newSubcompactions(nil)[0].newInputIters(newIters, nil)
 				if err != nil {
 					result = fmt.Sprint(err)
 				}
@@ -2326,13 +2330,13 @@ func TestCompactFlushQueuedLargeBatch(t *testing.T) {
 	// size in order to more easily create a situation where a large batch is
 	// queued but not automatically flushed.
 	d.mu.Lock()
-	d.largeBatchThreshold = d.opts.MemTableSize / 8
+	d.largeBatchThreshold.Store(d.opts.MemTableSize / 8)
 	require.Equal(t, 1, len(d.mu.mem.queue))
 	d.mu.Unlock()
 
 	// Set a record with a large value. This will be transformed into a large
 	// batch and placed in the flushable queue.
-	require.NoError(t, d.Set([]byte("a"), bytes.Repeat([]byte("v"), int(d.largeBatchThreshold)), nil))
+	require.NoError(t, d.Set([]byte("a"), bytes.Repeat([]byte("v"), int(d.largeBatchThreshold.Load())), nil))
 	d.mu.Lock()
 	require.Greater(t, len(d.mu.mem.queue), 1)
 	d.mu.Unlock()
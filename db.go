@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,6 +24,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/keyspan/keyspanimpl"
 	"github.com/cockroachdb/pebble/internal/manifest"
 	"github.com/cockroachdb/pebble/internal/manual"
+	"github.com/cockroachdb/pebble/internal/rangemerge"
 	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/cockroachdb/pebble/objstorage/remote"
 	"github.com/cockroachdb/pebble/rangekey"
@@ -288,12 +290,36 @@ type DB struct {
 	split          Split
 	abbreviatedKey AbbreviatedKey
 	// The threshold for determining when a batch is "large" and will skip being
-	// inserted into a memtable.
-	largeBatchThreshold uint64
-	// The current OPTIONS file number.
+	// inserted into a memtable. It's derived from Options.MemTableSize, which
+	// may be changed by SetOptions, and is read without holding d.mu.
+	largeBatchThreshold atomic.Uint64
+	// memTableSize mirrors Options.MemTableSize for readers that don't hold
+	// d.mu, such as the WAL manager when preallocating new WAL files.
+	memTableSize atomic.Uint64
+	// keyRangeStats holds the sampled keys used by DB.KeyRangeActivity. It is
+	// nil if Options.Experimental.KeyRangeSampleSize is zero.
+	keyRangeStats *keyRangeSampler
+	// secondaryIndexes holds the secondary indexes maintained by batches
+	// committed to the DB. It is nil if Options.SecondaryIndexes is empty.
+	secondaryIndexes *secondaryIndexRegistry
+	// writeAdmission delays writes as the DB approaches a write stall. It is
+	// nil unless Options.Experimental.WriteAdmission.Enabled is set.
+	writeAdmission *writeAdmissionController
+	// writeStalled is set while a write stall is in effect. Writes of
+	// WritePriorityLow wait for it to be cleared before committing.
+	writeStalled atomic.Bool
+	// writeStats holds the per-priority counters reported by Metrics.Writes.
+	writeStats [NumWritePriorities]struct {
+		count, bytes, stallDuration, admissionDelay atomic.Int64
+	}
+	// The current OPTIONS file number. Protected by mu once the DB is open,
+	// since SetOptions writes a new OPTIONS file.
 	optionsFileNum base.DiskFileNum
-	// The on-disk size of the current OPTIONS file.
+	// The on-disk size of the current OPTIONS file. Protected by mu once the
+	// DB is open.
 	optionsFileSize uint64
+	// setOptionsMu serializes calls to SetOptions.
+	setOptionsMu sync.Mutex
 
 	// objProvider is used to access and manage SSTs.
 	objProvider objstorage.Provider
@@ -324,6 +350,24 @@ type DB struct {
 	// compactionShedulers.Wait() should not be called while the DB.mu is held.
 	compactionSchedulers sync.WaitGroup
 
+	// mergedValues queues the writes of merged values by reads of keys with
+	// more than Options.Experimental.MergeOperandLimit merge operands. A
+	// single goroutine writes the queued values. See DB.maybeWriteMergedValue.
+	mergedValues struct {
+		sync.Mutex
+		// queue holds the merged values waiting to be written, and queued
+		// holds their user keys.
+		queue  []mergedValue
+		queued map[string]struct{}
+		// running is true while the goroutine writing the queue runs.
+		running bool
+		// stopped is set by Close, or once writing a merged value fails. No
+		// more values are written once it's set.
+		stopped bool
+		// wg is waited on by Close, before the commit pipeline is locked.
+		wg sync.WaitGroup
+	}
+
 	// The main mutex protecting internal DB state. This mutex encompasses many
 	// fields because those fields need to be accessed and updated atomically. In
 	// particular, the current version, log.*, mem.*, and snapshot list need to
@@ -427,6 +471,9 @@ type DB struct {
 			// The list of deletion hints, suggesting ranges for delete-only
 			// compactions.
 			deletionHints []deleteCompactionHint
+			// The compaction priorities of key ranges, registered through
+			// SetCompactionPriority.
+			priorities compactionPriorities
 			// The list of manual compactions. The next manual compaction to perform
 			// is at the start of the list. New entries are added to the end.
 			manual []*manualCompaction
@@ -456,6 +503,12 @@ type DB struct {
 			// The idle start time for the flush "loop", i.e., when the flushing
 			// bool above transitions to false.
 			noOngoingFlushStartTime time.Time
+			// fifoAgeCheck schedules compactions once the oldest table expires
+			// under CompactionStyleFIFO. See DB.maybeScheduleFIFOAgeCheck.
+			fifoAgeCheck struct {
+				timer *time.Timer
+				due   time.Time
+			}
 		}
 
 		// Non-zero when file cleaning is disabled. The disabled count acts as a
@@ -570,8 +623,40 @@ func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer,
 	}
 
 	buf := getIterAllocPool.Get().(*getIterAlloc)
+	pointIter := d.initGetIter(&buf.get, key, b, readState, seqNum)
 
-	get := &buf.get
+	i := &buf.dbi
+	*i = Iterator{
+		ctx:          context.Background(),
+		getIterAlloc: buf,
+		iter:         pointIter,
+		pointIter:    pointIter,
+		merge:        d.merge,
+		comparer:     *d.opts.Comparer,
+		readState:    readState,
+		keyBuf:       buf.keyBuf,
+		seqNum:       seqNum,
+	}
+	if b == nil && !d.opts.ReadOnly {
+		i.mergeOperandLimit = d.opts.Experimental.MergeOperandLimit
+	}
+
+	if !i.First() {
+		err := i.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrNotFound
+	}
+	return i.Value(), i, nil
+}
+
+// initGetIter initializes get to read the records of key visible at seqNum in
+// b, if non-nil, and the memtables and version of readState. It returns the
+// iterator surfacing the records, which applies any range merges to them.
+func (d *DB) initGetIter(
+	get *getIter, key []byte, b *Batch, readState *readState, seqNum base.SeqNum,
+) topLevelIterator {
 	*get = getIter{
 		comparer: d.opts.Comparer,
 		newIters: d.newIters,
@@ -604,27 +689,128 @@ func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer,
 		get.mem = get.mem[:n-1]
 	}
 
-	i := &buf.dbi
-	pointIter := get
-	*i = Iterator{
-		ctx:          context.Background(),
-		getIterAlloc: buf,
-		iter:         pointIter,
-		pointIter:    pointIter,
-		merge:        d.merge,
-		comparer:     *d.opts.Comparer,
-		readState:    readState,
-		keyBuf:       buf.keyBuf,
+	var pointIter topLevelIterator = get
+	// Apply any range merges in the memtables or version.
+	if spans := newRangeMergeIter(context.Background(), d.opts.Comparer, d.newIters,
+		get.mem, readState.current, &get.iterOpts); spans != nil {
+		pointIter = rangemerge.NewIter(d.opts.Comparer.Compare, pointIter, spans, seqNum, nil)
 	}
+	return pointIter
+}
 
-	if !i.First() {
-		err := i.Close()
+// maxQueuedMergedValues bounds the number of merged values queued for
+// writing. Reads drop merged values while the queue is full.
+const maxQueuedMergedValues = 64
+
+// maxMergedValueAttempts bounds the number of times the write of a merged
+// value is attempted while other batches are committed concurrently.
+const maxMergedValueAttempts = 3
+
+// mergedValue is a merged value queued by maybeWriteMergedValue.
+type mergedValue struct {
+	key, value []byte
+	seqNum     base.SeqNum
+}
+
+// maybeWriteMergedValue asynchronously writes value, the result of merging the
+// records of key visible at seqNum, back to the DB as a SET of key. Reads call
+// it when the key has more merge operands than
+// Options.Experimental.MergeOperandLimit, so that later reads of the key don't
+// need to merge the same operands again. Writing back is best effort: the
+// value is dropped if too many values are already queued.
+func (d *DB) maybeWriteMergedValue(key, value []byte, seqNum base.SeqNum) {
+	d.mergedValues.Lock()
+	defer d.mergedValues.Unlock()
+	if d.mergedValues.stopped || len(d.mergedValues.queue) >= maxQueuedMergedValues {
+		return
+	}
+	if _, ok := d.mergedValues.queued[string(key)]; ok {
+		// A concurrent read already queued the key's merged value.
+		return
+	}
+	if d.mergedValues.queued == nil {
+		d.mergedValues.queued = make(map[string]struct{})
+	}
+	d.mergedValues.queued[string(key)] = struct{}{}
+	d.mergedValues.queue = append(d.mergedValues.queue, mergedValue{
+		key:    slices.Clone(key),
+		value:  slices.Clone(value),
+		seqNum: seqNum,
+	})
+	if !d.mergedValues.running {
+		d.mergedValues.running = true
+		d.mergedValues.wg.Add(1)
+		go d.writeMergedValues()
+	}
+}
+
+// writeMergedValues writes the queued merged values until the queue is empty.
+func (d *DB) writeMergedValues() {
+	defer d.mergedValues.wg.Done()
+	for {
+		d.mergedValues.Lock()
+		if len(d.mergedValues.queue) == 0 || d.mergedValues.stopped {
+			d.mergedValues.running = false
+			d.mergedValues.Unlock()
+			return
+		}
+		v := d.mergedValues.queue[0]
+		d.mergedValues.queue[0] = mergedValue{}
+		d.mergedValues.queue = d.mergedValues.queue[1:]
+		d.mergedValues.Unlock()
+
+		err := d.writeMergedValue(v)
+
+		d.mergedValues.Lock()
+		delete(d.mergedValues.queued, string(v.key))
 		if err != nil {
-			return nil, nil, err
+			// Writing back is only an optimization, so the error isn't
+			// surfaced to reads. A failed commit leaves the commit pipeline
+			// unusable though, so stop writing merged values.
+			d.opts.Logger.Errorf("pebble: writing merged value: %v", err)
+			d.mergedValues.stopped = true
 		}
-		return nil, nil, ErrNotFound
+		d.mergedValues.Unlock()
 	}
-	return i.Value(), i, nil
+}
+
+// writeMergedValue writes v.value back to the DB as a SET of v.key, if no
+// records of the key were written at or after v.seqNum. Otherwise the SET
+// could hide those records, which aren't reflected in the value.
+func (d *DB) writeMergedValue(v mergedValue) error {
+	b := newBatch(d)
+	defer func() { _ = b.Close() }()
+	// NB: Batch.Set would maintain secondary indexes, but the key's value
+	// isn't changing.
+	ikey := base.MakeInternalKey(v.key, 0, InternalKeyKindSet)
+	if err := b.AddInternalKey(&ikey, v.value, nil); err != nil {
+		return err
+	}
+	if b.memTableSize >= d.largeBatchThreshold.Load() {
+		return nil
+	}
+	for attempt := 0; attempt < maxMergedValueAttempts; attempt++ {
+		// Read the newest record of the key visible at the visible sequence
+		// number. If a range deletion written since v.seqNum deletes the key,
+		// no record is visible. The read may need to read sstables, so it's
+		// performed outside of the commit pipeline, which then only verifies
+		// that no batch was sequenced since.
+		visibleSeqNum := d.mu.versions.visibleSeqNum.Load()
+		readState := d.loadReadState()
+		var get getIter
+		iter := d.initGetIter(&get, v.key, nil /* batch */, readState, visibleSeqNum)
+		kv := iter.First()
+		unchanged := kv != nil && kv.SeqNum() < v.seqNum
+		err := firstError(iter.Error(), iter.Close())
+		readState.unref()
+		if err != nil || !unchanged {
+			return err
+		}
+		if ok, err := d.commit.CommitIfUnchanged(b, visibleSeqNum); ok || err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Set sets the value for the given key. It overwrites any previous value
@@ -633,7 +819,11 @@ func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer,
 // It is safe to modify the contents of the arguments after Set returns.
 func (d *DB) Set(key, value []byte, opts *WriteOptions) error {
 	b := newBatch(d)
-	_ = b.Set(key, value, opts)
+	if err := b.Set(key, value, opts); err != nil {
+		// Maintaining secondary indexes may fail.
+		_ = b.Close()
+		return err
+	}
 	if err := d.Apply(b, opts); err != nil {
 		return err
 	}
@@ -647,7 +837,10 @@ func (d *DB) Set(key, value []byte, opts *WriteOptions) error {
 // It is safe to modify the contents of the arguments after Delete returns.
 func (d *DB) Delete(key []byte, opts *WriteOptions) error {
 	b := newBatch(d)
-	_ = b.Delete(key, opts)
+	if err := b.Delete(key, opts); err != nil {
+		_ = b.Close()
+		return err
+	}
 	if err := d.Apply(b, opts); err != nil {
 		return err
 	}
@@ -670,7 +863,10 @@ func (d *DB) Delete(key []byte, opts *WriteOptions) error {
 // returns.
 func (d *DB) DeleteSized(key []byte, valueSize uint32, opts *WriteOptions) error {
 	b := newBatch(d)
-	_ = b.DeleteSized(key, valueSize, opts)
+	if err := b.DeleteSized(key, valueSize, opts); err != nil {
+		_ = b.Close()
+		return err
+	}
 	if err := d.Apply(b, opts); err != nil {
 		return err
 	}
@@ -684,7 +880,10 @@ func (d *DB) DeleteSized(key []byte, valueSize uint32, opts *WriteOptions) error
 // It is safe to modify the contents of the arguments after SingleDelete returns.
 func (d *DB) SingleDelete(key []byte, opts *WriteOptions) error {
 	b := newBatch(d)
-	_ = b.SingleDelete(key, opts)
+	if err := b.SingleDelete(key, opts); err != nil {
+		_ = b.Close()
+		return err
+	}
 	if err := d.Apply(b, opts); err != nil {
 		return err
 	}
@@ -707,6 +906,21 @@ func (d *DB) DeleteRange(start, end []byte, opts *WriteOptions) error {
 	return b.Close()
 }
 
+// RangeMerge merges operand into the value of every point key in the range
+// [start,end) that exists at the time the range merge is applied. See
+// Batch.RangeMerge for more details.
+//
+// It is safe to modify the contents of the arguments after RangeMerge returns.
+func (d *DB) RangeMerge(start, end, operand []byte, opts *WriteOptions) error {
+	b := newBatch(d)
+	_ = b.RangeMerge(start, end, operand, opts)
+	if err := d.Apply(b, opts); err != nil {
+		return err
+	}
+	// Only release the batch on success.
+	return b.Close()
+}
+
 // Merge adds an action to the DB that merges the value at key with the new
 // value. The details of the merge are dependent upon the configured merge
 // operator.
@@ -841,6 +1055,10 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 	if sync && d.opts.DisableWAL {
 		return errors.New("pebble: WAL disabled")
 	}
+	priority := opts.GetPriority()
+	if priority < 0 || priority >= NumWritePriorities {
+		return errors.Errorf("pebble: invalid write priority %d", errors.Safe(priority))
+	}
 
 	if fmv := d.FormatMajorVersion(); fmv < batch.minimumFormatMajorVersion {
 		panic(fmt.Sprintf(
@@ -861,7 +1079,21 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 			return err
 		}
 	}
-	if batch.memTableSize >= d.largeBatchThreshold {
+	if priority == WritePriorityLow && d.writeStalled.Load() {
+		// Wait for the stall to end before entering the commit pipeline, where
+		// the write would hold up the writes queued behind it.
+		now := time.Now()
+		d.mu.Lock()
+		for d.writeStalled.Load() {
+			d.mu.compact.cond.Wait()
+		}
+		d.mu.Unlock()
+		batch.commitStats.PriorityWaitDuration = time.Since(now)
+	}
+	if d.writeAdmission != nil {
+		batch.commitStats.AdmissionDelayDuration = d.writeAdmission.admit(batch, priority)
+	}
+	if batch.memTableSize >= d.largeBatchThreshold.Load() {
 		var err error
 		batch.flushable, err = newFlushableBatch(batch, d.opts.Comparer)
 		if err != nil {
@@ -873,6 +1105,15 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 		// horked at this point.
 		d.opts.Logger.Fatalf("pebble: fatal commit error: %v", err)
 	}
+	stats := &d.writeStats[priority]
+	stats.count.Add(1)
+	stats.bytes.Add(int64(len(batch.data)))
+	stats.stallDuration.Add(int64(batch.commitStats.MemTableWriteStallDuration +
+		batch.commitStats.L0ReadAmpWriteStallDuration + batch.commitStats.PriorityWaitDuration))
+	stats.admissionDelay.Add(int64(batch.commitStats.AdmissionDelayDuration))
+	if d.keyRangeStats != nil {
+		d.keyRangeStats.sampleBatch(batch)
+	}
 	// If this is a large batch, we need to clear the batch contents as the
 	// flushable batch may still be present in the flushables queue.
 	//
@@ -975,6 +1216,9 @@ func (d *DB) commitWrite(b *Batch, syncWG *sync.WaitGroup, syncErr *error) (*mem
 	if err != nil {
 		return nil, err
 	}
+	if d.writeAdmission != nil && !b.ingestedSSTBatch && b.flushable == nil {
+		d.writeAdmission.updateMutableMemTable(uint64(mem.reserved))
+	}
 	if d.opts.DisableWAL {
 		return mem, nil
 	}
@@ -1064,6 +1308,7 @@ func (d *DB) newIter(
 	var readState *readState
 	var newIters tableNewIters
 	var newIterRangeKey keyspanimpl.TableNewSpanIter
+	var tableCache *tableCacheContainer
 	if !internalOpts.batch.batchOnly {
 		// Grab and reference the current readState. This prevents the underlying
 		// files in the associated version from being deleted if there is a current
@@ -1088,6 +1333,7 @@ func (d *DB) newIter(
 		}
 		newIters = d.newIters
 		newIterRangeKey = d.tableNewRangeKeyIter
+		tableCache = d.tableCache
 	}
 
 	// Bundle various structures under a single umbrella in order to allocate
@@ -1107,6 +1353,7 @@ func (d *DB) newIter(
 		batch:               batch,
 		newIters:            newIters,
 		newIterRangeKey:     newIterRangeKey,
+		tableCache:          tableCache,
 		seqNum:              seqNum,
 		batchOnlyIter:       internalOpts.batch.batchOnly,
 	}
@@ -1120,6 +1367,8 @@ func (d *DB) newIter(
 	}
 	if batch != nil {
 		dbi.batchSeqNum = dbi.batch.nextSeqNum()
+	} else if readState != nil && !d.opts.ReadOnly {
+		dbi.mergeOperandLimit = d.opts.Experimental.MergeOperandLimit
 	}
 	return finishInitializingIter(ctx, buf)
 }
@@ -1403,6 +1652,9 @@ func (i *Iterator) constructPointIter(
 	if i.opts.RangeKeyMasking.Filter != nil {
 		internalOpts.boundLimitedFilter = &i.rangeKeyMasking
 	}
+	if i.opts.Prefetch.Files > 0 && i.tableCache != nil && i.prefetcher == nil {
+		i.prefetcher = newTablePrefetcher(ctx, i.tableCache)
+	}
 
 	// Merging levels and levels from iterAlloc.
 	mlevels := buf.mlevels[:0]
@@ -1428,11 +1680,8 @@ func (i *Iterator) constructPointIter(
 		numMergingLevels += len(current.L0SublevelFiles)
 		numLevelIters += len(current.L0SublevelFiles)
 		for level := 1; level < len(current.Levels); level++ {
-			if current.Levels[level].Empty() {
-				continue
-			}
-			numMergingLevels++
-			numLevelIters++
+			numMergingLevels += current.NumRuns(level)
+			numLevelIters += current.NumRuns(level)
 		}
 	}
 
@@ -1489,6 +1738,7 @@ func (i *Iterator) constructPointIter(
 			li.init(ctx, i.opts, &i.comparer, i.newIters, files, level, internalOpts)
 			li.initRangeDel(&mlevels[mlevelsIndex])
 			li.initCombinedIterState(&i.lazyCombinedIter.combinedIterState)
+			li.initPrefetch(i.prefetcher)
 			mlevels[mlevelsIndex].levelIter = li
 			mlevels[mlevelsIndex].iter = invalidating.MaybeWrapIfInvariants(li)
 
@@ -1502,8 +1752,16 @@ func (i *Iterator) constructPointIter(
 			addLevelIterForFiles(current.L0SublevelFiles[i].Iter(), manifest.L0Sublevel(i))
 		}
 
-		// Add level iterators for the non-empty non-L0 levels.
+		// Add level iterators for the non-empty non-L0 levels. A level holding
+		// multiple sorted runs gets one level iterator per run, iterating from
+		// newest to oldest.
 		for level := 1; level < len(current.Levels); level++ {
+			if runs := current.Runs[level]; runs != nil {
+				for run := len(runs) - 1; run >= 0; run-- {
+					addLevelIterForFiles(runs[run].Iter(), manifest.LevelRun(level, run))
+				}
+				continue
+			}
 			if current.Levels[level].Empty() {
 				continue
 			}
@@ -1519,6 +1777,105 @@ func (i *Iterator) constructPointIter(
 	buf.merging.combinedIterState = &i.lazyCombinedIter.combinedIterState
 	i.pointIter = invalidating.MaybeWrapIfInvariants(&buf.merging).(topLevelIterator)
 	i.merging = &buf.merging
+
+	// Apply any range merges in the memtables or version. Range merges within
+	// the batch are not indexed, and aren't visible until the batch commits.
+	if !i.batchOnlyIter {
+		if spans := newRangeMergeIter(ctx, &i.comparer, i.newIters, memtables, current, &i.opts); spans != nil {
+			i.pointIter = rangemerge.NewIter(i.comparer.Compare, i.pointIter, spans, i.seqNum, nil)
+		}
+	}
+}
+
+// newRangeMergeIter returns a fragment iterator over the range merges within
+// the provided memtables and version, or nil if there are none.
+func newRangeMergeIter(
+	ctx context.Context,
+	comparer *Comparer,
+	newIters tableNewIters,
+	memtables flushableList,
+	current *version,
+	opts *IterOptions,
+) keyspan.FragmentIterator {
+	var iters []keyspan.FragmentIterator
+	for j := len(memtables) - 1; j >= 0; j-- {
+		if iter := memtables[j].newRangeMergeIter(opts); iter != nil {
+			iters = append(iters, iter)
+		}
+	}
+	if !current.HasRangeMerges {
+		return newRangeMergeMergingIter(comparer, iters)
+	}
+	var newIter keyspanimpl.TableNewSpanIter
+	// As with range keys, we don't maintain a separate L0Sublevels for files
+	// containing range merges. Instead, we add a level iterator for each L0
+	// sublevel containing a file with range merges.
+	if !current.RangeMergeLevels[0].Empty() {
+		newIter = tableNewRangeMergeIter(newIters)
+		for j := len(current.L0SublevelFiles) - 1; j >= 0; j-- {
+			iter := current.L0SublevelFiles[j].Iter()
+			if !containsAnyRangeMerges(iter) {
+				continue
+			}
+			iters = append(iters, keyspanimpl.NewLevelIter(
+				ctx, opts.SpanIterOptions(), comparer.Compare, newIter,
+				iter.Filter(manifest.KeyTypePoint), manifest.L0Sublevel(j), manifest.KeyTypePoint,
+			))
+		}
+	}
+	for level := 1; level < len(current.RangeMergeLevels); level++ {
+		if current.RangeMergeLevels[level].Empty() {
+			continue
+		}
+		if newIter == nil {
+			newIter = tableNewRangeMergeIter(newIters)
+		}
+		// Likewise, a level holding multiple sorted runs gets a level iterator
+		// for each run containing a file with range merges.
+		if runs := current.Runs[level]; runs != nil {
+			for run := len(runs) - 1; run >= 0; run-- {
+				iter := runs[run].Iter()
+				if !containsAnyRangeMerges(iter) {
+					continue
+				}
+				iters = append(iters, keyspanimpl.NewLevelIter(
+					ctx, opts.SpanIterOptions(), comparer.Compare, newIter,
+					iter.Filter(manifest.KeyTypePoint), manifest.LevelRun(level, run), manifest.KeyTypePoint,
+				))
+			}
+			continue
+		}
+		iters = append(iters, keyspanimpl.NewLevelIter(
+			ctx, opts.SpanIterOptions(), comparer.Compare, newIter,
+			current.RangeMergeLevels[level].Iter(), manifest.Level(level), manifest.KeyTypePoint,
+		))
+	}
+	return newRangeMergeMergingIter(comparer, iters)
+}
+
+// newRangeMergeMergingIter returns a fragment iterator merging the range
+// merges of iters, or nil if there are no iters.
+func newRangeMergeMergingIter(
+	comparer *Comparer, iters []keyspan.FragmentIterator,
+) keyspan.FragmentIterator {
+	switch len(iters) {
+	case 0:
+		return nil
+	case 1:
+		return iters[0]
+	}
+	mi := &keyspanimpl.MergingIter{}
+	mi.Init(comparer, keyspan.NoopTransform, new(keyspanimpl.MergingBuffers), iters...)
+	return mi
+}
+
+func containsAnyRangeMerges(iter manifest.LevelIterator) bool {
+	for f := iter.First(); f != nil; f = iter.Next() {
+		if f.HasRangeMerges {
+			return true
+		}
+	}
+	return false
 }
 
 // NewBatch returns a new empty write-only batch. Any reads on the batch will
@@ -1611,6 +1968,17 @@ func (d *DB) NewEventuallyFileOnlySnapshot(keyRanges []KeyRange) *EventuallyFile
 // or to call Close concurrently with any other DB method. It is not valid
 // to call any of a DB's methods after the DB has been closed.
 func (d *DB) Close() error {
+	// Stop any secondary index backfills before locking, as they commit
+	// batches and hold iterators open.
+	if d.secondaryIndexes != nil {
+		d.secondaryIndexes.stopBackfills()
+	}
+	// Wait for the writes of merged values by reads, which commit batches.
+	d.mergedValues.Lock()
+	d.mergedValues.stopped = true
+	d.mergedValues.Unlock()
+	d.mergedValues.wg.Wait()
+
 	// Lock the commit pipeline for the duration of Close. This prevents a race
 	// with makeRoomForWrite. Rotating the WAL in makeRoomForWrite requires
 	// dropping d.mu several times for I/O. If Close only holds d.mu, an
@@ -1638,6 +2006,9 @@ func (d *DB) Close() error {
 
 	d.closed.Store(errors.WithStack(ErrClosed))
 	close(d.closedCh)
+	if d.mu.compact.fifoAgeCheck.timer != nil {
+		d.mu.compact.fifoAgeCheck.timer.Stop()
+	}
 
 	defer d.opts.Cache.Unref()
 
@@ -1666,6 +2037,9 @@ func (d *DB) Close() error {
 		panic("pebble: log-writer should be nil in read-only mode")
 	}
 	err = firstError(err, d.mu.log.manager.Close())
+	if d.keyRangeStats != nil && !d.opts.ReadOnly {
+		err = firstError(err, d.keyRangeStats.save(d.opts.FS, d.dirname, d.dataDir))
+	}
 	err = firstError(err, d.fileLock.Close())
 
 	// Note that versionSet.close() only closes the MANIFEST. The versions list
@@ -1771,6 +2145,13 @@ func (d *DB) Compact(start, end []byte, parallelize bool) error {
 			maxLevelWithFiles = level + 1
 		}
 	}
+	// Under CompactionStyleTiered, a compaction merges all the sorted runs of
+	// a level into a new run in the next level, so the data is carried all
+	// the way down, and the runs of the bottommost level are merged into one.
+	tiered := d.opts.Experimental.CompactionStyle == CompactionStyleTiered
+	if tiered {
+		maxLevelWithFiles = numLevels
+	}
 
 	// Determine if any memtable overlaps with the compaction range. We wait for
 	// any such overlap to flush (initiating a flush if necessary).
@@ -1831,7 +2212,7 @@ func (d *DB) Compact(start, end []byte, parallelize bool) error {
 			break
 		}
 		level++
-		if level == numLevels-1 {
+		if level == numLevels-1 && !tiered {
 			// A manual compaction of the bottommost level occurred.
 			// There is no next level to try and compact.
 			break
@@ -1850,7 +2231,9 @@ func (d *DB) manualCompact(start, end []byte, level int, parallelize bool) error
 	}
 
 	var compactions []*manualCompaction
-	if parallelize {
+	if parallelize && d.opts.Experimental.CompactionStyle != CompactionStyleTiered {
+		// Under CompactionStyleTiered, a compaction merges the whole level, so
+		// it isn't split.
 		compactions = append(compactions, d.splitManualCompaction(start, end, level)...)
 	} else {
 		compactions = append(compactions, &manualCompaction{
@@ -2018,6 +2401,18 @@ func (d *DB) Metrics() *Metrics {
 		d.opts.Logger.Errorf("metrics error: %s", err)
 	}
 	metrics.Flush.WriteThroughput = d.mu.compact.flushWriteThroughput
+	if d.writeAdmission != nil {
+		d.writeAdmission.metrics(metrics)
+	}
+	for i := range d.writeStats {
+		s := &d.writeStats[i]
+		metrics.Writes[i] = WriteMetrics{
+			Count:          s.count.Load(),
+			Bytes:          s.bytes.Load(),
+			StallDuration:  time.Duration(s.stallDuration.Load()),
+			AdmissionDelay: time.Duration(s.admissionDelay.Load()),
+		}
+	}
 	if d.mu.compact.flushing {
 		metrics.Flush.NumInProgress = 1
 	}
@@ -2320,7 +2715,7 @@ func (d *DB) walPreallocateSize() int {
 	// TODO(peter): 110% of the memtable size is quite hefty for a block
 	// size. This logic is taken from GetWalPreallocateBlockSize in
 	// RocksDB. Could a smaller preallocation block size be used?
-	size := d.opts.MemTableSize
+	size := d.memTableSize.Load()
 	size = (size / 10) + size
 	return int(size)
 }
@@ -2330,14 +2725,17 @@ func (d *DB) newMemTable(
 ) (*memTable, *flushableEntry) {
 	targetSize := minSize + uint64(memTableEmptySize)
 	// The targetSize should be less than MemTableSize, because any batch >=
-	// MemTableSize/2 should be treated as a large flushable batch.
-	if targetSize > d.opts.MemTableSize {
-		panic(errors.AssertionFailedf("attempting to allocate memtable larger than MemTableSize"))
+	// MemTableSize/2 should be treated as a large flushable batch. The
+	// exception is a batch that was sized against a larger MemTableSize that
+	// SetOptions has since reduced; the memtable is then sized to fit it.
+	maxSize := d.opts.MemTableSize
+	if targetSize > maxSize {
+		maxSize = targetSize
 	}
 	// Double until the next memtable size is at least large enough to fit
 	// minSize.
 	for d.mu.mem.nextSize < targetSize {
-		d.mu.mem.nextSize = min(2*d.mu.mem.nextSize, d.opts.MemTableSize)
+		d.mu.mem.nextSize = min(2*d.mu.mem.nextSize, maxSize)
 	}
 	size := d.mu.mem.nextSize
 	// The next memtable should be double the size, up to Options.MemTableSize.
@@ -2462,6 +2860,7 @@ func (d *DB) maybeInduceWriteStall(b *Batch) {
 			// are still flushing, so we wait.
 			if !stalled {
 				stalled = true
+				d.writeStalled.Store(true)
 				d.opts.EventListener.WriteStallBegin(WriteStallBeginInfo{
 					Reason: "memtable count limit reached",
 				})
@@ -2473,11 +2872,16 @@ func (d *DB) maybeInduceWriteStall(b *Batch) {
 			}
 			continue
 		}
+		// Under CompactionStyleFIFO, flushed tables are never compacted out of
+		// L0, so a stall on the L0 read amplification might never end. The
+		// FIFO retention limits bound L0 instead.
 		l0ReadAmp := d.mu.versions.currentVersion().L0Sublevels.ReadAmplification()
-		if l0ReadAmp >= d.opts.L0StopWritesThreshold {
+		if l0ReadAmp >= d.opts.L0StopWritesThreshold &&
+			d.opts.Experimental.CompactionStyle != CompactionStyleFIFO {
 			// There are too many level-0 files, so we wait.
 			if !stalled {
 				stalled = true
+				d.writeStalled.Store(true)
 				d.opts.EventListener.WriteStallBegin(WriteStallBeginInfo{
 					Reason: "L0 file count limit exceeded",
 				})
@@ -2491,6 +2895,9 @@ func (d *DB) maybeInduceWriteStall(b *Batch) {
 		}
 		// Not stalled.
 		if stalled {
+			d.writeStalled.Store(false)
+			// Wake the writes of WritePriorityLow waiting for the stall to end.
+			d.mu.compact.cond.Broadcast()
 			d.opts.EventListener.WriteStallEnd()
 		}
 		return
@@ -363,14 +363,18 @@ func (c downloadCursor) NextExternalFile(
 func (c downloadCursor) NextExternalFileOnLevel(
 	cmp base.Compare, objProvider objstorage.Provider, endBound base.UserKeyBoundary, v *version,
 ) *fileMetadata {
+	sublevels := v.L0SublevelFiles
 	if c.level > 0 {
-		it := v.Levels[c.level].Iter()
-		return firstExternalFileInLevelIter(cmp, objProvider, c, it, endBound)
+		if sublevels = v.Runs[c.level]; sublevels == nil {
+			it := v.Levels[c.level].Iter()
+			return firstExternalFileInLevelIter(cmp, objProvider, c, it, endBound)
+		}
 	}
-	// For L0, we look at all sublevel iterators and take the first file.
+	// For L0, and levels holding multiple sorted runs, we look at all sublevel
+	// (or run) iterators and take the first file.
 	var first *fileMetadata
 	var firstCursor downloadCursor
-	for _, sublevel := range v.L0SublevelFiles {
+	for _, sublevel := range sublevels {
 		f := firstExternalFileInLevelIter(cmp, objProvider, c, sublevel.Iter(), endBound)
 		if f != nil {
 			c := makeCursorAtFile(f, c.level)
@@ -0,0 +1,224 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/sstable"
+)
+
+// DynamicOptions holds the subset of Options that may be changed while a DB
+// is open, using DB.SetOptions. The fields have the same meaning as the
+// corresponding fields of Options. Unlike Options, zero values are not
+// replaced with defaults, with the exception of the fields of the LevelOptions.
+type DynamicOptions struct {
+	// L0CompactionFileThreshold is Options.L0CompactionFileThreshold.
+	L0CompactionFileThreshold int
+	// L0CompactionThreshold is Options.L0CompactionThreshold.
+	L0CompactionThreshold int
+	// L0StopWritesThreshold is Options.L0StopWritesThreshold.
+	L0StopWritesThreshold int
+	// LBaseMaxBytes is Options.LBaseMaxBytes.
+	LBaseMaxBytes int64
+	// Levels is Options.Levels. The per-level options, such as the target file
+	// size, compression and filter policy, apply to sstables written after the
+	// change; existing sstables are not rewritten. A filter policy must have
+	// been known to the DB when it was opened, either through Options.Filters
+	// or through the Options.Levels in use at the time, so that the sstables
+	// written with it can be read.
+	Levels []LevelOptions
+	// MemTableSize is Options.MemTableSize. A change takes effect when the next
+	// memtable is allocated.
+	MemTableSize uint64
+	// MemTableStopWritesThreshold is Options.MemTableStopWritesThreshold.
+	MemTableStopWritesThreshold int
+	// TargetByteDeletionRate is Options.TargetByteDeletionRate.
+	TargetByteDeletionRate int
+}
+
+// DynamicOptions returns the current values of the options that may be
+// changed using SetOptions.
+func (d *DB) DynamicOptions() DynamicOptions {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return DynamicOptions{
+		L0CompactionFileThreshold:   d.opts.L0CompactionFileThreshold,
+		L0CompactionThreshold:       d.opts.L0CompactionThreshold,
+		L0StopWritesThreshold:       d.opts.L0StopWritesThreshold,
+		LBaseMaxBytes:               d.opts.LBaseMaxBytes,
+		Levels:                      slices.Clone(d.opts.Levels),
+		MemTableSize:                d.opts.MemTableSize,
+		MemTableStopWritesThreshold: d.opts.MemTableStopWritesThreshold,
+		TargetByteDeletionRate:      d.opts.TargetByteDeletionRate,
+	}
+}
+
+// SetOptions validates and applies changes to the options of an open DB. The
+// new options are persisted to a new OPTIONS file before they're applied, and
+// the OptionsChanged event is invoked once the change has been attempted. Once
+// applied, the previous OPTIONS file is deleted. If an error is returned, the
+// options of the DB are unchanged.
+//
+// Callers typically obtain the current options using DynamicOptions, modify
+// them and pass them to SetOptions.
+func (d *DB) SetOptions(o DynamicOptions) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	d.setOptionsMu.Lock()
+	defer d.setOptionsMu.Unlock()
+
+	d.mu.Lock()
+	// Options.Clone is shallow, so the Levels are copied to avoid modifying
+	// the slice in use by the DB (or the caller).
+	candidate := d.opts.Clone()
+	candidate.L0CompactionFileThreshold = o.L0CompactionFileThreshold
+	candidate.L0CompactionThreshold = o.L0CompactionThreshold
+	candidate.L0StopWritesThreshold = o.L0StopWritesThreshold
+	candidate.LBaseMaxBytes = o.LBaseMaxBytes
+	candidate.Levels = slices.Clone(o.Levels)
+	candidate.MemTableSize = o.MemTableSize
+	candidate.MemTableStopWritesThreshold = o.MemTableStopWritesThreshold
+	candidate.TargetByteDeletionRate = o.TargetByteDeletionRate
+	if err := d.validateDynamicOptions(candidate); err != nil {
+		d.mu.Unlock()
+		return err
+	}
+	fileNum := d.mu.versions.getNextDiskFileNum()
+	d.mu.Unlock()
+
+	// Persist the new options before applying them, so that a failure leaves
+	// both the in-memory and on-disk options unchanged.
+	serializedOpts := []byte(candidate.String())
+	err := d.writeOptionsFile(fileNum, serializedOpts)
+	d.opts.EventListener.OptionsChanged(OptionsChangedInfo{
+		Path:    base.MakeFilepath(d.opts.FS, d.dirname, fileTypeOptions, fileNum),
+		FileNum: fileNum,
+		Err:     err,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opts.L0CompactionFileThreshold = candidate.L0CompactionFileThreshold
+	d.opts.L0CompactionThreshold = candidate.L0CompactionThreshold
+	d.opts.L0StopWritesThreshold = candidate.L0StopWritesThreshold
+	d.opts.LBaseMaxBytes = candidate.LBaseMaxBytes
+	d.opts.Levels = candidate.Levels
+	d.opts.MemTableSize = candidate.MemTableSize
+	d.opts.MemTableStopWritesThreshold = candidate.MemTableStopWritesThreshold
+	d.opts.TargetByteDeletionRate = candidate.TargetByteDeletionRate
+	// The previous OPTIONS file is now obsolete. Like the obsolete OPTIONS
+	// files found by Open, it's deleted by the cleanup manager, which defers
+	// the deletion while file deletions are disabled (eg, by a checkpoint that
+	// may be copying it).
+	d.mu.versions.obsoleteOptions = merge(d.mu.versions.obsoleteOptions, []fileInfo{{
+		FileNum:  d.optionsFileNum,
+		FileSize: d.optionsFileSize,
+	}})
+	d.optionsFileNum = fileNum
+	d.optionsFileSize = uint64(len(serializedOpts))
+
+	d.setMemTableSize(d.opts.MemTableSize)
+	d.mu.mem.nextSize = min(d.mu.mem.nextSize, d.opts.MemTableSize)
+	d.cleanupManager.deletePacer.SetTargetByteDeletionRate(int64(d.opts.TargetByteDeletionRate))
+
+	// The compaction picker captures the level sizes and L0 thresholds when
+	// it's constructed, so it's rebuilt to reflect the new options.
+	vs := d.mu.versions
+	vs.picker = newCompactionPickerByScore(
+		vs.currentVersion(), &vs.virtualBackings, d.opts, d.getInProgressCompactionInfoLocked(nil))
+	if !vs.dynamicBaseLevel {
+		vs.picker.forceBaseLevel1()
+	}
+	// The write admission pressure is relative to the stop writes thresholds.
+	if d.writeAdmission != nil {
+		d.updateWriteAdmissionLocked()
+	}
+	// Raising the stop writes thresholds may release stalled writers, and
+	// lowering the compaction thresholds may make compactions necessary.
+	d.mu.compact.cond.Broadcast()
+	d.maybeScheduleFlush()
+	d.maybeScheduleCompaction()
+	d.deleteObsoleteFiles(d.newJobIDLocked())
+	return nil
+}
+
+// validateDynamicOptions verifies that the options changed by SetOptions are
+// valid and consistent with the rest of the options. It fills in the defaults
+// of the LevelOptions.
+//
+// d.mu must be held when calling this.
+func (d *DB) validateDynamicOptions(o *Options) error {
+	var buf strings.Builder
+	if o.L0CompactionFileThreshold <= 0 {
+		fmt.Fprintf(&buf, "L0CompactionFileThreshold (%d) must be > 0\n", o.L0CompactionFileThreshold)
+	}
+	if o.L0CompactionThreshold <= 0 {
+		fmt.Fprintf(&buf, "L0CompactionThreshold (%d) must be > 0\n", o.L0CompactionThreshold)
+	}
+	if o.LBaseMaxBytes <= 0 {
+		fmt.Fprintf(&buf, "LBaseMaxBytes (%d) must be > 0\n", o.LBaseMaxBytes)
+	}
+	if o.MemTableSize <= uint64(memTableEmptySize) {
+		fmt.Fprintf(&buf, "MemTableSize (%d) must be > %d\n", o.MemTableSize, memTableEmptySize)
+	}
+	if o.TargetByteDeletionRate < 0 {
+		fmt.Fprintf(&buf, "TargetByteDeletionRate (%d) must be >= 0\n", o.TargetByteDeletionRate)
+	}
+	if len(o.Levels) == 0 {
+		fmt.Fprintf(&buf, "Levels must not be empty\n")
+	}
+	for i := range o.Levels {
+		l := &o.Levels[i]
+		if l.BlockSize > sstable.MaximumBlockSize {
+			fmt.Fprintf(&buf, "Levels[%d].BlockSize (%d) must be <= %d\n",
+				i, l.BlockSize, sstable.MaximumBlockSize)
+			continue
+		}
+		l.EnsureDefaults()
+		// The table cache is configured with the filter policies known when
+		// the DB was opened, so sstables written with any other policy would
+		// be read without their filters.
+		if l.FilterPolicy != nil {
+			if _, ok := d.opts.Filters[l.FilterPolicy.Name()]; !ok {
+				fmt.Fprintf(&buf, "Levels[%d].FilterPolicy %q is not in Options.Filters\n",
+					i, l.FilterPolicy.Name())
+			}
+		}
+	}
+	if buf.Len() > 0 {
+		return errors.New(strings.TrimSuffix(buf.String(), "\n"))
+	}
+	return o.Validate()
+}
+
+// setMemTableSize updates the fields derived from Options.MemTableSize that
+// are read without holding d.mu.
+func (d *DB) setMemTableSize(size uint64) {
+	d.memTableSize.Store(size)
+	d.largeBatchThreshold.Store((size - uint64(memTableEmptySize)) / 2)
+}
+
+// makeWriterOptions returns the sstable writer options for the specified
+// level. The options may be changed concurrently by SetOptions, so d.mu is
+// acquired to read them.
+//
+// d.mu must not be held when calling this.
+func (d *DB) makeWriterOptions(level int, format sstable.TableFormat) sstable.WriterOptions {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.opts.MakeWriterOptions(level, format)
+}
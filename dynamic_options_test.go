@@ -0,0 +1,126 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cockroachdb/pebble/bloom"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOptions(t *testing.T) {
+	mem := vfs.NewMem()
+	var events []OptionsChangedInfo
+	opts := &Options{
+		FS:     mem,
+		Levels: []LevelOptions{{TargetFileSize: 2 << 20}},
+		EventListener: &EventListener{
+			OptionsChanged: func(info OptionsChangedInfo) {
+				events = append(events, info)
+			},
+		},
+		Filters: map[string]FilterPolicy{
+			bloom.FilterPolicy(10).Name(): bloom.FilterPolicy(10),
+		},
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.Set([]byte("a"), []byte("a"), nil))
+	prevOptionsFileNum := d.optionsFileNum
+
+	o := d.DynamicOptions()
+	o.L0CompactionThreshold = 8
+	o.L0StopWritesThreshold = 24
+	o.LBaseMaxBytes = 128 << 20
+	o.MemTableSize = 8 << 20
+	o.TargetByteDeletionRate = 1 << 20
+	o.Levels[0].TargetFileSize = 4 << 20
+	o.Levels[0].Compression = func() Compression { return ZstdCompression }
+	o.Levels[0].FilterPolicy = bloom.FilterPolicy(10)
+	require.NoError(t, d.SetOptions(o))
+
+	require.Len(t, events, 1)
+	require.NoError(t, events[0].Err)
+	require.Greater(t, events[0].FileNum, prevOptionsFileNum)
+	require.Equal(t, d.optionsFileNum, events[0].FileNum)
+
+	// The options in use have changed, without modifying the caller's Options.
+	require.Equal(t, 8, d.opts.L0CompactionThreshold)
+	require.Equal(t, uint64(8<<20), d.memTableSize.Load())
+	require.Equal(t, int64(1<<20), d.cleanupManager.deletePacer.targetByteDeletionRate.Load())
+	require.Equal(t, ZstdCompression, d.DynamicOptions().Levels[0].Compression())
+	require.Equal(t, int64(2<<20), opts.Levels[0].TargetFileSize)
+	writerOpts := d.makeWriterOptions(0, d.FormatMajorVersion().MaxTableFormat())
+	require.Equal(t, ZstdCompression, writerOpts.Compression)
+	require.Equal(t, "rocksdb.BuiltinBloomFilter", writerOpts.FilterPolicy.Name())
+
+	// The new options were persisted.
+	f, err := mem.Open(events[0].Path)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Contains(t, buf.String(), "l0_compaction_threshold=8\n")
+	require.Contains(t, buf.String(), "mem_table_size=8388608\n")
+	require.Contains(t, buf.String(), "compression=ZSTD\n")
+	require.Contains(t, buf.String(), "filter_policy=rocksdb.BuiltinBloomFilter\n")
+
+	// The previous OPTIONS file was deleted.
+	d.cleanupManager.Wait()
+	ls, err := mem.List("")
+	require.NoError(t, err)
+	var optionsFiles []string
+	for _, filename := range ls {
+		if ft, _, ok := base.ParseFilename(mem, filename); ok && ft == base.FileTypeOptions {
+			optionsFiles = append(optionsFiles, filename)
+		}
+	}
+	require.Equal(t, []string{mem.PathBase(events[0].Path)}, optionsFiles)
+
+	// Invalid options are rejected without persisting or applying them.
+	for _, tc := range []struct {
+		modify func(o *DynamicOptions)
+		err    string
+	}{
+		{func(o *DynamicOptions) { o.L0StopWritesThreshold = 4 }, "L0StopWritesThreshold (4) must be >= L0CompactionThreshold (8)"},
+		{func(o *DynamicOptions) { o.LBaseMaxBytes = 0 }, "LBaseMaxBytes (0) must be > 0"},
+		{func(o *DynamicOptions) { o.MemTableStopWritesThreshold = 1 }, "MemTableStopWritesThreshold (1) must be >= 2"},
+		{func(o *DynamicOptions) { o.Levels = nil }, "Levels must not be empty"},
+		{func(o *DynamicOptions) { o.Levels[0].FilterPolicy = bloom.FilterPolicy(5) }, ""},
+		{func(o *DynamicOptions) { o.Levels[0].FilterPolicy = testFilterPolicy{} }, `Levels[0].FilterPolicy "test" is not in Options.Filters`},
+	} {
+		o := d.DynamicOptions()
+		tc.modify(&o)
+		err := d.SetOptions(o)
+		if tc.err == "" {
+			require.NoError(t, err)
+			continue
+		}
+		require.ErrorContains(t, err, tc.err)
+	}
+	require.Len(t, events, 2)
+	require.Equal(t, 8, d.opts.L0CompactionThreshold)
+	require.NoError(t, d.Close())
+
+	// The DB can be reopened using the most recent OPTIONS file.
+	d, err = Open("", &Options{FS: mem})
+	require.NoError(t, err)
+	v, closer, err := d.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("a"), v)
+	require.NoError(t, closer.Close())
+	require.NoError(t, d.Close())
+}
+
+type testFilterPolicy struct {
+	base.FilterPolicy
+}
+
+func (testFilterPolicy) Name() string { return "test" }
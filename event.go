@@ -314,6 +314,29 @@ func (i ManifestCreateInfo) SafeFormat(w redact.SafePrinter, _ rune) {
 	w.Printf("[JOB %d] MANIFEST created %s", redact.Safe(i.JobID), i.FileNum)
 }
 
+// OptionsChangedInfo contains info about the options of a DB being changed by
+// DB.SetOptions.
+type OptionsChangedInfo struct {
+	// Path is the path of the new OPTIONS file.
+	Path string
+	// The file number of the new OPTIONS file.
+	FileNum base.DiskFileNum
+	Err     error
+}
+
+func (i OptionsChangedInfo) String() string {
+	return redact.StringWithoutMarkers(i)
+}
+
+// SafeFormat implements redact.SafeFormatter.
+func (i OptionsChangedInfo) SafeFormat(w redact.SafePrinter, _ rune) {
+	if i.Err != nil {
+		w.Printf("OPTIONS change error: %s", i.Err)
+		return
+	}
+	w.Printf("OPTIONS changed %s", i.FileNum)
+}
+
 // ManifestDeleteInfo contains the info for a Manifest deletion event.
 type ManifestDeleteInfo struct {
 	// JobID is the ID of the job the caused the Manifest to be deleted.
@@ -584,6 +607,10 @@ type EventListener struct {
 	// ManifestDeleted is invoked after a manifest has been deleted.
 	ManifestDeleted func(ManifestDeleteInfo)
 
+	// OptionsChanged is invoked after DB.SetOptions has attempted to persist
+	// and apply new options.
+	OptionsChanged func(OptionsChangedInfo)
+
 	// TableCreated is invoked when a table has been created.
 	TableCreated func(TableCreateInfo)
 
@@ -658,6 +685,9 @@ func (l *EventListener) EnsureDefaults(logger Logger) {
 	if l.ManifestDeleted == nil {
 		l.ManifestDeleted = func(info ManifestDeleteInfo) {}
 	}
+	if l.OptionsChanged == nil {
+		l.OptionsChanged = func(info OptionsChangedInfo) {}
+	}
 	if l.TableCreated == nil {
 		l.TableCreated = func(info TableCreateInfo) {}
 	}
@@ -728,6 +758,9 @@ func MakeLoggingEventListener(logger Logger) EventListener {
 		ManifestDeleted: func(info ManifestDeleteInfo) {
 			logger.Infof("%s", info)
 		},
+		OptionsChanged: func(info OptionsChangedInfo) {
+			logger.Infof("%s", info)
+		},
 		TableCreated: func(info TableCreateInfo) {
 			logger.Infof("%s", info)
 		},
@@ -807,6 +840,10 @@ func TeeEventListener(a, b EventListener) EventListener {
 			a.ManifestDeleted(info)
 			b.ManifestDeleted(info)
 		},
+		OptionsChanged: func(info OptionsChangedInfo) {
+			a.OptionsChanged(info)
+			b.OptionsChanged(info)
+		},
 		TableCreated: func(info TableCreateInfo) {
 			a.TableCreated(info)
 			b.TableCreated(info)
@@ -58,7 +58,7 @@ func TestEventListener(t *testing.T) {
 				// tested separately in TestTableStats.
 				DisableTableStats:     true,
 				FS:                    vfs.WithLogging(mem, memLog.Infof),
-				FormatMajorVersion:    internalFormatNewest,
+				FormatMajorVersion:    FormatNewest,
 				EventListener:         &lel,
 				MaxManifestFileSize:   1,
 				L0CompactionThreshold: 10,
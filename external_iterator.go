@@ -154,7 +154,10 @@ func createExternalPointIter(ctx context.Context, it *Iterator) (topLevelIterato
 			// not have obsolete points (so the performance optimization is
 			// unnecessary), and we don't want to bother constructing a
 			// BlockPropertiesFilterer that includes obsoleteKeyBlockPropertyFilter.
-			transforms := sstable.IterTransforms{SyntheticSeqNum: sstable.SyntheticSeqNum(seqNum)}
+			transforms := sstable.IterTransforms{
+				SyntheticSeqNum: sstable.SyntheticSeqNum(seqNum),
+				KeysOnly:        it.opts.KeysOnly,
+			}
 			seqNum--
 			pointIter, err = r.NewPointIter(
 				ctx, transforms, it.opts.LowerBound, it.opts.UpperBound, nil, /* BlockPropertiesFilterer */
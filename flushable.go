@@ -24,6 +24,9 @@ type flushable interface {
 	newFlushIter(o *IterOptions) internalIterator
 	newRangeDelIter(o *IterOptions) keyspan.FragmentIterator
 	newRangeKeyIter(o *IterOptions) keyspan.FragmentIterator
+	// newRangeMergeIter returns an iterator over the flushable's range
+	// merges, or nil if it contains none.
+	newRangeMergeIter(o *IterOptions) keyspan.FragmentIterator
 	containsRangeKeys() bool
 	// inuseBytes returns the number of inuse bytes by the flushable.
 	inuseBytes() uint64
@@ -308,6 +311,12 @@ func (s *ingestedFlushable) newRangeKeyIter(o *IterOptions) keyspan.FragmentIter
 	return miter
 }
 
+// newRangeMergeIter is part of the flushable interface. Ingested sstables
+// never contain range merges.
+func (s *ingestedFlushable) newRangeMergeIter(o *IterOptions) keyspan.FragmentIterator {
+	return nil
+}
+
 // containsRangeKeys is part of the flushable interface.
 func (s *ingestedFlushable) containsRangeKeys() bool {
 	return s.hasRangeKeys || s.exciseSpan.Valid()
@@ -391,8 +400,12 @@ func computePossibleOverlapsGenericImpl[F flushable](
 	iter := f.newIter(nil)
 	rangeDelIter := f.newRangeDelIter(nil)
 	rangeKeyIter := f.newRangeKeyIter(nil)
+	rangeMergeIter := f.newRangeMergeIter(nil)
 	for _, b := range bounded {
 		overlap, err := determineOverlapAllIters(cmp, b.UserKeyBounds(), iter, rangeDelIter, rangeKeyIter)
+		if !overlap && err == nil && rangeMergeIter != nil {
+			overlap, err = determineOverlapKeyspanIterator(cmp, b.UserKeyBounds(), rangeMergeIter)
+		}
 		if invariants.Enabled && err != nil {
 			panic(errors.AssertionFailedf("expected iterator to be infallible: %v", err))
 		}
@@ -416,6 +429,9 @@ func computePossibleOverlapsGenericImpl[F flushable](
 	if rangeKeyIter != nil {
 		rangeKeyIter.Close()
 	}
+	if rangeMergeIter != nil {
+		rangeMergeIter.Close()
+	}
 }
 
 // determineOverlapAllIters checks for overlap in a point iterator, range
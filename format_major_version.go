@@ -203,6 +203,13 @@ const (
 
 	// -- Add experimental versions here --
 
+	// FormatRangeMerges is a format major version that adds support for range
+	// merges: merge operands applied to every point key within a span of user
+	// keys (see Batch.RangeMerge). It introduces a new key kind that may be
+	// committed through batches, a new sstable block and a new field in the
+	// Manifest, and therefore requires a format major version.
+	FormatRangeMerges
+
 	// internalFormatNewest is the most recent, possibly experimental format major
 	// version.
 	internalFormatNewest FormatMajorVersion = iota - 2
@@ -229,7 +236,7 @@ func (v FormatMajorVersion) MaxTableFormat() sstable.TableFormat {
 	case FormatDefault, FormatFlushableIngest, FormatPrePebblev1MarkedCompacted:
 		return sstable.TableFormatPebblev3
 	case FormatDeleteSizedAndObsolete, FormatVirtualSSTables, FormatSyntheticPrefixSuffix,
-		FormatFlushableIngestExcises:
+		FormatFlushableIngestExcises, FormatRangeMerges:
 		return sstable.TableFormatPebblev4
 	default:
 		panic(fmt.Sprintf("pebble: unsupported format major version: %s", v))
@@ -242,7 +249,7 @@ func (v FormatMajorVersion) MinTableFormat() sstable.TableFormat {
 	switch v {
 	case FormatDefault, FormatFlushableIngest, FormatPrePebblev1MarkedCompacted,
 		FormatDeleteSizedAndObsolete, FormatVirtualSSTables, FormatSyntheticPrefixSuffix,
-		FormatFlushableIngestExcises:
+		FormatFlushableIngestExcises, FormatRangeMerges:
 		return sstable.TableFormatPebblev1
 	default:
 		panic(fmt.Sprintf("pebble: unsupported format major version: %s", v))
@@ -282,6 +289,9 @@ var formatMajorVersionMigrations = map[FormatMajorVersion]func(*DB) error{
 	FormatFlushableIngestExcises: func(d *DB) error {
 		return d.finalizeFormatVersUpgrade(FormatFlushableIngestExcises)
 	},
+	FormatRangeMerges: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(FormatRangeMerges)
+	},
 }
 
 const formatVersionMarkerName = `format-version`
@@ -25,11 +25,12 @@ func TestFormatMajorVersionStableValues(t *testing.T) {
 	require.Equal(t, FormatVirtualSSTables, FormatMajorVersion(16))
 	require.Equal(t, FormatSyntheticPrefixSuffix, FormatMajorVersion(17))
 	require.Equal(t, FormatFlushableIngestExcises, FormatMajorVersion(18))
+	require.Equal(t, FormatRangeMerges, FormatMajorVersion(19))
 
 	// When we add a new version, we should add a check for the new version in
 	// addition to updating these expected values.
 	require.Equal(t, FormatNewest, FormatMajorVersion(18))
-	require.Equal(t, internalFormatNewest, FormatMajorVersion(18))
+	require.Equal(t, internalFormatNewest, FormatMajorVersion(19))
 }
 
 func TestFormatMajorVersion_MigrationDefined(t *testing.T) {
@@ -56,6 +57,8 @@ func TestRatchetFormat(t *testing.T) {
 	require.Equal(t, FormatSyntheticPrefixSuffix, d.FormatMajorVersion())
 	require.NoError(t, d.RatchetFormatMajorVersion(FormatFlushableIngestExcises))
 	require.Equal(t, FormatFlushableIngestExcises, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatRangeMerges))
+	require.Equal(t, FormatRangeMerges, d.FormatMajorVersion())
 
 	require.NoError(t, d.Close())
 
@@ -207,6 +210,7 @@ func TestFormatMajorVersions_TableFormat(t *testing.T) {
 		FormatVirtualSSTables:            {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
 		FormatSyntheticPrefixSuffix:      {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
 		FormatFlushableIngestExcises:     {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
+		FormatRangeMerges:                {sstable.TableFormatPebblev1, sstable.TableFormatPebblev4},
 	}
 
 	// Valid versions.
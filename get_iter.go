@@ -30,8 +30,11 @@ type getIter struct {
 	batch    *Batch
 	mem      flushableList
 	l0       []manifest.LevelSlice
-	version  *version
-	iterKV   *base.InternalKV
+	// runs holds the sorted runs of g.level that are yet to be visited, if
+	// the level holds multiple runs.
+	runs    []manifest.LevelSlice
+	version *version
+	iterKV  *base.InternalKV
 	// tombstoned and tombstonedSeqNum track whether the key has been deleted by
 	// a range delete tombstone. The first visible (at getIter.snapshot) range
 	// deletion encounterd transitions tombstoned to true. The tombstonedSeqNum
@@ -231,6 +234,30 @@ func (g *getIter) initializeNextIterator() (ok bool) {
 		g.level++
 	}
 	for g.level < numLevels {
+		// Like the sublevels of L0, visit each sorted run of a level holding
+		// multiple runs individually, from newest to oldest.
+		if n := len(g.runs); n > 0 {
+			layer := manifest.LevelRun(g.level, n-1)
+			files := g.runs[n-1].Iter()
+			g.runs = g.runs[:n-1]
+			if n == 1 {
+				g.level++
+			}
+			iter, rangeDelIter, err := g.getSSTableIterators(files, layer)
+			if err != nil {
+				g.err = firstError(g.err, err)
+				return false
+			}
+			if !g.maybeSetTombstone(rangeDelIter) {
+				return false
+			}
+			g.iter = iter
+			return true
+		}
+		if runs := g.version.Runs[g.level]; runs != nil {
+			g.runs = runs
+			continue
+		}
 		if g.version.Levels[g.level].Empty() {
 			g.level++
 			continue
@@ -291,6 +291,12 @@ func ingestLoad1(
 	// calculating stats before we can remove the original link.
 	maybeSetStatsFromProperties(meta.PhysicalMeta(), &r.Properties)
 
+	// Range merges are applied relative to the keys beneath them in the LSM,
+	// which an ingested table's keys are not ordered against.
+	if r.HasRangeMerges() {
+		return nil, errors.New("pebble: cannot ingest sstable containing range merges")
+	}
+
 	{
 		iter, err := r.NewIter(sstable.NoTransforms, nil /* lower */, nil /* upper */)
 		if err != nil {
@@ -1337,6 +1343,10 @@ func (d *DB) ingest(
 	if len(shared) > 0 && d.opts.Experimental.RemoteStorage == nil {
 		panic("cannot ingest shared sstables with nil SharedStorage")
 	}
+	if (exciseSpan.Valid() || len(shared) > 0 || len(external) > 0) &&
+		d.opts.Experimental.CompactionStyle == CompactionStyleTiered {
+		return IngestOperationStats{}, errors.New("pebble: excise, shared or external sstable ingestion is not supported with the tiered compaction style")
+	}
 	if (exciseSpan.Valid() || len(shared) > 0 || len(external) > 0) && d.FormatMajorVersion() < FormatVirtualSSTables {
 		return IngestOperationStats{}, errors.New("pebble: format major version too old for excise, shared or external sstable ingestion")
 	}
@@ -1513,6 +1523,21 @@ func (d *DB) ingest(
 				return continueIteration
 			}, overlapBounds...)
 		}
+		if mem == nil && d.opts.Experimental.CompactionStyle == CompactionStyleTiered {
+			// Under CompactionStyleTiered, L0 is merged as a whole into a new
+			// sorted run in L1. If the ingested sstables reached L0 before the
+			// earlier writes, they could be merged into an older run than those
+			// writes, so the ingestion must be flushed or ingested after the
+			// newest flushable holding any writes, as if they overlapped.
+			for i := len(d.mu.mem.queue) - 1; i >= 0; i-- {
+				m := d.mu.mem.queue[i]
+				if mt, ok := m.flushable.(*memTable); ok && mt.reserved == memTableEmptySize {
+					continue
+				}
+				mem = m
+				break
+			}
+		}
 
 		if mem == nil {
 			// No overlap with any of the queued flushables, so no need to queue
@@ -1749,7 +1774,7 @@ func (d *DB) excise(
 				QoSLevel: sstable.LatencySensitiveQoSLevel,
 			},
 			layer: manifest.Level(level),
-		}, internalIterOpts{}, iterPointKeys|iterRangeDeletions|iterRangeKeys)
+		}, internalIterOpts{}, iterPointKeys|iterRangeDeletions|iterRangeKeys|iterRangeMerges)
 		itersLoaded = true
 		return err
 	}
@@ -1813,6 +1838,18 @@ func (d *DB) excise(
 			if lastRangeDel != nil {
 				leftFile.ExtendPointKeyBounds(d.cmp, smallestPointKey, base.MakeExclusiveSentinelKey(InternalKeyKindRangeDelete, lastRangeDel))
 			}
+			// Likewise for range merges, which are included in the point key
+			// bounds.
+			if rm, err := iters.RangeMerge().SeekLT(exciseSpan.Start); err != nil {
+				return nil, err
+			} else if rm != nil {
+				lastRangeMerge := slices.Clone(rm.End)
+				if d.cmp(lastRangeMerge, exciseSpan.Start) > 0 {
+					lastRangeMerge = exciseSpan.Start
+				}
+				leftFile.HasRangeMerges = true
+				leftFile.ExtendPointKeyBounds(d.cmp, smallestPointKey, base.MakeExclusiveSentinelKey(InternalKeyKindRangeMerge, lastRangeMerge))
+			}
 		}
 		if m.HasRangeKeys && !exciseSpan.ContainsInternalKey(d.cmp, m.SmallestRangeKey) {
 			// This file will probably contain range keys.
@@ -1920,6 +1957,25 @@ func (d *DB) excise(
 			smallestPointKey.UserKey = firstRangeDel
 			rightFile.ExtendPointKeyBounds(d.cmp, smallestPointKey, largestPointKey)
 		}
+		// Likewise for range merges, which are included in the point key
+		// bounds.
+		rm, err := iters.RangeMerge().SeekGE(exciseSpan.End.Key)
+		if err != nil {
+			return nil, err
+		} else if rm != nil {
+			firstRangeMerge := slices.Clone(rm.Start)
+			if d.cmp(firstRangeMerge, exciseSpan.End.Key) < 0 {
+				// NB: This can only be done if the end bound is exclusive.
+				if exciseSpan.End.Kind != base.Exclusive {
+					return nil, base.AssertionFailedf("cannot truncate range merge during excise with an inclusive upper bound")
+				}
+				firstRangeMerge = exciseSpan.End.Key
+			}
+			smallestPointKey := rm.SmallestKey()
+			smallestPointKey.UserKey = firstRangeMerge
+			rightFile.HasRangeMerges = true
+			rightFile.ExtendPointKeyBounds(d.cmp, smallestPointKey, largestPointKey)
+		}
 	}
 	if m.HasRangeKeys && !exciseSpan.ContainsInternalKey(d.cmp, m.LargestRangeKey) {
 		// This file will probably contain range keys.
@@ -2203,6 +2259,10 @@ func (d *DB) ingestApply(
 		var err error
 		if specifiedLevel != -1 {
 			f.Level = specifiedLevel
+		} else if d.opts.Experimental.CompactionStyle == CompactionStyleTiered {
+			// Under CompactionStyleTiered, ingested sstables are always added to
+			// L0, to be merged into the lower levels after the earlier writes.
+			f.Level = 0
 		} else {
 			var splitFile *fileMetadata
 			if exciseSpan.Valid() && exciseSpan.Contains(d.cmp, m.Smallest) && exciseSpan.Contains(d.cmp, m.Largest) {
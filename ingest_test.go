@@ -465,7 +465,7 @@ func TestOverlappingIngestedSSTs(t *testing.T) {
 			L0CompactionThreshold:       100,
 			L0StopWritesThreshold:       100,
 			DebugCheck:                  DebugCheckLevels,
-			FormatMajorVersion:          internalFormatNewest,
+			FormatMajorVersion:          FormatNewest,
 			Logger:                      testLogger{t},
 		}).WithFSDefaults()
 		if testing.Verbose() {
@@ -2905,12 +2905,12 @@ func TestIngestFlushQueuedLargeBatch(t *testing.T) {
 	// size in order to more easily create a situation where a large batch is
 	// queued but not automatically flushed.
 	d.mu.Lock()
-	d.largeBatchThreshold = d.opts.MemTableSize / 8
+	d.largeBatchThreshold.Store(d.opts.MemTableSize / 8)
 	d.mu.Unlock()
 
 	// Set a record with a large value. This will be transformed into a large
 	// batch and placed in the flushable queue.
-	require.NoError(t, d.Set([]byte("a"), bytes.Repeat([]byte("v"), int(d.largeBatchThreshold)), nil))
+	require.NoError(t, d.Set([]byte("a"), bytes.Repeat([]byte("v"), int(d.largeBatchThreshold.Load())), nil))
 
 	ingest := func(keys ...string) {
 		t.Helper()
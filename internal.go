@@ -30,6 +30,7 @@ const (
 	InternalKeyKindIngestSST      = base.InternalKeyKindIngestSST
 	InternalKeyKindDeleteSized    = base.InternalKeyKindDeleteSized
 	InternalKeyKindExcise         = base.InternalKeyKindExcise
+	InternalKeyKindRangeMerge     = base.InternalKeyKindRangeMerge
 	InternalKeyKindInvalid        = base.InternalKeyKindInvalid
 )
 
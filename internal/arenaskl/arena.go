@@ -103,6 +103,20 @@ func (a *Arena) alloc(size, alignment, overflow uint32) (uint32, uint32, error)
 	return offset, uint32(padded), nil
 }
 
+// Alloc allocates a buffer of the given size from the arena, returning its
+// offset, or ErrArenaFull if the arena has insufficient space. The offset is
+// never zero. See Bytes.
+func (a *Arena) Alloc(size uint32) (uint32, error) {
+	offset, _, err := a.alloc(size, 1, 0)
+	return offset, err
+}
+
+// Bytes returns the buffer of the given size at the offset returned by Alloc.
+// The buffer remains valid for the lifetime of the arena.
+func (a *Arena) Bytes(offset, size uint32) []byte {
+	return a.getBytes(offset, size)
+}
+
 func (a *Arena) getBytes(offset uint32, size uint32) []byte {
 	if offset == 0 {
 		return nil
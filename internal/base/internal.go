@@ -145,6 +145,11 @@ const (
 	// InternalKeyKindIngestSST), or in an sstable.
 	InternalKeyKindExcise InternalKeyKind = 24
 
+	// InternalKeyKindRangeMerge applies a merge operand to every point key
+	// within a key range that exists when the range merge is written. See the
+	// internal/rangemerge package for more details.
+	InternalKeyKindRangeMerge InternalKeyKind = 25
+
 	// This maximum value isn't part of the file format. Future extensions may
 	// increase this value.
 	//
@@ -154,7 +159,7 @@ const (
 	// which sorts 'less than or equal to' any other valid internalKeyKind, when
 	// searching for any kind of internal key formed by a certain user key and
 	// seqNum.
-	InternalKeyKindMax InternalKeyKind = 24
+	InternalKeyKindMax InternalKeyKind = 25
 
 	// InternalKeyKindMaxForSSTable is the largest valid key kind that can exist
 	// in an SSTable. This should usually equal InternalKeyKindMax, except
@@ -206,6 +211,7 @@ var internalKeyKindNames = []string{
 	InternalKeyKindIngestSST:      "INGESTSST",
 	InternalKeyKindDeleteSized:    "DELSIZED",
 	InternalKeyKindExcise:         "EXCISE",
+	InternalKeyKindRangeMerge:     "RANGEMERGE",
 	InternalKeyKindInvalid:        "INVALID",
 }
 
@@ -311,6 +317,7 @@ var kindsMap = map[string]InternalKeyKind{
 	"INGESTSST":     InternalKeyKindIngestSST,
 	"DELSIZED":      InternalKeyKindDeleteSized,
 	"EXCISE":        InternalKeyKindExcise,
+	"RANGEMERGE":    InternalKeyKindRangeMerge,
 }
 
 // ParseSeqNum parses the string representation of a sequence number.
@@ -529,7 +536,8 @@ func (k InternalKey) IsExclusiveSentinel() bool {
 	}
 	switch kind := k.Kind(); kind {
 	case InternalKeyKindRangeDelete, InternalKeyKindRangeKeyDelete,
-		InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeySet:
+		InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeySet,
+		InternalKeyKindRangeMerge:
 		return true
 	default:
 		return false
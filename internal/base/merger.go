@@ -76,6 +76,83 @@ type DeletableValueMerger interface {
 	DeletableFinish(includesBase bool) (value []byte, delete bool, closer io.Closer, err error)
 }
 
+// ManyValueMerger is an extension to ValueMerger which allows a merger to
+// receive a batch of older operands at once. When a ValueMerger implements
+// ManyValueMerger, iterators and compactions buffer the older operands of a key
+// and pass them with a single call to MergeMany, instead of calling MergeOlder
+// for each operand. This allows mergers to combine long stacks of operands
+// efficiently, for example by allocating the result once.
+type ManyValueMerger interface {
+	ValueMerger
+
+	// MergeMany adds operands that are older than all existing operands,
+	// ordered from newest to oldest. It's equivalent to calling MergeOlder for
+	// each of the values in order. The caller retains ownership of values.
+	//
+	// If an error is returned the merge is aborted and no other methods must
+	// be called.
+	MergeMany(values [][]byte) error
+}
+
+// MergeOperandBuffer passes older merge operands to a ValueMerger. If the
+// ValueMerger implements ManyValueMerger, the operands are copied into the
+// buffer and passed with a single call to MergeMany by Flush. Otherwise each
+// operand is passed to MergeOlder immediately. A MergeOperandBuffer may be
+// reused for many merges to amortize its allocations.
+type MergeOperandBuffer struct {
+	merger ValueMerger
+	many   ManyValueMerger
+	buf    []byte
+	ends   []int
+	values [][]byte
+	count  int
+}
+
+// Reset prepares the buffer for passing operands to the given ValueMerger.
+func (b *MergeOperandBuffer) Reset(merger ValueMerger) {
+	b.merger = merger
+	b.many, _ = merger.(ManyValueMerger)
+	b.buf = b.buf[:0]
+	b.ends = b.ends[:0]
+	b.count = 0
+}
+
+// Count returns the number of operands added since the last call to Reset.
+func (b *MergeOperandBuffer) Count() int {
+	return b.count
+}
+
+// MergeOlder adds an operand that is older than all existing operands. The
+// caller retains ownership of value.
+func (b *MergeOperandBuffer) MergeOlder(value []byte) error {
+	b.count++
+	if b.many == nil {
+		return b.merger.MergeOlder(value)
+	}
+	b.buf = append(b.buf, value...)
+	b.ends = append(b.ends, len(b.buf))
+	return nil
+}
+
+// Flush passes any buffered operands to the ValueMerger. It must be called
+// before the ValueMerger is finished.
+func (b *MergeOperandBuffer) Flush() error {
+	if len(b.ends) == 0 {
+		return nil
+	}
+	b.values = b.values[:0]
+	start := 0
+	for _, end := range b.ends {
+		b.values = append(b.values, b.buf[start:end:end])
+		start = end
+	}
+	b.buf = b.buf[:0]
+	b.ends = b.ends[:0]
+	err := b.many.MergeMany(b.values)
+	clear(b.values)
+	return err
+}
+
 // Merger defines an associative merge operation. The merge operation merges
 // two or more values for a single key. A merge operation is requested by
 // writing a value using {Batch,DB}.Merge(). The value at that key is merged
@@ -115,6 +192,24 @@ func (a *AppendValueMerger) MergeOlder(value []byte) error {
 	return nil
 }
 
+// MergeMany prepends values, ordered from newest to oldest, to the result.
+// Unlike repeated calls to MergeOlder, it allocates a new buffer only once.
+func (a *AppendValueMerger) MergeMany(values [][]byte) error {
+	n := len(a.buf)
+	for _, v := range values {
+		n += len(v)
+	}
+	buf := make([]byte, n)
+	copy(buf[n-len(a.buf):], a.buf)
+	n -= len(a.buf)
+	for _, v := range values {
+		n -= len(v)
+		copy(buf[n:], v)
+	}
+	a.buf = buf
+	return nil
+}
+
 // Finish returns the buffer that was constructed on-demand in `Merge{OlderNewer}()` calls.
 func (a *AppendValueMerger) Finish(includesBase bool) ([]byte, io.Closer, error) {
 	return a.buf, nil, nil
@@ -0,0 +1,46 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package base
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeOperandBuffer(t *testing.T) {
+	operands := []string{"d", "c", "", "b", "a"}
+	finish := func(m ValueMerger) string {
+		v, _, err := m.Finish(true)
+		require.NoError(t, err)
+		return string(v)
+	}
+
+	// AppendValueMerger implements ManyValueMerger, so the operands are
+	// buffered until Flush.
+	m, err := DefaultMerger.Merge(nil, []byte("e"))
+	require.NoError(t, err)
+	var b MergeOperandBuffer
+	b.Reset(m)
+	for _, op := range operands {
+		require.NoError(t, b.MergeOlder([]byte(op)))
+	}
+	require.Equal(t, 5, b.Count())
+	require.Equal(t, "e", finish(m))
+	require.NoError(t, b.Flush())
+	require.Equal(t, "abcde", finish(m))
+
+	// The buffer is reusable, and passes operands directly to mergers that
+	// don't implement ManyValueMerger.
+	m, err = NewDeletableSumValueMerger(nil, []byte("1"))
+	require.NoError(t, err)
+	b.Reset(m)
+	require.Zero(t, b.Count())
+	for _, op := range []string{"2", "3"} {
+		require.NoError(t, b.MergeOlder([]byte(op)))
+	}
+	require.Equal(t, "6", finish(m))
+	require.NoError(t, b.Flush())
+}
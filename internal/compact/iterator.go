@@ -7,6 +7,7 @@ package compact
 import (
 	"encoding/binary"
 	"io"
+	"slices"
 	"strconv"
 
 	"github.com/cockroachdb/errors"
@@ -15,6 +16,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/invariants"
 	"github.com/cockroachdb/pebble/internal/keyspan"
 	"github.com/cockroachdb/pebble/internal/rangekey"
+	"github.com/cockroachdb/pebble/internal/rangemerge"
 	"github.com/cockroachdb/redact"
 )
 
@@ -127,6 +129,17 @@ import (
 // keys. Just as with point deletions, a range deletion covering an entry can
 // cause the entry to be elided.
 //
+// 5. Range Merges
+//
+// Range merges apply a merge operand to every live key within a span (see the
+// rangemerge package). A range merge fragment that does not overlap any data
+// beneath the compaction's output level is folded into the point keys it
+// covers: compact.Iter surfaces a MERGE record carrying the operand for each
+// key it applies to, and the fragment itself is dropped. Other fragments are
+// passed through to the output. Because readers apply those fragments based on
+// the records older than the range merge, their sequence numbers are treated
+// as snapshots so that entries are never collapsed across them.
+//
 // A note on the stability of keys and values.
 //
 // The stability guarantees of keys and values returned by the iterator tree
@@ -158,9 +171,12 @@ type Iter struct {
 	rangeDelInterleaving keyspan.InterleavingIter
 	// rangeKeyInterleaving is the interleaving iter for range keys.
 	rangeKeyInterleaving keyspan.InterleavingIter
+	// rangeMergeInterleaving is the interleaving iter for the range merges
+	// that are not folded into point keys.
+	rangeMergeInterleaving keyspan.InterleavingIter
 
-	// iter is the iterator which interleaves points with RANGEDELs and range
-	// keys.
+	// iter is the iterator which interleaves points with RANGEDELs, range
+	// merges and range keys.
 	iter base.InternalIterator
 
 	delElider         pointTombstoneElider
@@ -187,7 +203,10 @@ type Iter struct {
 	// Temporary buffer used for storing the previous value, which may be an
 	// unsafe, i.iter-owned slice that could be altered when the iterator is
 	// advanced.
-	valueBuf         []byte
+	valueBuf []byte
+	// mergeOperands buffers the older operands of a merge for mergers that
+	// implement base.ManyValueMerger.
+	mergeOperands    base.MergeOperandBuffer
 	iterKV           *base.InternalKV
 	iterValue        []byte
 	iterStripeChange stripeChangeType
@@ -301,11 +320,11 @@ const (
 
 // NewIter creates a new compaction iterator. See the comment for Iter for a
 // detailed description.
-// rangeDelIter and rangeKeyIter can be nil.
+// rangeDelIter, rangeKeyIter and rangeMergeIter can be nil.
 func NewIter(
 	cfg IterConfig,
 	pointIter base.InternalIterator,
-	rangeDelIter, rangeKeyIter keyspan.FragmentIterator,
+	rangeDelIter, rangeKeyIter, rangeMergeIter keyspan.FragmentIterator,
 ) *Iter {
 	cfg.ensureDefaults()
 	i := &Iter{
@@ -322,6 +341,13 @@ func NewIter(
 		i.rangeDelInterleaving.Init(cfg.Comparer, iter, rangeDelIter, keyspan.InterleavingIterOpts{})
 		iter = &i.rangeDelInterleaving
 	}
+	if rangeMergeIter != nil {
+		var rangeDelSpan func() *keyspan.Span
+		if rangeDelIter != nil {
+			rangeDelSpan = i.rangeDelInterleaving.Span
+		}
+		iter = i.initRangeMerges(iter, rangeMergeIter, rangeDelSpan)
+	}
 	if rangeKeyIter != nil {
 		i.rangeKeyInterleaving.Init(cfg.Comparer, iter, rangeKeyIter, keyspan.InterleavingIterOpts{})
 		iter = &i.rangeKeyInterleaving
@@ -330,12 +356,62 @@ func NewIter(
 
 	i.frontiers.Init(i.cmp)
 	i.delElider.Init(i.cmp, cfg.TombstoneElision)
-	i.rangeDelCompactor = MakeRangeDelSpanCompactor(i.cmp, i.cfg.Comparer.Equal, cfg.Snapshots, cfg.TombstoneElision)
-	i.rangeKeyCompactor = MakeRangeKeySpanCompactor(i.cmp, i.suffixCmp, cfg.Snapshots, cfg.RangeKeyElision)
+	i.rangeDelCompactor = MakeRangeDelSpanCompactor(i.cmp, i.cfg.Comparer.Equal, i.cfg.Snapshots, cfg.TombstoneElision)
+	i.rangeKeyCompactor = MakeRangeKeySpanCompactor(i.cmp, i.suffixCmp, i.cfg.Snapshots, cfg.RangeKeyElision)
 	i.lastRangeDelSpanFrontier.Init(&i.frontiers, nil, i.lastRangeDelSpanFrontierReached)
 	return i
 }
 
+// initRangeMerges reads all the range merge fragments surfaced by
+// rangeMergeIter and returns an iterator wrapping iter that folds the fragments
+// that can be folded into the point keys they cover and interleaves the rest.
+// The sequence numbers of the fragments that are not folded are added to the
+// iterator's snapshots.
+//
+// The fragments are buffered in memory for the duration of the compaction.
+// Range merges are expected to be rare.
+func (i *Iter) initRangeMerges(
+	iter base.InternalIterator,
+	rangeMergeIter keyspan.FragmentIterator,
+	rangeDelSpan func() *keyspan.Span,
+) base.InternalIterator {
+	// A fragment may be folded if no data beneath the output level overlaps
+	// it, which is the same condition under which a range deletion may be
+	// elided.
+	var elider rangeTombstoneElider
+	elider.Init(i.cmp, i.cfg.TombstoneElision)
+	var folded, retained []keyspan.Span
+	s, err := rangeMergeIter.First()
+	for ; s != nil; s, err = rangeMergeIter.Next() {
+		if elider.ShouldElide(s.Start, s.End) {
+			folded = append(folded, s.Clone())
+		} else {
+			retained = append(retained, s.Clone())
+		}
+	}
+	rangeMergeIter.Close()
+	if err != nil {
+		i.err = err
+		return iter
+	}
+	if len(folded) > 0 {
+		iter = rangemerge.NewIter(i.cmp, iter, keyspan.NewIter(i.cmp, folded), base.SeqNumMax, rangeDelSpan)
+	}
+	if len(retained) > 0 {
+		snapshots := slices.Clone(i.cfg.Snapshots)
+		for j := range retained {
+			for k := range retained[j].Keys {
+				snapshots = append(snapshots, retained[j].Keys[k].SeqNum())
+			}
+		}
+		slices.Sort(snapshots)
+		i.cfg.Snapshots = slices.Compact(snapshots)
+		i.rangeMergeInterleaving.Init(i.cfg.Comparer, iter, keyspan.NewIter(i.cmp, retained), keyspan.InterleavingIterOpts{})
+		iter = &i.rangeMergeInterleaving
+	}
+	return iter
+}
+
 // Frontiers returns the frontiers for the compaction iterator.
 func (i *Iter) Frontiers() *Frontiers {
 	return &i.frontiers
@@ -427,7 +503,8 @@ func (i *Iter) Next() (*base.InternalKey, []byte) {
 		// stripe.
 		i.snapshotPinned = i.iterStripeChange == newStripeSameKey
 
-		if i.iterKV.Kind() == base.InternalKeyKindRangeDelete || rangekey.IsRangeKey(i.iterKV.Kind()) {
+		if i.iterKV.Kind() == base.InternalKeyKindRangeDelete || rangekey.IsRangeKey(i.iterKV.Kind()) ||
+			i.iterKV.Kind() == base.InternalKeyKindRangeMerge {
 			// Return the span so the compaction can use it for file truncation and add
 			// it to the relevant fragmenter. In the case of range deletions, we do not
 			// set `skip` to true before returning as there may be any number of point
@@ -453,6 +530,10 @@ func (i *Iter) Next() (*base.InternalKey, []byte) {
 					i.nextInStripe()
 					continue
 				}
+			} else if i.iterKV.Kind() == base.InternalKeyKindRangeMerge {
+				// Range merges that reach here were not folded and are output
+				// unmodified.
+				i.span.CopyFrom(i.rangeMergeInterleaving.Span())
 			} else {
 				i.rangeKeyCompactor.Compact(i.rangeKeyInterleaving.Span(), &i.span)
 				if i.span.Empty() {
@@ -631,9 +712,9 @@ func (i *Iter) Next() (*base.InternalKey, []byte) {
 	return nil, nil
 }
 
-// Span returns the range deletion or range key span corresponding to the
-// current key. Can only be called right after a Next() call that returned a
-// RANGEDEL or a range key. The keys in the span should not be retained or
+// Span returns the range deletion, range merge or range key span corresponding
+// to the current key. Can only be called right after a Next() call that
+// returned a RANGEDEL, a RANGEMERGE or a range key. The keys in the span should not be retained or
 // modified.
 func (i *Iter) Span() *keyspan.Span {
 	return &i.span
@@ -750,8 +831,8 @@ func (i *Iter) nextInStripeHelper() stripeChangeType {
 		i.curSnapshotIdx, i.curSnapshotSeqNum = i.cfg.Snapshots.IndexAndSeqNum(kv.SeqNum())
 		switch kv.Kind() {
 		case base.InternalKeyKindRangeKeySet, base.InternalKeyKindRangeKeyUnset, base.InternalKeyKindRangeKeyDelete,
-			base.InternalKeyKindRangeDelete:
-			// Range tombstones and range keys are interleaved at the max
+			base.InternalKeyKindRangeDelete, base.InternalKeyKindRangeMerge:
+			// Range tombstones, range merges and range keys are interleaved at the max
 			// sequence number for a given user key, and the first key after one
 			// is always considered a newStripeNewKey, so we should never reach
 			// this.
@@ -825,6 +906,16 @@ func (i *Iter) setNext() {
 }
 
 func (i *Iter) mergeNext(valueMerger base.ValueMerger) {
+	i.mergeOperands.Reset(valueMerger)
+	i.mergeOlderOperands()
+	if i.err == nil {
+		i.err = i.mergeOperands.Flush()
+	}
+}
+
+// mergeOlderOperands adds the older operands of a merge in the current
+// snapshot stripe to i.mergeOperands.
+func (i *Iter) mergeOlderOperands() {
 	// Save the current key.
 	i.saveKey()
 
@@ -874,7 +965,7 @@ func (i *Iter) mergeNext(valueMerger base.ValueMerger) {
 			// value and return. We change the kind of the resulting key to a
 			// Set so that it shadows keys in lower levels. That is:
 			// MERGE + (SET*) -> SET.
-			i.err = valueMerger.MergeOlder(i.iterValue)
+			i.err = i.mergeOperands.MergeOlder(i.iterValue)
 			if i.err != nil {
 				return
 			}
@@ -885,7 +976,7 @@ func (i *Iter) mergeNext(valueMerger base.ValueMerger) {
 		case base.InternalKeyKindMerge:
 			// We've hit another Merge value. Merge with the existing value and
 			// continue looping.
-			i.err = valueMerger.MergeOlder(i.iterValue)
+			i.err = i.mergeOperands.MergeOlder(i.iterValue)
 			if i.err != nil {
 				return
 			}
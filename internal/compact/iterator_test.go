@@ -89,7 +89,7 @@ func TestCompactionIter(t *testing.T) {
 			},
 		}
 		pointIter, rangeDelIter, rangeKeyIter := makeInputIters(kvs, rangeDels, rangeKeys)
-		return NewIter(cfg, pointIter, rangeDelIter, rangeKeyIter)
+		return NewIter(cfg, pointIter, rangeDelIter, rangeKeyIter, nil /* rangeMergeIter */)
 	}
 
 	runTest := func(t *testing.T, file string) {
@@ -106,7 +106,10 @@ type Runner struct {
 	lastRangeDelSpan keyspan.Span
 	// Last range key span (or portion of it) that was not yet written to a table.
 	lastRangeKeySpan keyspan.Span
-	stats            Stats
+	// Last range merge span (or portion of it) that was not yet written to a
+	// table.
+	lastRangeMergeSpan keyspan.Span
+	stats              Stats
 }
 
 // NewRunner creates a new Runner.
@@ -125,7 +128,8 @@ func (r *Runner) MoreDataToWrite() bool {
 	if r.err != nil {
 		return false
 	}
-	return r.key != nil || !r.lastRangeDelSpan.Empty() || !r.lastRangeKeySpan.Empty()
+	return r.key != nil || !r.lastRangeDelSpan.Empty() || !r.lastRangeKeySpan.Empty() ||
+		!r.lastRangeMergeSpan.Empty()
 }
 
 // WriteTable writes a new output table. This table will be part of
@@ -161,6 +165,7 @@ func (r *Runner) WriteTable(objMeta objstorage.ObjectMetadata, tw sstable.RawWri
 
 func (r *Runner) writeKeysToTable(tw sstable.RawWriter) (splitKey []byte, _ error) {
 	firstKey := base.MinUserKey(r.cmp, spanStartOrNil(&r.lastRangeDelSpan), spanStartOrNil(&r.lastRangeKeySpan))
+	firstKey = base.MinUserKey(r.cmp, firstKey, spanStartOrNil(&r.lastRangeMergeSpan))
 	if r.key != nil && firstKey == nil {
 		firstKey = r.key.UserKey
 	}
@@ -199,6 +204,15 @@ func (r *Runner) writeKeysToTable(tw sstable.RawWriter) (splitKey []byte, _ erro
 			}
 			r.lastRangeKeySpan.CopyFrom(r.iter.Span())
 			continue
+
+		case base.InternalKeyKindRangeMerge:
+			// The previous span (if any) must end at or before this key, since the
+			// spans we receive are non-overlapping.
+			if err := tw.EncodeSpan(r.lastRangeMergeSpan); err != nil {
+				return nil, err
+			}
+			r.lastRangeMergeSpan.CopyFrom(r.iter.Span())
+			continue
 		}
 		if err := tw.AddWithForceObsolete(*key, value, r.iter.ForceObsoleteDueToRangeDel()); err != nil {
 			return nil, err
@@ -220,6 +234,9 @@ func (r *Runner) writeKeysToTable(tw sstable.RawWriter) (splitKey []byte, _ erro
 	if err := SplitAndEncodeSpan(r.cmp, &r.lastRangeKeySpan, splitKey, tw); err != nil {
 		return nil, err
 	}
+	if err := SplitAndEncodeSpan(r.cmp, &r.lastRangeMergeSpan, splitKey, tw); err != nil {
+		return nil, err
+	}
 	// Set internal sstable properties.
 	tw.SetSnapshotPinnedProperties(pinnedCount, pinnedKeySize, pinnedValueSize)
 	r.stats.CumulativePinnedKeys += pinnedCount
@@ -291,7 +308,7 @@ func (r *Runner) TableSplitLimit(startKey []byte) []byte {
 // table that was just finished. splitKey is the key where the table must have
 // ended (or nil).
 func (r *Runner) validateWriterMeta(meta *sstable.WriterMetadata, splitKey []byte) error {
-	if !meta.HasPointKeys && !meta.HasRangeDelKeys && !meta.HasRangeKeys {
+	if !meta.HasPointKeys && !meta.HasRangeDelKeys && !meta.HasRangeKeys && !meta.HasRangeMergeKeys {
 		return base.AssertionFailedf("output table has no keys")
 	}
 
@@ -321,6 +338,9 @@ func (r *Runner) validateWriterMeta(meta *sstable.WriterMetadata, splitKey []byt
 	if meta.HasRangeKeys {
 		checkBounds(meta.SmallestRangeKey, meta.LargestRangeKey, "range key")
 	}
+	if meta.HasRangeMergeKeys {
+		checkBounds(meta.SmallestRangeMerge, meta.LargestRangeMerge, "range merge")
+	}
 	return err
 }
 
@@ -170,6 +170,15 @@ func SetupTombstoneElision(
 	if outputLevel > 0 {
 		startLevel = outputLevel + 1
 	}
+	return SetupTombstoneElisionFromLevel(cmp, v, startLevel, compactionBounds)
+}
+
+// SetupTombstoneElisionFromLevel calculates the TombstoneElision policies for
+// a compaction from the in-use key ranges of the given level and the levels
+// below it.
+func SetupTombstoneElisionFromLevel(
+	cmp base.Compare, v *manifest.Version, startLevel int, compactionBounds base.UserKeyBounds,
+) (dels, rangeKeys TombstoneElision) {
 	// CalculateInuseKeyRanges will return a series of sorted spans. Overlapping
 	// or abutting spans have already been merged.
 	inUseKeyRanges := v.CalculateInuseKeyRanges(
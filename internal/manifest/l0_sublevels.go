@@ -1194,16 +1194,42 @@ func (l *L0CompactionFiles) addFile(f *FileMetadata) {
 	}
 }
 
+// IntervalPriority returns the priority of compacting the L0 files within the
+// given key bounds. The default priority is zero. The stack depth of an
+// interval with a positive priority is doubled when scoring it for compaction,
+// and that of an interval with a negative priority is halved, so a priority
+// favors an interval over others of similar depth without starving much deeper
+// intervals with a lower priority.
+type IntervalPriority func(bounds base.UserKeyBounds) int
+
+// prioritizedDepth returns the stack depth of an interval with the given
+// priority, scaled as described at IntervalPriority.
+func prioritizedDepth(depth, priority int) int {
+	switch {
+	case priority > 0:
+		return 2 * depth
+	case priority < 0:
+		return (depth + 1) / 2
+	default:
+		return depth
+	}
+}
+
 // Helper to order intervals being considered for compaction.
 type intervalAndScore struct {
 	interval int
 	score    int
+	// priority breaks ties between intervals with the same score.
+	priority int
 }
 type intervalSorterByDecreasingScore []intervalAndScore
 
 func (is intervalSorterByDecreasingScore) Len() int { return len(is) }
 func (is intervalSorterByDecreasingScore) Less(i, j int) bool {
-	return is[i].score > is[j].score
+	if is[i].score != is[j].score {
+		return is[i].score > is[j].score
+	}
+	return is[i].priority > is[j].priority
 }
 func (is intervalSorterByDecreasingScore) Swap(i, j int) {
 	is[i], is[j] = is[j], is[i]
@@ -1365,12 +1391,24 @@ func (is intervalSorterByDecreasingScore) Swap(i, j int) {
 //    Lbase a---------i    m---------w
 //
 
+// intervalPriority returns the priority of the interval at index i, as
+// determined by the optional priority function.
+func (s *L0Sublevels) intervalPriority(priority IntervalPriority, i int) int {
+	if priority == nil || i+1 >= len(s.orderedIntervals) {
+		return 0
+	}
+	end := s.orderedIntervals[i+1].startKey
+	return priority(base.UserKeyBoundsEndExclusiveIf(
+		s.orderedIntervals[i].startKey.key, end.key, !end.isInclusiveEndBound))
+}
+
 // PickBaseCompaction picks a base compaction based on the above specified
 // heuristics, for the specified Lbase files and a minimum depth of overlapping
-// files that can be selected for compaction. Returns nil if no compaction is
-// possible.
+// files that can be selected for compaction. The intervals' scores are scaled
+// by the optional priority function (see IntervalPriority). Returns nil if no
+// compaction is possible.
 func (s *L0Sublevels) PickBaseCompaction(
-	minCompactionDepth int, baseFiles LevelSlice,
+	minCompactionDepth int, baseFiles LevelSlice, priority IntervalPriority,
 ) (*L0CompactionFiles, error) {
 	// For LBase compactions, we consider intervals in a greedy manner in the
 	// following order:
@@ -1392,12 +1430,14 @@ func (s *L0Sublevels) PickBaseCompaction(
 		if interval.isBaseCompacting || minCompactionDepth > depth {
 			continue
 		}
+		p := s.intervalPriority(priority, i)
+		score := prioritizedDepth(depth, p)
 		if interval.intervalRangeIsBaseCompacting {
-			scoredIntervals = append(scoredIntervals, intervalAndScore{interval: i, score: depth})
+			scoredIntervals = append(scoredIntervals, intervalAndScore{interval: i, score: score, priority: p})
 		} else {
 			// Prioritize this interval by incrementing the score by the number
 			// of sublevels.
-			scoredIntervals = append(scoredIntervals, intervalAndScore{interval: i, score: depth + sublevelCount})
+			scoredIntervals = append(scoredIntervals, intervalAndScore{interval: i, score: score + sublevelCount, priority: p})
 		}
 	}
 	sort.Sort(intervalSorterByDecreasingScore(scoredIntervals))
@@ -1595,16 +1635,19 @@ func (s *L0Sublevels) extendFiles(
 // See comment above [PickBaseCompaction] for heuristics involved in this
 // selection.
 func (s *L0Sublevels) PickIntraL0Compaction(
-	earliestUnflushedSeqNum base.SeqNum, minCompactionDepth int,
+	earliestUnflushedSeqNum base.SeqNum, minCompactionDepth int, priority IntervalPriority,
 ) (*L0CompactionFiles, error) {
-	scoredIntervals := make([]intervalAndScore, len(s.orderedIntervals))
+	scoredIntervals := make([]intervalAndScore, 0, len(s.orderedIntervals))
 	for i := range s.orderedIntervals {
 		interval := &s.orderedIntervals[i]
 		depth := len(interval.files) - interval.compactingFileCount
 		if minCompactionDepth > depth {
 			continue
 		}
-		scoredIntervals[i] = intervalAndScore{interval: i, score: depth}
+		p := s.intervalPriority(priority, i)
+		scoredIntervals = append(scoredIntervals, intervalAndScore{
+			interval: i, score: prioritizedDepth(depth, p), priority: p,
+		})
 	}
 	sort.Sort(intervalSorterByDecreasingScore(scoredIntervals))
 
@@ -1620,7 +1663,7 @@ func (s *L0Sublevels) PickIntraL0Compaction(
 		var f *FileMetadata
 		// Pick the seed file for the interval as the file in the highest
 		// sub-level.
-		stackDepthReduction := scoredInterval.score
+		stackDepthReduction := len(interval.files) - interval.compactingFileCount
 		for i := len(interval.files) - 1; i >= 0; i-- {
 			f = interval.files[i]
 			if f.IsCompacting() {
@@ -339,6 +339,7 @@ func TestL0Sublevels(t *testing.T) {
 		case "pick-intra-l0-compaction":
 			minCompactionDepth := 3
 			earliestUnflushedSeqNum := base.SeqNum(math.MaxUint64)
+			var priority IntervalPriority
 			for _, arg := range td.CmdArgs {
 				switch arg.Key {
 				case "min_depth":
@@ -348,13 +349,29 @@ func TestL0Sublevels(t *testing.T) {
 					}
 				case "earliest_unflushed_seqnum":
 					earliestUnflushedSeqNum = base.ParseSeqNum(arg.Vals[0])
+				case "priority":
+					// Assign the priority p to the intervals overlapping the
+					// key range [start, end), specified as start-end:p.
+					keys, p, _ := strings.Cut(arg.Vals[0], ":")
+					start, end, _ := strings.Cut(keys, "-")
+					prioritized := base.UserKeyBoundsEndExclusive([]byte(start), []byte(end))
+					p1, err := strconv.Atoi(p)
+					if err != nil {
+						return err.Error()
+					}
+					priority = func(bounds base.UserKeyBounds) int {
+						if bounds.Overlaps(base.DefaultComparer.Compare, &prioritized) {
+							return p1
+						}
+						return 0
+					}
 				}
 			}
 
 			var lcf *L0CompactionFiles
 			if pickBaseCompaction {
 				baseFiles := NewLevelSliceKeySorted(base.DefaultComparer.Compare, fileMetas[baseLevel])
-				lcf, err = sublevels.PickBaseCompaction(minCompactionDepth, baseFiles)
+				lcf, err = sublevels.PickBaseCompaction(minCompactionDepth, baseFiles, priority)
 				if err == nil && lcf != nil {
 					// Try to extend the base compaction into a more rectangular
 					// shape, using the smallest/largest keys of the files before
@@ -381,7 +398,7 @@ func TestL0Sublevels(t *testing.T) {
 						lcf)
 				}
 			} else {
-				lcf, err = sublevels.PickIntraL0Compaction(earliestUnflushedSeqNum, minCompactionDepth)
+				lcf, err = sublevels.PickIntraL0Compaction(earliestUnflushedSeqNum, minCompactionDepth, priority)
 			}
 			if err != nil {
 				return fmt.Sprintf("error: %s", err.Error())
@@ -609,7 +626,7 @@ func BenchmarkL0SublevelsInitAndPick(b *testing.B) {
 		if sl == nil {
 			b.Fatal("expected non-nil L0Sublevels to be generated")
 		}
-		c, err := sl.PickBaseCompaction(2, LevelSlice{})
+		c, err := sl.PickBaseCompaction(2, LevelSlice{}, nil)
 		require.NoError(b, err)
 		if c == nil {
 			b.Fatal("expected non-nil compaction to be generated")
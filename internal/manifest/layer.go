@@ -15,10 +15,14 @@ import (
 //   - a level L1 through L6, or
 //   - the entire L0 level, or
 //   - a specific L0 sublevel, or
+//   - a specific sorted run of a level L1 through L6 that holds multiple runs,
+//     or
 //   - the layer of flushable ingests (which is conceptually above the LSM).
 type Layer struct {
 	kind  layerKind
 	value uint16
+	// run is the sorted run within the level, for a level run layer.
+	run uint16
 }
 
 // Level returns a Layer that represents an entire level (L0 through L6).
@@ -44,6 +48,22 @@ func L0Sublevel(sublevel int) Layer {
 	}
 }
 
+// LevelRun returns a Layer that represents a specific sorted run of a level
+// below L0 that holds multiple sorted runs (see Version.Runs).
+func LevelRun(level, run int) Layer {
+	if level <= 0 || level >= NumLevels {
+		panic("invalid level")
+	}
+	if run < 0 || run > math.MaxUint16 {
+		panic("invalid run")
+	}
+	return Layer{
+		kind:  levelRunLayer,
+		value: uint16(level),
+		run:   uint16(run),
+	}
+}
+
 // FlushableIngestsLayer returns a Layer that represents the flushable ingests
 // layer (which is logically above L0).
 func FlushableIngestsLayer() Layer {
@@ -71,7 +91,7 @@ func (l Layer) IsL0Sublevel() bool {
 // the layer represents flushable ingests.
 func (l Layer) Level() int {
 	switch l.kind {
-	case levelLayer:
+	case levelLayer, levelRunLayer:
 		return int(l.value)
 	case l0SublevelLayer:
 		return 0
@@ -91,12 +111,29 @@ func (l Layer) Sublevel() int {
 	return int(l.value)
 }
 
+// IsLevelRun returns true if the layer represents a sorted run of a level
+// below L0.
+func (l Layer) IsLevelRun() bool {
+	return l.kind == levelRunLayer
+}
+
+// Run returns the sorted run within the level. Can only be called if the
+// layer represents a sorted run of a level below L0.
+func (l Layer) Run() int {
+	if !l.IsLevelRun() {
+		panic("not a level run layer")
+	}
+	return int(l.run)
+}
+
 func (l Layer) String() string {
 	switch l.kind {
 	case levelLayer:
 		return fmt.Sprintf("L%d", l.value)
 	case l0SublevelLayer:
 		return fmt.Sprintf("L0.%d", l.value)
+	case levelRunLayer:
+		return fmt.Sprintf("L%d.%d", l.value, l.run)
 	case flushableIngestsLayer:
 		return "flushable-ingests"
 	default:
@@ -118,4 +155,7 @@ const (
 	l0SublevelLayer
 	// Flushable ingests layer: value is unused.
 	flushableIngestsLayer
+	// Sorted run of a level below L0: value contains the level number (1
+	// through 6), and run the run number.
+	levelRunLayer
 )
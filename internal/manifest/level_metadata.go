@@ -132,9 +132,13 @@ func (lm *LevelMetadata) Find(cmp base.Compare, m *FileMetadata) LevelSlice {
 			}
 		}
 	} else {
-		// For levels other than L0, UserKeyBounds in the level are non-overlapping
-		// so we only need to check one file.
-		if f := iter.SeekGE(cmp, m.UserKeyBounds().Start); f == m {
+		// For levels other than L0, files are ordered by their smallest keys,
+		// which are unique within the level even if the level holds multiple
+		// overlapping sorted runs, so we only need to check one file.
+		f := iter.seek(func(f *FileMetadata) bool {
+			return f.cmpSmallestKey(m, cmp) >= 0
+		})
+		if f == m {
 			return iter.Take().slice
 		}
 	}
@@ -244,8 +244,10 @@ type FileMetadata struct {
 	// we'd have to write virtual sstable stats to the version edit.
 	Stats TableStats
 
-	// For L0 files only. Protected by DB.mu. Used to generate L0 sublevels and
-	// pick L0 compactions. Only accurate for the most recent Version.
+	// For L0 files, and files in levels that hold multiple sorted runs (see
+	// Version.Runs). Protected by DB.mu. Used to generate L0 sublevels and
+	// the sorted runs of a level, and to pick L0 compactions. Only accurate
+	// for the most recent Version.
 	SubLevel         int
 	L0Index          int
 	minIntervalIndex int
@@ -283,6 +285,9 @@ type FileMetadata struct {
 	HasPointKeys bool
 	// HasRangeKeys tracks whether the table contains any range keys.
 	HasRangeKeys bool
+	// HasRangeMerges tracks whether the table contains any range merges. Range
+	// merges are included in the table's point key bounds.
+	HasRangeMerges bool
 	// smallestSet and largestSet track whether the overall bounds have been set.
 	boundsSet bool
 	// boundTypeSmallest and boundTypeLargest provide an indication as to which
@@ -967,6 +972,7 @@ var (
 		base.InternalKeyKindRangeDelete:   true,
 		base.InternalKeyKindSetWithDelete: true,
 		base.InternalKeyKindDeleteSized:   true,
+		base.InternalKeyKindRangeMerge:    true,
 	}
 	isValidRangeKeyBoundKeyKind = [base.InternalKeyKindMax + 1]bool{
 		base.InternalKeyKindRangeKeySet:    true,
@@ -1154,11 +1160,28 @@ type Version struct {
 
 	Levels [NumLevels]LevelMetadata
 
+	// Runs holds the sorted runs of the levels below L0 that hold more than
+	// one sorted run, which is only permitted for versions built by a
+	// BulkVersionEdit with MultipleRunsPerLevel set. The files of such a level
+	// may overlap, and are organized into runs the same way L0 files are
+	// organized into sublevels: Runs[level][n] contains older tables than
+	// Runs[level][n+1], and the tables within a run don't overlap. Runs[level]
+	// is nil for L0 and for the levels that hold a single sorted run.
+	Runs [NumLevels][]LevelSlice
+
 	// RangeKeyLevels holds a subset of the same files as Levels that contain range
 	// keys (i.e. fileMeta.HasRangeKeys == true). The memory amplification of this
 	// duplication should be minimal, as range keys are expected to be rare.
 	RangeKeyLevels [NumLevels]LevelMetadata
 
+	// RangeMergeLevels holds a subset of the same files as Levels that contain
+	// range merges (i.e. fileMeta.HasRangeMerges == true).
+	RangeMergeLevels [NumLevels]LevelMetadata
+	// HasRangeMerges is true if any of RangeMergeLevels is non-empty. Range
+	// merges are expected to be rare, and reads consult HasRangeMerges to
+	// avoid looking for them in every level.
+	HasRangeMerges bool
+
 	// The callback to invoke when the last reference to a version is
 	// removed. Will be called with list.mu held.
 	Deleted func(obsolete []*FileBacking)
@@ -1212,6 +1235,15 @@ func (v *Version) string(verbose bool) string {
 		if v.Levels[level].Empty() {
 			continue
 		}
+		if runs := v.Runs[level]; runs != nil {
+			for run := len(runs) - 1; run >= 0; run-- {
+				fmt.Fprintf(&buf, "%s:\n", LevelRun(level, run))
+				runs[run].Each(func(f *FileMetadata) {
+					fmt.Fprintf(&buf, "  %s\n", f.DebugString(v.cmp.FormatKey, verbose))
+				})
+			}
+			continue
+		}
 		fmt.Fprintf(&buf, "L%d:\n", level)
 		iter := v.Levels[level].Iter()
 		for f := iter.First(); f != nil; f = iter.Next() {
@@ -1298,6 +1330,9 @@ func (v *Version) unrefFiles() []*FileBacking {
 	for _, lm := range v.RangeKeyLevels {
 		obsolete = append(obsolete, lm.release()...)
 	}
+	for _, lm := range v.RangeMergeLevels {
+		obsolete = append(obsolete, lm.release()...)
+	}
 	return obsolete
 }
 
@@ -1316,6 +1351,54 @@ func (v *Version) InitL0Sublevels(flushSplitBytes int64) error {
 	return err
 }
 
+// initRuns organizes the files of the given level below L0 into sorted runs,
+// using the L0 sublevel machinery, if any of them overlap.
+func (v *Version) initRuns(level int) error {
+	v.Runs[level] = nil
+	lm := &v.Levels[level]
+	if lm.Len() < 2 {
+		return nil
+	}
+	files := make([]*FileMetadata, 0, lm.Len())
+	overlapping := false
+	iter := lm.Iter()
+	for f := iter.First(); f != nil; f = iter.Next() {
+		if n := len(files); n > 0 {
+			bounds := f.UserKeyBounds()
+			overlapping = overlapping || files[n-1].Overlaps(v.cmp.Compare, &bounds)
+		}
+		files = append(files, f)
+	}
+	if !overlapping {
+		return nil
+	}
+	// NewL0Sublevels requires the files in L0 order, by increasing sequence
+	// numbers.
+	seqSorted := MakeLevelMetadata(v.cmp.Compare, 0, files)
+	defer seqSorted.release()
+	s, err := NewL0Sublevels(&seqSorted, v.cmp.Compare, v.cmp.FormatKey, 0 /* flushSplitMaxBytes */)
+	if err != nil {
+		return err
+	}
+	v.Runs[level] = s.Levels
+	return nil
+}
+
+// NumRuns returns the number of sorted runs in the given level: the number of
+// sublevels for L0, and the number of runs for the other levels.
+func (v *Version) NumRuns(level int) int {
+	switch {
+	case level == 0:
+		return len(v.L0SublevelFiles)
+	case v.Runs[level] != nil:
+		return len(v.Runs[level])
+	case v.Levels[level].Empty():
+		return 0
+	default:
+		return 1
+	}
+}
+
 // CalculateInuseKeyRanges examines file metadata in levels [level, maxLevel]
 // within bounds [smallest,largest], returning an ordered slice of key ranges
 // that include all keys that exist within levels [level, maxLevel] and within
@@ -1339,8 +1422,10 @@ func (v *Version) CalculateInuseKeyRanges(
 	// there's little consequence to calculating slightly broader in-use key
 	// ranges.
 	bounds := base.UserKeyBoundsInclusive(smallest, largest)
-	for ; level <= maxLevel; level++ {
-		overlaps := v.Overlaps(level, bounds)
+	// addFiles merges the in-use key ranges accumulated so far with the
+	// files of a level, or of a sorted run of a level, which don't overlap
+	// one another.
+	addFiles := func(overlaps LevelSlice) {
 		iter := overlaps.Iter()
 
 		// We may already have in-use key ranges from higher levels. Iterate
@@ -1423,6 +1508,15 @@ func (v *Version) CalculateInuseKeyRanges(
 			output = append(output, currFile.UserKeyBounds())
 		}
 	}
+	for ; level <= maxLevel; level++ {
+		if runs := v.Runs[level]; runs != nil {
+			for _, run := range runs {
+				addFiles(run.Overlaps(v.cmp.Compare, bounds))
+			}
+			continue
+		}
+		addFiles(v.Overlaps(level, bounds))
+	}
 	return output
 }
 
@@ -1534,16 +1628,43 @@ func (v *Version) Overlaps(level int, bounds base.UserKeyBounds) LevelSlice {
 		return slice
 	}
 
+	if runs := v.Runs[level]; runs != nil {
+		// The level's sorted runs may overlap one another, so the overlapping
+		// files of the level aren't contiguous. Construct a B-Tree containing
+		// the overlapping files of each run.
+		var tr btree
+		tr.cmp = v.cmp.Compare
+		tr.bcmp = v.Levels[level].tree.bcmp
+		for _, run := range runs {
+			overlaps := run.Overlaps(v.cmp.Compare, bounds)
+			iter := overlaps.Iter()
+			for f := iter.First(); f != nil; f = iter.Next() {
+				if err := tr.Insert(f); err != nil {
+					panic(err)
+				}
+			}
+		}
+		slice := newLevelSlice(tr.Iter())
+		tr.Release()
+		return slice
+	}
 	return v.Levels[level].Slice().Overlaps(v.cmp.Compare, bounds)
 }
 
 // IterAllLevelsAndSublevels calls fn with an iterator for each L0 sublevel
-// (from top to bottom), then once for each level below L0.
+// (from top to bottom), then once for each level below L0, or once for each
+// sorted run (from newest to oldest) of a level that holds multiple runs.
 func (v *Version) IterAllLevelsAndSublevels(fn func(it LevelIterator, level Layer)) {
 	for sublevel := len(v.L0SublevelFiles) - 1; sublevel >= 0; sublevel-- {
 		fn(v.L0SublevelFiles[sublevel].Iter(), L0Sublevel(sublevel))
 	}
 	for level := 1; level < NumLevels; level++ {
+		if runs := v.Runs[level]; runs != nil {
+			for run := len(runs) - 1; run >= 0; run-- {
+				fn(runs[run].Iter(), LevelRun(level, run))
+			}
+			continue
+		}
 		fn(v.Levels[level].Iter(), Level(level))
 	}
 }
@@ -1560,6 +1681,14 @@ func (v *Version) CheckOrdering() error {
 	}
 
 	for level, lm := range v.Levels {
+		if runs := v.Runs[level]; runs != nil {
+			for run := range runs {
+				if err := CheckOrdering(v.cmp.Compare, v.cmp.FormatKey, LevelRun(level, run), runs[run].Iter()); err != nil {
+					return base.CorruptionErrorf("%s\n%s", err, v.DebugString())
+				}
+			}
+			continue
+		}
 		if err := CheckOrdering(v.cmp.Compare, v.cmp.FormatKey, Level(level), lm.Iter()); err != nil {
 			return base.CorruptionErrorf("%s\n%s", err, v.DebugString())
 		}
@@ -69,6 +69,7 @@ const (
 	customTagVirtual           = 66
 	customTagSyntheticPrefix   = 67
 	customTagSyntheticSuffix   = 68
+	customTagRangeMerges       = 69
 )
 
 // DeletedFileEntry holds the state for a file deletion from a level. The file
@@ -346,6 +347,7 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 			}{}
 			var syntheticPrefix sstable.SyntheticPrefix
 			var syntheticSuffix sstable.SyntheticSuffix
+			var hasRangeMerges bool
 			if tag == tagNewFile4 || tag == tagNewFile5 {
 				for {
 					customTag, err := d.readUvarint()
@@ -398,6 +400,16 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 							return err
 						}
 
+					case customTagRangeMerges:
+						field, err := d.readBytes()
+						if err != nil {
+							return err
+						}
+						if len(field) != 1 {
+							return base.CorruptionErrorf("new-file4: range-merges field wrong size")
+						}
+						hasRangeMerges = (field[0] == 1)
+
 					default:
 						if (customTag & customTagNonSafeIgnoreMask) != 0 {
 							return base.CorruptionErrorf("new-file4: custom field not supported: %d", customTag)
@@ -419,6 +431,7 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 				Virtual:               virtualState.virtual,
 				SyntheticPrefix:       syntheticPrefix,
 				SyntheticSuffix:       syntheticSuffix,
+				HasRangeMerges:        hasRangeMerges,
 			}
 			if tag != tagNewFile5 { // no range keys present
 				m.SmallestPointKey = base.DecodeInternalKey(smallestPointKey)
@@ -646,7 +659,8 @@ func (v *VersionEdit) Encode(w io.Writer) error {
 		e.writeUvarint(uint64(x.FileNum))
 	}
 	for _, x := range v.NewFiles {
-		customFields := x.Meta.MarkedForCompaction || x.Meta.CreationTime != 0 || x.Meta.Virtual
+		customFields := x.Meta.MarkedForCompaction || x.Meta.CreationTime != 0 || x.Meta.Virtual ||
+			x.Meta.HasRangeMerges
 		var tag uint64
 		switch {
 		case x.Meta.HasRangeKeys:
@@ -711,6 +725,10 @@ func (v *VersionEdit) Encode(w io.Writer) error {
 				e.writeUvarint(customTagSyntheticSuffix)
 				e.writeBytes(x.Meta.SyntheticSuffix)
 			}
+			if x.Meta.HasRangeMerges {
+				e.writeUvarint(customTagRangeMerges)
+				e.writeBytes([]byte{1})
+			}
 			e.writeUvarint(customTagTerminate)
 		}
 	}
@@ -836,6 +854,13 @@ type BulkVersionEdit struct {
 	// MarkedForCompactionCountDiff holds the aggregated count of files
 	// marked for compaction added or removed.
 	MarkedForCompactionCountDiff int
+
+	// MultipleRunsPerLevel permits the files of a level below L0 to overlap,
+	// as they do under a tiered compaction style. Apply organizes the files
+	// of such a level into sorted runs (see Version.Runs) and checks the
+	// ordering of each run, instead of requiring that the files of the level
+	// don't overlap.
+	MultipleRunsPerLevel bool
 }
 
 // Accumulate adds the file addition and deletions in the specified version
@@ -976,9 +1001,17 @@ func (b *BulkVersionEdit) Apply(
 		} else {
 			v.RangeKeyLevels[level] = curr.RangeKeyLevels[level].clone()
 		}
+		if curr == nil || curr.RangeMergeLevels[level].tree.root == nil {
+			v.RangeMergeLevels[level] = MakeLevelMetadata(comparer.Compare, level, nil /* files */)
+		} else {
+			v.RangeMergeLevels[level] = curr.RangeMergeLevels[level].clone()
+		}
 
 		if len(b.Added[level]) == 0 && len(b.Deleted[level]) == 0 {
 			// There are no edits on this level.
+			if level > 0 && curr != nil && b.MultipleRunsPerLevel {
+				v.Runs[level] = curr.Runs[level]
+			}
 			if level == 0 {
 				// Initialize L0Sublevels.
 				if curr == nil || curr.L0Sublevels == nil {
@@ -996,6 +1029,7 @@ func (b *BulkVersionEdit) Apply(
 		// Some edits on this level.
 		lm := &v.Levels[level]
 		lmRange := &v.RangeKeyLevels[level]
+		lmRangeMerge := &v.RangeMergeLevels[level]
 
 		addedFilesMap := b.Added[level]
 		deletedFilesMap := b.Deleted[level]
@@ -1028,6 +1062,12 @@ func (b *BulkVersionEdit) Apply(
 					return nil, err
 				}
 			}
+			if f.HasRangeMerges {
+				if obsolete := v.RangeMergeLevels[level].remove(f); obsolete {
+					err := errors.Errorf("pebble: internal error: file L%d.%s obsolete during range-merge B-Tree removal", level, f.FileNum)
+					return nil, err
+				}
+			}
 		}
 
 		addedFiles := make([]*FileMetadata, 0, len(addedFilesMap))
@@ -1065,6 +1105,12 @@ func (b *BulkVersionEdit) Apply(
 					return nil, errors.Wrap(err, "pebble")
 				}
 			}
+			if f.HasRangeMerges {
+				err = lmRangeMerge.insert(f)
+				if err != nil {
+					return nil, errors.Wrap(err, "pebble")
+				}
+			}
 			// Track the keys with the smallest and largest keys, so that we can
 			// check consistency of the modified span.
 			if sm == nil || base.InternalCompare(comparer.Compare, sm.Smallest, f.Smallest) > 0 {
@@ -1113,6 +1159,23 @@ func (b *BulkVersionEdit) Apply(
 			continue
 		}
 
+		if b.MultipleRunsPerLevel {
+			// The level may hold multiple sorted runs. Check the consistency of
+			// each run instead.
+			if err := v.initRuns(level); err != nil {
+				return nil, errors.Wrap(err, "pebble: internal error")
+			}
+			for run := range v.Runs[level] {
+				iter := v.Runs[level][run].Iter()
+				if err := CheckOrdering(comparer.Compare, comparer.FormatKey, LevelRun(level, run), iter); err != nil {
+					return nil, errors.Wrap(err, "pebble: internal error")
+				}
+			}
+			if v.Runs[level] != nil {
+				continue
+			}
+		}
+
 		// Check consistency of the level in the vicinity of our edits.
 		if sm != nil && la != nil {
 			overlap := v.Levels[level].Slice().Overlaps(comparer.Compare, sm.UserKeyBounds())
@@ -1132,5 +1195,11 @@ func (b *BulkVersionEdit) Apply(
 			}
 		}
 	}
+	for level := range v.RangeMergeLevels {
+		if !v.RangeMergeLevels[level].Empty() {
+			v.HasRangeMerges = true
+			break
+		}
+	}
 	return v, nil
 }
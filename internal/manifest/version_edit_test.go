@@ -541,3 +541,63 @@ func TestParseVersionEditDebugRoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestBulkVersionEditMultipleRunsPerLevel(t *testing.T) {
+	cmp := base.DefaultComparer
+	newFile := func(fileNum base.FileNum, smallest, largest string, seqNum base.SeqNum) *FileMetadata {
+		m := (&FileMetadata{
+			FileNum:               fileNum,
+			SmallestSeqNum:        seqNum,
+			LargestSeqNum:         seqNum,
+			LargestSeqNumAbsolute: seqNum,
+		}).ExtendPointKeyBounds(
+			cmp.Compare,
+			base.MakeInternalKey([]byte(smallest), seqNum, base.InternalKeyKindSet),
+			base.MakeInternalKey([]byte(largest), seqNum, base.InternalKeyKindSet),
+		)
+		m.InitPhysicalBacking()
+		return m
+	}
+	filesOf := func(ls LevelSlice) []*FileMetadata {
+		var files []*FileMetadata
+		ls.Each(func(f *FileMetadata) { files = append(files, f) })
+		return files
+	}
+	// L6 holds an older run of 000001 and 000002, and a newer run of 000003,
+	// which overlaps both.
+	f1, f2, f3 := newFile(1, "a", "c", 1), newFile(2, "d", "f", 2), newFile(3, "b", "e", 3)
+	ve := &VersionEdit{NewFiles: []NewFileEntry{{Level: 6, Meta: f1}, {Level: 6, Meta: f2}, {Level: 6, Meta: f3}}}
+
+	var bve BulkVersionEdit
+	require.NoError(t, bve.Accumulate(ve))
+	_, err := bve.Apply(nil, cmp, 0, 0)
+	require.Error(t, err)
+
+	bve = BulkVersionEdit{MultipleRunsPerLevel: true}
+	require.NoError(t, bve.Accumulate(ve))
+	v, err := bve.Apply(nil, cmp, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, v.CheckOrdering())
+	require.Equal(t, 2, v.NumRuns(6))
+	require.Equal(t, []*FileMetadata{f1, f2}, filesOf(v.Runs[6][0]))
+	require.Equal(t, []*FileMetadata{f3}, filesOf(v.Runs[6][1]))
+	require.Equal(t, "L6.1", LevelRun(6, 1).String())
+
+	// Overlaps returns the overlapping files of every run, in key order.
+	overlaps := v.Overlaps(6, base.UserKeyBoundsInclusive([]byte("e"), []byte("z")))
+	require.Equal(t, []*FileMetadata{f3, f2}, filesOf(overlaps))
+	inUse := v.CalculateInuseKeyRanges(6, 6, []byte("a"), []byte("z"))
+	require.Len(t, inUse, 1)
+	require.Equal(t, "a", string(inUse[0].Start))
+	require.Equal(t, "f", string(inUse[0].End.Key))
+
+	// Removing the newer run leaves a single run, which isn't split.
+	bve = BulkVersionEdit{MultipleRunsPerLevel: true}
+	require.NoError(t, bve.Accumulate(&VersionEdit{
+		DeletedFiles: map[DeletedFileEntry]*FileMetadata{{Level: 6, FileNum: 3}: f3},
+	}))
+	v, err = bve.Apply(v, cmp, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, v.NumRuns(6))
+	require.Nil(t, v.Runs[6])
+}
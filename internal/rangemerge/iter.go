@@ -0,0 +1,458 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package rangemerge
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/bytealloc"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/internal/treeprinter"
+)
+
+// Iter wraps a point iterator and applies range merges from a fragment
+// iterator to the point keys it surfaces. For every user key covered by a
+// visible range merge that applies to the key (see the package documentation),
+// Iter surfaces an additional MERGE record carrying the range merge's operand
+// at the range merge's sequence number. Records for user keys not covered by
+// any range merge are passed through unmodified.
+//
+// Iter requires that the point iterator surface all records for a user key;
+// when a user key is covered by a range merge, Iter buffers the user key's
+// records in order to interleave the synthesized MERGE records. Keys of kinds
+// that aren't point keys (eg, interleaved range deletion boundaries) are always
+// passed through.
+type Iter struct {
+	cmp      base.Compare
+	iter     base.InternalIterator
+	spans    keyspan.FragmentIterator
+	snapshot base.SeqNum
+	// rangeDelSpan, if non-nil, returns the span of range deletions covering
+	// the point iterator's current key. It's used when the point iterator does
+	// not itself elide keys deleted by range deletions (eg, during compactions).
+	rangeDelSpan func() *keyspan.Span
+
+	// cov caches the visible range merges covering the user key span
+	// [cov.span.Start, cov.span.End). If startUnbounded or endUnbounded is
+	// set, the corresponding bound is infinite. The span may have no keys, in
+	// which case no range merge covers the cached span.
+	cov struct {
+		span           keyspan.Span
+		startUnbounded bool
+		endUnbounded   bool
+		valid          bool
+	}
+
+	// When buffered is set, the iterator is positioned within buf at bufIdx.
+	// All records in buf share the user key key. bufDir records the direction
+	// in which buf was populated: if positive, the point iterator is positioned
+	// at pending, the first key after key; if negative, the point iterator is
+	// positioned at pending, the last key before key.
+	buffered bool
+	buf      []base.InternalKV
+	bufIdx   int
+	bufDir   int8
+	pending  *base.InternalKV
+	key      []byte
+	delSeqs  []base.SeqNum
+	alloc    bytealloc.A
+	seekKey  []byte
+	err      error
+}
+
+var _ base.TopLevelIterator = (*Iter)(nil)
+
+// NewIter constructs a new iterator applying the range merges surfaced by spans
+// that are visible at the provided snapshot to the keys surfaced by iter. If
+// rangeDelSpan is non-nil, it's consulted for the range deletions covering the
+// point iterator's current position when determining whether a range merge
+// applies to a key.
+//
+// The returned iterator takes ownership of both iter and spans, closing them
+// when it's closed.
+func NewIter(
+	cmp base.Compare,
+	iter base.InternalIterator,
+	spans keyspan.FragmentIterator,
+	snapshot base.SeqNum,
+	rangeDelSpan func() *keyspan.Span,
+) *Iter {
+	return &Iter{
+		cmp:          cmp,
+		iter:         iter,
+		spans:        spans,
+		snapshot:     snapshot,
+		rangeDelSpan: rangeDelSpan,
+	}
+}
+
+// SeekGE implements base.InternalIterator.
+func (i *Iter) SeekGE(key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	flags = i.resetForSeek(flags)
+	return i.processForward(i.iter.SeekGE(key, flags))
+}
+
+// SeekPrefixGE implements base.InternalIterator.
+func (i *Iter) SeekPrefixGE(prefix, key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	flags = i.resetForSeek(flags)
+	return i.processForward(i.iter.SeekPrefixGE(prefix, key, flags))
+}
+
+// SeekPrefixGEStrict implements base.TopLevelIterator. It requires that the
+// wrapped point iterator is also a base.TopLevelIterator.
+func (i *Iter) SeekPrefixGEStrict(prefix, key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	flags = i.resetForSeek(flags)
+	return i.processForward(i.iter.(base.TopLevelIterator).SeekPrefixGEStrict(prefix, key, flags))
+}
+
+// SeekLT implements base.InternalIterator.
+func (i *Iter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
+	i.resetForSeek(base.SeekGEFlagsNone)
+	return i.processBackward(i.iter.SeekLT(key, flags))
+}
+
+// First implements base.InternalIterator.
+func (i *Iter) First() *base.InternalKV {
+	i.resetForSeek(base.SeekGEFlagsNone)
+	return i.processForward(i.iter.First())
+}
+
+// Last implements base.InternalIterator.
+func (i *Iter) Last() *base.InternalKV {
+	i.resetForSeek(base.SeekGEFlagsNone)
+	return i.processBackward(i.iter.Last())
+}
+
+// Next implements base.InternalIterator.
+func (i *Iter) Next() *base.InternalKV {
+	if !i.buffered {
+		return i.processForward(i.iter.Next())
+	}
+	i.bufIdx++
+	if i.bufIdx < len(i.buf) {
+		return &i.buf[i.bufIdx]
+	}
+	i.buffered = false
+	if i.bufDir > 0 {
+		return i.processForward(i.pending)
+	}
+	// The point iterator is positioned before the buffered user key. Reposition
+	// it after the buffered user key.
+	i.seekKey = append(i.seekKey[:0], i.key...)
+	kv := i.iter.SeekGE(i.seekKey, base.SeekGEFlagsNone)
+	for kv != nil && i.cmp(kv.K.UserKey, i.seekKey) == 0 {
+		kv = i.iter.Next()
+	}
+	return i.processForward(kv)
+}
+
+// NextPrefix implements base.InternalIterator.
+func (i *Iter) NextPrefix(succKey []byte) *base.InternalKV {
+	if !i.buffered {
+		return i.processForward(i.iter.NextPrefix(succKey))
+	}
+	i.buffered = false
+	if i.bufDir < 0 {
+		return i.processForward(i.iter.SeekGE(succKey, base.SeekGEFlagsNone))
+	}
+	if i.pending == nil || i.cmp(i.pending.K.UserKey, succKey) >= 0 {
+		return i.processForward(i.pending)
+	}
+	return i.processForward(i.iter.NextPrefix(succKey))
+}
+
+// Prev implements base.InternalIterator.
+func (i *Iter) Prev() *base.InternalKV {
+	if !i.buffered {
+		return i.processBackward(i.iter.Prev())
+	}
+	i.bufIdx--
+	if i.bufIdx >= 0 {
+		return &i.buf[i.bufIdx]
+	}
+	i.buffered = false
+	if i.bufDir < 0 {
+		return i.processBackward(i.pending)
+	}
+	// The point iterator is positioned after the buffered user key. Reposition
+	// it before the buffered user key.
+	i.seekKey = append(i.seekKey[:0], i.key...)
+	return i.processBackward(i.iter.SeekLT(i.seekKey, base.SeekLTFlagsNone))
+}
+
+// Error implements base.InternalIterator.
+func (i *Iter) Error() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.iter.Error()
+}
+
+// Close implements base.InternalIterator.
+func (i *Iter) Close() error {
+	err := i.iter.Close()
+	i.spans.Close()
+	i.buffered = false
+	i.pending = nil
+	return err
+}
+
+// SetBounds implements base.InternalIterator.
+func (i *Iter) SetBounds(lower, upper []byte) {
+	i.buffered = false
+	i.pending = nil
+	i.iter.SetBounds(lower, upper)
+}
+
+// SetContext implements base.InternalIterator.
+func (i *Iter) SetContext(ctx context.Context) {
+	i.iter.SetContext(ctx)
+	i.spans.SetContext(ctx)
+}
+
+// DebugTree is part of the InternalIterator interface.
+func (i *Iter) DebugTree(tp treeprinter.Node) {
+	n := tp.Childf("%T(%p)", i, i)
+	i.iter.DebugTree(n)
+	i.spans.DebugTree(n)
+}
+
+// String implements fmt.Stringer.
+func (i *Iter) String() string {
+	return fmt.Sprintf("rangemerge(%s)", i.iter.String())
+}
+
+// resetForSeek discards any buffered state in preparation for an absolute
+// positioning operation, returning the seek flags to use for the point
+// iterator.
+func (i *Iter) resetForSeek(flags base.SeekGEFlags) base.SeekGEFlags {
+	if i.buffered {
+		// The point iterator's position does not correspond to this iterator's
+		// position.
+		flags = flags.DisableTrySeekUsingNext()
+	}
+	i.buffered = false
+	i.pending = nil
+	i.err = nil
+	return flags
+}
+
+// processForward returns the key to surface when the point iterator is
+// positioned at kv during forward iteration, buffering kv's user key if it's
+// covered by a range merge.
+func (i *Iter) processForward(kv *base.InternalKV) *base.InternalKV {
+	if kv == nil || !isPointKind(kv.Kind()) {
+		return kv
+	}
+	keys := i.covering(kv.K.UserKey)
+	if i.err != nil {
+		return nil
+	}
+	if len(keys) == 0 {
+		return kv
+	}
+	i.startBuffer(kv)
+	for {
+		if !i.appendRecord(kv) {
+			return nil
+		}
+		kv = i.iter.Next()
+		if kv == nil || !isPointKind(kv.Kind()) || i.cmp(kv.K.UserKey, i.key) != 0 {
+			break
+		}
+	}
+	i.pending = kv
+	i.finishBuffer(keys)
+	i.buffered = true
+	i.bufDir = +1
+	i.bufIdx = 0
+	return &i.buf[0]
+}
+
+// processBackward returns the key to surface when the point iterator is
+// positioned at kv during reverse iteration, buffering kv's user key if it's
+// covered by a range merge.
+func (i *Iter) processBackward(kv *base.InternalKV) *base.InternalKV {
+	if kv == nil || !isPointKind(kv.Kind()) {
+		return kv
+	}
+	keys := i.covering(kv.K.UserKey)
+	if i.err != nil {
+		return nil
+	}
+	if len(keys) == 0 {
+		return kv
+	}
+	i.startBuffer(kv)
+	for {
+		if !i.appendRecord(kv) {
+			return nil
+		}
+		kv = i.iter.Prev()
+		if kv == nil || !isPointKind(kv.Kind()) || i.cmp(kv.K.UserKey, i.key) != 0 {
+			break
+		}
+	}
+	i.pending = kv
+	// Records were appended in increasing trailer order.
+	slices.Reverse(i.buf)
+	i.finishBuffer(keys)
+	i.buffered = true
+	i.bufDir = -1
+	i.bufIdx = len(i.buf) - 1
+	return &i.buf[i.bufIdx]
+}
+
+// startBuffer resets the buffer to hold the records of kv's user key.
+func (i *Iter) startBuffer(kv *base.InternalKV) {
+	i.alloc = i.alloc.Reset()
+	i.alloc, i.key = i.alloc.Copy(kv.K.UserKey)
+	i.buf = i.buf[:0]
+	i.delSeqs = i.delSeqs[:0]
+	if i.rangeDelSpan != nil {
+		if s := i.rangeDelSpan(); s != nil {
+			for j := range s.Keys {
+				i.delSeqs = append(i.delSeqs, s.Keys[j].SeqNum())
+			}
+		}
+	}
+}
+
+// appendRecord appends a stable copy of kv to the buffer. It returns false if
+// the value could not be retrieved.
+func (i *Iter) appendRecord(kv *base.InternalKV) bool {
+	v, _, err := kv.Value(nil)
+	if err != nil {
+		i.err = err
+		return false
+	}
+	var vCopy []byte
+	i.alloc, vCopy = i.alloc.Copy(v)
+	i.buf = append(i.buf, base.InternalKV{
+		K: base.InternalKey{UserKey: i.key, Trailer: kv.K.Trailer},
+		V: base.MakeInPlaceValue(vCopy),
+	})
+	return true
+}
+
+// finishBuffer adds MERGE records for each of the provided range merge keys
+// that apply to the buffered records, and sorts the buffer by trailer
+// descending. The buffer must already be sorted by trailer descending.
+func (i *Iter) finishBuffer(keys []keyspan.Key) {
+	n := len(i.buf)
+	for j := range keys {
+		seq := keys[j].SeqNum()
+		if !i.applies(i.buf[:n], seq) {
+			continue
+		}
+		var operand []byte
+		i.alloc, operand = i.alloc.Copy(keys[j].Value)
+		i.buf = append(i.buf, base.InternalKV{
+			K: base.MakeInternalKey(i.key, seq, base.InternalKeyKindMerge),
+			V: base.MakeInPlaceValue(operand),
+		})
+	}
+	if len(i.buf) > n {
+		slices.SortFunc(i.buf, func(a, b base.InternalKV) int {
+			switch {
+			case a.K.Trailer > b.K.Trailer:
+				return -1
+			case a.K.Trailer < b.K.Trailer:
+				return +1
+			default:
+				return 0
+			}
+		})
+	}
+}
+
+// applies returns true if a range merge with the provided sequence number
+// applies to the user key with the provided records, sorted by trailer
+// descending.
+func (i *Iter) applies(records []base.InternalKV, seq base.SeqNum) bool {
+	for j := range records {
+		recSeq := records[j].SeqNum()
+		if recSeq >= seq {
+			continue
+		}
+		// records[j] is the newest record older than the range merge. The range
+		// merge applies if it's a live value and it was not deleted by a range
+		// deletion before the range merge was written.
+		for _, delSeq := range i.delSeqs {
+			if delSeq > recSeq && delSeq < seq {
+				return false
+			}
+		}
+		switch records[j].Kind() {
+		case base.InternalKeyKindSet, base.InternalKeyKindSetWithDelete, base.InternalKeyKindMerge:
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// covering returns the visible range merge keys covering the provided user
+// key.
+func (i *Iter) covering(key []byte) []keyspan.Key {
+	c := &i.cov
+	if c.valid && (c.startUnbounded || i.cmp(c.span.Start, key) <= 0) &&
+		(c.endUnbounded || i.cmp(key, c.span.End) < 0) {
+		return c.span.Keys
+	}
+	c.valid = false
+	s, err := i.spans.SeekGE(key)
+	if err != nil {
+		i.err = err
+		return nil
+	}
+	if s != nil && i.cmp(s.Start, key) <= 0 {
+		c.span.CopyFrom(s)
+		c.startUnbounded, c.endUnbounded = false, false
+		// Retain only the keys visible at the snapshot.
+		keys := c.span.Keys[:0]
+		for j := range c.span.Keys {
+			if c.span.Keys[j].VisibleAt(i.snapshot) {
+				keys = append(keys, c.span.Keys[j])
+			}
+		}
+		c.span.Keys = keys
+		c.valid = true
+		return c.span.Keys
+	}
+	// No range merge covers key. Cache the gap between the surrounding spans.
+	c.span.Keys = c.span.Keys[:0]
+	c.endUnbounded = s == nil
+	if s != nil {
+		c.span.End = append(c.span.End[:0], s.Start...)
+	}
+	s, err = i.spans.SeekLT(key)
+	if err != nil {
+		i.err = err
+		return nil
+	}
+	c.startUnbounded = s == nil
+	if s != nil {
+		c.span.Start = append(c.span.Start[:0], s.End...)
+	}
+	c.valid = true
+	return nil
+}
+
+// isPointKind returns true if the provided kind is the kind of a point key that
+// range merges may apply to, or that determine whether a range merge applies.
+func isPointKind(kind base.InternalKeyKind) bool {
+	switch kind {
+	case base.InternalKeyKindSet, base.InternalKeyKindSetWithDelete, base.InternalKeyKindMerge,
+		base.InternalKeyKindDelete, base.InternalKeyKindSingleDelete, base.InternalKeyKindDeleteSized:
+		return true
+	default:
+		return false
+	}
+}
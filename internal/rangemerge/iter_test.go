@@ -0,0 +1,115 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package rangemerge
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIter(t *testing.T) {
+	cmp := base.DefaultComparer.Compare
+	parseKVs := func(s string) []base.InternalKV {
+		var kvs []base.InternalKV
+		for _, f := range strings.Fields(s) {
+			k, v, _ := strings.Cut(f, "=")
+			kvs = append(kvs, base.InternalKV{
+				K: base.ParseInternalKey(k),
+				V: base.MakeInPlaceValue([]byte(v)),
+			})
+		}
+		return kvs
+	}
+	format := func(kv *base.InternalKV) string {
+		v, _, err := kv.Value(nil)
+		require.NoError(t, err)
+		return fmt.Sprintf("%s=%s", kv.K, v)
+	}
+
+	testCases := []struct {
+		points    string
+		spans     []string
+		rangeDels []string
+		snapshot  base.SeqNum
+		want      string
+	}{
+		{
+			// The range merge applies to the live keys within its bounds only.
+			points: "a#3,SET=a b#5,SET=b b#2,SET=b0 c#3,DEL= c#1,SET=c0 d#7,SET=d e#1,SET=e",
+			spans:  []string{"a-e:{(#6,RANGEMERGE,x)}"},
+			want:   "a#6,MERGE=x a#3,SET=a b#6,MERGE=x b#5,SET=b b#2,SET=b0 c#3,DEL= c#1,SET=c0 d#7,SET=d e#1,SET=e",
+		},
+		{
+			// Range merges stack, and are only visible at their snapshot.
+			points:   "a#1,SET=a b#1,MERGE=b",
+			spans:    []string{"a-c:{(#9,RANGEMERGE,z) (#4,RANGEMERGE,y) (#3,RANGEMERGE,x)}"},
+			snapshot: 5,
+			want:     "a#4,MERGE=y a#3,MERGE=x a#1,SET=a b#4,MERGE=y b#3,MERGE=x b#1,MERGE=b",
+		},
+		{
+			// A range deletion between the key and the range merge prevents it
+			// from applying.
+			points:    "a#1,SET=a b#4,SET=b",
+			spans:     []string{"a-c:{(#5,RANGEMERGE,x)}"},
+			rangeDels: []string{"a-c:{(#3,RANGEDEL)}"},
+			want:      "a#1,SET=a b#5,MERGE=x b#4,SET=b",
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			snapshot := tc.snapshot
+			if snapshot == 0 {
+				snapshot = base.SeqNumMax
+			}
+			var spans []keyspan.Span
+			for _, s := range tc.spans {
+				span := keyspan.ParseSpan(s)
+				// ParseSpan parses the third field of a key as a suffix. Range
+				// merges have no suffix, so use it as the operand.
+				for j := range span.Keys {
+					span.Keys[j].Value, span.Keys[j].Suffix = span.Keys[j].Suffix, nil
+				}
+				spans = append(spans, span)
+			}
+			var rangeDelSpan func() *keyspan.Span
+			if len(tc.rangeDels) > 0 {
+				// The range deletions in these tests cover every point key.
+				s := keyspan.ParseSpan(tc.rangeDels[0])
+				rangeDelSpan = func() *keyspan.Span { return &s }
+			}
+			iter := NewIter(cmp, base.NewFakeIter(parseKVs(tc.points)), keyspan.NewIter(cmp, spans), snapshot, rangeDelSpan)
+			defer iter.Close()
+
+			var fwd, rev []string
+			for kv := iter.First(); kv != nil; kv = iter.Next() {
+				fwd = append(fwd, format(kv))
+			}
+			for kv := iter.Last(); kv != nil; kv = iter.Prev() {
+				rev = append([]string{format(kv)}, rev...)
+			}
+			require.NoError(t, iter.Error())
+			require.Equal(t, tc.want, strings.Join(fwd, " "))
+			require.Equal(t, tc.want, strings.Join(rev, " "))
+
+			// Seeking and then changing direction surfaces the neighbouring
+			// records.
+			idx := 0
+			for idx < len(fwd) && fwd[idx] < "b" {
+				idx++
+			}
+			kv := iter.SeekGE([]byte("b"), base.SeekGEFlagsNone)
+			require.Equal(t, fwd[idx], format(kv))
+			require.Equal(t, fwd[idx+1], format(iter.Next()))
+			require.Equal(t, fwd[idx], format(iter.Prev()))
+			require.Equal(t, fwd[idx-1], format(iter.Prev()))
+			require.Equal(t, fwd[idx], format(iter.Next()))
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+// Package rangemerge provides facilities for encoding, decoding and applying
+// range merges.
+//
+// A range merge applies a merge operand to every point key within a span of
+// user keys `[start, end)` that exists at the time the range merge is written.
+// Range merges are stored as keyspan fragments, much like range deletions, and
+// are applied lazily: readers synthesize a MERGE record carrying the operand
+// for every covered key, and compactions fold the operand into the covered
+// keys using the configured Merger once no older data for the span may exist
+// beneath the compaction's output level.
+//
+// # Semantics
+//
+// A range merge with sequence number r applies to a user key k if and only if
+// the newest record for k with a sequence number less than r is a SET,
+// SETWITHDEL or MERGE, and no range deletion with a sequence number less than r
+// but greater than that record's covers k. In other words, a range merge never
+// creates keys; it only updates the keys that are live when it's written.
+//
+// # Encoding
+//
+// A `RANGEMERGE` key's user key holds the start key. Its value is a varstring
+// end key, followed by the merge operand. Each internal key encodes exactly one
+// operand.
+package rangemerge
+
+import (
+	"encoding/binary"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/invariants"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+)
+
+// Encode takes a Span containing only range merges. It invokes the provided
+// closure with the encoded internal keys that represent the Span's state. The
+// keys and values passed to emit are only valid until the closure returns. If
+// emit returns an error, Encode stops and returns the error.
+func Encode(s keyspan.Span, emit func(k base.InternalKey, v []byte) error) error {
+	var buf []byte
+	for _, k := range s.Keys {
+		if k.Kind() != base.InternalKeyKindRangeMerge {
+			return base.CorruptionErrorf("pebble: rangemerge.Encode cannot encode %s key", k.Kind())
+		}
+		ik := base.InternalKey{
+			UserKey: s.Start,
+			Trailer: k.Trailer,
+		}
+		buf = EncodeValue(buf[:0], s.End, k.Value)
+		if err := emit(ik, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodedValueLen returns the length of the value encoding a range merge over
+// a span with the provided end key and merge operand.
+func EncodedValueLen(end, operand []byte) int {
+	return lenVarint(len(end)) + len(end) + len(operand)
+}
+
+// EncodeValue appends the encoding of a range merge value with the provided
+// end key and merge operand to dst.
+func EncodeValue(dst, end, operand []byte) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(end)))
+	dst = append(dst, end...)
+	return append(dst, operand...)
+}
+
+// DecodeValue decodes the end key and merge operand from a range merge's value.
+func DecodeValue(v []byte) (end, operand []byte, _ error) {
+	l, n := binary.Uvarint(v)
+	if n <= 0 || uint64(len(v)-n) < l {
+		return nil, nil, base.CorruptionErrorf("pebble: unable to decode range merge end key")
+	}
+	return v[n : n+int(l)], v[n+int(l):], nil
+}
+
+// Decode takes an internal key pair encoding a range merge and returns a
+// decoded keyspan containing the key. If keysDst is provided, the key will be
+// appended to keysDst, avoiding an allocation.
+func Decode(ik base.InternalKey, v []byte, keysDst []keyspan.Key) (keyspan.Span, error) {
+	end, operand, err := DecodeValue(v)
+	if err != nil {
+		return keyspan.Span{}, err
+	}
+	return keyspan.Span{
+		Start: ik.UserKey,
+		End:   end,
+		Keys: append(keysDst, keyspan.Key{
+			Trailer: ik.Trailer,
+			Value:   operand,
+		}),
+	}, nil
+}
+
+// DecodeIntoSpan decodes an internal key pair encoding a range merge and
+// appends a key to the given span. The start and end keys must match those in
+// the span.
+func DecodeIntoSpan(cmp base.Compare, ik base.InternalKey, v []byte, s *keyspan.Span) error {
+	// This function should only be called when ik.UserKey matches the Start of
+	// the span we already have. If this is not the case, it is a bug in the
+	// calling code.
+	if invariants.Enabled && cmp(s.Start, ik.UserKey) != 0 {
+		return base.AssertionFailedf("DecodeIntoSpan called with different start key")
+	}
+	end, operand, err := DecodeValue(v)
+	if err != nil {
+		return err
+	}
+	// The value can come from disk or from the user, so we want to check the end
+	// key in all builds.
+	if cmp(s.End, end) != 0 {
+		return base.CorruptionErrorf("pebble: corrupt range merge fragmentation")
+	}
+	s.Keys = append(s.Keys, keyspan.Key{Trailer: ik.Trailer, Value: operand})
+	return nil
+}
+
+func lenVarint(v int) (n int) {
+	x := uint32(v)
+	n++
+	for x >= 0x80 {
+		x >>= 7
+		n++
+	}
+	return n
+}
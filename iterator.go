@@ -238,8 +238,21 @@ type Iterator struct {
 	getIterAlloc        *getIterAlloc
 	prefixOrFullSeekKey []byte
 	readSampling        readSampling
-	stats               IteratorStats
-	externalReaders     [][]*sstable.Reader
+	// mergeOperands buffers the older operands of a merge for mergers that
+	// implement ManyValueMerger.
+	mergeOperands base.MergeOperandBuffer
+	// mergeOperandLimit is Options.Experimental.MergeOperandLimit if the
+	// Iterator reads from a DB without a batch, and zero otherwise. See
+	// maybeWriteMergedValue.
+	mergeOperandLimit int
+	stats             IteratorStats
+	externalReaders   [][]*sstable.Reader
+
+	// tableCache is used to prefetch sstables. It's nil if the Iterator
+	// doesn't read sstables through the DB's table cache.
+	tableCache *tableCacheContainer
+	// prefetcher prefetches sstables when IterOptions.Prefetch is enabled.
+	prefetcher *tablePrefetcher
 
 	// Following fields used when constructing an iterator stack, eg, in Clone
 	// and SetOptions or when re-fragmenting a batch's range keys/range dels.
@@ -602,7 +615,11 @@ func (i *Iterator) findNextEntry(limit []byte) {
 		case InternalKeyKindSet, InternalKeyKindSetWithDelete:
 			i.keyBuf = append(i.keyBuf[:0], key.UserKey...)
 			i.key = i.keyBuf
-			i.value = i.iterKV.V
+			if i.opts.KeysOnly {
+				i.value = LazyValue{}
+			} else {
+				i.value = i.iterKV.V
+			}
 			i.iterValidityState = IterValid
 			i.saveRangeKey()
 			return
@@ -686,7 +703,11 @@ func (i *Iterator) nextPointCurrentUserKey() bool {
 		return false
 
 	case InternalKeyKindSet, InternalKeyKindSetWithDelete:
-		i.value = i.iterKV.V
+		if i.opts.KeysOnly {
+			i.value = LazyValue{}
+		} else {
+			i.value = i.iterKV.V
+		}
 		return true
 
 	case InternalKeyKindMerge:
@@ -706,6 +727,21 @@ func (i *Iterator) nextPointCurrentUserKey() bool {
 //
 // mergeForward does not update iterValidityState.
 func (i *Iterator) mergeForward(key base.InternalKey) (valid bool) {
+	if i.opts.KeysOnly {
+		// Merging requires the values of the operands, which aren't retrieved
+		// in KeysOnly mode. Surface the key without invoking the merger, and
+		// skip its remaining records.
+		i.keyBuf = append(i.keyBuf[:0], key.UserKey...)
+		i.key = i.keyBuf
+		i.value = LazyValue{}
+		i.nextUserKey()
+		if i.err != nil {
+			return false
+		}
+		i.pos = iterPosNext
+		return true
+	}
+
 	var iterValue []byte
 	iterValue, _, i.err = i.iterKV.Value(nil)
 	if i.err != nil {
@@ -734,9 +770,29 @@ func (i *Iterator) mergeForward(key base.InternalKey) (valid bool) {
 		_ = i.closeValueCloser()
 		return false
 	}
+	// The first operand of the key isn't counted by i.mergeOperands.
+	i.maybeWriteMergedValue(value, i.mergeOperands.Count())
 	return true
 }
 
+// maybeWriteMergedValue is called after mergeForward or findPrevEntry merges
+// the records of i.key into value, where merged is the number of records that
+// were merged into the first. If the key had more than mergeOperandLimit
+// operands, it asynchronously writes value back to the DB, so that later reads
+// of the key don't need to merge its operands again. Iterator options that hide
+// some of the key's records disable writing back, since the merged value may
+// be incomplete.
+func (i *Iterator) maybeWriteMergedValue(value []byte, merged int) {
+	if i.mergeOperandLimit <= 0 || merged < i.mergeOperandLimit {
+		return
+	}
+	if i.opts.PointKeyFilters != nil || i.opts.SkipPoint != nil ||
+		i.opts.OnlyReadGuaranteedDurable || i.opts.RangeKeyMasking.Suffix != nil {
+		return
+	}
+	i.readState.db.maybeWriteMergedValue(i.key, value, i.seqNum)
+}
+
 func (i *Iterator) closeValueCloser() error {
 	if i.valueCloser != nil {
 		i.err = i.valueCloser.Close()
@@ -827,6 +883,9 @@ func (i *Iterator) maybeSampleRead() {
 }
 
 func (i *Iterator) sampleRead() {
+	if s := i.readState.db.keyRangeStats; s != nil {
+		s.recordRead(i.key)
+	}
 	var topFile *manifest.FileMetadata
 	topLevel, numOverlappingLevels := numLevels, 0
 	mi := i.merging
@@ -910,6 +969,8 @@ func (i *Iterator) findPrevEntry(limit []byte) {
 	}
 
 	var valueMerger ValueMerger
+	// merged counts the records merged into valueMerger after the first.
+	var merged int
 	firstLoopIter := true
 	rangeKeyBoundary := false
 	// The code below compares with limit in multiple places. As documented in
@@ -940,6 +1001,9 @@ func (i *Iterator) findPrevEntry(limit []byte) {
 					var value []byte
 					value, needDelete, i.valueCloser, i.err = finishValueMerger(valueMerger, true /* includesBase */)
 					i.value = base.MakeInPlaceValue(value)
+					if i.err == nil && !needDelete {
+						i.maybeWriteMergedValue(value, merged)
+					}
 					if i.err == nil && needDelete {
 						// The point key at this key is deleted. If we also have
 						// a range key boundary at this key, we still want to
@@ -1047,7 +1111,11 @@ func (i *Iterator) findPrevEntry(limit []byte) {
 			// call, so use valueBuf instead. Note that valueBuf is only used
 			// in this one instance; everywhere else (eg. in findNextEntry),
 			// we just point i.value to the unsafe i.iter-owned value buffer.
-			i.value, i.valueBuf = i.iterKV.V.Clone(i.valueBuf[:0], &i.fetcher)
+			if i.opts.KeysOnly {
+				i.value = LazyValue{}
+			} else {
+				i.value, i.valueBuf = i.iterKV.V.Clone(i.valueBuf[:0], &i.fetcher)
+			}
 			i.saveRangeKey()
 			i.iterValidityState = IterValid
 			i.iterKV = i.iter.Prev()
@@ -1056,6 +1124,19 @@ func (i *Iterator) findPrevEntry(limit []byte) {
 			continue
 
 		case InternalKeyKindMerge:
+			if i.opts.KeysOnly {
+				// As in mergeForward, surface the key without invoking the
+				// merger, treating the operand like a SET.
+				i.keyBuf = append(i.keyBuf[:0], key.UserKey...)
+				i.key = i.keyBuf
+				i.value = LazyValue{}
+				i.saveRangeKey()
+				i.iterValidityState = IterValid
+				i.iterKV = i.iter.Prev()
+				i.stats.ReverseStepCount[InternalIterCall]++
+				valueMerger = nil
+				continue
+			}
 			if i.iterValidityState == IterExhausted {
 				i.keyBuf = append(i.keyBuf[:0], key.UserKey...)
 				i.key = i.keyBuf
@@ -1069,6 +1150,7 @@ func (i *Iterator) findPrevEntry(limit []byte) {
 				if i.err != nil {
 					return
 				}
+				merged = 0
 				i.iterValidityState = IterValid
 			} else if valueMerger == nil {
 				// Extract value before iterValue since we use value before iterValue
@@ -1098,6 +1180,7 @@ func (i *Iterator) findPrevEntry(limit []byte) {
 					i.iterValidityState = IterExhausted
 					return
 				}
+				merged = 1
 			} else {
 				var iterValue []byte
 				iterValue, _, i.err = i.iterKV.Value(nil)
@@ -1110,6 +1193,7 @@ func (i *Iterator) findPrevEntry(limit []byte) {
 					i.iterValidityState = IterExhausted
 					return
 				}
+				merged++
 			}
 			i.iterKV = i.iter.Prev()
 			i.stats.ReverseStepCount[InternalIterCall]++
@@ -1136,6 +1220,9 @@ func (i *Iterator) findPrevEntry(limit []byte) {
 			var value []byte
 			value, needDelete, i.valueCloser, i.err = finishValueMerger(valueMerger, true /* includesBase */)
 			i.value = base.MakeInPlaceValue(value)
+			if i.err == nil && !needDelete {
+				i.maybeWriteMergedValue(value, merged)
+			}
 			if i.err == nil && needDelete {
 				i.key = nil
 				i.value = LazyValue{}
@@ -1175,6 +1262,16 @@ func (i *Iterator) prevUserKey() {
 }
 
 func (i *Iterator) mergeNext(key InternalKey, valueMerger ValueMerger) {
+	i.mergeOperands.Reset(valueMerger)
+	i.mergeOlderOperands(key)
+	if i.err == nil {
+		i.err = i.mergeOperands.Flush()
+	}
+}
+
+// mergeOlderOperands adds the older operands of the merge of key to
+// i.mergeOperands, advancing the underlying iterator to the next key.
+func (i *Iterator) mergeOlderOperands(key InternalKey) {
 	// Save the current key.
 	i.keyBuf = append(i.keyBuf[:0], key.UserKey...)
 	i.key = i.keyBuf
@@ -1213,7 +1310,7 @@ func (i *Iterator) mergeNext(key InternalKey, valueMerger ValueMerger) {
 			if i.err != nil {
 				return
 			}
-			i.err = valueMerger.MergeOlder(iterValue)
+			i.err = i.mergeOperands.MergeOlder(iterValue)
 			return
 
 		case InternalKeyKindMerge:
@@ -1224,7 +1321,7 @@ func (i *Iterator) mergeNext(key InternalKey, valueMerger ValueMerger) {
 			if i.err != nil {
 				return
 			}
-			i.err = valueMerger.MergeOlder(iterValue)
+			i.err = i.mergeOperands.MergeOlder(iterValue)
 			if i.err != nil {
 				return
 			}
@@ -2357,6 +2454,10 @@ func (i *Iterator) Close() error {
 			i.rangeKey.rangeKeyIter.Close()
 		}
 	}
+	if i.prefetcher != nil {
+		i.prefetcher.close()
+		i.prefetcher = nil
+	}
 	err := i.err
 
 	if i.readState != nil {
@@ -2591,7 +2692,7 @@ func (i *Iterator) SetOptions(o *IterOptions) {
 	// reconstruct it.
 	if i.pointIter != nil && (closeBoth || len(o.PointKeyFilters) > 0 || len(i.opts.PointKeyFilters) > 0 ||
 		o.RangeKeyMasking.Filter != nil || i.opts.RangeKeyMasking.Filter != nil || o.SkipPoint != nil ||
-		i.opts.SkipPoint != nil) {
+		i.opts.SkipPoint != nil || o.Prefetch != i.opts.Prefetch || o.KeysOnly != i.opts.KeysOnly) {
 		i.err = firstError(i.err, i.pointIter.Close())
 		i.pointIter = nil
 	}
@@ -2865,8 +2966,10 @@ func (i *Iterator) CloneWithContext(ctx context.Context, opts CloneOptions) (*It
 		batch:               i.batch,
 		batchSeqNum:         i.batchSeqNum,
 		newIters:            i.newIters,
+		tableCache:          i.tableCache,
 		newIterRangeKey:     i.newIterRangeKey,
 		seqNum:              i.seqNum,
+		mergeOperandLimit:   i.mergeOperandLimit,
 	}
 	dbi.processBounds(dbi.opts.LowerBound, dbi.opts.UpperBound)
 
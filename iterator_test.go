@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"io"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -1190,6 +1191,139 @@ func TestIteratorGuaranteedDurable(t *testing.T) {
 	})
 }
 
+func TestIteratorKeysOnly(t *testing.T) {
+	opts := &Options{
+		FS:                 vfs.NewMem(),
+		Comparer:           testkeys.Comparer,
+		FormatMajorVersion: FormatNewest,
+	}
+	opts.DisableAutomaticCompactions = true
+	opts.Experimental.EnableValueBlocks = func() bool { return true }
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Write several versions of each key to an sstable, storing the older
+	// versions in value blocks, and a few more keys to the memtable.
+	var want []string
+	for _, k := range []string{"a", "b", "c"} {
+		for ts := 3; ts >= 1; ts-- {
+			key := fmt.Sprintf("%s@%d", k, ts)
+			require.NoError(t, d.Set([]byte(key), []byte("value-"+key), nil))
+			want = append(want, key)
+		}
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("d@1"), []byte("value-d@1"), nil))
+	require.NoError(t, d.Merge([]byte("e@1"), []byte("value-e@1"), nil))
+	require.NoError(t, d.Delete([]byte("b@2"), nil))
+	want = append(want[:4], append(want[5:], "d@1", "e@1")...)
+
+	// scan returns the keys surfaced by the iterator, checking that their
+	// values are empty.
+	scan := func(iter *Iterator, reverse bool) []string {
+		var keys []string
+		valid := iter.First()
+		if reverse {
+			valid = iter.Last()
+		}
+		for valid {
+			keys = append(keys, string(iter.Key()))
+			v, err := iter.ValueAndErr()
+			require.NoError(t, err)
+			require.Empty(t, v, iter.Key())
+			if reverse {
+				valid = iter.Prev()
+			} else {
+				valid = iter.Next()
+			}
+		}
+		require.NoError(t, iter.Error())
+		if reverse {
+			slices.Reverse(keys)
+		}
+		return keys
+	}
+	for _, reverse := range []bool{false, true} {
+		iter, err := d.NewIter(&IterOptions{KeysOnly: true})
+		require.NoError(t, err)
+		require.Equal(t, want, scan(iter, reverse))
+		// None of the values in value blocks were retrieved, and the sstable
+		// iterators didn't decode the value handles of the older versions.
+		require.Zero(t, iter.Stats().InternalStats.SeparatedPointValue.ValueBytesFetched)
+		require.Zero(t, iter.Stats().InternalStats.SeparatedPointValue.Count)
+		require.NoError(t, iter.Close())
+	}
+
+	// Values are retrieved once KeysOnly is disabled.
+	iter, err := d.NewIter(&IterOptions{KeysOnly: true})
+	require.NoError(t, err)
+	require.True(t, iter.SeekGE([]byte("a@2")))
+	require.Empty(t, iter.Value())
+	iter.SetOptions(&IterOptions{})
+	require.True(t, iter.SeekGE([]byte("a@2")))
+	require.Equal(t, []byte("value-a@2"), iter.Value())
+	require.Greater(t, iter.Stats().InternalStats.SeparatedPointValue.ValueBytesFetched, uint64(0))
+	require.Greater(t, iter.Stats().InternalStats.SeparatedPointValue.Count, uint64(0))
+	require.NoError(t, iter.Close())
+}
+
+func TestIteratorKeysOnlyMerge(t *testing.T) {
+	opts := &Options{
+		FS:     vfs.NewMem(),
+		Merger: CounterMerger,
+	}
+	opts.DisableAutomaticCompactions = true
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Merge into counters in both an sstable and the memtable. KeysOnly
+	// surfaces the operands in the sstable with empty values, which the
+	// CounterMerger would reject.
+	keys := []string{"a", "b", "c", "d"}
+	for _, k := range keys {
+		require.NoError(t, d.Merge([]byte(k), EncodeCounter(1), nil))
+	}
+	require.NoError(t, d.Set([]byte("b"), EncodeCounter(5), nil))
+	require.NoError(t, d.Merge([]byte("b"), EncodeCounter(1), nil))
+	require.NoError(t, d.Flush())
+	for _, k := range keys[1:] {
+		require.NoError(t, d.Merge([]byte(k), EncodeCounter(1), nil))
+	}
+	require.NoError(t, d.Delete([]byte("c"), nil))
+	want := []string{"a", "b", "d"}
+
+	iter, err := d.NewIter(&IterOptions{KeysOnly: true})
+	require.NoError(t, err)
+	var got []string
+	for valid := iter.First(); valid; valid = iter.Next() {
+		got = append(got, string(iter.Key()))
+		require.Empty(t, iter.Value())
+	}
+	require.NoError(t, iter.Error())
+	require.Equal(t, want, got)
+
+	got = got[:0]
+	for valid := iter.Last(); valid; valid = iter.Prev() {
+		got = append(got, string(iter.Key()))
+		require.Empty(t, iter.Value())
+	}
+	require.NoError(t, iter.Error())
+	slices.Reverse(got)
+	require.Equal(t, want, got)
+	require.NoError(t, iter.Close())
+
+	// The values are merged once KeysOnly is disabled.
+	iter, err = d.NewIter(nil)
+	require.NoError(t, err)
+	require.True(t, iter.SeekGE([]byte("b")))
+	n, err := DecodeCounter(iter.Value())
+	require.NoError(t, err)
+	require.Equal(t, int64(7), n)
+	require.NoError(t, iter.Close())
+}
+
 func TestIteratorBoundsLifetimes(t *testing.T) {
 	rng := rand.New(rand.NewSource(uint64(time.Now().UnixNano())))
 	d := newPointTestkeysDatabase(t, testkeys.Alpha(2))
@@ -0,0 +1,420 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/fastrand"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// writeBytesPeriod is the average number of bytes written between two write
+// samples recorded by the keyRangeSampler. It mirrors the default read
+// sampling period (readBytesPeriod * the default ReadSamplingMultiplier).
+const writeBytesPeriod uint64 = 1 << 20
+
+// keyRangeStatsFilename is the name of the file in the DB directory to which
+// the key range samples are saved when the DB is closed, so that they survive
+// restarts. keyRangeStatsVersion identifies its encoding.
+const (
+	keyRangeStatsFilename = "KEY-RANGE-STATS"
+	keyRangeStatsVersion  = 1
+)
+
+// errKeyRangeSamplingDisabled is returned by DB.KeyRangeActivity when
+// Options.Experimental.KeyRangeSampleSize is zero.
+var errKeyRangeSamplingDisabled = errors.New("pebble: key range sampling is disabled")
+
+// KeyRangeActivity describes the sampled read and write activity within the
+// key range [Start, End). A nil Start or End indicates that the range is
+// unbounded on that side.
+type KeyRangeActivity struct {
+	Start []byte
+	End   []byte
+	// ReadSamples and WriteSamples are the number of retained read and write
+	// samples that fall within the key range.
+	ReadSamples  int
+	WriteSamples int
+	// ReadBytes and WriteBytes are the estimated number of bytes read from and
+	// written to the key range since sampling began (or was last reset).
+	ReadBytes  uint64
+	WriteBytes uint64
+	// Hot is set if the bytes read from and written to the key range exceed
+	// the mean over all of the returned key ranges by more than
+	// KeyRangeActivityOptions.HotFactor.
+	Hot bool
+}
+
+// KeyRangeActivityOptions configures DB.KeyRangeActivity.
+type KeyRangeActivityOptions struct {
+	// LowerBound and UpperBound restrict the histogram to the key range
+	// [LowerBound, UpperBound). Either may be nil to leave that side
+	// unbounded.
+	LowerBound []byte
+	UpperBound []byte
+	// Buckets is the maximum number of key ranges returned. Bucket boundaries
+	// are chosen so that each bucket holds roughly the same number of samples,
+	// so fewer buckets may be returned if there are too few distinct sampled
+	// keys. Values less than 1 are treated as 1.
+	Buckets int
+	// HotFactor is the factor by which the activity within a key range must
+	// exceed the mean activity of the returned key ranges for the key range to
+	// be marked as hot. Since buckets hold roughly the same number of samples,
+	// a bucket is only hot if many samples share the same keys, such as when a
+	// few keys are read or written far more often than others. Values less
+	// than or equal to 1 are treated as the default of 2.
+	HotFactor float64
+}
+
+// KeyRangeActivity returns a histogram of the sampled read and write activity
+// over the keyspace. Reads are sampled by Iterator in proportion to the bytes
+// returned (see Options.Experimental.ReadSamplingMultiplier) and writes are
+// sampled in proportion to the bytes of committed batches. The returned key
+// ranges are ordered, disjoint and together span [LowerBound, UpperBound).
+//
+// Sampling is only performed if Options.Experimental.KeyRangeSampleSize is
+// positive. The samples are held in memory, and are saved to the DB directory
+// when the DB is closed and loaded again when it's opened, so the histogram
+// reflects the activity since ResetKeyRangeActivity was last called across
+// restarts. Samples taken since the last close are lost if the process
+// crashes.
+func (d *DB) KeyRangeActivity(opts KeyRangeActivityOptions) ([]KeyRangeActivity, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.keyRangeStats == nil {
+		return nil, errKeyRangeSamplingDisabled
+	}
+	return d.keyRangeStats.histogram(opts), nil
+}
+
+// ResetKeyRangeActivity discards all read and write samples accumulated for
+// DB.KeyRangeActivity.
+func (d *DB) ResetKeyRangeActivity() {
+	if d.keyRangeStats != nil {
+		d.keyRangeStats.reset()
+	}
+}
+
+// keySampleReservoir holds a uniformly random subset of the keys offered to
+// it, using reservoir sampling.
+type keySampleReservoir struct {
+	keys [][]byte
+	// seen is the total number of keys offered to the reservoir.
+	seen uint64
+}
+
+func (r *keySampleReservoir) add(key []byte, capacity int) {
+	r.seen++
+	if len(r.keys) < capacity {
+		r.keys = append(r.keys, slices.Clone(key))
+		return
+	}
+	if j := rand.Int63n(int64(r.seen)); j < int64(capacity) {
+		r.keys[j] = append(r.keys[j][:0], key...)
+	}
+}
+
+// estimate returns the estimated total weight of n of the retained samples,
+// given that each offered sample represents weight bytes.
+func (r *keySampleReservoir) estimate(n int, weight uint64) uint64 {
+	if len(r.keys) == 0 {
+		return 0
+	}
+	return uint64(float64(n) / float64(len(r.keys)) * float64(r.seen) * float64(weight))
+}
+
+// encode appends the reservoir to buf, given that each offered sample
+// represents weight bytes.
+func (r *keySampleReservoir) encode(buf []byte, weight uint64) []byte {
+	buf = binary.AppendUvarint(buf, weight)
+	buf = binary.AppendUvarint(buf, r.seen)
+	buf = binary.AppendUvarint(buf, uint64(len(r.keys)))
+	for _, k := range r.keys {
+		buf = binary.AppendUvarint(buf, uint64(len(k)))
+		buf = append(buf, k...)
+	}
+	return buf
+}
+
+// decode decodes a reservoir encoded by encode from buf, returning the rest of
+// buf. The number of offered samples is scaled to the provided weight, and
+// samples beyond capacity are dropped.
+func (r *keySampleReservoir) decode(buf []byte, weight uint64, capacity int) ([]byte, error) {
+	errCorrupt := base.CorruptionErrorf("pebble: corrupt key range stats")
+	uvarint := func() (uint64, bool) {
+		v, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return 0, false
+		}
+		buf = buf[n:]
+		return v, true
+	}
+	savedWeight, ok1 := uvarint()
+	seen, ok2 := uvarint()
+	n, ok3 := uvarint()
+	if !ok1 || !ok2 || !ok3 || n > seen {
+		return nil, errCorrupt
+	}
+	*r = keySampleReservoir{seen: seen}
+	if savedWeight != weight && savedWeight != 0 && weight != 0 {
+		r.seen = uint64(float64(seen) * float64(savedWeight) / float64(weight))
+	}
+	for i := uint64(0); i < n; i++ {
+		l, ok := uvarint()
+		if !ok || l > uint64(len(buf)) {
+			return nil, errCorrupt
+		}
+		if len(r.keys) < capacity {
+			r.keys = append(r.keys, slices.Clone(buf[:l]))
+		}
+		buf = buf[l:]
+	}
+	r.seen = max(r.seen, uint64(len(r.keys)))
+	return buf, nil
+}
+
+// keyRangeSampler records sampled user keys from reads and writes and
+// aggregates them into a histogram over the keyspace.
+type keyRangeSampler struct {
+	cmp      Compare
+	capacity int
+	// readWeight and writeWeight are the number of bytes each read and write
+	// sample represents.
+	readWeight  uint64
+	writeWeight uint64
+
+	mu struct {
+		sync.Mutex
+		reads  keySampleReservoir
+		writes keySampleReservoir
+	}
+}
+
+func newKeyRangeSampler(opts *Options) *keyRangeSampler {
+	if opts.Experimental.KeyRangeSampleSize <= 0 {
+		return nil
+	}
+	s := &keyRangeSampler{
+		cmp:         opts.Comparer.Compare,
+		capacity:    opts.Experimental.KeyRangeSampleSize,
+		writeWeight: writeBytesPeriod,
+	}
+	if m := opts.Experimental.ReadSamplingMultiplier; m > 0 {
+		s.readWeight = readBytesPeriod * uint64(m)
+	}
+	return s
+}
+
+// recordRead records a sampled read of the provided user key. It is called by
+// Iterator.sampleRead.
+func (s *keyRangeSampler) recordRead(key []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.reads.add(key, s.capacity)
+}
+
+// sampleBatch samples the keys written by b. On average one key is sampled
+// for every writeBytesPeriod bytes of batch data.
+func (s *keyRangeSampler) sampleBatch(b *Batch) {
+	count := int(b.Count())
+	n := len(b.data) / int(s.writeWeight)
+	if rem := uint32(len(b.data) % int(s.writeWeight)); rem > 0 && fastrand.Uint32n(uint32(s.writeWeight)) < rem {
+		n++
+	}
+	if n == 0 || count == 0 {
+		return
+	}
+	// Pick n keys (with replacement) uniformly at random and read them in a
+	// single pass over the batch.
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = int(fastrand.Uint32n(uint32(count)))
+	}
+	slices.Sort(indexes)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := b.Reader()
+	for i := 0; len(indexes) > 0; {
+		kind, ukey, _, ok, err := r.Next()
+		if !ok || err != nil {
+			return
+		}
+		if kind == InternalKeyKindLogData {
+			// LogData records aren't counted by the batch, and don't write to
+			// the keyspace.
+			continue
+		}
+		for len(indexes) > 0 && indexes[0] == i {
+			s.mu.writes.add(ukey, s.capacity)
+			indexes = indexes[1:]
+		}
+		i++
+	}
+}
+
+// save writes the samples to the stats file in dirname, replacing the file
+// atomically.
+func (s *keyRangeSampler) save(fs vfs.FS, dirname string, dir vfs.File) error {
+	s.mu.Lock()
+	buf := []byte{keyRangeStatsVersion}
+	buf = s.mu.reads.encode(buf, s.readWeight)
+	buf = s.mu.writes.encode(buf, s.writeWeight)
+	s.mu.Unlock()
+
+	path := fs.PathJoin(dirname, keyRangeStatsFilename)
+	tmpPath := path + ".tmp"
+	f, err := fs.Create(tmpPath, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		return errors.CombineErrors(err, f.Close())
+	}
+	if err := f.Sync(); err != nil {
+		return errors.CombineErrors(err, f.Close())
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := fs.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return dir.Sync()
+}
+
+// load reads the samples saved by save, if any, in place of the current
+// samples.
+func (s *keyRangeSampler) load(fs vfs.FS, dirname string) error {
+	f, err := fs.Open(fs.PathJoin(dirname, keyRangeStatsFilename))
+	if oserror.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	buf, err := io.ReadAll(f)
+	err = errors.CombineErrors(err, f.Close())
+	if err != nil {
+		return err
+	}
+	if len(buf) == 0 || buf[0] != keyRangeStatsVersion {
+		return base.CorruptionErrorf("pebble: unknown key range stats version")
+	}
+	buf = buf[1:]
+	var reads, writes keySampleReservoir
+	if buf, err = reads.decode(buf, s.readWeight, s.capacity); err != nil {
+		return err
+	}
+	if _, err = writes.decode(buf, s.writeWeight, s.capacity); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.reads, s.mu.writes = reads, writes
+	return nil
+}
+
+func (s *keyRangeSampler) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.reads = keySampleReservoir{}
+	s.mu.writes = keySampleReservoir{}
+}
+
+func (s *keyRangeSampler) histogram(opts KeyRangeActivityOptions) []KeyRangeActivity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inBounds := func(key []byte) bool {
+		return (opts.LowerBound == nil || s.cmp(key, opts.LowerBound) >= 0) &&
+			(opts.UpperBound == nil || s.cmp(key, opts.UpperBound) < 0)
+	}
+	filter := func(keys [][]byte) [][]byte {
+		var res [][]byte
+		for _, k := range keys {
+			if inBounds(k) {
+				res = append(res, k)
+			}
+		}
+		slices.SortFunc(res, s.cmp)
+		return res
+	}
+	reads := filter(s.mu.reads.keys)
+	writes := filter(s.mu.writes.keys)
+
+	// Choose the bucket boundaries as quantiles of the combined samples.
+	all := make([][]byte, 0, len(reads)+len(writes))
+	all = append(append(all, reads...), writes...)
+	slices.SortFunc(all, s.cmp)
+	buckets := max(opts.Buckets, 1)
+	var boundaries [][]byte
+	for i := 1; i < buckets && len(all) > 0; i++ {
+		k := all[i*len(all)/buckets]
+		if opts.LowerBound != nil && s.cmp(k, opts.LowerBound) == 0 {
+			continue
+		}
+		if len(boundaries) > 0 && s.cmp(boundaries[len(boundaries)-1], k) == 0 {
+			continue
+		}
+		boundaries = append(boundaries, k)
+	}
+
+	// countIn returns the number of keys in the sorted slice keys that fall
+	// within [start, end). A nil start or end leaves that side unbounded.
+	countIn := func(keys [][]byte, start, end []byte) int {
+		lo, hi := 0, len(keys)
+		if start != nil {
+			lo = sort.Search(len(keys), func(i int) bool { return s.cmp(keys[i], start) >= 0 })
+		}
+		if end != nil {
+			hi = sort.Search(len(keys), func(i int) bool { return s.cmp(keys[i], end) >= 0 })
+		}
+		return hi - lo
+	}
+
+	res := make([]KeyRangeActivity, 0, len(boundaries)+1)
+	start := opts.LowerBound
+	for i := 0; i <= len(boundaries); i++ {
+		end := opts.UpperBound
+		if i < len(boundaries) {
+			end = boundaries[i]
+		}
+		a := KeyRangeActivity{
+			Start:        slices.Clone(start),
+			End:          slices.Clone(end),
+			ReadSamples:  countIn(reads, start, end),
+			WriteSamples: countIn(writes, start, end),
+		}
+		a.ReadBytes = s.mu.reads.estimate(a.ReadSamples, s.readWeight)
+		a.WriteBytes = s.mu.writes.estimate(a.WriteSamples, s.writeWeight)
+		res = append(res, a)
+		start = end
+	}
+
+	// Mark the key ranges whose activity exceeds the mean by the hot factor.
+	hotFactor := opts.HotFactor
+	if hotFactor <= 1 {
+		hotFactor = 2
+	}
+	var total uint64
+	for i := range res {
+		total += res[i].ReadBytes + res[i].WriteBytes
+	}
+	mean := float64(total) / float64(len(res))
+	for i := range res {
+		res[i].Hot = total > 0 && float64(res[i].ReadBytes+res[i].WriteBytes) > hotFactor*mean
+	}
+	return res
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRangeSamplerHistogram(t *testing.T) {
+	opts := (&Options{}).EnsureDefaults()
+	opts.Experimental.KeyRangeSampleSize = 1000
+	s := newKeyRangeSampler(opts)
+	require.NotNil(t, s)
+
+	// Record 900 reads in [a, b) and 100 reads in [b, c).
+	for i := 0; i < 900; i++ {
+		s.recordRead([]byte(fmt.Sprintf("a%03d", i)))
+	}
+	for i := 0; i < 100; i++ {
+		s.recordRead([]byte(fmt.Sprintf("b%03d", i)))
+	}
+
+	h := s.histogram(KeyRangeActivityOptions{Buckets: 10})
+	require.Len(t, h, 10)
+	require.Nil(t, h[0].Start)
+	require.Nil(t, h[len(h)-1].End)
+	total := 0
+	for i := range h {
+		if i > 0 {
+			require.Equal(t, h[i-1].End, h[i].Start)
+		}
+		total += h[i].ReadSamples
+		require.Equal(t, 100, h[i].ReadSamples)
+		require.Equal(t, 100*s.readWeight, h[i].ReadBytes)
+	}
+	require.Equal(t, 1000, total)
+	require.Equal(t, []byte("b000"), h[9].Start)
+
+	// Bounds restrict the histogram.
+	h = s.histogram(KeyRangeActivityOptions{
+		LowerBound: []byte("b"),
+		UpperBound: []byte("c"),
+		Buckets:    1,
+	})
+	require.Len(t, h, 1)
+	require.Equal(t, []byte("b"), h[0].Start)
+	require.Equal(t, []byte("c"), h[0].End)
+	require.Equal(t, 100, h[0].ReadSamples)
+
+	for i := range h {
+		require.False(t, h[i].Hot)
+	}
+
+	// A single key read as often as all other keys together is marked hot.
+	s.reset()
+	for i := 0; i < 500; i++ {
+		s.recordRead([]byte("hot"))
+		s.recordRead([]byte(fmt.Sprintf("a%03d", i)))
+	}
+	h = s.histogram(KeyRangeActivityOptions{Buckets: 10})
+	var hot []KeyRangeActivity
+	for i := range h {
+		if h[i].Hot {
+			hot = append(hot, h[i])
+		}
+	}
+	require.Len(t, hot, 1)
+	require.Equal(t, []byte("hot"), hot[0].Start)
+	require.Equal(t, 500, hot[0].ReadSamples)
+
+	s.reset()
+	h = s.histogram(KeyRangeActivityOptions{Buckets: 4})
+	require.Len(t, h, 1)
+	require.Zero(t, h[0].ReadSamples)
+	require.False(t, h[0].Hot)
+}
+
+func TestKeyRangeSamplerSampleBatch(t *testing.T) {
+	opts := (&Options{}).EnsureDefaults()
+	opts.Experimental.KeyRangeSampleSize = 1000
+	s := newKeyRangeSampler(opts)
+	// Sample every byte of batch data.
+	s.writeWeight = 1
+
+	// LogData records aren't keys, and must not be sampled.
+	b := newBatch(nil)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, b.LogData([]byte(fmt.Sprintf("log%d", i)), nil))
+	}
+	require.NoError(t, b.Set([]byte("key"), nil, nil))
+	s.sampleBatch(b)
+	require.NotEmpty(t, s.mu.writes.keys)
+	for _, k := range s.mu.writes.keys {
+		require.Equal(t, []byte("key"), k)
+	}
+}
+
+func TestKeyRangeActivity(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	_, err = d.KeyRangeActivity(KeyRangeActivityOptions{})
+	require.ErrorIs(t, err, errKeyRangeSamplingDisabled)
+	require.NoError(t, d.Close())
+
+	opts := &Options{FS: vfs.NewMem()}
+	opts.Experimental.KeyRangeSampleSize = 100
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Write 8 MB to keys prefixed with "w", which should yield ~8 write
+	// samples.
+	value := make([]byte, 1<<10)
+	for i := 0; i < 8<<10; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("w%05d", i)), value, NoSync))
+	}
+	h, err := d.KeyRangeActivity(KeyRangeActivityOptions{Buckets: 1})
+	require.NoError(t, err)
+	require.Len(t, h, 1)
+	require.Greater(t, h[0].WriteSamples, 0)
+	require.Greater(t, h[0].WriteBytes, uint64(0))
+
+	// Reads are sampled by iterators.
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	for valid := iter.First(); valid; valid = iter.Next() {
+	}
+	require.NoError(t, iter.Close())
+	h, err = d.KeyRangeActivity(KeyRangeActivityOptions{
+		LowerBound: []byte("w"),
+		UpperBound: []byte("x"),
+		Buckets:    4,
+	})
+	require.NoError(t, err)
+	var reads int
+	for _, a := range h {
+		reads += a.ReadSamples
+	}
+	require.Greater(t, reads, 0)
+
+	d.ResetKeyRangeActivity()
+	h, err = d.KeyRangeActivity(KeyRangeActivityOptions{Buckets: 4})
+	require.NoError(t, err)
+	require.Len(t, h, 1)
+	require.Zero(t, h[0].ReadSamples+h[0].WriteSamples)
+}
+
+func TestKeyRangeActivityPersisted(t *testing.T) {
+	fs := vfs.NewMem()
+	open := func(readOnly bool) *DB {
+		opts := &Options{FS: fs, ReadOnly: readOnly}
+		opts.Experimental.KeyRangeSampleSize = 100
+		d, err := Open("", opts)
+		require.NoError(t, err)
+		return d
+	}
+	histogram := func(d *DB) []KeyRangeActivity {
+		h, err := d.KeyRangeActivity(KeyRangeActivityOptions{Buckets: 4})
+		require.NoError(t, err)
+		return h
+	}
+
+	d := open(false)
+	value := make([]byte, 1<<10)
+	for i := 0; i < 4<<10; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("w%05d", i)), value, NoSync))
+	}
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	for valid := iter.First(); valid; valid = iter.Next() {
+	}
+	require.NoError(t, iter.Close())
+	want := histogram(d)
+	require.Greater(t, want[0].ReadSamples+want[0].WriteSamples, 0)
+	require.NoError(t, d.Close())
+
+	// The samples are saved when the DB is closed, and loaded when it's
+	// reopened, including read-only. Replaying the WAL doesn't sample the
+	// replayed writes a second time.
+	for _, readOnly := range []bool{true, false} {
+		d = open(readOnly)
+		require.Equal(t, want, histogram(d), "read-only=%t", readOnly)
+		require.NoError(t, d.Close())
+	}
+
+	// A reset is persisted too.
+	d = open(false)
+	d.ResetKeyRangeActivity()
+	require.NoError(t, d.Close())
+	d = open(false)
+	h := histogram(d)
+	require.Len(t, h, 1)
+	require.Zero(t, h[0].ReadSamples+h[0].WriteSamples)
+	require.NoError(t, d.Close())
+
+	// A stats file that can't be decoded is ignored.
+	f, err := fs.Create(keyRangeStatsFilename, vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{keyRangeStatsVersion, 0xff})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	d = open(false)
+	h = histogram(d)
+	require.Zero(t, h[0].ReadSamples+h[0].WriteSamples)
+	require.NoError(t, d.Close())
+}
@@ -428,6 +428,17 @@ func checkRangeTombstones(c *checkConfig) error {
 		level++
 	}
 	for i := 1; i < len(current.Levels); i++ {
+		if runs := current.Runs[i]; runs != nil {
+			// Each sorted run of a level holding multiple runs is a separate
+			// level of the merging iterator, from newest to oldest.
+			for run := len(runs) - 1; run >= 0; run-- {
+				if err := addTombstonesFromLevel(runs[run].Iter(), i); err != nil {
+					return err
+				}
+				level++
+			}
+			continue
+		}
 		if err := addTombstonesFromLevel(current.Levels[i].Iter(), i); err != nil {
 			return err
 		}
@@ -626,10 +637,9 @@ func checkLevelsInternal(c *checkConfig) (err error) {
 		mlevels = append(mlevels, simpleMergingIterLevel{})
 	}
 	for level := 1; level < len(current.Levels); level++ {
-		if current.Levels[level].Empty() {
-			continue
+		for range current.NumRuns(level) {
+			mlevels = append(mlevels, simpleMergingIterLevel{})
 		}
-		mlevels = append(mlevels, simpleMergingIterLevel{})
 	}
 	mlevelAlloc := mlevels[start:]
 	// Add L0 files by sublevel.
@@ -646,19 +656,27 @@ func checkLevelsInternal(c *checkConfig) (err error) {
 		mlevelAlloc[0].iter = li
 		mlevelAlloc = mlevelAlloc[1:]
 	}
-	for level := 1; level < len(current.Levels); level++ {
-		if current.Levels[level].Empty() {
-			continue
-		}
-
+	addLevel := func(files manifest.LevelIterator, layer manifest.Layer) {
 		iterOpts := IterOptions{logger: c.logger}
 		li := &levelIter{}
 		li.init(context.Background(), iterOpts, c.comparer, c.newIters,
-			current.Levels[level].Iter(), manifest.Level(level), internalIterOpts{})
+			files, layer, internalIterOpts{})
 		li.initRangeDel(&mlevelAlloc[0])
 		mlevelAlloc[0].iter = li
 		mlevelAlloc = mlevelAlloc[1:]
 	}
+	for level := 1; level < len(current.Levels); level++ {
+		if runs := current.Runs[level]; runs != nil {
+			for run := len(runs) - 1; run >= 0; run-- {
+				addLevel(runs[run].Iter(), manifest.LevelRun(level, run))
+			}
+			continue
+		}
+		if current.Levels[level].Empty() {
+			continue
+		}
+		addLevel(current.Levels[level].Iter(), manifest.Level(level))
+	}
 
 	mergingIter := &simpleMergingIter{}
 	mergingIter.init(c.merge, c.cmp, c.seqNum, c.formatKey, mlevels...)
@@ -118,6 +118,13 @@ type levelIter struct {
 	// first or last key within iteration bounds.
 	exhaustedDir int8
 
+	// prefetcher, if set, prefetches the sstables that follow the current file.
+	// See IterOptions.Prefetch.
+	prefetcher *tablePrefetcher
+	prefetch   PrefetchOptions
+	// prefetched holds the files most recently scheduled for prefetching.
+	prefetched []*fileMetadata
+
 	// Disable invariant checks even if they are otherwise enabled. Used by tests
 	// which construct "impossible" situations (e.g. seeking to a key before the
 	// lower bound).
@@ -168,6 +175,7 @@ func (l *levelIter) init(
 		l.tableOpts.PointKeyFilters = l.filtersBuf[:0:1]
 	}
 	l.tableOpts.UseL6Filters = opts.UseL6Filters
+	l.tableOpts.KeysOnly = opts.KeysOnly
 	l.tableOpts.CategoryAndQoS = opts.CategoryAndQoS
 	l.tableOpts.layer = l.layer
 	l.tableOpts.snapshotForHideObsoletePoints = opts.snapshotForHideObsoletePoints
@@ -179,6 +187,9 @@ func (l *levelIter) init(
 	l.files = files
 	l.exhaustedDir = 0
 	l.internalOpts = internalOpts
+	l.prefetcher = nil
+	l.prefetch = opts.Prefetch
+	l.prefetched = l.prefetched[:0]
 }
 
 // initRangeDel puts the level iterator into a mode where it interleaves range
@@ -195,6 +206,14 @@ func (l *levelIter) initCombinedIterState(state *combinedIterState) {
 	l.combinedIterState = state
 }
 
+// initPrefetch configures the level iterator to prefetch sstables through the
+// provided prefetcher, if IterOptions.Prefetch enables prefetching.
+func (l *levelIter) initPrefetch(prefetcher *tablePrefetcher) {
+	if l.prefetch.Files > 0 {
+		l.prefetcher = prefetcher
+	}
+}
+
 func (l *levelIter) maybeTriggerCombinedIteration(file *fileMetadata, dir int) {
 	// If we encounter a file that contains range keys, we may need to
 	// trigger a switch to combined range-key and point-key iteration,
@@ -609,6 +628,7 @@ func (l *levelIter) loadFile(file *fileMetadata, dir int) loadFileReturnIndicato
 			// Relinquish iters.rangeDeletion to the caller.
 			l.rangeDelIterSetter.setRangeDelIter(iters.rangeDeletion)
 		}
+		l.maybePrefetch(dir)
 		return newFileLoaded
 	}
 }
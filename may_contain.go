@@ -0,0 +1,134 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/sstable"
+)
+
+// MayContain reports whether the DB may contain key, without performing any
+// I/O. It consults the memtables, and then the filters of the sstables whose
+// point keys may include key, using only the sstables already open in the
+// table cache and the filter blocks already in the block cache.
+//
+// If exact is true, mayContain is a definitive answer: either the memtables
+// determine whether the key exists, or no sstable may contain the key
+// according to its filter. Otherwise, mayContain is true and the key may or
+// may not exist; a Get is required to find out. An sstable without a filter,
+// or whose filter block isn't cached, can't exclude any key.
+func (d *DB) MayContain(key []byte) (mayContain, exact bool) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	readState := d.loadReadState()
+	defer readState.unref()
+	seqNum := d.mu.versions.visibleSeqNum.Load()
+
+	// Consult the memtables from newest to oldest. The newest visible record
+	// of the key decides its existence.
+	memtables := readState.memtables
+	for i := len(memtables) - 1; i >= 0; i-- {
+		if f, ok := memtables[i].flushable.(*ingestedFlushable); ok {
+			if !d.filesExclude(key, f.slice.Iter()) {
+				return true, false
+			}
+			// An excise removes all older keys within its span.
+			if f.exciseSpan.Valid() && f.exciseSpan.Contains(d.cmp, base.MakeSearchKey(key)) {
+				return false, true
+			}
+			continue
+		}
+		if decided, present := d.probeMemtable(memtables[i].flushable, key, seqNum); decided {
+			return present, true
+		}
+	}
+
+	// No sstable may contain the key unless its filter says otherwise. Filters
+	// have no false negatives, so the key is known to be absent if every
+	// filter excludes it, regardless of any range deletions.
+	current := readState.current
+	bounds := base.UserKeyBoundsInclusive(key, key)
+	for level := 0; level < numLevels; level++ {
+		overlaps := current.Overlaps(level, bounds)
+		if !d.filesExclude(key, overlaps.Iter()) {
+			return true, false
+		}
+	}
+	return false, true
+}
+
+// probeMemtable returns whether the newest record of key within the memtable
+// that's visible at seqNum decides the key's existence, and if so, whether
+// the key exists.
+func (d *DB) probeMemtable(f flushable, key []byte, seqNum base.SeqNum) (decided, present bool) {
+	var delSeqNum base.SeqNum
+	if rangeDelIter := f.newRangeDelIter(nil); rangeDelIter != nil {
+		span, err := rangeDelIter.SeekGE(key)
+		if err == nil && span != nil && span.Contains(d.cmp, key) {
+			if s := span.Visible(seqNum); !s.Empty() {
+				delSeqNum = s.LargestSeqNum()
+			}
+		}
+		rangeDelIter.Close()
+		if err != nil {
+			return false, false
+		}
+	}
+
+	iter := f.newIter(nil)
+	defer func() { _ = iter.Close() }()
+	for kv := iter.SeekGE(key, base.SeekGEFlagsNone); kv != nil && d.equal(kv.K.UserKey, key); kv = iter.Next() {
+		if kv.K.SeqNum() >= seqNum {
+			continue
+		}
+		if kv.K.SeqNum() < delSeqNum {
+			// The record is deleted by a newer range deletion.
+			break
+		}
+		switch kv.K.Kind() {
+		case InternalKeyKindSet, InternalKeyKindSetWithDelete, InternalKeyKindMerge:
+			return true, true
+		case InternalKeyKindDelete, InternalKeyKindSingleDelete, InternalKeyKindDeleteSized:
+			return true, false
+		}
+	}
+	// A visible range deletion within the memtable covering the key deletes
+	// all older records of it.
+	return delSeqNum > 0, false
+}
+
+// filesExclude returns true if the filters of all of the provided sstables
+// whose point keys may include key are cached and exclude key.
+func (d *DB) filesExclude(key []byte, files manifest.LevelIterator) bool {
+	prefix := key[:d.split(key)]
+	for f := files.First(); f != nil; f = files.Next() {
+		if !f.HasPointKeys || d.cmp(key, f.SmallestPointKey.UserKey) < 0 ||
+			d.cmp(key, f.LargestPointKey.UserKey) > 0 {
+			continue
+		}
+		// The filter was built over the keys of the backing sstable, which
+		// don't carry the file's synthetic prefix.
+		filterPrefix := prefix
+		if f.SyntheticPrefix.IsSet() {
+			var found bool
+			if filterPrefix, found = bytes.CutPrefix(prefix, f.SyntheticPrefix); !found {
+				// The key can't be found inside this file.
+				continue
+			}
+		}
+		mayContain, ok := true, false
+		d.tableCache.withCachedReader(f.FileBacking, func(r *sstable.Reader) {
+			mayContain, ok = r.MayContainCached(filterPrefix)
+		})
+		if !ok || mayContain {
+			return false
+		}
+	}
+	return true
+}
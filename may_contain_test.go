@@ -0,0 +1,145 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/pebble/bloom"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMayContain(t *testing.T) {
+	opts := &Options{FS: vfs.NewMem(), DisableTableStats: true}
+	opts.EnsureDefaults()
+	for i := range opts.Levels {
+		opts.Levels[i].FilterPolicy = bloom.FilterPolicy(10)
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	type probe struct{ mayContain, exact bool }
+	mayContain := func(key string) probe {
+		m, e := d.MayContain([]byte(key))
+		return probe{m, e}
+	}
+	key := func(i int) string { return fmt.Sprintf("key%04d", i) }
+	for i := 0; i < 100; i += 2 {
+		require.NoError(t, d.Set([]byte(key(i)), []byte("v"), nil))
+	}
+
+	// The memtable answers exactly for the keys it contains. Other keys are
+	// absent, since the LSM is empty.
+	require.Equal(t, probe{true, true}, mayContain(key(0)))
+	require.Equal(t, probe{false, true}, mayContain(key(1)))
+
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte(key(2)), []byte("v"), nil))
+	require.NoError(t, d.Delete([]byte(key(4)), nil))
+	require.NoError(t, d.DeleteRange([]byte(key(10)), []byte(key(20)), nil))
+	require.Equal(t, probe{true, true}, mayContain(key(2)))
+	require.Equal(t, probe{false, true}, mayContain(key(4)))
+	require.Equal(t, probe{false, true}, mayContain(key(12)))
+
+	// Before the sstable's filter block is cached, keys that aren't within the
+	// memtable may be present.
+	require.Equal(t, probe{true, false}, mayContain(key(6)))
+	require.Equal(t, probe{true, false}, mayContain(key(7)))
+	// Keys outside the sstable's bounds are absent.
+	require.Equal(t, probe{false, true}, mayContain("zzz"))
+
+	// Once a read has loaded the filter block, it excludes absent keys
+	// without performing any I/O.
+	_, closer, err := d.Get([]byte(key(6)))
+	require.NoError(t, err)
+	require.NoError(t, closer.Close())
+	before := d.Metrics().BlockCache
+	require.Equal(t, probe{true, false}, mayContain(key(6)))
+	var excluded int
+	for i := 1; i < 100; i += 2 {
+		p := mayContain(key(i))
+		if p.exact {
+			require.False(t, p.mayContain)
+			excluded++
+		}
+	}
+	require.Greater(t, excluded, 40)
+	after := d.Metrics().BlockCache
+	require.Equal(t, before.Misses, after.Misses)
+}
+
+func TestMayContainSyntheticPrefix(t *testing.T) {
+	opts := &Options{
+		FS:                 vfs.NewMem(),
+		FormatMajorVersion: FormatNewest,
+		DisableTableStats:  true,
+	}
+	opts.EnsureDefaults()
+	for i := range opts.Levels {
+		opts.Levels[i].FilterPolicy = bloom.FilterPolicy(10)
+	}
+	storage := remote.NewInMem()
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": storage,
+	})
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Write an external sstable whose keys don't carry the synthetic prefix.
+	obj, err := storage.CreateObject("ext")
+	require.NoError(t, err)
+	w := sstable.NewWriter(objstorageprovider.NewRemoteWritable(obj), sstable.WriterOptions{
+		TableFormat:  sstable.TableFormatPebblev4,
+		FilterPolicy: bloom.FilterPolicy(10),
+	})
+	key := func(i int) string { return fmt.Sprintf("key%04d", i) }
+	for i := 0; i < 100; i += 2 {
+		require.NoError(t, w.Set([]byte(key(i)), []byte("v")))
+	}
+	require.NoError(t, w.Close())
+	size, err := storage.Size("ext")
+	require.NoError(t, err)
+	_, err = d.IngestExternalFiles(context.Background(), []ExternalFile{{
+		ObjName:           "ext",
+		Size:              uint64(size),
+		StartKey:          []byte("p/" + key(0)),
+		EndKey:            []byte("p/" + key(98)),
+		EndKeyIsInclusive: true,
+		HasPointKey:       true,
+		SyntheticPrefix:   []byte("p/"),
+	}})
+	require.NoError(t, err)
+
+	// Load the filter block through a read. The external file is ingested
+	// into L6, whose filters are only read when requested.
+	iter, err := d.NewIter(&IterOptions{UseL6Filters: true})
+	require.NoError(t, err)
+	require.True(t, iter.SeekPrefixGE([]byte("p/"+key(0))))
+	require.NoError(t, iter.Close())
+
+	// Keys within the file must never be excluded by its filter.
+	for i := 0; i < 100; i += 2 {
+		mayContain, _ := d.MayContain([]byte("p/" + key(i)))
+		require.True(t, mayContain, "key %s", key(i))
+	}
+	// Absent keys within the file's bounds are excluded by the filter, which
+	// is probed without the synthetic prefix.
+	var excluded int
+	for i := 1; i < 100; i += 2 {
+		if mayContain, exact := d.MayContain([]byte("p/" + key(i))); exact {
+			require.False(t, mayContain)
+			excluded++
+		}
+	}
+	require.Greater(t, excluded, 40)
+}
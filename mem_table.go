@@ -12,14 +12,21 @@ import (
 	"sync/atomic"
 
 	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/batchrepr"
 	"github.com/cockroachdb/pebble/internal/arenaskl"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/keyspan"
 	"github.com/cockroachdb/pebble/internal/manual"
 	"github.com/cockroachdb/pebble/internal/rangedel"
 	"github.com/cockroachdb/pebble/internal/rangekey"
+	"github.com/cockroachdb/pebble/internal/rangemerge"
 )
 
+// memTableMinKeysPerApplier is the minimum number of keys of a batch that
+// each goroutine applies to the memtable when a batch is applied in parallel.
+// Smaller batches aren't worth the cost of starting goroutines.
+const memTableMinKeysPerApplier = 256
+
 func memTableEntrySize(keyBytes, valueBytes int) uint64 {
 	return arenaskl.MaxNodeSize(uint32(keyBytes)+8, uint32(valueBytes))
 }
@@ -37,6 +44,11 @@ var memTableEmptySize = func() uint32 {
 	return arena.Size()
 }()
 
+// memTableRangeMergeSklSize is the maximum amount of space allocated in the
+// arena when a memtable's range merge skiplist is initialized, which allocates
+// the skiplist's head and tail nodes.
+var memTableRangeMergeSklSize = 2 * arenaskl.MaxNodeSize(0, 0)
+
 // A memTable implements an in-memory layer of the LSM. A memTable is mutable,
 // but append-only. Records are added, but never removed. Deletion is supported
 // via tombstones, but it is up to higher level code (see Iterator) to support
@@ -48,6 +60,9 @@ var memTableEmptySize = func() uint32 {
 // time of creation (with the exception of the cached fragmented range
 // tombstones). The arena-backed skiplist provides both forward and reverse
 // links which makes forward and reverse iteration the same speed.
+// Options.Experimental.MemTableRep may select another representation for the
+// point keys, which still allocates from the memtable's arena (see
+// MemTableRep).
 //
 // A batch is "applied" to a memTable in a two step process: prepare(batch) ->
 // apply(batch). memTable.prepare() is not thread-safe and must be called with
@@ -70,6 +85,21 @@ type memTable struct {
 	skl         arenaskl.Skiplist
 	rangeDelSkl arenaskl.Skiplist
 	rangeKeySkl arenaskl.Skiplist
+	// rangeMergeSkl holds range merges (see Batch.RangeMerge). Range merges
+	// are rare, so rangeMergeSkl is only initialized, allocating its head and
+	// tail nodes from the arena, when the first range merge is applied.
+	rangeMergeSkl     arenaskl.Skiplist
+	rangeMergeSklInit sync.Once
+	// rangeMergeSklReserved is set once prepare has reserved space for
+	// initializing rangeMergeSkl. Like prepare, it requires external
+	// synchronization.
+	rangeMergeSklReserved bool
+	// points holds the point keys, using skl's arena. It's skl itself unless
+	// Options.Experimental.MemTableRep selects another representation.
+	points memTableRep
+	// applyParallelism is the maximum number of goroutines that apply a single
+	// batch. See Options.Experimental.MemTableApplyParallelism.
+	applyParallelism int
 	// reserved tracks the amount of space used by the memtable, both by actual
 	// data stored in the memtable as well as inflight batch commit
 	// operations. This value is incremented pessimistically by prepare() in
@@ -80,9 +110,10 @@ type memTable struct {
 	// inflight mutations that have reserved space in the memtable but not yet
 	// applied. The memtable cannot be flushed to disk until the writer refs
 	// drops to zero.
-	writerRefs atomic.Int32
-	tombstones keySpanCache
-	rangeKeys  keySpanCache
+	writerRefs  atomic.Int32
+	tombstones  keySpanCache
+	rangeKeys   keySpanCache
+	rangeMerges keySpanCache
 	// The current logSeqNum at the time the memtable was created. This is
 	// guaranteed to be less than or equal to any seqnum stored in the memtable.
 	logSeqNum                    base.SeqNum
@@ -135,6 +166,7 @@ func (m *memTable) init(opts memTableOptions) {
 		equal:                        opts.Comparer.Equal,
 		arenaBuf:                     opts.arenaBuf,
 		logSeqNum:                    opts.logSeqNum,
+		applyParallelism:             opts.Experimental.MemTableApplyParallelism,
 		releaseAccountingReservation: opts.releaseAccountingReservation,
 	}
 	m.writerRefs.Store(1)
@@ -150,6 +182,12 @@ func (m *memTable) init(opts memTableOptions) {
 		skl:           &m.rangeKeySkl,
 		constructSpan: rangekey.Decode,
 	}
+	m.rangeMerges = keySpanCache{
+		cmp:           m.cmp,
+		formatKey:     m.formatKey,
+		skl:           &m.rangeMergeSkl,
+		constructSpan: rangemerge.Decode,
+	}
 
 	if m.arenaBuf == nil {
 		m.arenaBuf = make([]byte, opts.size)
@@ -159,6 +197,9 @@ func (m *memTable) init(opts memTableOptions) {
 	m.skl.Reset(arena, m.cmp)
 	m.rangeDelSkl.Reset(arena, m.cmp)
 	m.rangeKeySkl.Reset(arena, m.cmp)
+	m.rangeMergeSklInit = sync.Once{}
+	m.rangeMergeSklReserved = false
+	m.points = newMemTableRep(opts.Options, &m.skl)
 	m.reserved = arena.Size()
 }
 
@@ -192,10 +233,16 @@ func (m *memTable) readyForFlush() bool {
 // writerUnref() after the batch has been applied.
 func (m *memTable) prepare(batch *Batch) error {
 	avail := m.availBytes()
-	if batch.memTableSize > uint64(avail) {
+	size := batch.memTableSize
+	reserveRangeMergeSkl := batch.countRangeMerges > 0 && !m.rangeMergeSklReserved
+	if reserveRangeMergeSkl {
+		size += memTableRangeMergeSklSize
+	}
+	if size > uint64(avail) {
 		return arenaskl.ErrArenaFull
 	}
-	m.reserved += uint32(batch.memTableSize)
+	m.reserved += uint32(size)
+	m.rangeMergeSklReserved = m.rangeMergeSklReserved || reserveRangeMergeSkl
 
 	m.writerRef()
 	return nil
@@ -206,15 +253,113 @@ func (m *memTable) apply(batch *Batch, seqNum base.SeqNum) error {
 		return base.CorruptionErrorf("pebble: batch seqnum %d is less than memtable creation seqnum %d",
 			errors.Safe(seqNum), errors.Safe(m.logSeqNum))
 	}
+	if n := min(m.applyParallelism, int(batch.Count())/memTableMinKeysPerApplier); n > 1 {
+		return m.applyParallel(batch, seqNum, n)
+	}
 
+	endSeqNum, counts, err := m.applyRecords(batch.Reader(), seqNum, -1)
+	if err != nil {
+		return err
+	}
+	if endSeqNum != seqNum+base.SeqNum(batch.Count()) {
+		return base.CorruptionErrorf("pebble: inconsistent batch count: %d vs %d",
+			errors.Safe(endSeqNum), errors.Safe(seqNum+base.SeqNum(batch.Count())))
+	}
+	m.invalidateSpans(counts)
+	return nil
+}
+
+// applyParallel applies the batch to the memtable using n goroutines, each
+// inserting a contiguous range of the batch's keys.
+func (m *memTable) applyParallel(batch *Batch, seqNum base.SeqNum, n int) error {
+	keysPerApplier := (int(batch.Count()) + n - 1) / n
+	starts, seqNums, err := splitBatchKeys(batch, seqNum, keysPerApplier)
+	if err != nil {
+		return err
+	}
+
+	counts := make([]memTableSpanCounts, len(starts))
+	errs := make([]error, len(starts))
+	var wg sync.WaitGroup
+	for i := range starts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, counts[i], errs[i] = m.applyRecords(starts[i], seqNums[i], keysPerApplier)
+		}(i)
+	}
+	wg.Wait()
+	var total memTableSpanCounts
+	for i := range starts {
+		err = firstError(err, errs[i])
+		total.tombstones += counts[i].tombstones
+		total.rangeKeys += counts[i].rangeKeys
+		total.rangeMerges += counts[i].rangeMerges
+	}
+	// Invalidate the caches of spans even on error, since some spans may
+	// have been added.
+	m.invalidateSpans(total)
+	return err
+}
+
+// splitBatchKeys splits the batch into contiguous ranges of keysPerApplier
+// keys, returning the position and sequence number of the first key of each
+// range. LogData records aren't applied to the memtable, so they don't count
+// towards the size of a range. Decoding the batch's records without inserting
+// them is cheap relative to the inserts.
+func splitBatchKeys(
+	batch *Batch, seqNum base.SeqNum, keysPerApplier int,
+) ([]batchrepr.Reader, []base.SeqNum, error) {
+	var starts []batchrepr.Reader
+	var seqNums []base.SeqNum
+	keys := 0
+	r := batch.Reader()
+	for {
+		start := r
+		kind, _, _, ok, err := r.Next()
+		if !ok {
+			if err != nil {
+				return nil, nil, err
+			}
+			break
+		}
+		if kind == InternalKeyKindLogData {
+			continue
+		}
+		if keys%keysPerApplier == 0 {
+			starts = append(starts, start)
+			seqNums = append(seqNums, seqNum+base.SeqNum(keys))
+		}
+		keys++
+	}
+	if keys != int(batch.Count()) {
+		return nil, nil, base.CorruptionErrorf("pebble: inconsistent batch count: %d vs %d",
+			errors.Safe(keys), errors.Safe(batch.Count()))
+	}
+	return starts, seqNums, nil
+}
+
+// memTableSpanCounts holds the number of spans of each kind added to a
+// memtable.
+type memTableSpanCounts struct {
+	tombstones, rangeKeys, rangeMerges uint32
+}
+
+// applyRecords inserts up to n keys read from r into the memtable, or all of
+// them if n is negative, assigning sequence numbers from seqNum. LogData
+// records are skipped and don't count towards n. It returns the sequence
+// number following the last key, and the counts of the spans it added, whose
+// caches the caller must invalidate.
+func (m *memTable) applyRecords(
+	r batchrepr.Reader, seqNum base.SeqNum, n int,
+) (base.SeqNum, memTableSpanCounts, error) {
 	var ins arenaskl.Inserter
-	var tombstoneCount, rangeKeyCount uint32
-	startSeqNum := seqNum
-	for r := batch.Reader(); ; seqNum++ {
+	var counts memTableSpanCounts
+	for n != 0 {
 		kind, ukey, value, ok, err := r.Next()
 		if !ok {
 			if err != nil {
-				return err
+				return seqNum, counts, err
 			}
 			break
 		}
@@ -222,46 +367,58 @@ func (m *memTable) apply(batch *Batch, seqNum base.SeqNum) error {
 		switch kind {
 		case InternalKeyKindRangeDelete:
 			err = m.rangeDelSkl.Add(ikey, value)
-			tombstoneCount++
+			counts.tombstones++
 		case InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeyDelete:
 			err = m.rangeKeySkl.Add(ikey, value)
-			rangeKeyCount++
+			counts.rangeKeys++
+		case InternalKeyKindRangeMerge:
+			m.rangeMergeSklInit.Do(func() {
+				m.rangeMergeSkl.Reset(m.skl.Arena(), m.cmp)
+			})
+			err = m.rangeMergeSkl.Add(ikey, value)
+			counts.rangeMerges++
 		case InternalKeyKindLogData:
 			// Don't increment seqNum for LogData, since these are not applied
 			// to the memtable.
-			seqNum--
+			continue
 		case InternalKeyKindIngestSST, InternalKeyKindExcise:
 			panic("pebble: cannot apply ingested sstable or excise kind keys to memtable")
 		default:
-			err = ins.Add(&m.skl, ikey, value)
+			err = m.points.add(&ins, ikey, value)
 		}
 		if err != nil {
-			return err
+			return seqNum, counts, err
 		}
+		seqNum++
+		n--
 	}
-	if seqNum != startSeqNum+base.SeqNum(batch.Count()) {
-		return base.CorruptionErrorf("pebble: inconsistent batch count: %d vs %d",
-			errors.Safe(seqNum), errors.Safe(startSeqNum+base.SeqNum(batch.Count())))
+	return seqNum, counts, nil
+}
+
+// invalidateSpans invalidates the caches of the kinds of spans that were
+// added to the memtable.
+func (m *memTable) invalidateSpans(counts memTableSpanCounts) {
+	if counts.tombstones != 0 {
+		m.tombstones.invalidate(counts.tombstones)
 	}
-	if tombstoneCount != 0 {
-		m.tombstones.invalidate(tombstoneCount)
+	if counts.rangeKeys != 0 {
+		m.rangeKeys.invalidate(counts.rangeKeys)
 	}
-	if rangeKeyCount != 0 {
-		m.rangeKeys.invalidate(rangeKeyCount)
+	if counts.rangeMerges != 0 {
+		m.rangeMerges.invalidate(counts.rangeMerges)
 	}
-	return nil
 }
 
 // newIter is part of the flushable interface. It returns an iterator that is
 // unpositioned (Iterator.Valid() will return false). The iterator can be
 // positioned via a call to SeekGE, SeekLT, First or Last.
 func (m *memTable) newIter(o *IterOptions) internalIterator {
-	return m.skl.NewIter(o.GetLowerBound(), o.GetUpperBound())
+	return m.points.newIter(o.GetLowerBound(), o.GetUpperBound())
 }
 
 // newFlushIter is part of the flushable interface.
 func (m *memTable) newFlushIter(o *IterOptions) internalIterator {
-	return m.skl.NewFlushIter()
+	return m.points.newFlushIter()
 }
 
 // newRangeDelIter is part of the flushable interface.
@@ -282,6 +439,15 @@ func (m *memTable) newRangeKeyIter(*IterOptions) keyspan.FragmentIterator {
 	return keyspan.NewIter(m.cmp, rangeKeys)
 }
 
+// newRangeMergeIter is part of the flushable interface.
+func (m *memTable) newRangeMergeIter(*IterOptions) keyspan.FragmentIterator {
+	rangeMerges := m.rangeMerges.get()
+	if rangeMerges == nil {
+		return nil
+	}
+	return keyspan.NewIter(m.cmp, rangeMerges)
+}
+
 // containsRangeKeys is part of the flushable interface.
 func (m *memTable) containsRangeKeys() bool {
 	return m.rangeKeys.count.Load() > 0
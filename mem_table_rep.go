@@ -0,0 +1,539 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cockroachdb/pebble/internal/arenaskl"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/treeprinter"
+)
+
+// MemTableRep selects the data structure that holds a memtable's point keys.
+// Range deletions, range keys and range merges are always held in skiplists.
+type MemTableRep int8
+
+const (
+	// MemTableRepSkiplist holds point keys in a lock-free arena-backed
+	// skiplist. Writes and reads are O(log n), and writes don't block reads or
+	// each other.
+	MemTableRepSkiplist MemTableRep = iota
+	// MemTableRepVector holds point keys in sorted vectors. Writes append to
+	// a log in O(1), avoiding the skiplist's splice search, which makes it
+	// well suited to the bulk insertion of sorted or mostly-sorted keys. The
+	// keys written since the last read are sorted when an iterator is next
+	// created, either extending the vector of the preceding keys when they
+	// sort after them, or forming a new vector that is merged into the
+	// existing ones as they grow. The vectors are reused by later iterators
+	// until more keys are written. Reads merge the O(log n) vectors of a
+	// memtable of n keys, so workloads that interleave reads with many
+	// out-of-order writes are better served by MemTableRepSkiplist.
+	MemTableRepVector
+)
+
+// String implements fmt.Stringer.
+func (r MemTableRep) String() string {
+	switch r {
+	case MemTableRepSkiplist:
+		return "skiplist"
+	case MemTableRepVector:
+		return "vector"
+	default:
+		return "unknown"
+	}
+}
+
+// memTableRep is the representation of a memtable's point keys. Keys and
+// values are allocated from the memtable's arena, so that the memtable's
+// memory consumption remains bounded by its arena. All methods may be called
+// concurrently.
+type memTableRep interface {
+	// add adds the key and value to the memtable. The inserter may be used to
+	// speed up consecutive additions by the same goroutine.
+	add(ins *arenaskl.Inserter, key base.InternalKey, value []byte) error
+	// newIter returns an unpositioned iterator over the keys within the
+	// bounds. It observes at least all keys added before it was created.
+	newIter(lower, upper []byte) internalIterator
+	// newFlushIter returns an iterator used to flush the memtable's keys.
+	newFlushIter() internalIterator
+}
+
+func newMemTableRep(opts *Options, skl *arenaskl.Skiplist) memTableRep {
+	if opts.Experimental.MemTableRep == MemTableRepVector {
+		return newVectorRep(opts, skl.Arena())
+	}
+	return (*skiplistRep)(skl)
+}
+
+// skiplistRep implements MemTableRepSkiplist.
+type skiplistRep arenaskl.Skiplist
+
+var _ memTableRep = (*skiplistRep)(nil)
+
+func (r *skiplistRep) add(ins *arenaskl.Inserter, key base.InternalKey, value []byte) error {
+	return ins.Add((*arenaskl.Skiplist)(r), key, value)
+}
+
+func (r *skiplistRep) newIter(lower, upper []byte) internalIterator {
+	return (*arenaskl.Skiplist)(r).NewIter(lower, upper)
+}
+
+func (r *skiplistRep) newFlushIter() internalIterator {
+	return (*arenaskl.Skiplist)(r).NewFlushIter()
+}
+
+// vectorEntryHeaderSize is the size of the header that precedes the key and
+// value of each of a vectorRep's entries in the arena: the trailer, followed by
+// the key and value sizes.
+const vectorEntryHeaderSize = 16
+
+// vectorEntryOverhead is the memory held outside of the arena for each of a
+// vectorRep's entries: the entry's offset within the log and within a sorted
+// run, plus the same again for the spare capacity of the run being appended
+// to and the output of a merge of runs. It's allocated from the arena
+// alongside the entry in order to charge it against the memtable's size.
+const vectorEntryOverhead = 4 * 4
+
+// vectorLogChunkSize is the number of entries held by each chunk of a
+// vectorRep's log.
+const vectorLogChunkSize = 1024
+
+// vectorLogConsumed marks a slot of a vectorRep's log whose entry has been
+// sorted into a run out of order. It's never a valid arena offset.
+const vectorLogConsumed = math.MaxUint32
+
+type vectorLogChunk [vectorLogChunkSize]atomic.Uint32
+
+// vectorRep implements MemTableRepVector. The entries are held in the arena,
+// and referenced by their offsets. Adding an entry appends its offset to a
+// log, without synchronizing with other writers. The entries added since the
+// last read are sorted when an iterator is next created, and held in a small
+// number of sorted runs whose sizes decrease geometrically, so that each
+// entry is merged into a larger run O(log n) times. An iterator merges the
+// runs, of which there's just one when the keys were added in order.
+type vectorRep struct {
+	cmp    Compare
+	split  Split
+	logger Logger
+	arena  *arenaskl.Arena
+	// log holds the offsets of the entries in the order they were added. An
+	// add reserves the next slot by incrementing logLen, and publishes the
+	// entry by storing its offset in the slot, which is zero until then. The
+	// chunks are allocated on demand, and there are enough of them for the
+	// arena to be filled with entries with empty keys and values.
+	log    []atomic.Pointer[vectorLogChunk]
+	logLen atomic.Uint32
+	// published holds the sorted runs last returned by sortedRuns, which are
+	// returned again without holding mu until more entries are added.
+	published atomic.Pointer[vectorRuns]
+	mu        struct {
+		sync.Mutex
+		// scanned is the number of leading slots of the log whose entries
+		// have been sorted into runs. Entries beyond it that were sorted
+		// while an earlier slot was still unpublished have their slots
+		// marked with vectorLogConsumed.
+		scanned uint32
+		// runs holds the sorted runs of entries, in order of decreasing size.
+		// The entries of a published run are never modified, so iterators
+		// may read them without holding mu. Appending to a run only writes
+		// beyond the length of any published slice.
+		runs [][]uint32
+	}
+}
+
+var _ memTableRep = (*vectorRep)(nil)
+
+// vectorRuns holds the published sorted runs of a vectorRep.
+type vectorRuns struct {
+	runs [][]uint32
+	// scanned is the value of vectorRep.mu.scanned when the runs were
+	// published. The runs hold all the entries added so far if it's equal to
+	// the length of the log.
+	scanned uint32
+}
+
+func newVectorRep(opts *Options, arena *arenaskl.Arena) *vectorRep {
+	maxEntries := arena.Capacity() / (vectorEntryHeaderSize + vectorEntryOverhead)
+	return &vectorRep{
+		cmp:    opts.Comparer.Compare,
+		split:  opts.Comparer.Split,
+		logger: opts.Logger,
+		arena:  arena,
+		log:    make([]atomic.Pointer[vectorLogChunk], maxEntries/vectorLogChunkSize+1),
+	}
+}
+
+func (r *vectorRep) add(_ *arenaskl.Inserter, key base.InternalKey, value []byte) error {
+	keySize, valueSize := uint32(len(key.UserKey)), uint32(len(value))
+	size := vectorEntryHeaderSize + keySize + valueSize
+	offset, err := r.arena.Alloc(size + vectorEntryOverhead)
+	if err != nil {
+		return err
+	}
+	buf := r.arena.Bytes(offset, size)
+	binary.LittleEndian.PutUint64(buf, uint64(key.Trailer))
+	binary.LittleEndian.PutUint32(buf[8:], keySize)
+	binary.LittleEndian.PutUint32(buf[12:], valueSize)
+	copy(buf[vectorEntryHeaderSize:], key.UserKey)
+	copy(buf[vectorEntryHeaderSize+keySize:], value)
+	r.logSlot(r.logLen.Add(1) - 1).Store(offset)
+	return nil
+}
+
+// logSlot returns the i-th slot of the log, allocating its chunk if
+// necessary.
+func (r *vectorRep) logSlot(i uint32) *atomic.Uint32 {
+	chunk := &r.log[i/vectorLogChunkSize]
+	c := chunk.Load()
+	if c == nil {
+		chunk.CompareAndSwap(nil, new(vectorLogChunk))
+		c = chunk.Load()
+	}
+	return &c[i%vectorLogChunkSize]
+}
+
+// decode returns the user key, trailer and value of the entry at offset.
+func (r *vectorRep) decode(
+	offset uint32,
+) (userKey []byte, trailer base.InternalKeyTrailer, value []byte) {
+	h := r.arena.Bytes(offset, vectorEntryHeaderSize)
+	keySize, valueSize := binary.LittleEndian.Uint32(h[8:]), binary.LittleEndian.Uint32(h[12:])
+	buf := r.arena.Bytes(offset+vectorEntryHeaderSize, keySize+valueSize)
+	return buf[:keySize:keySize], base.InternalKeyTrailer(binary.LittleEndian.Uint64(h)), buf[keySize:]
+}
+
+// userKey returns the user key of the entry at offset.
+func (r *vectorRep) userKey(offset uint32) []byte {
+	userKey, _, _ := r.decode(offset)
+	return userKey
+}
+
+// compare orders entries by internal key.
+func (r *vectorRep) compare(a, b uint32) int {
+	aKey, aTrailer, _ := r.decode(a)
+	bKey, bTrailer, _ := r.decode(b)
+	if c := r.cmp(aKey, bKey); c != 0 {
+		return c
+	}
+	return cmp.Compare(bTrailer, aTrailer)
+}
+
+// sortedRuns returns the sorted runs of the entries, first sorting the
+// entries added since it was last called into the runs.
+func (r *vectorRep) sortedRuns() [][]uint32 {
+	if p := r.published.Load(); p != nil && p.scanned == r.logLen.Load() {
+		return p.runs
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var added []uint32
+	unpublished := false
+	for i, n := r.mu.scanned, r.logLen.Load(); i < n; i++ {
+		slot := r.logSlot(i)
+		offset := slot.Load()
+		if offset == 0 {
+			// The entry is still being added, and will be sorted by a later
+			// call.
+			unpublished = true
+			continue
+		}
+		if offset != vectorLogConsumed {
+			added = append(added, offset)
+			if unpublished {
+				slot.Store(vectorLogConsumed)
+			}
+		}
+		if !unpublished {
+			r.mu.scanned = i + 1
+		}
+	}
+
+	if len(added) > 0 {
+		if !slices.IsSortedFunc(added, r.compare) {
+			slices.SortFunc(added, r.compare)
+		}
+		runs := r.mu.runs
+		if n := len(runs); n > 0 && r.compare(runs[n-1][len(runs[n-1])-1], added[0]) < 0 {
+			// Keys added in order extend the last run.
+			runs[n-1] = append(runs[n-1], added...)
+		} else {
+			runs = append(runs, slices.Clip(added))
+		}
+		// Merge the last run into the one before it until each run is more
+		// than twice the size of the next.
+		for n := len(runs); n > 1 && len(runs[n-2]) <= 2*len(runs[n-1]); n-- {
+			runs[n-2] = r.merge(runs[n-2], runs[n-1])
+			runs = runs[:n-1]
+		}
+		r.mu.runs = runs
+	} else if p := r.published.Load(); p != nil {
+		// No entries were sorted since the runs were last published.
+		if p.scanned != r.mu.scanned {
+			r.published.Store(&vectorRuns{runs: p.runs, scanned: r.mu.scanned})
+		}
+		return p.runs
+	}
+
+	published := make([][]uint32, len(r.mu.runs))
+	for i, run := range r.mu.runs {
+		published[i] = run[:len(run):len(run)]
+	}
+	r.published.Store(&vectorRuns{runs: published, scanned: r.mu.scanned})
+	return published
+}
+
+// merge returns a new run holding the entries of the runs a and b.
+func (r *vectorRep) merge(a, b []uint32) []uint32 {
+	merged := make([]uint32, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if r.compare(a[0], b[0]) <= 0 {
+			merged, a = append(merged, a[0]), a[1:]
+		} else {
+			merged, b = append(merged, b[0]), b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+func (r *vectorRep) newIter(lower, upper []byte) internalIterator {
+	it := &vectorIter{rep: r, runs: r.sortedRuns(), cur: -1, lower: lower, upper: upper}
+	if len(it.runs) <= len(it.indexBuf) {
+		it.index = it.indexBuf[:len(it.runs)]
+	} else {
+		it.index = make([]int, len(it.runs))
+	}
+	return it
+}
+
+func (r *vectorRep) newFlushIter() internalIterator {
+	return r.newIter(nil, nil)
+}
+
+// vectorIter iterates over the sorted runs of a vectorRep's entries, merging
+// them. There are few runs, so the run holding the next entry is found by
+// comparing the entry at the position of each run, and a seek is a binary
+// search of each run; a point lookup costs no more than the binary searches.
+// Like arenaskl.Iterator, seeks and absolute positioning only check the bound
+// in the direction of iteration.
+type vectorIter struct {
+	rep  *vectorRep
+	runs [][]uint32
+	// index holds the position of the iterator within each run. When
+	// iterating forward, it's the index of the run's first entry that isn't
+	// before the current entry, and len(run) if there's none. When iterating
+	// backward, it's the index of the run's last entry that isn't after the
+	// current entry, and -1 if there's none.
+	index    []int
+	indexBuf [4]int
+	// cur is the run holding the current entry, or -1 if the iterator is
+	// exhausted in its direction of iteration, or unpositioned.
+	cur     int
+	forward bool
+	kv      base.InternalKV
+	lower   []byte
+	upper   []byte
+}
+
+var _ base.InternalIterator = (*vectorIter)(nil)
+
+// search returns the index of the first entry of run at or after start whose
+// user key is greater than or equal to key.
+func (it *vectorIter) search(run []uint32, start int, key []byte) int {
+	return start + sort.Search(len(run)-start, func(i int) bool {
+		return it.rep.cmp(it.rep.userKey(run[start+i]), key) >= 0
+	})
+}
+
+// searchEntry returns the index of the first entry of run that's after the
+// entry at offset, or at or after it if inclusive is true.
+func (it *vectorIter) searchEntry(run []uint32, offset uint32, inclusive bool) int {
+	return sort.Search(len(run), func(i int) bool {
+		c := it.rep.compare(run[i], offset)
+		return c > 0 || (c == 0 && inclusive)
+	})
+}
+
+// current returns the offset of the current entry.
+func (it *vectorIter) current() uint32 {
+	return it.runs[it.cur][it.index[it.cur]]
+}
+
+// nextForward positions the iterator at the smallest entry at the positions
+// of the runs, checking the upper bound.
+func (it *vectorIter) nextForward() *base.InternalKV {
+	it.forward = true
+	it.cur = -1
+	for i, run := range it.runs {
+		if it.index[i] < len(run) &&
+			(it.cur < 0 || it.rep.compare(run[it.index[i]], it.current()) < 0) {
+			it.cur = i
+		}
+	}
+	if it.cur < 0 {
+		return nil
+	}
+	it.kv.K.UserKey = it.rep.userKey(it.current())
+	if it.upper != nil && it.rep.cmp(it.upper, it.kv.K.UserKey) <= 0 {
+		return nil
+	}
+	return it.decode()
+}
+
+// nextBackward positions the iterator at the largest entry at the positions
+// of the runs, checking the lower bound.
+func (it *vectorIter) nextBackward() *base.InternalKV {
+	it.forward = false
+	it.cur = -1
+	for i, run := range it.runs {
+		if it.index[i] >= 0 &&
+			(it.cur < 0 || it.rep.compare(run[it.index[i]], it.current()) > 0) {
+			it.cur = i
+		}
+	}
+	if it.cur < 0 {
+		return nil
+	}
+	it.kv.K.UserKey = it.rep.userKey(it.current())
+	if it.lower != nil && it.rep.cmp(it.lower, it.kv.K.UserKey) > 0 {
+		return nil
+	}
+	return it.decode()
+}
+
+// decode decodes the remainder of the current entry, whose user key has
+// already been decoded.
+func (it *vectorIter) decode() *base.InternalKV {
+	_, trailer, value := it.rep.decode(it.current())
+	it.kv.K.Trailer = trailer
+	it.kv.V = base.MakeInPlaceValue(value)
+	return &it.kv
+}
+
+// SeekGE implements base.InternalIterator.
+func (it *vectorIter) SeekGE(key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	// The key is at least the key of the previous seek, so the entries before
+	// the iterator's positions can be skipped.
+	trySeekUsingNext := flags.TrySeekUsingNext() && it.forward
+	for i, run := range it.runs {
+		start := 0
+		if trySeekUsingNext {
+			start = it.index[i]
+		}
+		it.index[i] = it.search(run, start, key)
+	}
+	return it.nextForward()
+}
+
+// SeekPrefixGE implements base.InternalIterator.
+func (it *vectorIter) SeekPrefixGE(prefix, key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	return it.SeekGE(key, flags)
+}
+
+// SeekLT implements base.InternalIterator.
+func (it *vectorIter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
+	for i, run := range it.runs {
+		it.index[i] = it.search(run, 0, key) - 1
+	}
+	return it.nextBackward()
+}
+
+// First implements base.InternalIterator.
+func (it *vectorIter) First() *base.InternalKV {
+	for i := range it.runs {
+		it.index[i] = 0
+	}
+	return it.nextForward()
+}
+
+// Last implements base.InternalIterator.
+func (it *vectorIter) Last() *base.InternalKV {
+	for i, run := range it.runs {
+		it.index[i] = len(run) - 1
+	}
+	return it.nextBackward()
+}
+
+// Next implements base.InternalIterator.
+func (it *vectorIter) Next() *base.InternalKV {
+	switch {
+	case it.cur < 0 && it.forward:
+		return nil
+	case it.cur < 0:
+		return it.First()
+	case it.forward:
+		it.index[it.cur]++
+	default:
+		// Position each run after the current entry.
+		offset := it.current()
+		for i, run := range it.runs {
+			it.index[i] = it.searchEntry(run, offset, false /* inclusive */)
+		}
+	}
+	return it.nextForward()
+}
+
+// NextPrefix implements base.InternalIterator.
+func (it *vectorIter) NextPrefix(succKey []byte) *base.InternalKV {
+	return it.SeekGE(succKey, base.SeekGEFlagsNone.EnableTrySeekUsingNext())
+}
+
+// Prev implements base.InternalIterator.
+func (it *vectorIter) Prev() *base.InternalKV {
+	switch {
+	case it.cur < 0 && !it.forward:
+		return nil
+	case it.cur < 0:
+		return it.Last()
+	case !it.forward:
+		it.index[it.cur]--
+	default:
+		// Position each run before the current entry.
+		offset := it.current()
+		for i, run := range it.runs {
+			it.index[i] = it.searchEntry(run, offset, true /* inclusive */) - 1
+		}
+	}
+	return it.nextBackward()
+}
+
+// Error implements base.InternalIterator.
+func (it *vectorIter) Error() error {
+	return nil
+}
+
+// Close implements base.InternalIterator.
+func (it *vectorIter) Close() error {
+	it.runs = nil
+	return nil
+}
+
+// SetBounds implements base.InternalIterator.
+func (it *vectorIter) SetBounds(lower, upper []byte) {
+	it.lower = lower
+	it.upper = upper
+	it.cur = -1
+	it.forward = false
+}
+
+// SetContext implements base.InternalIterator.
+func (it *vectorIter) SetContext(_ context.Context) {}
+
+// DebugTree implements base.InternalIterator.
+func (it *vectorIter) DebugTree(tp treeprinter.Node) {
+	tp.Childf("%T(%p)", it, it)
+}
+
+func (it *vectorIter) String() string {
+	return "memtable"
+}
@@ -0,0 +1,308 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/rand"
+)
+
+func TestMemTableVectorRep(t *testing.T) {
+	newMem := func(rep MemTableRep) *memTable {
+		opts := &Options{MemTableSize: 4 << 20}
+		opts.Experimental.MemTableRep = rep
+		return newMemTable(memTableOptions{Options: opts})
+	}
+	skl, vec := newMem(MemTableRepSkiplist), newMem(MemTableRepVector)
+
+	// iterate returns the keys visited by the operations applied to an
+	// iterator over the memtable.
+	iterate := func(m *memTable, o *IterOptions, ops func(it internalIterator, emit func(*base.InternalKV))) string {
+		var buf strings.Builder
+		it := m.newIter(o)
+		ops(it, func(kv *base.InternalKV) {
+			if kv == nil {
+				buf.WriteString(". ")
+				return
+			}
+			fmt.Fprintf(&buf, "%s=%s ", kv.K, kv.InPlaceValue())
+		})
+		require.NoError(t, it.Close())
+		return buf.String()
+	}
+	key := func(i int) []byte { return []byte(fmt.Sprintf("%04d", i)) }
+
+	rng := rand.New(rand.NewSource(1))
+	seqNum := base.SeqNum(1)
+	for round := 0; round < 20; round++ {
+		// Alternate between sorted runs, which are appended to the vector,
+		// and random keys, which are merged into it.
+		b := newBatch(nil)
+		start := rng.Intn(900)
+		for i := 0; i < 50; i++ {
+			k := key(start + i)
+			if round%2 == 1 {
+				k = key(rng.Intn(1000))
+			}
+			switch rng.Intn(3) {
+			case 0:
+				require.NoError(t, b.Delete(k, nil))
+			default:
+				require.NoError(t, b.Set(k, []byte(fmt.Sprint(round)), nil))
+			}
+		}
+		for _, m := range []*memTable{skl, vec} {
+			require.NoError(t, m.prepare(b))
+			require.NoError(t, m.apply(b, seqNum))
+			m.writerUnref()
+		}
+		seqNum += base.SeqNum(b.Count())
+		require.NoError(t, b.Close())
+
+		lower, upper := key(rng.Intn(500)), key(500+rng.Intn(500))
+		seeks := make([][]byte, 10)
+		for i := range seeks {
+			seeks[i] = key(rng.Intn(1000))
+		}
+		backward := make([]bool, 100)
+		for i := range backward {
+			backward[i] = rng.Intn(3) == 0
+		}
+		ops := []func(it internalIterator, emit func(*base.InternalKV)){
+			func(it internalIterator, emit func(*base.InternalKV)) {
+				for kv := it.First(); kv != nil; kv = it.Next() {
+					emit(kv)
+				}
+			},
+			func(it internalIterator, emit func(*base.InternalKV)) {
+				for kv := it.Last(); kv != nil; kv = it.Prev() {
+					emit(kv)
+				}
+			},
+			func(it internalIterator, emit func(*base.InternalKV)) {
+				for _, k := range seeks {
+					if kv := it.SeekGE(k, base.SeekGEFlagsNone); kv != nil {
+						emit(kv)
+						emit(it.Next())
+					}
+					if kv := it.SeekLT(k, base.SeekLTFlagsNone); kv != nil {
+						emit(kv)
+						emit(it.Prev())
+					}
+				}
+			},
+			func(it internalIterator, emit func(*base.InternalKV)) {
+				// Switch directions at random.
+				kv := it.SeekGE(seeks[0], base.SeekGEFlagsNone)
+				for i := 0; kv != nil && i < len(backward); i++ {
+					emit(kv)
+					if backward[i] {
+						kv = it.Prev()
+					} else {
+						kv = it.Next()
+					}
+				}
+				emit(kv)
+			},
+			func(it internalIterator, emit func(*base.InternalKV)) {
+				emit(it.SeekGE(lower, base.SeekGEFlagsNone))
+				for k := lower; ; k = append(k, 0) {
+					kv := it.SeekGE(k, base.SeekGEFlagsNone.EnableTrySeekUsingNext())
+					emit(kv)
+					if kv == nil {
+						break
+					}
+					k = append(k[:0:0], kv.K.UserKey...)
+				}
+			},
+		}
+		for i, op := range ops {
+			for _, o := range []*IterOptions{nil, {LowerBound: lower, UpperBound: upper}} {
+				require.Equal(t, iterate(skl, o, op), iterate(vec, o, op), "round %d op %d", round, i)
+			}
+		}
+	}
+
+	flushed := func(m *memTable) string {
+		var buf strings.Builder
+		it := m.newFlushIter(nil)
+		for kv := it.First(); kv != nil; kv = it.Next() {
+			fmt.Fprintf(&buf, "%s=%s ", kv.K, kv.InPlaceValue())
+		}
+		require.NoError(t, it.Close())
+		return buf.String()
+	}
+	require.Equal(t, flushed(skl), flushed(vec))
+}
+
+func TestMemTableVectorRepConcurrent(t *testing.T) {
+	opts := &Options{MemTableSize: 16 << 20}
+	opts.Experimental.MemTableRep = MemTableRepVector
+	m := newMemTable(memTableOptions{Options: opts})
+
+	// Concurrently apply batches while iterating over the memtable. Every
+	// iterator observes at least the batches applied before it was created.
+	const workers, batches, keys = 4, 50, 20
+	var seqNum base.AtomicSeqNum
+	seqNum.Store(1)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < batches; i++ {
+				b := newBatch(nil)
+				for j := 0; j < keys; j++ {
+					require.NoError(t, b.Set([]byte(fmt.Sprintf("%d-%04d-%02d", w, i, j)), nil, nil))
+				}
+				require.NoError(t, m.apply(b, seqNum.Add(keys)-keys))
+				require.NoError(t, b.Close())
+				require.GreaterOrEqual(t, m.count(), (i+1)*keys)
+			}
+		}(w)
+	}
+	wg.Wait()
+	require.Equal(t, workers*batches*keys, m.count())
+}
+
+func TestMemTableVectorRepRuns(t *testing.T) {
+	opts := &Options{MemTableSize: 4 << 20}
+	opts.Experimental.MemTableRep = MemTableRepVector
+	m := newMemTable(memTableOptions{Options: opts})
+	r := m.points.(*vectorRep)
+	seqNum := base.SeqNum(1)
+	apply := func(keys ...string) {
+		b := newBatch(nil)
+		for _, k := range keys {
+			require.NoError(t, b.Set([]byte(k), nil, nil))
+		}
+		require.NoError(t, m.prepare(b))
+		require.NoError(t, m.apply(b, seqNum))
+		m.writerUnref()
+		seqNum += base.SeqNum(b.Count())
+		require.NoError(t, b.Close())
+	}
+	runSizes := func() []int {
+		var sizes []int
+		for _, run := range r.sortedRuns() {
+			sizes = append(sizes, len(run))
+		}
+		return sizes
+	}
+
+	// Each entry's memory outside of the arena is charged to the arena.
+	size := m.skl.Size()
+	apply("")
+	require.GreaterOrEqual(t, m.skl.Size()-size, uint32(vectorEntryHeaderSize+vectorEntryOverhead))
+
+	// Keys added in order extend a single run, even when read in between.
+	for i := 0; i < 100; i++ {
+		apply(fmt.Sprintf("a%03d-0", i), fmt.Sprintf("a%03d-1", i))
+		require.Equal(t, []int{2*i + 3}, runSizes())
+	}
+
+	// Keys added out of order between reads form runs that are merged as
+	// they grow, rather than being merged into the existing entries on every
+	// read.
+	rng := rand.New(rand.NewSource(1))
+	first := r.sortedRuns()[0]
+	for i := 0; i < 1000; i++ {
+		apply(fmt.Sprintf("a%03d-%d", rng.Intn(100), 2+i))
+		sizes := runSizes()
+		for j := 1; j < len(sizes); j++ {
+			require.Greater(t, sizes[j-1], 2*sizes[j])
+		}
+		if i < 50 {
+			require.Same(t, &first[0], &r.sortedRuns()[0][0])
+		}
+	}
+	require.Equal(t, 1201, m.count())
+
+	// The runs are published once, and reused until more keys are added.
+	runs := r.sortedRuns()
+	require.Same(t, &runs[0], &r.sortedRuns()[0])
+	apply("b")
+	require.NotSame(t, &runs[0], &r.sortedRuns()[0])
+}
+
+func TestMemTableRepDB(t *testing.T) {
+	opts := &Options{FS: vfs.NewMem()}
+	opts.Experimental.MemTableRep = MemTableRepVector
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Write mostly-sorted keys, reading them back from the memtable and after
+	// a flush.
+	rng := rand.New(rand.NewSource(1))
+	want := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("%05d", i)
+		if rng.Intn(10) == 0 {
+			k = fmt.Sprintf("%05d", rng.Intn(1000))
+		}
+		require.NoError(t, d.Set([]byte(k), []byte(fmt.Sprint(i)), nil))
+		want[k] = fmt.Sprint(i)
+	}
+	check := func() {
+		got := make(map[string]string)
+		iter, _ := d.NewIter(nil)
+		for valid := iter.First(); valid; valid = iter.Next() {
+			got[string(iter.Key())] = string(iter.Value())
+		}
+		require.NoError(t, iter.Close())
+		require.Equal(t, want, got)
+	}
+	check()
+	require.NoError(t, d.Flush())
+	check()
+}
+
+func TestMemTableRepOptions(t *testing.T) {
+	opts := &Options{}
+	opts.Experimental.MemTableRep = MemTableRepVector
+	opts.EnsureDefaults()
+	require.Contains(t, opts.String(), "mem_table_rep=vector")
+
+	var parsed Options
+	require.NoError(t, parsed.Parse(opts.String(), nil))
+	require.Equal(t, MemTableRepVector, parsed.Experimental.MemTableRep)
+	require.Error(t, parsed.Parse("[Options]\n  mem_table_rep=hash\n", nil))
+}
+
+func BenchmarkMemTableRepSortedInsert(b *testing.B) {
+	for _, rep := range []MemTableRep{MemTableRepSkiplist, MemTableRepVector} {
+		b.Run(rep.String(), func(b *testing.B) {
+			opts := &Options{MemTableSize: 64 << 20}
+			opts.Experimental.MemTableRep = rep
+			m := newMemTable(memTableOptions{Options: opts})
+			const batchSize = 100
+			batch := newBatch(nil)
+			var seqNum base.SeqNum
+			for i := 0; i < b.N; i += batchSize {
+				b.StopTimer()
+				batch.Reset()
+				for j := i; j < min(i+batchSize, b.N); j++ {
+					require.NoError(b, batch.Set([]byte(fmt.Sprintf("%012d", j)), nil, nil))
+				}
+				b.StartTimer()
+				if err := m.apply(batch, seqNum); err != nil {
+					b.StopTimer()
+					m = newMemTable(memTableOptions{Options: opts})
+					require.NoError(b, m.apply(batch, seqNum))
+					b.StartTimer()
+				}
+				seqNum += base.SeqNum(batch.Count())
+			}
+		})
+	}
+}
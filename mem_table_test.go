@@ -19,6 +19,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/arenaskl"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/itertest"
+	"github.com/cockroachdb/pebble/internal/keyspan"
 	"github.com/cockroachdb/pebble/internal/rangekey"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/exp/rand"
@@ -28,7 +29,8 @@ import (
 // get gets the value for the given key. It returns ErrNotFound if the DB does
 // not contain the key.
 func (m *memTable) get(key []byte) (value []byte, err error) {
-	it := m.skl.NewIter(nil, nil)
+	it := m.newIter(nil)
+	defer it.Close()
 	kv := it.SeekGE(key, base.SeekGEFlagsNone)
 	if kv == nil {
 		return nil, ErrNotFound
@@ -62,7 +64,8 @@ func (m *memTable) set(key InternalKey, value []byte) error {
 		m.rangeKeys.invalidate(1)
 		return nil
 	}
-	return m.skl.Add(key, value)
+	var ins arenaskl.Inserter
+	return m.points.add(&ins, key, value)
 }
 
 // count returns the number of entries in a DB.
@@ -380,6 +383,83 @@ func TestMemTableReserved(t *testing.T) {
 	require.Equal(t, int(m.reserved), int(b.memTableSize)+int(prevReserved))
 }
 
+func TestMemTableApplyParallel(t *testing.T) {
+	// Build a large batch containing each kind of record.
+	b := newBatch(nil)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 4000; i++ {
+		k := []byte(fmt.Sprintf("%05d", rng.Intn(10000)))
+		switch {
+		case i%100 == 0:
+			require.NoError(t, b.DeleteRange(k, append(k, 'x'), nil))
+		case i%150 == 0:
+			require.NoError(t, b.RangeKeySet(k, append(k, 'x'), nil, []byte("v"), nil))
+		case i%50 == 0:
+			require.NoError(t, b.LogData(k, nil))
+		case i%3 == 0:
+			require.NoError(t, b.Delete(k, nil))
+		default:
+			require.NoError(t, b.Set(k, []byte(fmt.Sprint(i)), nil))
+		}
+	}
+
+	// dump applies the batch to a new memtable and returns its contents.
+	dump := func(rep MemTableRep, parallelism int) string {
+		opts := &Options{MemTableSize: 16 << 20}
+		opts.Experimental.MemTableRep = rep
+		opts.Experimental.MemTableApplyParallelism = parallelism
+		m := newMemTable(memTableOptions{Options: opts})
+		require.NoError(t, m.apply(b, 10))
+		var buf strings.Builder
+		it := m.newIter(nil)
+		for kv := it.First(); kv != nil; kv = it.Next() {
+			fmt.Fprintf(&buf, "%s=%s\n", kv.K, kv.InPlaceValue())
+		}
+		require.NoError(t, it.Close())
+		for _, spans := range []keyspan.FragmentIterator{m.newRangeDelIter(nil), m.newRangeKeyIter(nil)} {
+			s, err := spans.First()
+			for ; s != nil; s, err = spans.Next() {
+				fmt.Fprintf(&buf, "%s\n", s)
+			}
+			require.NoError(t, err)
+			spans.Close()
+		}
+		return buf.String()
+	}
+	for _, rep := range []MemTableRep{MemTableRepSkiplist, MemTableRepVector} {
+		want := dump(rep, 0)
+		for _, parallelism := range []int{2, 4, 64} {
+			require.Equal(t, want, dump(rep, parallelism), "%s parallelism=%d", rep, parallelism)
+		}
+	}
+}
+
+func TestSplitBatchKeys(t *testing.T) {
+	// A batch whose first half is mostly LogData. The ranges must hold equal
+	// numbers of keys regardless of where the LogData records fall.
+	b := newBatch(nil)
+	for i := 0; i < 1000; i++ {
+		k := []byte(fmt.Sprintf("%04d", i))
+		if i < 500 && i%5 != 0 {
+			require.NoError(t, b.LogData(k, nil))
+			continue
+		}
+		require.NoError(t, b.Set(k, nil, nil))
+	}
+	require.Equal(t, uint32(600), b.Count())
+
+	starts, seqNums, err := splitBatchKeys(b, 10, 200)
+	require.NoError(t, err)
+	require.Equal(t, []base.SeqNum{10, 210, 410}, seqNums)
+	for i, r := range starts {
+		kind, ukey, _, ok, err := r.Next()
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, InternalKeyKindSet, kind)
+		require.Equal(t, []string{"0000", "0600", "0800"}[i], string(ukey))
+	}
+}
+
 func TestMemTable(t *testing.T) {
 	var m *memTable
 	var buf bytes.Buffer
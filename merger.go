@@ -22,6 +22,9 @@ type ValueMerger = base.ValueMerger
 // DeletableValueMerger exports the base.DeletableValueMerger type.
 type DeletableValueMerger = base.DeletableValueMerger
 
+// ManyValueMerger exports the base.ManyValueMerger type.
+type ManyValueMerger = base.ManyValueMerger
+
 // DefaultMerger exports the base.DefaultMerger variable.
 var DefaultMerger = base.DefaultMerger
 
@@ -0,0 +1,369 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+)
+
+// builtinMergers holds the built-in mergers by name, so that they're
+// recognized when parsing options.
+var builtinMergers = map[string]*Merger{
+	CounterMerger.Name:        CounterMerger,
+	MaxMerger.Name:            MaxMerger,
+	MinMerger.Name:            MinMerger,
+	SetUnionMerger.Name:       SetUnionMerger,
+	JSONMergePatchMerger.Name: JSONMergePatchMerger,
+}
+
+// CounterMerger is a Merger that adds signed 64-bit integers, wrapping around
+// on overflow. Operands and merged values are encoded by EncodeCounter.
+var CounterMerger = &Merger{
+	Merge: func(key, value []byte) (ValueMerger, error) {
+		m := &counterValueMerger{}
+		return m, m.add(value)
+	},
+	Name: "pebble.counter",
+}
+
+// EncodeCounter encodes an operand or value of the CounterMerger.
+func EncodeCounter(v int64) []byte {
+	return binary.AppendVarint(nil, v)
+}
+
+// DecodeCounter decodes an operand or value of the CounterMerger.
+func DecodeCounter(value []byte) (int64, error) {
+	v, n := binary.Varint(value)
+	if n <= 0 || n != len(value) {
+		return 0, errors.Errorf("pebble: invalid counter value %x", value)
+	}
+	return v, nil
+}
+
+type counterValueMerger struct {
+	sum int64
+}
+
+var _ ManyValueMerger = (*counterValueMerger)(nil)
+
+func (m *counterValueMerger) add(value []byte) error {
+	v, err := DecodeCounter(value)
+	m.sum += v
+	return err
+}
+
+func (m *counterValueMerger) MergeNewer(value []byte) error { return m.add(value) }
+func (m *counterValueMerger) MergeOlder(value []byte) error { return m.add(value) }
+
+func (m *counterValueMerger) MergeMany(values [][]byte) error {
+	for _, v := range values {
+		if err := m.add(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *counterValueMerger) Finish(includesBase bool) ([]byte, io.Closer, error) {
+	return EncodeCounter(m.sum), nil, nil
+}
+
+// MaxMerger is a Merger that retains the greatest operand, comparing operands
+// bytewise. Numbers should be encoded in a form that sorts bytewise, such as
+// big-endian unsigned integers of a fixed width.
+var MaxMerger = &Merger{
+	Merge: func(key, value []byte) (ValueMerger, error) {
+		return &extremumValueMerger{value: slices.Clone(value), sign: +1}, nil
+	},
+	Name: "pebble.max",
+}
+
+// MinMerger is a Merger that retains the least operand, comparing operands
+// bytewise. See MaxMerger.
+var MinMerger = &Merger{
+	Merge: func(key, value []byte) (ValueMerger, error) {
+		return &extremumValueMerger{value: slices.Clone(value), sign: -1}, nil
+	},
+	Name: "pebble.min",
+}
+
+// extremumValueMerger retains the greatest operand if sign is +1, and the least
+// operand if sign is -1.
+type extremumValueMerger struct {
+	value []byte
+	sign  int
+}
+
+var _ ManyValueMerger = (*extremumValueMerger)(nil)
+
+func (m *extremumValueMerger) add(value []byte) {
+	if bytes.Compare(value, m.value)*m.sign > 0 {
+		m.value = append(m.value[:0], value...)
+	}
+}
+
+func (m *extremumValueMerger) MergeNewer(value []byte) error {
+	m.add(value)
+	return nil
+}
+
+func (m *extremumValueMerger) MergeOlder(value []byte) error {
+	m.add(value)
+	return nil
+}
+
+func (m *extremumValueMerger) MergeMany(values [][]byte) error {
+	for _, v := range values {
+		m.add(v)
+	}
+	return nil
+}
+
+func (m *extremumValueMerger) Finish(includesBase bool) ([]byte, io.Closer, error) {
+	return m.value, nil, nil
+}
+
+// SetUnionMerger is a Merger that computes the union of sets of byte strings.
+// Operands are encoded by EncodeSet, and merged values are sets of unique
+// elements in sorted order.
+var SetUnionMerger = &Merger{
+	Merge: func(key, value []byte) (ValueMerger, error) {
+		m := &setUnionValueMerger{}
+		return m, m.add(value)
+	},
+	Name: "pebble.set_union",
+}
+
+// EncodeSet encodes a set of elements as an operand or value of the
+// SetUnionMerger. The elements need not be sorted or unique.
+func EncodeSet(elems ...[]byte) []byte {
+	var buf []byte
+	for _, e := range elems {
+		buf = binary.AppendUvarint(buf, uint64(len(e)))
+		buf = append(buf, e...)
+	}
+	return buf
+}
+
+// DecodeSet decodes an operand or value of the SetUnionMerger. The returned
+// elements alias value.
+func DecodeSet(value []byte) ([][]byte, error) {
+	var elems [][]byte
+	for len(value) > 0 {
+		n, w := binary.Uvarint(value)
+		if w <= 0 || n > uint64(len(value)-w) {
+			return nil, errors.Errorf("pebble: invalid set value")
+		}
+		elems = append(elems, value[w:w+int(n)])
+		value = value[w+int(n):]
+	}
+	return elems, nil
+}
+
+type setUnionValueMerger struct {
+	elems [][]byte
+}
+
+var _ ManyValueMerger = (*setUnionValueMerger)(nil)
+
+func (m *setUnionValueMerger) add(value []byte) error {
+	elems, err := DecodeSet(value)
+	for _, e := range elems {
+		m.elems = append(m.elems, slices.Clone(e))
+	}
+	return err
+}
+
+func (m *setUnionValueMerger) MergeNewer(value []byte) error { return m.add(value) }
+func (m *setUnionValueMerger) MergeOlder(value []byte) error { return m.add(value) }
+
+func (m *setUnionValueMerger) MergeMany(values [][]byte) error {
+	for _, v := range values {
+		if err := m.add(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *setUnionValueMerger) Finish(includesBase bool) ([]byte, io.Closer, error) {
+	slices.SortFunc(m.elems, bytes.Compare)
+	m.elems = slices.CompactFunc(m.elems, bytes.Equal)
+	return EncodeSet(m.elems...), nil, nil
+}
+
+// JSONMergePatchMerger is a Merger that applies JSON merge patches, as
+// specified by RFC 7386. The oldest operand, or the value written by Set, is
+// the initial JSON document, and every newer operand is a merge patch applied
+// to it. Since the initial document is treated as a patch applied to an empty
+// document, null members of objects in the initial document are removed.
+//
+// Merging patches without the initial document produces a patch that's
+// equivalent to applying them in sequence. When the patches can't be combined
+// into one, such as when a member is replaced by null and then by an object,
+// the result is an internal encoding of the sequence of patches that's only
+// understood by the JSONMergePatchMerger. Such results are never returned by
+// reads.
+var JSONMergePatchMerger = &Merger{
+	Merge: func(key, value []byte) (ValueMerger, error) {
+		m := &jsonMergePatchValueMerger{}
+		return m, m.MergeOlder(value)
+	},
+	Name: "pebble.json_merge_patch",
+}
+
+// jsonPatchSequencePrefix prefixes the encoding of a sequence of JSON merge
+// patches that couldn't be combined into one. It can't begin valid JSON.
+const jsonPatchSequencePrefix = 0
+
+type jsonMergePatchValueMerger struct {
+	// patches holds the decoded operands, from newest to oldest.
+	patches []any
+}
+
+var _ ManyValueMerger = (*jsonMergePatchValueMerger)(nil)
+
+// decodeJSONPatches decodes an operand of the JSONMergePatchMerger into its
+// patches, ordered from oldest to newest.
+func decodeJSONPatches(value []byte) ([]any, error) {
+	seq := len(value) > 0 && value[0] == jsonPatchSequencePrefix
+	if seq {
+		value = value[1:]
+	}
+	d := json.NewDecoder(bytes.NewReader(value))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, errors.Wrap(err, "pebble: invalid JSON merge operand")
+	}
+	if !seq {
+		return []any{v}, nil
+	}
+	patches, ok := v.([]any)
+	if !ok {
+		return nil, errors.New("pebble: invalid JSON merge patch sequence")
+	}
+	return patches, nil
+}
+
+func (m *jsonMergePatchValueMerger) MergeNewer(value []byte) error {
+	patches, err := decodeJSONPatches(value)
+	if err != nil {
+		return err
+	}
+	slices.Reverse(patches)
+	m.patches = append(patches, m.patches...)
+	return nil
+}
+
+func (m *jsonMergePatchValueMerger) MergeOlder(value []byte) error {
+	patches, err := decodeJSONPatches(value)
+	if err != nil {
+		return err
+	}
+	for i := len(patches) - 1; i >= 0; i-- {
+		m.patches = append(m.patches, patches[i])
+	}
+	return nil
+}
+
+func (m *jsonMergePatchValueMerger) MergeMany(values [][]byte) error {
+	for _, v := range values {
+		if err := m.MergeOlder(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *jsonMergePatchValueMerger) Finish(includesBase bool) ([]byte, io.Closer, error) {
+	if includesBase {
+		var doc any
+		for i := len(m.patches) - 1; i >= 0; i-- {
+			doc = applyJSONMergePatch(doc, m.patches[i])
+		}
+		value, err := json.Marshal(doc)
+		return value, nil, err
+	}
+
+	// Combine adjacent patches where possible.
+	var combined []any
+	for i := len(m.patches) - 1; i >= 0; i-- {
+		if n := len(combined); n > 0 {
+			if c, ok := composeJSONMergePatches(combined[n-1], m.patches[i]); ok {
+				combined[n-1] = c
+				continue
+			}
+		}
+		combined = append(combined, m.patches[i])
+	}
+	if len(combined) == 1 {
+		value, err := json.Marshal(combined[0])
+		return value, nil, err
+	}
+	value, err := json.Marshal(combined)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append([]byte{jsonPatchSequencePrefix}, value...), nil, nil
+}
+
+// applyJSONMergePatch applies the patch to the target document, as specified
+// by RFC 7386. The target may be modified in place, but the patch isn't.
+func applyJSONMergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = applyJSONMergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+// composeJSONMergePatches returns a patch that's equivalent to applying a and
+// then b, if there is one. The patches aren't modified.
+func composeJSONMergePatches(a, b any) (any, bool) {
+	bm, ok := b.(map[string]any)
+	if !ok {
+		// b replaces the document.
+		return b, true
+	}
+	am, ok := a.(map[string]any)
+	if !ok {
+		// a replaces the document, and b is applied to the replacement. A
+		// patch can't express replacing the document with an object.
+		return nil, false
+	}
+	c := make(map[string]any, len(am)+len(bm))
+	for k, v := range am {
+		c[k] = v
+	}
+	for k, v := range bm {
+		av, ok := am[k]
+		if _, isObject := v.(map[string]any); !ok || !isObject {
+			c[k] = v
+			continue
+		}
+		if c[k], ok = composeJSONMergePatches(av, v); !ok {
+			return nil, false
+		}
+	}
+	return c, true
+}
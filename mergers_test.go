@@ -0,0 +1,259 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinMergers(t *testing.T) {
+	decodeSet := func(v []byte) string {
+		elems, err := DecodeSet(v)
+		require.NoError(t, err)
+		var parts []string
+		for _, e := range elems {
+			parts = append(parts, string(e))
+		}
+		return strings.Join(parts, ",")
+	}
+	testCases := []struct {
+		merger   *Merger
+		base     []byte
+		operands [][]byte
+		format   func([]byte) string
+		want     string
+	}{
+		{
+			merger:   CounterMerger,
+			base:     EncodeCounter(10),
+			operands: [][]byte{EncodeCounter(1), EncodeCounter(-5), EncodeCounter(100), EncodeCounter(2)},
+			format: func(v []byte) string {
+				n, err := DecodeCounter(v)
+				require.NoError(t, err)
+				return fmt.Sprint(n)
+			},
+			want: "108",
+		},
+		{
+			merger:   MaxMerger,
+			base:     []byte("c"),
+			operands: [][]byte{[]byte("a"), []byte("e"), []byte("b")},
+			want:     "e",
+		},
+		{
+			merger:   MinMerger,
+			base:     []byte("c"),
+			operands: [][]byte{[]byte("d"), []byte("b"), []byte("e")},
+			want:     "b",
+		},
+		{
+			merger: SetUnionMerger,
+			base:   EncodeSet([]byte("b"), []byte("a")),
+			operands: [][]byte{
+				EncodeSet([]byte("c")), EncodeSet([]byte("a"), []byte("d")), EncodeSet(),
+			},
+			format: decodeSet,
+			want:   "a,b,c,d",
+		},
+		{
+			merger: JSONMergePatchMerger,
+			base:   []byte(`{"a":1,"b":{"c":2,"d":3},"e":null}`),
+			operands: [][]byte{
+				[]byte(`{"b":{"c":null,"f":[1,2]}}`),
+				[]byte(`{"a":null}`),
+				[]byte(`{"a":{"x":1}}`),
+				[]byte(`{"g":"h","b":{"d":4}}`),
+			},
+			want: `{"a":{"x":1},"b":{"d":4,"f":[1,2]},"g":"h"}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.merger.Name, func(t *testing.T) {
+			format := tc.format
+			if format == nil {
+				format = func(v []byte) string { return string(v) }
+			}
+			opts := &Options{FS: vfs.NewMem(), Merger: tc.merger}
+			d, err := Open("", opts)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+
+			get := func(key string) string {
+				v, closer, err := d.Get([]byte(key))
+				require.NoError(t, err)
+				defer closer.Close()
+				return format(v)
+			}
+
+			// Write the operands of key "base" on top of a Set, and the operands
+			// of key "nobase" alone. Snapshots between the operands force
+			// flushes and compactions to merge subsets of them.
+			require.NoError(t, d.Set([]byte("base"), tc.base, nil))
+			require.NoError(t, d.Merge([]byte("nobase"), tc.base, nil))
+			var snaps []*Snapshot
+			for _, op := range tc.operands {
+				require.NoError(t, d.Merge([]byte("base"), op, nil))
+				require.NoError(t, d.Merge([]byte("nobase"), op, nil))
+				snaps = append(snaps, d.NewSnapshot())
+				require.NoError(t, d.Merge([]byte("nobase"), op, nil))
+				require.NoError(t, d.Merge([]byte("nobase"), op, nil))
+			}
+			require.Equal(t, tc.want, get("base"))
+			want := get("nobase")
+
+			require.NoError(t, d.Flush())
+			require.Equal(t, tc.want, get("base"))
+			require.Equal(t, want, get("nobase"))
+			require.NoError(t, d.Compact([]byte("a"), []byte("z"), false))
+			require.Equal(t, tc.want, get("base"))
+			require.Equal(t, want, get("nobase"))
+
+			// Reverse iteration merges the operands in the opposite order.
+			iter, err := d.NewIter(nil)
+			require.NoError(t, err)
+			require.True(t, iter.Last())
+			require.Equal(t, want, format(iter.Value()))
+			require.True(t, iter.Prev())
+			require.Equal(t, tc.want, format(iter.Value()))
+			require.NoError(t, iter.Close())
+
+			for _, s := range snaps {
+				require.NoError(t, s.Close())
+			}
+			require.NoError(t, d.Compact([]byte("a"), []byte("z"), false))
+			require.Equal(t, tc.want, get("base"))
+			require.Equal(t, want, get("nobase"))
+		})
+	}
+}
+
+func TestBuiltinMergersOptions(t *testing.T) {
+	for _, m := range []*Merger{CounterMerger, MaxMerger, MinMerger, SetUnionMerger, JSONMergePatchMerger} {
+		opts := &Options{Merger: m}
+		opts.EnsureDefaults()
+		var parsed Options
+		require.NoError(t, parsed.Parse(opts.String(), nil))
+		require.Equal(t, m, parsed.Merger)
+	}
+}
+
+// countingValueMerger counts the operands merged into a ValueMerger.
+type countingValueMerger struct {
+	ValueMerger
+	count *atomic.Int64
+}
+
+func (m countingValueMerger) MergeNewer(value []byte) error {
+	m.count.Add(1)
+	return m.ValueMerger.MergeNewer(value)
+}
+
+func (m countingValueMerger) MergeOlder(value []byte) error {
+	m.count.Add(1)
+	return m.ValueMerger.MergeOlder(value)
+}
+
+func TestMergeOperandLimit(t *testing.T) {
+	var merged atomic.Int64
+	opts := &Options{
+		FS: vfs.NewMem(),
+		Merger: &Merger{
+			Merge: func(key, value []byte) (ValueMerger, error) {
+				m, err := CounterMerger.Merge(key, value)
+				return countingValueMerger{ValueMerger: m, count: &merged}, err
+			},
+			Name: "counting",
+		},
+	}
+	opts.Experimental.MergeOperandLimit = 4
+	// Compactions would merge the operands in sstables.
+	opts.DisableAutomaticCompactions = true
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	key := []byte("counter")
+	merge := func(n int) {
+		for j := 0; j < n; j++ {
+			require.NoError(t, d.Merge(key, EncodeCounter(1), nil))
+		}
+	}
+	// check reads the counter through r, waits for any merged value to be
+	// written, and returns the number of operands merged into the counter by
+	// the read.
+	check := func(r Reader, want int64) int64 {
+		merged.Store(0)
+		v, closer, err := r.Get(key)
+		require.NoError(t, err)
+		n, err := DecodeCounter(v)
+		require.NoError(t, err)
+		require.Equal(t, want, n)
+		require.NoError(t, closer.Close())
+		d.mergedValues.wg.Wait()
+		return merged.Load()
+	}
+
+	// Reading a key with no more operands than the limit merges the operands
+	// on every read.
+	for j := 0; j < 2; j++ {
+		merge(1)
+		require.NoError(t, d.Flush())
+	}
+	merge(2)
+	require.Equal(t, int64(3), check(d, 4))
+	require.Equal(t, int64(3), check(d, 4))
+
+	// Reading a key with more operands than the limit writes the merged value
+	// back, so that later reads don't merge the operands.
+	merge(1)
+	require.Equal(t, int64(4), check(d, 5))
+	require.Zero(t, check(d, 5))
+	merge(2)
+	require.Equal(t, int64(2), check(d, 7))
+
+	// Iterators write merged values back too.
+	merge(4)
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	require.True(t, iter.First())
+	require.NoError(t, iter.Close())
+	d.mergedValues.wg.Wait()
+	require.Zero(t, check(d, 11))
+
+	// So do iterators iterating in reverse.
+	merge(4)
+	iter, err = d.NewIter(nil)
+	require.NoError(t, err)
+	require.True(t, iter.Last())
+	require.NoError(t, iter.Close())
+	d.mergedValues.wg.Wait()
+	require.Zero(t, check(d, 15))
+
+	// The merged value isn't written back if the key was written since the
+	// read's sequence number.
+	merge(5)
+	s := d.NewSnapshot()
+	merge(1)
+	require.Equal(t, int64(5), check(s, 20))
+	require.NoError(t, s.Close())
+	require.Equal(t, int64(6), check(d, 21))
+	require.Zero(t, check(d, 21))
+
+	// Nor is it written back if a range deletion was written since the read's
+	// sequence number.
+	merge(5)
+	s = d.NewSnapshot()
+	require.NoError(t, d.DeleteRange([]byte("a"), []byte("z"), nil))
+	require.Equal(t, int64(5), check(s, 26))
+	require.NoError(t, s.Close())
+	_, _, err = d.Get(key)
+	require.ErrorIs(t, err, ErrNotFound)
+}
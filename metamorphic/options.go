@@ -685,6 +685,18 @@ func RandomOptions(
 		opts.Experimental.MaxWriterConcurrency = 2
 		opts.Experimental.ForceWriterParallelism = true
 	}
+	if rng.Intn(4) == 0 {
+		// Write committing batches to the WAL in groups for 25% of the random
+		// options.
+		opts.Experimental.PipelinedWrites = true
+	}
+	// Split large compactions into up to 4 subcompactions, when compaction
+	// concurrency slots are available.
+	opts.Experimental.MaxSubcompactions = 1 + rng.Intn(4)
+	if rng.Intn(10) == 0 {
+		opts.Experimental.CompactionStyle = pebble.CompactionStyleTiered
+		opts.Experimental.TieredCompaction.RunsPerLevel = 2 + rng.Intn(4)
+	}
 	if rng.Intn(2) == 0 {
 		opts.Experimental.DisableIngestAsFlushable = func() bool { return true }
 	}
@@ -820,6 +832,14 @@ func RandomOptions(
 			testOpts.Opts.FormatMajorVersion = pebble.FormatVirtualSSTables
 		}
 	}
+	if opts.Experimental.CompactionStyle == pebble.CompactionStyleTiered {
+		// The tiered compaction style doesn't support excises, or the ingestion
+		// of shared or external sstables.
+		testOpts.useSharedReplicate = false
+		testOpts.externalStorageEnabled = false
+		testOpts.externalStorageFS = nil
+		testOpts.useExcise = false
+	}
 	testOpts.InitRemoteStorageFactory()
 	testOpts.Opts.EnsureDefaults()
 	return testOpts
@@ -41,8 +41,9 @@ type SecondaryCacheMetrics = sharedcache.Metrics
 type LevelMetrics struct {
 	// The number of sublevels within the level. The sublevel count corresponds
 	// to the read amplification for the level. An empty level will have a
-	// sublevel count of 0, implying no read amplification. Only L0 will have
-	// a sublevel count other than 0 or 1.
+	// sublevel count of 0, implying no read amplification. Only L0, and the
+	// levels holding multiple sorted runs under CompactionStyleTiered, will
+	// have a sublevel count other than 0 or 1.
 	Sublevels int32
 	// The total number of files in the level.
 	NumFiles int64
@@ -143,6 +144,20 @@ func (m *LevelMetrics) WriteAmp() float64 {
 	return float64(m.BytesFlushed+m.BytesCompacted) / float64(m.BytesIn)
 }
 
+// WriteMetrics holds the metrics of the writes of a WritePriority.
+type WriteMetrics struct {
+	// Count is the number of batches committed.
+	Count int64
+	// Bytes is the size of the batches committed.
+	Bytes int64
+	// StallDuration is the cumulative duration for which the batches waited
+	// on write stalls.
+	StallDuration time.Duration
+	// AdmissionDelay is the cumulative delay imposed on the batches by write
+	// admission control.
+	AdmissionDelay time.Duration
+}
+
 // Metrics holds metrics for various subsystems of the DB such as the Cache,
 // Compactions, WAL, and per-Level metrics.
 //
@@ -165,6 +180,16 @@ type Metrics struct {
 		RewriteCount          int64
 		MultiLevelCount       int64
 		CounterLevelCount     int64
+		FIFOCount             int64
+		PeriodicCount         int64
+		// FIFOBytesDropped is the total size of the tables deleted by FIFO
+		// compactions because they exceeded the retention limits of
+		// CompactionStyleFIFO.
+		FIFOBytesDropped uint64
+		// SubcompactionCount is the total number of subcompactions run by
+		// compactions that were split into concurrent subcompactions (see
+		// Options.Experimental.MaxSubcompactions).
+		SubcompactionCount int64
 		// An estimate of the number of bytes that need to be compacted for the LSM
 		// to reach a stable state.
 		EstimatedDebt uint64
@@ -325,6 +350,23 @@ type Metrics struct {
 		record.LogWriterMetrics
 	}
 
+	WriteAdmission struct {
+		// Pressure is the pressure on the DB, the largest fraction of its stop
+		// threshold reached by the memtables, L0 sublevels or compaction debt.
+		Pressure float64
+		// Rate is the rate, in bytes per second, at which writes are
+		// admitted, or zero if writes are admitted without delay.
+		Rate int64
+		// DelayedCount is the number of writes delayed by admission control.
+		DelayedCount int64
+		// DelayDuration is the cumulative delay of the delayed writes.
+		DelayDuration time.Duration
+	}
+
+	// Writes holds the metrics of the writes of each WritePriority, indexed
+	// by priority.
+	Writes [NumWritePriorities]WriteMetrics
+
 	CategoryStats []sstable.CategoryStatsAggregate
 
 	SecondaryCacheMetrics SecondaryCacheMetrics
@@ -0,0 +1,251 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cockroachdb/pebble/internal/manifest"
+)
+
+// multiGetMinKeysPerIter is the minimum number of keys looked up by each
+// iterator of a parallel MultiGet. Smaller groups aren't worth the cost of
+// cloning an iterator.
+const multiGetMinKeysPerIter = 16
+
+// MultiGetOptions configures a MultiGet.
+type MultiGetOptions struct {
+	// Parallelism is the maximum number of goroutines that look up keys
+	// concurrently, each reading a contiguous range of the sorted keys. The
+	// default of 1 performs all lookups on the calling goroutine.
+	Parallelism int
+}
+
+// MultiGetResult is the result of looking up a single key with MultiGet.
+type MultiGetResult struct {
+	// Value is the value of the key, or nil if the key was not found. It is
+	// owned by the caller.
+	Value []byte
+	// Found is true if the key was found.
+	Found bool
+}
+
+// MultiGet looks up the values of many keys at once, returning a result for
+// each key in the order of keys. It's equivalent to calling Get for each key,
+// but cheaper: the keys are sorted and looked up through a single iterator,
+// so the memtables, L0 sublevels and levels are only opened once, and each
+// lookup continues from the position of the previous one.
+//
+// With Parallelism greater than one, the sstables that may contain the keys
+// are read ahead of the lookups: the filter of each sstable is consulted once
+// for all of the keys within its bounds, and the data blocks that may contain
+// the keys that pass the filter are read into the block cache concurrently,
+// across and within sstables. Then disjoint ranges of the sorted keys are
+// looked up concurrently.
+//
+// All keys are read from the same view of the DB.
+func (d *DB) MultiGet(keys [][]byte, opts *MultiGetOptions) ([]MultiGetResult, error) {
+	iter, err := d.NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+	return multiGet(iter, d.cmp, keys, opts)
+}
+
+// MultiGet looks up the values of many keys at once within the snapshot. See
+// DB.MultiGet.
+func (s *Snapshot) MultiGet(keys [][]byte, opts *MultiGetOptions) ([]MultiGetResult, error) {
+	iter, err := s.NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+	return multiGet(iter, s.db.cmp, keys, opts)
+}
+
+// MultiGet looks up the values of many keys at once within the batch and its
+// DB. See DB.MultiGet. Only indexed batches support MultiGet.
+func (b *Batch) MultiGet(keys [][]byte, opts *MultiGetOptions) ([]MultiGetResult, error) {
+	iter, err := b.NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+	return multiGet(iter, b.db.cmp, keys, opts)
+}
+
+// multiGet looks up keys through iter, which it closes, and clones of iter.
+func multiGet(
+	iter *Iterator, cmp Compare, keys [][]byte, opts *MultiGetOptions,
+) ([]MultiGetResult, error) {
+	// Sort the keys' indexes, so that lookups only move the iterator forward.
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp(keys[a], keys[b])
+	})
+
+	parallelism := 1
+	if opts != nil && opts.Parallelism > 1 {
+		parallelism = min(opts.Parallelism, len(keys)/multiGetMinKeysPerIter)
+	}
+	results := make([]MultiGetResult, len(keys))
+	if parallelism > 1 {
+		sorted := make([][]byte, len(order))
+		for j, i := range order {
+			sorted[j] = keys[i]
+		}
+		multiGetPrefetch(iter, sorted, parallelism)
+	}
+	if parallelism <= 1 {
+		err := multiGetSorted(iter, keys, order, results)
+		return results, firstError(err, iter.Close())
+	}
+
+	// Divide the sorted keys into contiguous groups, each looked up by its own
+	// clone of the iterator. Clones read from the same view of the DB.
+	iters := make([]*Iterator, parallelism)
+	iters[0] = iter
+	var err error
+	for i := 1; i < parallelism && err == nil; i++ {
+		iters[i], err = iter.Clone(CloneOptions{})
+	}
+	errs := make([]error, parallelism)
+	if err == nil {
+		var wg sync.WaitGroup
+		groupSize := (len(order) + parallelism - 1) / parallelism
+		for i := range iters {
+			group := order[min(i*groupSize, len(order)):min((i+1)*groupSize, len(order))]
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = multiGetSorted(iters[i], keys, group, results)
+			}(i)
+		}
+		wg.Wait()
+	}
+	for i := range iters {
+		if iters[i] != nil {
+			err = firstError(err, errs[i])
+			err = firstError(err, iters[i].Close())
+		}
+	}
+	return results, err
+}
+
+// multiGetPrefetch reads the data blocks of iter's sstables that may contain
+// the sorted keys into the block cache, using up to parallelism goroutines
+// that each prefetch the blocks of one sstable at a time. Errors are ignored,
+// since the lookups will encounter them.
+func multiGetPrefetch(iter *Iterator, sorted [][]byte, parallelism int) {
+	v := iter.version
+	if iter.readState != nil {
+		v = iter.readState.current
+	}
+	if v == nil || iter.tableCache == nil {
+		return
+	}
+	cmp := iter.cmp
+	// search returns the index of the first key that's greater than key, or
+	// greater than or equal to key if inclusive is false.
+	search := func(key []byte, inclusive bool) int {
+		return sort.Search(len(sorted), func(i int) bool {
+			c := cmp(sorted[i], key)
+			return c > 0 || (c == 0 && !inclusive)
+		})
+	}
+	type prefetch struct {
+		file *fileMetadata
+		keys [][]byte
+	}
+	var prefetches []prefetch
+	// add schedules the prefetch of the keys within the file's point key
+	// bounds.
+	add := func(f *fileMetadata) {
+		if !f.HasPointKeys || f.SyntheticSuffix.IsSet() {
+			return
+		}
+		lo := search(f.SmallestPointKey.UserKey, false /* inclusive */)
+		hi := search(f.LargestPointKey.UserKey, true /* inclusive */)
+		if lo < hi {
+			prefetches = append(prefetches, prefetch{file: f, keys: sorted[lo:hi]})
+		}
+	}
+	// addSorted schedules the prefetches for files that don't overlap one
+	// another, seeking to the file containing each key.
+	addSorted := func(files manifest.LevelIterator) {
+		for i := 0; i < len(sorted); {
+			f := files.SeekGE(cmp, sorted[i])
+			if f == nil {
+				break
+			}
+			add(f)
+			i = max(search(f.Largest.UserKey, true /* inclusive */), i+1)
+		}
+	}
+	for level := range v.Levels {
+		files := v.Levels[level].Iter()
+		if level == 0 {
+			for f := files.First(); f != nil; f = files.Next() {
+				add(f)
+			}
+			continue
+		}
+		if runs := v.Runs[level]; runs != nil {
+			for _, run := range runs {
+				addSorted(run.Iter())
+			}
+			continue
+		}
+		addSorted(files)
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < min(parallelism, len(prefetches)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(prefetches) {
+					return
+				}
+				_ = iter.tableCache.prefetchPointKeys(iter.ctx, prefetches[i].file, prefetches[i].keys)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// multiGetSorted looks up the keys with the provided indexes, which are sorted
+// in key order, writing their results to the corresponding entries of results.
+func multiGetSorted(iter *Iterator, keys [][]byte, order []int, results []MultiGetResult) error {
+	for j, i := range order {
+		if j > 0 && iter.equal(keys[i], keys[order[j-1]]) {
+			prev := results[order[j-1]]
+			results[i] = MultiGetResult{Value: slices.Clone(prev.Value), Found: prev.Found}
+			continue
+		}
+		if !iter.SeekPrefixGE(keys[i]) || !iter.equal(iter.Key(), keys[i]) {
+			if err := iter.Error(); err != nil {
+				return err
+			}
+			continue
+		}
+		v, err := iter.ValueAndErr()
+		if err != nil {
+			return err
+		}
+		results[i] = MultiGetResult{Value: slices.Clone(v), Found: true}
+		if results[i].Value == nil {
+			results[i].Value = []byte{}
+		}
+	}
+	return nil
+}
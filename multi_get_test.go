@@ -0,0 +1,128 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiGet(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Spread the keys across the memtable and several levels, with some keys
+	// deleted and others overwritten.
+	rng := rand.New(rand.NewSource(1))
+	const n = 1000
+	key := func(i int) []byte { return []byte(fmt.Sprintf("key%05d", i)) }
+	for round := 0; round < 3; round++ {
+		for i := 0; i < n; i++ {
+			switch rng.Intn(4) {
+			case 0:
+				require.NoError(t, d.Delete(key(i), nil))
+			case 1:
+				require.NoError(t, d.Set(key(i), []byte(fmt.Sprint(round, i)), nil))
+			}
+		}
+		require.NoError(t, d.Flush())
+		if round == 0 {
+			require.NoError(t, d.Compact(key(0), key(n), false))
+		}
+	}
+	require.NoError(t, d.Set(key(1), nil, nil))
+	require.NoError(t, d.DeleteRange(key(100), key(200), nil))
+
+	snap := d.NewSnapshot()
+	defer func() { require.NoError(t, snap.Close()) }()
+	b := d.NewIndexedBatch()
+	defer func() { require.NoError(t, b.Close()) }()
+	require.NoError(t, b.Set(key(2), []byte("batch"), nil))
+	require.NoError(t, b.Delete(key(3), nil))
+
+	// Look up a random set of keys, including duplicates and keys that were
+	// never written.
+	var keys [][]byte
+	for i := 0; i < 500; i++ {
+		keys = append(keys, key(rng.Intn(n+100)))
+	}
+	keys = append(keys, key(1), key(2), key(3), key(2))
+
+	for _, r := range []Reader{d, snap, b} {
+		for _, parallelism := range []int{1, 4} {
+			var results []MultiGetResult
+			opts := &MultiGetOptions{Parallelism: parallelism}
+			switch r := r.(type) {
+			case *DB:
+				results, err = r.MultiGet(keys, opts)
+			case *Snapshot:
+				results, err = r.MultiGet(keys, opts)
+			case *Batch:
+				results, err = r.MultiGet(keys, opts)
+			}
+			require.NoError(t, err)
+			require.Len(t, results, len(keys))
+			for i, k := range keys {
+				v, closer, err := r.Get(k)
+				if errors.Is(err, ErrNotFound) {
+					require.False(t, results[i].Found, "key %s", k)
+					require.Nil(t, results[i].Value)
+					continue
+				}
+				require.NoError(t, err)
+				require.True(t, results[i].Found, "key %s", k)
+				require.Equal(t, string(v), string(results[i].Value))
+				require.NoError(t, closer.Close())
+			}
+		}
+	}
+
+	nb := d.NewBatch()
+	_, err = nb.MultiGet(keys, nil)
+	require.ErrorIs(t, err, ErrNotIndexed)
+	require.NoError(t, nb.Close())
+}
+
+func TestMultiGetPrefetch(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	const n = 2000
+	key := func(i int) []byte { return []byte(fmt.Sprintf("key%05d", i)) }
+	for i := 0; i < n; i++ {
+		require.NoError(t, d.Set(key(i), make([]byte, 100), nil))
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact(key(0), key(n), false))
+	for i := 0; i < n; i += 3 {
+		require.NoError(t, d.Set(key(i), []byte("l0"), nil))
+	}
+	require.NoError(t, d.Flush())
+
+	var keys [][]byte
+	for i := 0; i < n; i += 97 {
+		keys = append(keys, key(i))
+	}
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, iter.Close()) }()
+	multiGetPrefetch(iter, keys, 4)
+
+	// Every block needed to look up the keys is now cached.
+	misses := d.Metrics().BlockCache.Misses
+	for _, k := range keys {
+		_, closer, err := d.Get(k)
+		require.NoError(t, err)
+		require.NoError(t, closer.Close())
+	}
+	require.Equal(t, misses, d.Metrics().BlockCache.Misses)
+}
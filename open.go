@@ -210,22 +210,32 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 	}
 
 	d := &DB{
-		cacheID:             opts.Cache.NewID(),
-		dirname:             dirname,
-		opts:                opts,
-		cmp:                 opts.Comparer.Compare,
-		equal:               opts.Comparer.Equal,
-		merge:               opts.Merger.Merge,
-		split:               opts.Comparer.Split,
-		abbreviatedKey:      opts.Comparer.AbbreviatedKey,
-		largeBatchThreshold: (opts.MemTableSize - uint64(memTableEmptySize)) / 2,
-		fileLock:            fileLock,
-		dataDir:             dataDir,
-		closed:              new(atomic.Value),
-		closedCh:            make(chan struct{}),
+		cacheID:          opts.Cache.NewID(),
+		dirname:          dirname,
+		opts:             opts,
+		cmp:              opts.Comparer.Compare,
+		equal:            opts.Comparer.Equal,
+		merge:            opts.Merger.Merge,
+		split:            opts.Comparer.Split,
+		abbreviatedKey:   opts.Comparer.AbbreviatedKey,
+		keyRangeStats:    newKeyRangeSampler(opts),
+		writeAdmission:   newWriteAdmissionController(opts),
+		secondaryIndexes: newSecondaryIndexRegistry(opts),
+		fileLock:         fileLock,
+		dataDir:          dataDir,
+		closed:           new(atomic.Value),
+		closedCh:         make(chan struct{}),
 	}
 	d.mu.versions = &versionSet{}
 	d.diskAvailBytes.Store(math.MaxUint64)
+	d.setMemTableSize(opts.MemTableSize)
+	if d.keyRangeStats != nil {
+		// The samples are advisory, so a stats file that can't be read is
+		// ignored rather than failing the open.
+		if err := d.keyRangeStats.load(opts.FS, dirname); err != nil {
+			opts.Logger.Errorf("ignoring key range stats: %v", err)
+		}
+	}
 
 	defer func() {
 		// If an error or panic occurs during open, attempt to release the manually
@@ -272,6 +282,7 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		visibleSeqNum: &d.mu.versions.visibleSeqNum,
 		apply:         d.commitApply,
 		write:         d.commitWrite,
+		pipelined:     opts.Experimental.PipelinedWrites,
 	})
 	d.mu.nextJobID = 1
 	d.mu.mem.nextSize = opts.MemTableSize
@@ -566,36 +577,11 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 
 		// Write the current options to disk.
 		d.optionsFileNum = d.mu.versions.getNextDiskFileNum()
-		tmpPath := base.MakeFilepath(opts.FS, dirname, fileTypeTemp, d.optionsFileNum)
-		optionsPath := base.MakeFilepath(opts.FS, dirname, fileTypeOptions, d.optionsFileNum)
-
-		// Write them to a temporary file first, in case we crash before
-		// we're done. A corrupt options file prevents opening the
-		// database.
-		optionsFile, err := opts.FS.Create(tmpPath, vfs.WriteCategoryUnspecified)
-		if err != nil {
-			return nil, err
-		}
 		serializedOpts := []byte(opts.String())
-		if _, err := optionsFile.Write(serializedOpts); err != nil {
-			return nil, errors.CombineErrors(err, optionsFile.Close())
-		}
-		d.optionsFileSize = uint64(len(serializedOpts))
-		if err := optionsFile.Sync(); err != nil {
-			return nil, errors.CombineErrors(err, optionsFile.Close())
-		}
-		if err := optionsFile.Close(); err != nil {
-			return nil, err
-		}
-		// Atomically rename to the OPTIONS-XXXXXX path. This rename is
-		// guaranteed to be atomic because the destination path does not
-		// exist.
-		if err := opts.FS.Rename(tmpPath, optionsPath); err != nil {
-			return nil, err
-		}
-		if err := d.dataDir.Sync(); err != nil {
+		if err := d.writeOptionsFile(d.optionsFileNum, serializedOpts); err != nil {
 			return nil, err
 		}
+		d.optionsFileSize = uint64(len(serializedOpts))
 	}
 
 	if !d.opts.ReadOnly {
@@ -980,7 +966,7 @@ func (d *DB) replayWAL(
 			}
 		}
 
-		if b.memTableSize >= uint64(d.largeBatchThreshold) {
+		if b.memTableSize >= d.largeBatchThreshold.Load() {
 			flushMem()
 			// Make a copy of the data slice since it is currently owned by buf and will
 			// be reused in the next iteration.
@@ -1028,6 +1014,36 @@ func (d *DB) replayWAL(
 	return flushableIngests, maxSeqNum, err
 }
 
+// writeOptionsFile writes the serialized options to a new OPTIONS file with
+// the provided file number.
+func (d *DB) writeOptionsFile(fileNum base.DiskFileNum, serializedOpts []byte) error {
+	fs := d.opts.FS
+	tmpPath := base.MakeFilepath(fs, d.dirname, fileTypeTemp, fileNum)
+	optionsPath := base.MakeFilepath(fs, d.dirname, fileTypeOptions, fileNum)
+
+	// Write them to a temporary file first, in case we crash before we're
+	// done. A corrupt options file prevents opening the database.
+	optionsFile, err := fs.Create(tmpPath, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+	if _, err := optionsFile.Write(serializedOpts); err != nil {
+		return errors.CombineErrors(err, optionsFile.Close())
+	}
+	if err := optionsFile.Sync(); err != nil {
+		return errors.CombineErrors(err, optionsFile.Close())
+	}
+	if err := optionsFile.Close(); err != nil {
+		return err
+	}
+	// Atomically rename to the OPTIONS-XXXXXX path. This rename is guaranteed
+	// to be atomic because the destination path does not exist.
+	if err := fs.Rename(tmpPath, optionsPath); err != nil {
+		return err
+	}
+	return d.dataDir.Sync()
+}
+
 func readOptionsFile(opts *Options, path string) (string, error) {
 	f, err := opts.FS.Open(path)
 	if err != nil {
@@ -331,7 +331,7 @@ func TestNewDBFilenames(t *testing.T) {
 			"LOCK",
 			"MANIFEST-000001",
 			"OPTIONS-000003",
-			"marker.format-version.000005.018",
+			"marker.format-version.000006.019",
 			"marker.manifest.000001.MANIFEST-000001",
 		},
 	}
@@ -766,7 +766,7 @@ func TestWALReplaySequenceNumBug(t *testing.T) {
 	require.NoError(t, d.Set([]byte("2"), nil, nil))
 
 	// Write a large batch. This should go to a separate memtable.
-	largeValue := []byte(strings.Repeat("a", int(d.largeBatchThreshold)))
+	largeValue := []byte(strings.Repeat("a", int(d.largeBatchThreshold.Load())))
 	require.NoError(t, d.Set([]byte("1"), largeValue, nil))
 
 	// This write should go the mutable memtable after the large batch in the
@@ -834,7 +834,7 @@ func TestOpenWALReplay2(t *testing.T) {
 						require.NoError(t, d.Set([]byte("2"), largeValue, nil))
 						require.NoError(t, d.Set([]byte("3"), largeValue, nil))
 					case "large-batch":
-						largeValue := []byte(strings.Repeat("a", int(d.largeBatchThreshold)))
+						largeValue := []byte(strings.Repeat("a", int(d.largeBatchThreshold.Load())))
 						require.NoError(t, d.Set([]byte("1"), nil, nil))
 						require.NoError(t, d.Set([]byte("2"), largeValue, nil))
 						require.NoError(t, d.Set([]byte("3"), nil, nil))
@@ -183,6 +183,19 @@ type IterOptions struct {
 	// existing is not low or if we just expect a one-time Seek (where loading the
 	// data block directly is better).
 	UseL6Filters bool
+	// KeysOnly configures the iterator to surface keys without retrieving
+	// their values: the value of every point key is empty. Values stored in
+	// sstable value blocks are never loaded, values are never copied, and the
+	// value columns of columnar data blocks are never decoded. It's intended
+	// for counting or listing keys.
+	//
+	// Keys whose newest records are merge operands are surfaced without
+	// invoking the Merger, so KeysOnly shouldn't be used with a Merger whose
+	// ValueMergers may delete keys (see DeletableValueMerger).
+	KeysOnly bool
+	// Prefetch configures the asynchronous prefetching of sstables during
+	// large scans. See PrefetchOptions.
+	Prefetch PrefetchOptions
 	// CategoryAndQoS is used for categorized iterator stats. This should not be
 	// changed by calling SetOptions.
 	sstable.CategoryAndQoS
@@ -308,6 +321,27 @@ type RangeKeyMasking struct {
 	Filter func() BlockPropertyFilterMask
 }
 
+// PrefetchOptions configures an iterator's asynchronous prefetching of
+// sstables. Without prefetching, a scan that exhausts an sstable opens the
+// next sstable of the level and reads its first blocks synchronously, which
+// makes scans over remote storage latency-bound. With prefetching, whenever
+// the iterator moves to a new sstable of a level, it opens the following
+// sstables in the direction of iteration in the background, and reads their
+// first data blocks into the block cache.
+//
+// Prefetching is intended for scans that read many sstables. It's wasteful
+// for iterators that are seeked repeatedly, and it's disabled for prefix
+// iteration.
+type PrefetchOptions struct {
+	// Files is the number of upcoming sstables of each level to prefetch. Zero
+	// disables prefetching.
+	Files int
+	// Blocks is the number of data blocks to read from the start of each
+	// prefetched sstable (or the end, when iterating in reverse). The blocks
+	// of an sstable are read concurrently. Defaults to 1.
+	Blocks int
+}
+
 // BlockPropertyFilterMask extends the BlockPropertyFilter interface for use
 // with range-key masking. Unlike an ordinary block property filter, a
 // BlockPropertyFilterMask's filtering criteria is allowed to change when Pebble
@@ -362,6 +396,49 @@ type WriteOptions struct {
 	//
 	// The default value is true.
 	Sync bool
+
+	// Priority is the priority class of the write. Under write pressure,
+	// writes of lower priority are delayed first. The default is
+	// WritePriorityNormal. Writes with a priority outside of the defined
+	// classes are rejected with an error.
+	Priority WritePriority
+}
+
+// WritePriority is the priority class of a write, analogous to
+// sstable.QoSLevel for reads. Metrics.Writes reports the writes of each
+// class.
+type WritePriority int8
+
+const (
+	// WritePriorityNormal is the default priority, for foreground writes.
+	WritePriorityNormal WritePriority = iota
+	// WritePriorityLow is the priority of background writes that tolerate
+	// delays, such as garbage collection or index backfills. Write admission
+	// control begins delaying them at the lower pressure of
+	// WriteAdmissionOptions.LowPrioritySlowdownFraction. While the DB is in a
+	// write stall, they wait for the stall to end before entering the commit
+	// pipeline, so that they don't hold up writes of higher priority.
+	WritePriorityLow
+	// WritePriorityHigh is the priority of latency-critical writes. Write
+	// admission control never delays them, though they remain subject to
+	// write stalls.
+	WritePriorityHigh
+	// NumWritePriorities is the number of write priority classes.
+	NumWritePriorities
+)
+
+// String implements fmt.Stringer.
+func (p WritePriority) String() string {
+	switch p {
+	case WritePriorityNormal:
+		return "normal"
+	case WritePriorityLow:
+		return "low"
+	case WritePriorityHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
 }
 
 // Sync specifies the default write options for writes which synchronize to
@@ -377,6 +454,74 @@ func (o *WriteOptions) GetSync() bool {
 	return o == nil || o.Sync
 }
 
+// GetPriority returns the Priority value or WritePriorityNormal if the
+// receiver is nil.
+func (o *WriteOptions) GetPriority() WritePriority {
+	if o == nil {
+		return WritePriorityNormal
+	}
+	return o.Priority
+}
+
+// CompactionStyle selects the built-in policy used to choose automatic
+// compactions.
+type CompactionStyle int8
+
+const (
+	// CompactionStyleLeveled keeps the size of each level a fixed multiple of
+	// the size of the level above it, compacting a few tables at a time from a
+	// level into the next. It bounds read and space amplification at the cost
+	// of higher write amplification.
+	CompactionStyleLeveled CompactionStyle = iota
+	// CompactionStyleTiered lets each level below L0 hold multiple sorted
+	// runs, in the manner of size-tiered (universal) compaction. A compaction
+	// merges all the sorted runs of a level into a single new run in the next
+	// level, without rewriting the runs already there, so the runs merged
+	// together are of similar size. It trades read and space amplification
+	// for much lower write amplification, which suits write-heavy workloads
+	// that rarely read.
+	//
+	// L0 is merged into a new run in L1 once it has L0CompactionThreshold
+	// sublevels, and a level below L0 is merged into a new run in the next
+	// level once it holds TieredCompactionOptions.RunsPerLevel runs. The runs
+	// of the bottommost level are merged into a single run in place. Reads
+	// consult every run of every level.
+	//
+	// A manual compaction (DB.Compact) merges the runs of every level it
+	// overlaps, carrying them down into a single run in the bottommost level.
+	// Ingested sstables are always added to L0, after the memtables holding
+	// earlier writes have been flushed. Excises, the ingestion of external or
+	// shared sstables, and Experimental.CompactionPicker aren't supported. A DB
+	// written with CompactionStyleTiered must continue to be opened with it,
+	// unless a DB.Compact of the entire keyspace has since left a single run in
+	// each level.
+	CompactionStyleTiered
+	// CompactionStyleFIFO never merges tables. Instead, the oldest tables are
+	// deleted once the total size of the tables or their age exceeds the
+	// limits configured by FIFOCompactionOptions. It suits time-series and log
+	// data that's only retained for a bounded period.
+	//
+	// Flushed tables remain in L0, so the L0 read amplification grows with the
+	// number of overlapping tables retained. Writes don't stall on
+	// Options.L0StopWritesThreshold, since no compaction would ever lower the
+	// read amplification; the retention limits bound L0 instead.
+	CompactionStyleFIFO
+)
+
+// String implements fmt.Stringer.
+func (s CompactionStyle) String() string {
+	switch s {
+	case CompactionStyleLeveled:
+		return "leveled"
+	case CompactionStyleTiered:
+		return "tiered"
+	case CompactionStyleFIFO:
+		return "fifo"
+	default:
+		return "unknown"
+	}
+}
+
 // LevelOptions holds the optional per-level parameters.
 type LevelOptions struct {
 	// BlockRestartInterval is the number of keys between restart points
@@ -564,6 +709,15 @@ type Options struct {
 		// concurrency slots as determined by the two options is chosen.
 		CompactionDebtConcurrency uint64
 
+		// MaxSubcompactions is the maximum number of subcompactions a single
+		// large compaction may be split into. Subcompactions process disjoint
+		// key ranges of the compaction concurrently, split at the boundaries of
+		// the files in the compaction's output level. Each subcompaction beyond
+		// the first occupies one of the MaxConcurrentCompactions slots, so a
+		// compaction is only split when there are idle slots. Flushes are never
+		// split. The default value of 1 disables subcompactions.
+		MaxSubcompactions int
+
 		// IngestSplit, if it returns true, allows for ingest-time splitting of
 		// existing sstables into two virtual sstables to allow ingestion sstables to
 		// slot into a lower level than they otherwise would have.
@@ -599,6 +753,29 @@ type Options struct {
 		// gets multiplied with a constant of 1 << 16 to yield 1 << 20 (1MB).
 		ReadSamplingMultiplier int64
 
+		// MergeOperandLimit, if positive, bounds the number of merge operands
+		// of a key that reads are expected to combine. When a Get or an
+		// iterator reads a key with more than MergeOperandLimit operands, the
+		// merged value is written back to the DB as a SET of the key in the
+		// background, so that later reads of the key are cheaper. Writing back
+		// is best effort: the value isn't written back if the key is written
+		// concurrently, or if many merged values are already waiting to be
+		// written. It's useful for workloads that merge into the same keys
+		// frequently, such as counters.
+		//
+		// By default, there is no limit.
+		MergeOperandLimit int
+
+		// KeyRangeSampleSize is the number of sampled user keys retained for
+		// each of reads and writes in order to build the key-range activity
+		// histogram returned by DB.KeyRangeActivity. Reads are sampled at the
+		// rate configured by ReadSamplingMultiplier, and writes roughly once
+		// per MB of committed batch data. The samples are saved to the DB
+		// directory when the DB is closed and loaded when it's opened, so that
+		// the histogram covers activity across restarts. The default value of
+		// zero disables key-range sampling.
+		KeyRangeSampleSize int
+
 		// NumDeletionsThreshold defines the minimum number of point tombstones
 		// that must be present in a single data block for that block to be
 		// considered tombstone-dense for the purposes of triggering a
@@ -631,6 +808,18 @@ type Options struct {
 		// A zero or negative value disables tombstone density compactions.
 		TombstoneDenseCompactionThreshold float64
 
+		// PeriodicCompactionAge, if positive, enables periodic compactions:
+		// files created longer than PeriodicCompactionAge ago are rewritten in
+		// place, at the lowest priority among automatic compactions. This drops
+		// obsolete keys from files that would otherwise never be compacted, and
+		// rewrites their data with the current table format and compression
+		// settings. Files written by versions of Pebble that didn't record the
+		// creation time of files have an unknown age, and are never compacted
+		// periodically.
+		//
+		// By default, periodic compactions are disabled.
+		PeriodicCompactionAge time.Duration
+
 		// TableCacheShards is the number of shards per table cache.
 		// Reducing the value can reduce the number of idle goroutines per DB
 		// instance which can be useful in scenarios with a lot of DB instances
@@ -669,6 +858,52 @@ type Options struct {
 		// compaction will never get triggered.
 		MultiLevelCompactionHeuristic MultiLevelHeuristic
 
+		// CompactionStyle selects the built-in policy used to choose automatic
+		// compactions. Defaults to CompactionStyleLeveled.
+		CompactionStyle CompactionStyle
+
+		// TieredCompaction configures the choice of compactions when
+		// CompactionStyle is CompactionStyleTiered.
+		TieredCompaction TieredCompactionOptions
+
+		// FIFOCompaction configures the retention of tables when
+		// CompactionStyle is CompactionStyleFIFO.
+		FIFOCompaction FIFOCompactionOptions
+
+		// MemTableRep selects the data structure that holds the point keys of
+		// memtables. Defaults to MemTableRepSkiplist. See MemTableRepVector for
+		// an alternative suited to mostly-sorted writes.
+		MemTableRep MemTableRep
+
+		// WriteAdmission configures write admission control, which delays
+		// writes gradually as the DB approaches a write stall. It's disabled
+		// by default.
+		WriteAdmission WriteAdmissionOptions
+
+		// MemTableApplyParallelism is the maximum number of goroutines that
+		// apply a single large batch to the memtable. With
+		// MemTableApplyParallelism greater than one, a batch with many keys is
+		// split into contiguous ranges of keys that are inserted concurrently,
+		// shortening the commit latency of large batches.
+		//
+		// The default of zero applies each batch on a single goroutine.
+		MemTableApplyParallelism int
+
+		// PipelinedWrites, if set, writes committing batches to the WAL in
+		// groups. One goroutine sequences and writes each group while holding
+		// the commit pipeline's mutex once, and the next group is written to
+		// the WAL while the batches of the previous group are applied to the
+		// memtable. This reduces contention on the commit pipeline when many
+		// goroutines commit small batches concurrently, at the cost of a
+		// handoff between goroutines for each group.
+		PipelinedWrites bool
+
+		// CompactionPicker, if set, replaces the built-in score-based policy
+		// for choosing automatic compactions. Pebble validates the compactions
+		// it proposes before running them. Flushes and delete-only, manual and
+		// download compactions are unaffected. See CompactionPicker.
+		CompactionPicker CompactionPicker
+
 		// MaxWriterConcurrency is used to indicate the maximum number of
 		// compression workers the compression queue is allowed to use. If
 		// MaxWriterConcurrency > 0, then the Writer will use parallelism, to
@@ -962,6 +1197,10 @@ type Options struct {
 	// The default merger concatenates values.
 	Merger *Merger
 
+	// SecondaryIndexes defines the secondary indexes maintained by the DB.
+	// See SecondaryIndex for details.
+	SecondaryIndexes []SecondaryIndex
+
 	// MaxConcurrentCompactions specifies the maximum number of concurrent
 	// compactions (not including download compactions).
 	//
@@ -1186,6 +1425,11 @@ func (o *Options) EnsureDefaults() *Options {
 	if o.Experimental.L0CompactionConcurrency <= 0 {
 		o.Experimental.L0CompactionConcurrency = 10
 	}
+	if o.Experimental.MaxSubcompactions <= 0 {
+		o.Experimental.MaxSubcompactions = 1
+	}
+	o.Experimental.TieredCompaction.EnsureDefaults()
+	o.Experimental.WriteAdmission.EnsureDefaults()
 	if o.Experimental.CompactionDebtConcurrency <= 0 {
 		o.Experimental.CompactionDebtConcurrency = 1 << 30 // 1 GB
 	}
@@ -1406,6 +1650,9 @@ func (o *Options) String() string {
 	fmt.Fprintf(&buf, "  cache_size=%d\n", cacheSize)
 	fmt.Fprintf(&buf, "  cleaner=%s\n", o.Cleaner)
 	fmt.Fprintf(&buf, "  compaction_debt_concurrency=%d\n", o.Experimental.CompactionDebtConcurrency)
+	if o.Experimental.CompactionStyle != CompactionStyleLeveled {
+		fmt.Fprintf(&buf, "  compaction_style=%s\n", o.Experimental.CompactionStyle)
+	}
 	fmt.Fprintf(&buf, "  comparer=%s\n", o.Comparer.Name)
 	fmt.Fprintf(&buf, "  disable_wal=%t\n", o.DisableWAL)
 	if o.Experimental.DisableIngestAsFlushable != nil && o.Experimental.DisableIngestAsFlushable() {
@@ -1427,6 +1674,15 @@ func (o *Options) String() string {
 	fmt.Fprintf(&buf, "  max_concurrent_downloads=%d\n", o.MaxConcurrentDownloads())
 	fmt.Fprintf(&buf, "  max_manifest_file_size=%d\n", o.MaxManifestFileSize)
 	fmt.Fprintf(&buf, "  max_open_files=%d\n", o.MaxOpenFiles)
+	if o.Experimental.MaxSubcompactions > 1 {
+		fmt.Fprintf(&buf, "  max_subcompactions=%d\n", o.Experimental.MaxSubcompactions)
+	}
+	if o.Experimental.MemTableApplyParallelism > 1 {
+		fmt.Fprintf(&buf, "  mem_table_apply_parallelism=%d\n", o.Experimental.MemTableApplyParallelism)
+	}
+	if o.Experimental.MemTableRep != MemTableRepSkiplist {
+		fmt.Fprintf(&buf, "  mem_table_rep=%s\n", o.Experimental.MemTableRep)
+	}
 	fmt.Fprintf(&buf, "  mem_table_size=%d\n", o.MemTableSize)
 	fmt.Fprintf(&buf, "  mem_table_stop_writes_threshold=%d\n", o.MemTableStopWritesThreshold)
 	fmt.Fprintf(&buf, "  min_deletion_rate=%d\n", o.TargetByteDeletionRate)
@@ -1436,16 +1692,41 @@ func (o *Options) String() string {
 	}
 	fmt.Fprintf(&buf, "  read_compaction_rate=%d\n", o.Experimental.ReadCompactionRate)
 	fmt.Fprintf(&buf, "  read_sampling_multiplier=%d\n", o.Experimental.ReadSamplingMultiplier)
+	if o.Experimental.MergeOperandLimit > 0 {
+		fmt.Fprintf(&buf, "  merge_operand_limit=%d\n", o.Experimental.MergeOperandLimit)
+	}
 	fmt.Fprintf(&buf, "  num_deletions_threshold=%d\n", o.Experimental.NumDeletionsThreshold)
 	fmt.Fprintf(&buf, "  deletion_size_ratio_threshold=%f\n", o.Experimental.DeletionSizeRatioThreshold)
 	fmt.Fprintf(&buf, "  tombstone_dense_compaction_threshold=%f\n", o.Experimental.TombstoneDenseCompactionThreshold)
+	if o.Experimental.PeriodicCompactionAge > 0 {
+		fmt.Fprintf(&buf, "  periodic_compaction_age=%s\n", o.Experimental.PeriodicCompactionAge)
+	}
+	if o.Experimental.PipelinedWrites {
+		fmt.Fprintf(&buf, "  pipelined_writes=%t\n", o.Experimental.PipelinedWrites)
+	}
 	// We no longer care about strict_wal_tail, but set it to true in case an
 	// older version reads the options.
 	fmt.Fprintf(&buf, "  strict_wal_tail=%t\n", true)
 	fmt.Fprintf(&buf, "  table_cache_shards=%d\n", o.Experimental.TableCacheShards)
+	if o.Experimental.CompactionStyle == CompactionStyleFIFO {
+		fmt.Fprintf(&buf, "  fifo_max_age=%s\n", o.Experimental.FIFOCompaction.MaxAge)
+		fmt.Fprintf(&buf, "  fifo_max_size=%d\n", o.Experimental.FIFOCompaction.MaxSize)
+	}
+	if o.Experimental.CompactionStyle == CompactionStyleTiered {
+		fmt.Fprintf(&buf, "  tiered_runs_per_level=%d\n", o.Experimental.TieredCompaction.RunsPerLevel)
+	}
 	fmt.Fprintf(&buf, "  validate_on_ingest=%t\n", o.Experimental.ValidateOnIngest)
 	fmt.Fprintf(&buf, "  wal_dir=%s\n", o.WALDir)
 	fmt.Fprintf(&buf, "  wal_bytes_per_sync=%d\n", o.WALBytesPerSync)
+	if w := &o.Experimental.WriteAdmission; w.Enabled {
+		fmt.Fprintf(&buf, "  write_admission_compaction_debt_limit=%d\n", w.CompactionDebtLimit)
+		fmt.Fprintf(&buf, "  write_admission_enabled=%t\n", w.Enabled)
+		fmt.Fprintf(&buf, "  write_admission_initial_flush_rate=%d\n", w.InitialFlushRate)
+		fmt.Fprintf(&buf, "  write_admission_low_priority_slowdown_fraction=%f\n", w.LowPrioritySlowdownFraction)
+		fmt.Fprintf(&buf, "  write_admission_max_delay=%s\n", w.MaxDelay)
+		fmt.Fprintf(&buf, "  write_admission_min_rate=%d\n", w.MinRate)
+		fmt.Fprintf(&buf, "  write_admission_slowdown_fraction=%f\n", w.SlowdownFraction)
+	}
 	fmt.Fprintf(&buf, "  max_writer_concurrency=%d\n", o.Experimental.MaxWriterConcurrency)
 	fmt.Fprintf(&buf, "  force_writer_parallelism=%t\n", o.Experimental.ForceWriterParallelism)
 	fmt.Fprintf(&buf, "  secondary_cache_size_bytes=%d\n", o.Experimental.SecondaryCacheSizeBytes)
@@ -1623,6 +1904,17 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				}
 			case "compaction_debt_concurrency":
 				o.Experimental.CompactionDebtConcurrency, err = strconv.ParseUint(value, 10, 64)
+			case "compaction_style":
+				switch value {
+				case "leveled":
+					o.Experimental.CompactionStyle = CompactionStyleLeveled
+				case "tiered":
+					o.Experimental.CompactionStyle = CompactionStyleTiered
+				case "fifo":
+					o.Experimental.CompactionStyle = CompactionStyleFIFO
+				default:
+					err = errors.Newf("unrecognized compaction style: %s", value)
+				}
 			case "delete_range_flush_delay":
 				// NB: This is a deprecated serialization of the
 				// `flush_delay_delete_range`.
@@ -1641,6 +1933,10 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				o.private.disableLazyCombinedIteration, err = strconv.ParseBool(value)
 			case "disable_wal":
 				o.DisableWAL, err = strconv.ParseBool(value)
+			case "fifo_max_age":
+				o.Experimental.FIFOCompaction.MaxAge, err = time.ParseDuration(value)
+			case "fifo_max_size":
+				o.Experimental.FIFOCompaction.MaxSize, err = strconv.ParseUint(value, 10, 64)
 			case "flush_delay_delete_range":
 				o.FlushDelayDeleteRange, err = time.ParseDuration(value)
 			case "flush_delay_range_key":
@@ -1694,6 +1990,19 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				o.MaxManifestFileSize, err = strconv.ParseInt(value, 10, 64)
 			case "max_open_files":
 				o.MaxOpenFiles, err = strconv.Atoi(value)
+			case "max_subcompactions":
+				o.Experimental.MaxSubcompactions, err = strconv.Atoi(value)
+			case "mem_table_apply_parallelism":
+				o.Experimental.MemTableApplyParallelism, err = strconv.Atoi(value)
+			case "mem_table_rep":
+				switch value {
+				case "skiplist":
+					o.Experimental.MemTableRep = MemTableRepSkiplist
+				case "vector":
+					o.Experimental.MemTableRep = MemTableRepVector
+				default:
+					err = errors.Newf("unrecognized memtable rep: %s", value)
+				}
 			case "mem_table_size":
 				o.MemTableSize, err = strconv.ParseUint(value, 10, 64)
 			case "mem_table_stop_writes_threshold":
@@ -1732,6 +2041,10 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				default:
 					err = errors.Newf("unrecognized multilevel compaction heuristic: %s", value)
 				}
+			case "periodic_compaction_age":
+				o.Experimental.PeriodicCompactionAge, err = time.ParseDuration(value)
+			case "pipelined_writes":
+				o.Experimental.PipelinedWrites, err = strconv.ParseBool(value)
 			case "point_tombstone_weight":
 				// Do nothing; deprecated.
 			case "strict_wal_tail":
@@ -1747,7 +2060,9 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				case "pebble.concatenate":
 					o.Merger = DefaultMerger
 				default:
-					if hooks != nil && hooks.NewMerger != nil {
+					if m, ok := builtinMergers[value]; ok {
+						o.Merger = m
+					} else if hooks != nil && hooks.NewMerger != nil {
 						o.Merger, err = hooks.NewMerger(value)
 					}
 				}
@@ -1755,6 +2070,8 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				o.Experimental.ReadCompactionRate, err = strconv.ParseInt(value, 10, 64)
 			case "read_sampling_multiplier":
 				o.Experimental.ReadSamplingMultiplier, err = strconv.ParseInt(value, 10, 64)
+			case "merge_operand_limit":
+				o.Experimental.MergeOperandLimit, err = strconv.Atoi(value)
 			case "num_deletions_threshold":
 				o.Experimental.NumDeletionsThreshold, err = strconv.Atoi(value)
 			case "deletion_size_ratio_threshold":
@@ -1765,6 +2082,8 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				o.Experimental.TombstoneDenseCompactionThreshold, err = strconv.ParseFloat(value, 64)
 			case "table_cache_shards":
 				o.Experimental.TableCacheShards, err = strconv.Atoi(value)
+			case "tiered_runs_per_level":
+				o.Experimental.TieredCompaction.RunsPerLevel, err = strconv.Atoi(value)
 			case "table_format":
 				switch value {
 				case "leveldb":
@@ -1780,6 +2099,20 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				o.WALDir = value
 			case "wal_bytes_per_sync":
 				o.WALBytesPerSync, err = strconv.Atoi(value)
+			case "write_admission_compaction_debt_limit":
+				o.Experimental.WriteAdmission.CompactionDebtLimit, err = strconv.ParseUint(value, 10, 64)
+			case "write_admission_enabled":
+				o.Experimental.WriteAdmission.Enabled, err = strconv.ParseBool(value)
+			case "write_admission_initial_flush_rate":
+				o.Experimental.WriteAdmission.InitialFlushRate, err = strconv.ParseInt(value, 10, 64)
+			case "write_admission_low_priority_slowdown_fraction":
+				o.Experimental.WriteAdmission.LowPrioritySlowdownFraction, err = strconv.ParseFloat(value, 64)
+			case "write_admission_max_delay":
+				o.Experimental.WriteAdmission.MaxDelay, err = time.ParseDuration(value)
+			case "write_admission_min_rate":
+				o.Experimental.WriteAdmission.MinRate, err = strconv.ParseInt(value, 10, 64)
+			case "write_admission_slowdown_fraction":
+				o.Experimental.WriteAdmission.SlowdownFraction, err = strconv.ParseFloat(value, 64)
 			case "max_writer_concurrency":
 				o.Experimental.MaxWriterConcurrency, err = strconv.Atoi(value)
 			case "force_writer_parallelism":
@@ -1965,6 +2298,25 @@ func (o *Options) Validate() error {
 		fmt.Fprintf(&buf, "L0CompactionConcurrency (%d) must be >= 1\n",
 			o.Experimental.L0CompactionConcurrency)
 	}
+	if o.Experimental.CompactionStyle == CompactionStyleTiered {
+		if t := &o.Experimental.TieredCompaction; t.RunsPerLevel < 2 {
+			fmt.Fprintf(&buf, "TieredCompaction.RunsPerLevel (%d) must be >= 2\n", t.RunsPerLevel)
+		}
+		if o.Experimental.CompactionPicker != nil {
+			fmt.Fprintf(&buf, "CompactionPicker can't be used with CompactionStyleTiered\n")
+		}
+	}
+	if f := &o.Experimental.FIFOCompaction; o.Experimental.CompactionStyle == CompactionStyleFIFO &&
+		f.MaxSize == 0 && f.MaxAge == 0 {
+		fmt.Fprintf(&buf, "FIFOCompaction.MaxSize or FIFOCompaction.MaxAge must be set\n")
+	}
+	if w := &o.Experimental.WriteAdmission; w.Enabled && w.SlowdownFraction >= 1 {
+		fmt.Fprintf(&buf, "WriteAdmission.SlowdownFraction (%f) must be < 1\n", w.SlowdownFraction)
+	}
+	if w := &o.Experimental.WriteAdmission; w.Enabled && w.LowPrioritySlowdownFraction > w.SlowdownFraction {
+		fmt.Fprintf(&buf, "WriteAdmission.LowPrioritySlowdownFraction (%f) must be <= SlowdownFraction (%f)\n",
+			w.LowPrioritySlowdownFraction, w.SlowdownFraction)
+	}
 	if o.L0StopWritesThreshold < o.L0CompactionThreshold {
 		fmt.Fprintf(&buf, "L0StopWritesThreshold (%d) must be >= L0CompactionThreshold (%d)\n",
 			o.L0StopWritesThreshold, o.L0CompactionThreshold)
@@ -1985,6 +2337,7 @@ func (o *Options) Validate() error {
 		fmt.Fprintf(&buf, "FormatMajorVersion (%d) when CreateOnShared is set must be at least %d\n",
 			o.FormatMajorVersion, FormatMinForSharedObjects)
 	}
+	validateSecondaryIndexes(&buf, o.SecondaryIndexes)
 	if o.TableCache != nil && o.Cache != o.TableCache.cache {
 		fmt.Fprintf(&buf, "underlying cache in the TableCache and the Cache dont match\n")
 	}
@@ -291,6 +291,7 @@ func TestOptionsParse(t *testing.T) {
 			opts.Experimental.NumDeletionsThreshold = 500
 			opts.Experimental.DeletionSizeRatioThreshold = 0.7
 			opts.Experimental.TombstoneDenseCompactionThreshold = 0.2
+			opts.Experimental.PeriodicCompactionAge = 24 * time.Hour
 			opts.Experimental.TableCacheShards = 500
 			opts.Experimental.MaxWriterConcurrency = 1
 			opts.Experimental.ForceWriterParallelism = true
@@ -0,0 +1,152 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// ParallelScan scans the keys within bounds using up to n goroutines. It splits
+// bounds into up to n chunks holding roughly equal amounts of data, and calls
+// fn concurrently for each chunk with an unpositioned Iterator bounded to the
+// chunk. The amount of data within a key range is estimated from the sizes and
+// key bounds of the sstables in the current version, as by EstimateDiskUsage.
+// The chunks are disjoint and together span bounds, and fewer than n chunks
+// are used if the sstables offer too few distinct split points.
+//
+// All of the iterators read from a single consistent snapshot of the DB, taken
+// when ParallelScan is called. Each Iterator is closed when its call to fn
+// returns, and must not be used afterwards. A nil bounds.Start or bounds.End
+// leaves the scan unbounded on that side.
+//
+// ParallelScan returns once every call to fn has returned. If any call to fn
+// returns an error, or an Iterator encounters an error, the error of the first
+// chunk to fail is returned. A failing call to fn doesn't stop the others.
+func (d *DB) ParallelScan(
+	bounds KeyRange, n int, fn func(iter *Iterator, chunk KeyRange) error,
+) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if bounds.Start != nil && bounds.End != nil && d.cmp(bounds.Start, bounds.End) >= 0 {
+		return errors.New("pebble: invalid key range (start >= end)")
+	}
+
+	// Grab and reference the current readState, from which every chunk's
+	// iterator reads at the same sequence number.
+	readState := d.loadReadState()
+	defer readState.unref()
+	seqNum := d.mu.versions.visibleSeqNum.Load()
+
+	d.mu.Lock()
+	chunks := d.partitionKeyRangeLocked(readState.current, bounds, n)
+	d.mu.Unlock()
+
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	wg.Add(len(chunks))
+	for i := range chunks {
+		go func(i int) {
+			defer wg.Done()
+			iter := d.newIter(context.Background(), nil /* batch */, newIterOpts{
+				snapshot: snapshotIterOpts{seqNum: seqNum, readState: readState},
+			}, &IterOptions{LowerBound: chunks[i].Start, UpperBound: chunks[i].End})
+			errs[i] = firstError(fn(iter, chunks[i]), iter.Close())
+		}(i)
+	}
+	wg.Wait()
+
+	var err error
+	for i := range errs {
+		err = firstError(err, errs[i])
+	}
+	return err
+}
+
+// partitionKeyRangeLocked splits bounds into up to n disjoint chunks holding
+// roughly equal amounts of the version's data. The chunks are split at the
+// smallest keys of the version's sstables, choosing each split point by binary
+// search over the estimated size of the data preceding the candidates. DB.mu
+// must be held.
+func (d *DB) partitionKeyRangeLocked(v *version, bounds KeyRange, n int) []KeyRange {
+	if n <= 1 {
+		return []KeyRange{bounds}
+	}
+
+	// Collect the candidate split points, and the smallest and largest keys of
+	// the sstables within the bounds.
+	var candidates [][]byte
+	var smallest, largest []byte
+	for level := range v.Levels {
+		iter := v.Levels[level].Iter()
+		for f := iter.First(); f != nil; f = iter.Next() {
+			if (bounds.Start != nil && d.cmp(f.Largest.UserKey, bounds.Start) < 0) ||
+				(bounds.End != nil && d.cmp(f.Smallest.UserKey, bounds.End) >= 0) {
+				continue
+			}
+			if smallest == nil || d.cmp(f.Smallest.UserKey, smallest) < 0 {
+				smallest = f.Smallest.UserKey
+			}
+			if largest == nil || d.cmp(f.Largest.UserKey, largest) > 0 {
+				largest = f.Largest.UserKey
+			}
+			if bounds.Start == nil || d.cmp(f.Smallest.UserKey, bounds.Start) > 0 {
+				candidates = append(candidates, f.Smallest.UserKey)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return []KeyRange{bounds}
+	}
+	lower := bounds.Start
+	if lower == nil || d.cmp(smallest, lower) > 0 {
+		lower = smallest
+	}
+	slices.SortFunc(candidates, d.cmp)
+	candidates = slices.CompactFunc(candidates, func(a, b []byte) bool { return d.equal(a, b) })
+	// A split at the smallest key would leave no data in the first chunk.
+	for len(candidates) > 0 && d.cmp(candidates[0], lower) <= 0 {
+		candidates = candidates[1:]
+	}
+	// sizeBefore returns the estimated size of the data in [lower, key).
+	sizeBefore := func(key []byte) uint64 {
+		return *d.mu.annotators.totalSize.VersionRangeAnnotation(v, base.UserKeyBoundsEndExclusive(lower, key))
+	}
+	var total uint64
+	if bounds.End != nil {
+		total = sizeBefore(bounds.End)
+	} else {
+		total = *d.mu.annotators.totalSize.VersionRangeAnnotation(v, base.UserKeyBoundsInclusive(lower, largest))
+	}
+
+	chunks := make([]KeyRange, 0, n)
+	start := bounds.Start
+	next := 0
+	for i := 1; i < n && next < len(candidates); i++ {
+		target := total * uint64(i) / uint64(n)
+		j := next + sort.Search(len(candidates)-next, func(j int) bool {
+			return sizeBefore(candidates[next+j]) >= target
+		})
+		// Split at whichever of the candidates surrounding the target is
+		// closer to it.
+		if j > next && (j == len(candidates) ||
+			target-sizeBefore(candidates[j-1]) < sizeBefore(candidates[j])-target) {
+			j--
+		}
+		if j == len(candidates) {
+			break
+		}
+		chunks = append(chunks, KeyRange{Start: start, End: candidates[j]})
+		start = candidates[j]
+		next = j + 1
+	}
+	return append(chunks, KeyRange{Start: start, End: bounds.End})
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelScan(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem(), DisableAutomaticCompactions: true})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Write keys into 20 sstables, and one more into the memtable.
+	key := func(i int) []byte { return []byte(fmt.Sprintf("%05d", i)) }
+	var want []string
+	for i := 0; i < 4000; i++ {
+		require.NoError(t, d.Set(key(i), bytes.Repeat([]byte("v"), 100), nil))
+		want = append(want, string(key(i)))
+		if i%200 == 199 {
+			require.NoError(t, d.Flush())
+		}
+	}
+	require.Equal(t, int64(20), d.Metrics().Levels[0].NumFiles)
+	require.NoError(t, d.Set(key(4000), nil, nil))
+	want = append(want, string(key(4000)))
+
+	// scan runs a parallel scan, returning the chunks and the keys of each.
+	scan := func(bounds KeyRange, n int) ([]KeyRange, [][]string) {
+		var mu sync.Mutex
+		var chunks []KeyRange
+		var keys [][]string
+		require.NoError(t, d.ParallelScan(bounds, n, func(iter *Iterator, chunk KeyRange) error {
+			var k []string
+			for valid := iter.First(); valid; valid = iter.Next() {
+				k = append(k, string(iter.Key()))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			chunks = append(chunks, chunk)
+			keys = append(keys, k)
+			return nil
+		}))
+		// Order the chunks by their start keys.
+		idx := make([]int, len(chunks))
+		for i := range idx {
+			idx[i] = i
+		}
+		slices.SortFunc(idx, func(a, b int) int { return bytes.Compare(chunks[a].Start, chunks[b].Start) })
+		sortedChunks := make([]KeyRange, len(chunks))
+		sortedKeys := make([][]string, len(chunks))
+		for i, j := range idx {
+			sortedChunks[i], sortedKeys[i] = chunks[j], keys[j]
+		}
+		return sortedChunks, sortedKeys
+	}
+
+	// The chunks are contiguous, span the bounds and hold roughly equal
+	// numbers of keys.
+	for _, tc := range []struct {
+		bounds KeyRange
+		want   []string
+	}{
+		{bounds: KeyRange{}, want: want},
+		{bounds: KeyRange{Start: key(1000), End: key(3000)}, want: want[1000:3000]},
+		{bounds: KeyRange{Start: key(2100)}, want: want[2100:]},
+	} {
+		chunks, keys := scan(tc.bounds, 4)
+		require.Len(t, chunks, 4)
+		require.Equal(t, tc.bounds.Start, chunks[0].Start)
+		require.Equal(t, tc.bounds.End, chunks[len(chunks)-1].End)
+		var got []string
+		for i := range chunks {
+			if i > 0 {
+				require.Equal(t, chunks[i-1].End, chunks[i].Start)
+			}
+			require.InDelta(t, len(tc.want)/4, len(keys[i]), float64(len(tc.want))/8, "chunk %d", i)
+			got = append(got, keys[i]...)
+		}
+		require.Equal(t, tc.want, got)
+	}
+
+	// A single chunk spans the bounds.
+	chunks, keys := scan(KeyRange{Start: key(10), End: key(20)}, 1)
+	require.Equal(t, []KeyRange{{Start: key(10), End: key(20)}}, chunks)
+	require.Equal(t, want[10:20], keys[0])
+
+	// Every chunk reads from the snapshot taken when the scan began, even if
+	// the DB is written to while the scan is in progress.
+	var once sync.Once
+	require.NoError(t, d.ParallelScan(KeyRange{}, 4, func(iter *Iterator, chunk KeyRange) error {
+		once.Do(func() {
+			require.NoError(t, d.Set(key(5000), nil, nil))
+			require.NoError(t, d.Delete(key(0), nil))
+		})
+		for valid := iter.First(); valid; valid = iter.Next() {
+			if bytes.Equal(iter.Key(), key(5000)) {
+				return errors.New("observed a key written during the scan")
+			}
+		}
+		if chunk.Start == nil && (!iter.First() || !bytes.Equal(iter.Key(), key(0))) {
+			return errors.New("missing a key deleted during the scan")
+		}
+		return nil
+	}))
+
+	// The error of a failed chunk is returned.
+	err = d.ParallelScan(KeyRange{}, 4, func(iter *Iterator, chunk KeyRange) error {
+		if chunk.Start == nil {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	require.EqualError(t, err, "boom")
+	require.Error(t, d.ParallelScan(KeyRange{Start: key(2), End: key(1)}, 4, nil))
+}
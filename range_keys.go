@@ -109,6 +109,21 @@ func (i *Iterator) constructRangeKeyIter() {
 			if current.RangeKeyLevels[level].Empty() {
 				continue
 			}
+			// As with L0, a level holding multiple sorted runs gets a level
+			// iterator for each run containing range keys, from newest to oldest.
+			if runs := current.Runs[level]; runs != nil {
+				for run := len(runs) - 1; run >= 0; run-- {
+					iter := runs[run].Iter()
+					if !containsAnyRangeKeys(iter) {
+						continue
+					}
+					li := i.rangeKey.iterConfig.NewLevelIter()
+					li.Init(i.ctx, i.opts.SpanIterOptions(), i.cmp, i.newIterRangeKey,
+						iter.Filter(manifest.KeyTypeRange), manifest.LevelRun(level, run), manifest.KeyTypeRange)
+					i.rangeKey.iterConfig.AddLevel(li)
+				}
+				continue
+			}
 			li := i.rangeKey.iterConfig.NewLevelIter()
 			spanIterOpts := i.opts.SpanIterOptions()
 			li.Init(i.ctx, spanIterOpts, i.cmp, i.newIterRangeKey, current.RangeKeyLevels[level].Iter(),
@@ -0,0 +1,125 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeMerge(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                 vfs.NewMem(),
+		Merger:             CounterMerger,
+		FormatMajorVersion: FormatRangeMerges,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	counter := func(n int64) []byte { return EncodeCounter(n) }
+	get := func(r Reader, key string) string {
+		v, closer, err := r.Get([]byte(key))
+		if errors.Is(err, ErrNotFound) {
+			return "<missing>"
+		}
+		require.NoError(t, err)
+		defer closer.Close()
+		n, err := DecodeCounter(v)
+		require.NoError(t, err)
+		return fmt.Sprint(n)
+	}
+	scan := func(r Reader) string {
+		iter, err := r.NewIter(nil)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, iter.Close()) }()
+		var fwd, rev []string
+		for valid := iter.First(); valid; valid = iter.Next() {
+			n, err := DecodeCounter(iter.Value())
+			require.NoError(t, err)
+			fwd = append(fwd, fmt.Sprintf("%s=%d", iter.Key(), n))
+		}
+		for valid := iter.Last(); valid; valid = iter.Prev() {
+			n, err := DecodeCounter(iter.Value())
+			require.NoError(t, err)
+			rev = append([]string{fmt.Sprintf("%s=%d", iter.Key(), n)}, rev...)
+		}
+		require.Equal(t, fwd, rev)
+		return strings.Join(fwd, " ")
+	}
+	check := func(want string) {
+		t.Helper()
+		require.Equal(t, want, scan(d))
+		for _, kv := range strings.Fields(want) {
+			k, v, _ := strings.Cut(kv, "=")
+			require.Equal(t, v, get(d, k), "key %s", k)
+		}
+	}
+
+	// Write the base values and move them to the bottom of the LSM.
+	require.NoError(t, d.Set([]byte("a"), counter(1), nil))
+	require.NoError(t, d.Set([]byte("b"), counter(2), nil))
+	require.NoError(t, d.Set([]byte("d"), counter(3), nil))
+	require.NoError(t, d.Set([]byte("f"), counter(4), nil))
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false))
+
+	snap := d.NewSnapshot()
+	defer func() { require.NoError(t, snap.Close()) }()
+
+	// The range merge applies to the keys live within [a,e) only: d is deleted
+	// before the range merge and c is written after it.
+	require.NoError(t, d.Delete([]byte("d"), nil))
+	require.NoError(t, d.RangeMerge([]byte("a"), []byte("e"), counter(10), nil))
+	require.NoError(t, d.Set([]byte("c"), counter(7), nil))
+	require.NoError(t, d.Merge([]byte("b"), counter(100), nil))
+	const want = "a=11 b=112 c=7 f=4"
+	check(want)
+	require.Equal(t, "a=1 b=2 d=3 f=4", scan(snap))
+	require.Equal(t, "<missing>", get(d, "d"))
+
+	// Flushing writes the range merge to an L0 table without folding it.
+	require.NoError(t, d.Flush())
+	check(want)
+	require.Equal(t, "a=1 b=2 d=3 f=4", scan(snap))
+
+	// A later range merge stacks with the first.
+	require.NoError(t, d.RangeMerge([]byte("b"), []byte("z"), counter(1000), nil))
+	check("a=11 b=1112 c=1007 f=1004")
+
+	// Once no older data remains beneath, compactions fold range merges into
+	// the keys they cover.
+	require.NoError(t, snap.Close())
+	snap = d.NewSnapshot()
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false))
+	check("a=11 b=1112 c=1007 f=1004")
+	m := d.Metrics()
+	require.Zero(t, m.Levels[0].NumFiles)
+	d.mu.Lock()
+	for _, level := range d.mu.versions.currentVersion().RangeMergeLevels {
+		require.True(t, level.Empty())
+	}
+	d.mu.Unlock()
+}
+
+func TestRangeMergeFormatMajorVersion(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                 vfs.NewMem(),
+		Merger:             CounterMerger,
+		FormatMajorVersion: FormatNewest,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Committing a batch that requires a newer format major version panics.
+	require.Panics(t, func() {
+		_ = d.RangeMerge([]byte("a"), []byte("b"), EncodeCounter(1), nil)
+	})
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatRangeMerges))
+	require.NoError(t, d.RangeMerge([]byte("a"), []byte("b"), EncodeCounter(1), nil))
+}
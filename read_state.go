@@ -91,6 +91,10 @@ func (d *DB) updateReadStateLocked(checker func(*DB) error) {
 		mem.readerRef()
 	}
 
+	if d.writeAdmission != nil {
+		d.updateWriteAdmissionLocked()
+	}
+
 	d.readState.Lock()
 	old := d.readState.val
 	d.readState.val = s
@@ -0,0 +1,409 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package replay
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/record"
+)
+
+// readsFilename is the name of the file within the workload directory holding
+// the captured read operations, if read capture was enabled.
+const readsFilename = "READS"
+
+// ReadOpKind identifies the kind of a captured read operation.
+type ReadOpKind uint8
+
+const (
+	// ReadOpGet is a point lookup performed through Get.
+	ReadOpGet ReadOpKind = iota + 1
+	// ReadOpSeekGE is an iterator SeekGE followed by Steps calls to Next.
+	ReadOpSeekGE
+	// ReadOpSeekPrefixGE is an iterator SeekPrefixGE followed by Steps calls
+	// to Next.
+	ReadOpSeekPrefixGE
+	// ReadOpSeekLT is an iterator SeekLT followed by Steps calls to Prev.
+	ReadOpSeekLT
+	// ReadOpFirst is an iterator First followed by Steps calls to Next.
+	ReadOpFirst
+	// ReadOpLast is an iterator Last followed by Steps calls to Prev.
+	ReadOpLast
+)
+
+// String implements fmt.Stringer.
+func (k ReadOpKind) String() string {
+	switch k {
+	case ReadOpGet:
+		return "get"
+	case ReadOpSeekGE:
+		return "seek-ge"
+	case ReadOpSeekPrefixGE:
+		return "seek-prefix-ge"
+	case ReadOpSeekLT:
+		return "seek-lt"
+	case ReadOpFirst:
+		return "first"
+	case ReadOpLast:
+		return "last"
+	default:
+		return "unknown"
+	}
+}
+
+// reverse returns true if the steps of the operation move the iterator
+// backwards.
+func (k ReadOpKind) reverse() bool {
+	return k == ReadOpSeekLT || k == ReadOpLast
+}
+
+// A ReadOp describes a single captured read operation. Iterator operations are
+// captured as a positioning operation followed by a number of steps in the
+// same direction; a change of direction or a new positioning operation begins
+// a new ReadOp.
+type ReadOp struct {
+	Kind ReadOpKind
+	// Start is the time at which the operation began, relative to the start of
+	// the workload capture.
+	Start time.Duration
+	// Duration is the time spent within Pebble performing the operation.
+	Duration time.Duration
+	// Key is the key passed to Get or to the seek. It's nil for First and Last.
+	Key []byte
+	// LowerBound, UpperBound and KeyTypes are the iterator options in effect
+	// for iterator operations.
+	LowerBound []byte
+	UpperBound []byte
+	KeyTypes   pebble.IterKeyType
+	// Steps is the number of Next (or Prev, for reverse operations) calls
+	// performed after positioning the iterator.
+	Steps int
+}
+
+func (op *ReadOp) encode(buf []byte) []byte {
+	appendBytes := func(buf, b []byte) []byte {
+		if b == nil {
+			return binary.AppendUvarint(buf, 0)
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(b))+1)
+		return append(buf, b...)
+	}
+	buf = append(buf, byte(op.Kind), byte(op.KeyTypes))
+	buf = binary.AppendUvarint(buf, uint64(op.Start))
+	buf = binary.AppendUvarint(buf, uint64(op.Duration))
+	buf = binary.AppendUvarint(buf, uint64(op.Steps))
+	buf = appendBytes(buf, op.Key)
+	buf = appendBytes(buf, op.LowerBound)
+	buf = appendBytes(buf, op.UpperBound)
+	return buf
+}
+
+var errCorruptReadOp = errors.New("replay: corrupt read operation")
+
+func (op *ReadOp) decode(buf []byte) error {
+	readUvarint := func() (uint64, error) {
+		v, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return 0, errCorruptReadOp
+		}
+		buf = buf[n:]
+		return v, nil
+	}
+	readBytes := func() ([]byte, error) {
+		n, err := readUvarint()
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		if uint64(len(buf)) < n-1 {
+			return nil, errCorruptReadOp
+		}
+		b := slices.Clone(buf[:n-1])
+		buf = buf[n-1:]
+		return b, nil
+	}
+	if len(buf) < 2 {
+		return errCorruptReadOp
+	}
+	*op = ReadOp{Kind: ReadOpKind(buf[0]), KeyTypes: pebble.IterKeyType(buf[1])}
+	buf = buf[2:]
+	var vals [3]uint64
+	for i := range vals {
+		v, err := readUvarint()
+		if err != nil {
+			return err
+		}
+		vals[i] = v
+	}
+	op.Start, op.Duration, op.Steps = time.Duration(vals[0]), time.Duration(vals[1]), int(vals[2])
+	for _, b := range []*[]byte{&op.Key, &op.LowerBound, &op.UpperBound} {
+		v, err := readBytes()
+		if err != nil {
+			return err
+		}
+		*b = v
+	}
+	return nil
+}
+
+// ReadOpReader reads captured read operations from a workload's READS file.
+type ReadOpReader struct {
+	r *record.Reader
+}
+
+// NewReadOpReader returns a ReadOpReader reading from r.
+func NewReadOpReader(r io.Reader) *ReadOpReader {
+	return &ReadOpReader{r: record.NewReader(r, 0 /* logNum */)}
+}
+
+// Next returns the next captured read operation. It returns io.EOF once all
+// operations have been read.
+func (r *ReadOpReader) Next() (ReadOp, error) {
+	rr, err := r.r.Next()
+	if err != nil {
+		return ReadOp{}, err
+	}
+	b, err := io.ReadAll(rr)
+	if err != nil {
+		return ReadOp{}, err
+	}
+	var op ReadOp
+	err = op.decode(b)
+	return op, err
+}
+
+// CapturingReader wraps a pebble.Reader (eg, a DB, Snapshot or indexed Batch),
+// recording the reads performed through it in the WorkloadCollector's
+// captured workload. Reads are only recorded while the collector is running
+// and read capture is enabled (see WorkloadCollector.EnableReadCapture).
+type CapturingReader struct {
+	reader    pebble.Reader
+	collector *WorkloadCollector
+}
+
+// WrapReader returns a CapturingReader that records the reads performed
+// through r.
+func (w *WorkloadCollector) WrapReader(r pebble.Reader) *CapturingReader {
+	return &CapturingReader{reader: r, collector: w}
+}
+
+// Get implements the same contract as pebble.Reader.Get.
+func (r *CapturingReader) Get(key []byte) ([]byte, io.Closer, error) {
+	if !r.collector.capturingReads() {
+		return r.reader.Get(key)
+	}
+	start := time.Now()
+	v, closer, err := r.reader.Get(key)
+	r.collector.recordRead(ReadOp{
+		Kind:     ReadOpGet,
+		Key:      key,
+		Duration: time.Since(start),
+	}, start)
+	return v, closer, err
+}
+
+// NewIter implements the same contract as pebble.Reader.NewIter, returning an
+// iterator that records the operations performed on it.
+func (r *CapturingReader) NewIter(o *pebble.IterOptions) (*CapturingIterator, error) {
+	return r.NewIterWithContext(context.Background(), o)
+}
+
+// NewIterWithContext implements the same contract as
+// pebble.Reader.NewIterWithContext, returning an iterator that records the
+// operations performed on it.
+func (r *CapturingReader) NewIterWithContext(
+	ctx context.Context, o *pebble.IterOptions,
+) (*CapturingIterator, error) {
+	iter, err := r.reader.NewIterWithContext(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	ci := &CapturingIterator{Iterator: iter, collector: r.collector}
+	if o != nil {
+		ci.lower = slices.Clone(o.LowerBound)
+		ci.upper = slices.Clone(o.UpperBound)
+		ci.keyTypes = o.KeyTypes
+	}
+	return ci, nil
+}
+
+// Close closes the wrapped reader.
+func (r *CapturingReader) Close() error {
+	return r.reader.Close()
+}
+
+// CapturingIterator wraps a *pebble.Iterator, recording positioning operations
+// and the steps that follow them. All of the iterator's positioning and
+// stepping methods are wrapped; the remaining methods (eg, Key, Value) are
+// passed through to the wrapped iterator.
+type CapturingIterator struct {
+	*pebble.Iterator
+	collector *WorkloadCollector
+	lower     []byte
+	upper     []byte
+	keyTypes  pebble.IterKeyType
+
+	// op is the operation currently being accumulated, if op.Kind != 0.
+	op        ReadOp
+	startTime time.Time
+}
+
+// finishOp records the operation currently being accumulated, if any.
+func (i *CapturingIterator) finishOp() {
+	if i.op.Kind != 0 {
+		i.collector.recordRead(i.op, i.startTime)
+		i.op = ReadOp{}
+	}
+}
+
+// position records a new positioning operation of the given kind, executing
+// fn to perform it.
+func (i *CapturingIterator) position(kind ReadOpKind, key []byte, fn func() bool) bool {
+	i.finishOp()
+	if !i.collector.capturingReads() {
+		return fn()
+	}
+	i.startTime = time.Now()
+	valid := fn()
+	i.op = ReadOp{
+		Kind:       kind,
+		Key:        slices.Clone(key),
+		LowerBound: i.lower,
+		UpperBound: i.upper,
+		KeyTypes:   i.keyTypes,
+		Duration:   time.Since(i.startTime),
+	}
+	return valid
+}
+
+// step records a step in the provided direction, executing fn to perform it.
+func (i *CapturingIterator) step(reverse bool, fn func() bool) bool {
+	if i.op.Kind == 0 || i.op.Kind.reverse() != reverse {
+		// A step without a preceding positioning operation, or a change of
+		// direction; there's no corresponding operation to attribute it to.
+		i.finishOp()
+		return fn()
+	}
+	start := time.Now()
+	valid := fn()
+	i.op.Duration += time.Since(start)
+	i.op.Steps++
+	return valid
+}
+
+// SeekGE implements the same contract as pebble.Iterator.SeekGE.
+func (i *CapturingIterator) SeekGE(key []byte) bool {
+	return i.position(ReadOpSeekGE, key, func() bool { return i.Iterator.SeekGE(key) })
+}
+
+// SeekPrefixGE implements the same contract as pebble.Iterator.SeekPrefixGE.
+func (i *CapturingIterator) SeekPrefixGE(key []byte) bool {
+	return i.position(ReadOpSeekPrefixGE, key, func() bool { return i.Iterator.SeekPrefixGE(key) })
+}
+
+// SeekLT implements the same contract as pebble.Iterator.SeekLT.
+func (i *CapturingIterator) SeekLT(key []byte) bool {
+	return i.position(ReadOpSeekLT, key, func() bool { return i.Iterator.SeekLT(key) })
+}
+
+// First implements the same contract as pebble.Iterator.First.
+func (i *CapturingIterator) First() bool {
+	return i.position(ReadOpFirst, nil, i.Iterator.First)
+}
+
+// Last implements the same contract as pebble.Iterator.Last.
+func (i *CapturingIterator) Last() bool {
+	return i.position(ReadOpLast, nil, i.Iterator.Last)
+}
+
+// Next implements the same contract as pebble.Iterator.Next.
+func (i *CapturingIterator) Next() bool {
+	return i.step(false /* reverse */, i.Iterator.Next)
+}
+
+// Prev implements the same contract as pebble.Iterator.Prev.
+func (i *CapturingIterator) Prev() bool {
+	return i.step(true /* reverse */, i.Iterator.Prev)
+}
+
+// NextPrefix implements the same contract as pebble.Iterator.NextPrefix. It's
+// recorded as a step of the current operation.
+func (i *CapturingIterator) NextPrefix() bool {
+	return i.step(false /* reverse */, i.Iterator.NextPrefix)
+}
+
+// SeekGEWithLimit implements the same contract as
+// pebble.Iterator.SeekGEWithLimit. It's recorded as a SeekGE; the limit is not
+// recorded.
+func (i *CapturingIterator) SeekGEWithLimit(key, limit []byte) pebble.IterValidityState {
+	var state pebble.IterValidityState
+	i.position(ReadOpSeekGE, key, func() bool {
+		state = i.Iterator.SeekGEWithLimit(key, limit)
+		return state == pebble.IterValid
+	})
+	return state
+}
+
+// SeekLTWithLimit implements the same contract as
+// pebble.Iterator.SeekLTWithLimit. It's recorded as a SeekLT; the limit is not
+// recorded.
+func (i *CapturingIterator) SeekLTWithLimit(key, limit []byte) pebble.IterValidityState {
+	var state pebble.IterValidityState
+	i.position(ReadOpSeekLT, key, func() bool {
+		state = i.Iterator.SeekLTWithLimit(key, limit)
+		return state == pebble.IterValid
+	})
+	return state
+}
+
+// NextWithLimit implements the same contract as pebble.Iterator.NextWithLimit.
+// It's recorded as a step of the current operation.
+func (i *CapturingIterator) NextWithLimit(limit []byte) pebble.IterValidityState {
+	var state pebble.IterValidityState
+	i.step(false /* reverse */, func() bool {
+		state = i.Iterator.NextWithLimit(limit)
+		return state == pebble.IterValid
+	})
+	return state
+}
+
+// PrevWithLimit implements the same contract as pebble.Iterator.PrevWithLimit.
+// It's recorded as a step of the current operation.
+func (i *CapturingIterator) PrevWithLimit(limit []byte) pebble.IterValidityState {
+	var state pebble.IterValidityState
+	i.step(true /* reverse */, func() bool {
+		state = i.Iterator.PrevWithLimit(limit)
+		return state == pebble.IterValid
+	})
+	return state
+}
+
+// SetBounds implements the same contract as pebble.Iterator.SetBounds.
+func (i *CapturingIterator) SetBounds(lower, upper []byte) {
+	i.finishOp()
+	i.lower = slices.Clone(lower)
+	i.upper = slices.Clone(upper)
+	i.Iterator.SetBounds(lower, upper)
+}
+
+// SetOptions implements the same contract as pebble.Iterator.SetOptions.
+func (i *CapturingIterator) SetOptions(o *pebble.IterOptions) {
+	i.finishOp()
+	i.lower = slices.Clone(o.LowerBound)
+	i.upper = slices.Clone(o.UpperBound)
+	i.keyTypes = o.KeyTypes
+	i.Iterator.SetOptions(o)
+}
+
+// Close implements the same contract as pebble.Iterator.Close.
+func (i *CapturingIterator) Close() error {
+	i.finishOp()
+	return i.Iterator.Close()
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOpEncodeDecode(t *testing.T) {
+	ops := []ReadOp{
+		{Kind: ReadOpGet, Start: time.Second, Duration: time.Millisecond, Key: []byte("foo")},
+		{Kind: ReadOpSeekGE, Key: []byte("a"), LowerBound: []byte("a"), UpperBound: []byte("z"), Steps: 10},
+		{Kind: ReadOpLast, KeyTypes: pebble.IterKeyTypePointsAndRanges, LowerBound: []byte{}, Steps: 3},
+	}
+	for _, op := range ops {
+		var decoded ReadOp
+		require.NoError(t, decoded.decode(op.encode(nil)))
+		require.Equal(t, op, decoded)
+	}
+	var decoded ReadOp
+	require.ErrorIs(t, decoded.decode([]byte{1}), errCorruptReadOp)
+}
+
+func TestReadCapture(t *testing.T) {
+	const srcDir, destDir = "src", "dst"
+	fs := vfs.NewMem()
+	require.NoError(t, fs.MkdirAll(destDir, 0755))
+	c := NewWorkloadCollector(srcDir)
+	c.EnableReadCapture()
+	opts := &pebble.Options{FS: fs}
+	c.Attach(opts)
+	d, err := pebble.Open(srcDir, opts)
+	require.NoError(t, err)
+	for _, k := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, d.Set([]byte(k), []byte(k), nil))
+	}
+
+	r := c.WrapReader(d)
+	// Reads before the collector is started are not captured.
+	_, closer, err := r.Get([]byte("a"))
+	require.NoError(t, err)
+	require.NoError(t, closer.Close())
+
+	c.Start(fs, destDir)
+	_, closer, err = r.Get([]byte("b"))
+	require.NoError(t, err)
+	require.NoError(t, closer.Close())
+	iter, err := r.NewIter(&pebble.IterOptions{UpperBound: []byte("d")})
+	require.NoError(t, err)
+	for valid := iter.SeekGE([]byte("a")); valid; valid = iter.Next() {
+	}
+	for valid := iter.Last(); valid; valid = iter.Prev() {
+	}
+	iter.SetOptions(&pebble.IterOptions{LowerBound: []byte("b")})
+	for state := iter.SeekGEWithLimit([]byte("a"), nil); state == pebble.IterValid; state = iter.NextWithLimit(nil) {
+	}
+	for valid := iter.SeekLT([]byte("c")); valid; valid = iter.Prev() {
+	}
+	for valid := iter.First(); valid; valid = iter.NextPrefix() {
+	}
+	require.NoError(t, iter.Close())
+	require.NoError(t, c.StopErr())
+	require.NoError(t, d.Close())
+
+	f, err := fs.Open(fs.PathJoin(destDir, readsFilename))
+	require.NoError(t, err)
+	defer f.Close()
+	var buf strings.Builder
+	rr := NewReadOpReader(f)
+	for {
+		op, err := rr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		fmt.Fprintf(&buf, "%s key=%q lower=%q upper=%q steps=%d\n",
+			op.Kind, op.Key, op.LowerBound, op.UpperBound, op.Steps)
+	}
+	require.Equal(t, `get key="b" lower="" upper="" steps=0
+seek-ge key="a" lower="" upper="d" steps=3
+last key="" lower="" upper="d" steps=3
+seek-ge key="a" lower="b" upper="" steps=3
+seek-lt key="c" lower="b" upper="" steps=1
+first key="" lower="b" upper="" steps=3
+`, buf.String())
+
+	// Replay the captured reads against the database.
+	d, err = pebble.Open(srcDir, &pebble.Options{FS: fs})
+	require.NoError(t, err)
+	defer d.Close()
+	runner := &Runner{WorkloadFS: fs, ReadConcurrency: 2, d: d}
+	runner.readMetrics.latency = newReadLatencyHistogram()
+	require.NoError(t, runner.replayReads(context.Background(), fs.PathJoin(destDir, readsFilename), time.Now()))
+	require.Equal(t, int64(6), runner.readMetrics.count)
+}
+
+func TestReadCaptureError(t *testing.T) {
+	fs := vfs.NewMem()
+	c := NewWorkloadCollector("src")
+	c.EnableReadCapture()
+	c.Attach(&pebble.Options{FS: fs})
+	// The destination directory doesn't exist, so creating the READS file
+	// fails. The error is returned from StopErr.
+	c.Start(fs, "missing")
+	require.False(t, c.capturingReads())
+	require.Error(t, c.StopErr())
+}
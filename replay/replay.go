@@ -7,7 +7,8 @@
 // with the corresponding manifests describing the order and grouping with which
 // they were applied. Replaying a workload flushes and ingests the same keys and
 // sstables to reproduce the write workload for the purpose of evaluating
-// compaction heuristics.
+// compaction heuristics. A workload may also include captured read operations,
+// which are replayed concurrently with the write workload.
 package replay
 
 import (
@@ -22,6 +23,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble"
 	"github.com/cockroachdb/pebble/internal/base"
@@ -110,7 +112,15 @@ type Metrics struct {
 	}
 	EstimatedDebt SampledMetric
 	Final         *pebble.Metrics
-	Ingest        struct {
+	// Reads holds statistics on the replayed read operations. It's zero if the
+	// workload contains no captured reads or Runner.ReadConcurrency is zero.
+	Reads struct {
+		Count int64
+		// Latency is a histogram of the latency of replayed read operations,
+		// in nanoseconds. It's nil if reads were not replayed.
+		Latency *hdrhistogram.Histogram
+	}
+	Ingest struct {
 		BytesIntoL0 uint64
 		// BytesWeightedByLevel is calculated as the number of bytes ingested
 		// into a level multiplied by the level's distance from the bottommost
@@ -233,6 +243,23 @@ func (m *Metrics) WriteBenchmarkString(name string, w io.Writer) error {
 		}},
 	}
 
+	if m.Reads.Count > 0 {
+		groups = append(groups, benchmarkSection{
+			label: "Reads",
+			values: []benchfmt.Value{
+				{Value: float64(m.Reads.Count), Unit: "reads"},
+			},
+		})
+		for _, q := range []float64{50, 99} {
+			groups = append(groups, benchmarkSection{
+				label: fmt.Sprintf("ReadLatency/p%d", int(q)),
+				values: []benchfmt.Value{
+					{Value: time.Duration(m.Reads.Latency.ValueAtQuantile(q)).Seconds(), Unit: "sec/op"},
+				},
+			})
+		}
+	}
+
 	for _, reason := range []string{"L0", "memtable"} {
 		groups = append(groups, benchmarkSection{
 			label: fmt.Sprintf("WriteStall/%s", reason),
@@ -266,6 +293,13 @@ type Runner struct {
 	Pacer         Pacer
 	Opts          *pebble.Options
 	MaxWriteBytes uint64
+	// ReadConcurrency is the number of goroutines used to replay the read
+	// operations captured in the workload (see
+	// WorkloadCollector.EnableReadCapture). Each read is issued at the same
+	// offset from the beginning of the replay as it was captured, concurrently
+	// with the write workload. If zero, or if the workload contains no
+	// captured reads, no reads are replayed.
+	ReadConcurrency int
 
 	// Internal state.
 
@@ -298,6 +332,11 @@ type Runner struct {
 		countByReason    map[string]int
 		durationByReason map[string]time.Duration
 	}
+	readMetrics struct {
+		sync.Mutex
+		count   int64
+		latency *hdrhistogram.Histogram
+	}
 	// compactionMu holds state for tracking the number of compactions
 	// started and completed and waking waiting goroutines when a new compaction
 	// completes. See nextCompactionCompletes.
@@ -374,9 +413,137 @@ func (r *Runner) Run(ctx context.Context) error {
 	r.errgroup.Go(func() error { return r.prepareWorkloadSteps(ctx) })
 	r.errgroup.Go(func() error { return r.applyWorkloadSteps(ctx) })
 	r.errgroup.Go(func() error { return r.refreshMetrics(ctx) })
+	if r.ReadConcurrency > 0 {
+		readsPath := r.WorkloadFS.PathJoin(r.WorkloadPath, readsFilename)
+		if _, err := r.WorkloadFS.Stat(readsPath); err == nil {
+			startAt := time.Now()
+			r.readMetrics.latency = newReadLatencyHistogram()
+			r.errgroup.Go(func() error { return r.replayReads(ctx, readsPath, startAt) })
+		}
+	}
 	return nil
 }
 
+// The range of read latencies recorded in Metrics.Reads.Latency. Latencies
+// outside the range are clamped.
+const (
+	minReadLatency = time.Microsecond
+	maxReadLatency = 10 * time.Second
+)
+
+func newReadLatencyHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(minReadLatency.Nanoseconds(), maxReadLatency.Nanoseconds(), 3)
+}
+
+// replayReads runs in its own goroutine, reading the captured read operations
+// from the file at path and replaying them against the test database using
+// r.ReadConcurrency goroutines. Each operation is issued no earlier than its
+// captured offset from the start of the workload capture, measured relative to
+// startAt.
+func (r *Runner) replayReads(ctx context.Context, path string, startAt time.Time) error {
+	f, err := r.WorkloadFS.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ops := make(chan ReadOp, r.ReadConcurrency)
+	g, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < r.ReadConcurrency; i++ {
+		g.Go(func() error {
+			for op := range ops {
+				start := time.Now()
+				if err := r.replayRead(op); err != nil {
+					return err
+				}
+				r.recordReadLatency(time.Since(start))
+			}
+			return nil
+		})
+	}
+	g.Go(func() error {
+		defer close(ops)
+		rr := NewReadOpReader(f)
+		for {
+			op, err := rr.Next()
+			if err == io.EOF || record.IsInvalidRecord(err) {
+				// The end of the captured reads; an invalid record may be
+				// a torn write at the tail of the file.
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if d := op.Start - time.Since(startAt); d > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(d):
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ops <- op:
+			}
+		}
+	})
+	return g.Wait()
+}
+
+// replayRead performs the provided read operation against the test database.
+func (r *Runner) replayRead(op ReadOp) error {
+	if op.Kind == ReadOpGet {
+		_, closer, err := r.d.Get(op.Key)
+		if errors.Is(err, pebble.ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return closer.Close()
+	}
+
+	iter, err := r.d.NewIter(&pebble.IterOptions{
+		LowerBound: op.LowerBound,
+		UpperBound: op.UpperBound,
+		KeyTypes:   op.KeyTypes,
+	})
+	if err != nil {
+		return err
+	}
+	var valid bool
+	switch op.Kind {
+	case ReadOpSeekGE:
+		valid = iter.SeekGE(op.Key)
+	case ReadOpSeekPrefixGE:
+		valid = iter.SeekPrefixGE(op.Key)
+	case ReadOpSeekLT:
+		valid = iter.SeekLT(op.Key)
+	case ReadOpFirst:
+		valid = iter.First()
+	case ReadOpLast:
+		valid = iter.Last()
+	default:
+		_ = iter.Close()
+		return errors.Newf("replay: unknown read operation kind %d", op.Kind)
+	}
+	for i := 0; i < op.Steps && valid; i++ {
+		if op.Kind.reverse() {
+			valid = iter.Prev()
+		} else {
+			valid = iter.Next()
+		}
+	}
+	return iter.Close()
+}
+
+func (r *Runner) recordReadLatency(d time.Duration) {
+	r.readMetrics.Lock()
+	defer r.readMetrics.Unlock()
+	r.readMetrics.count++
+	d = min(max(d, minReadLatency), maxReadLatency)
+	_ = r.readMetrics.latency.RecordValue(d.Nanoseconds())
+}
+
 // refreshMetrics runs in its own goroutine, collecting metrics from the Pebble
 // instance whenever a) a workload step completes, or b) a compaction completes.
 // The Pacer implementations that pace based on read-amplification rely on these
@@ -543,6 +710,11 @@ func (r *Runner) Wait() (Metrics, error) {
 		WriteThroughput:     r.metrics.writeThroughput,
 	}
 
+	r.readMetrics.Lock()
+	m.Reads.Count = r.readMetrics.count
+	m.Reads.Latency = r.readMetrics.latency
+	r.readMetrics.Unlock()
+
 	r.writeStallMetrics.Lock()
 	for reason, count := range r.writeStallMetrics.countByReason {
 		m.WriteStalls[reason] = count
@@ -711,7 +883,8 @@ func (r *Runner) prepareWorkloadSteps(ctx context.Context) error {
 	var flushBufs flushBuffers
 	var v *manifest.Version
 	var previousVersion *manifest.Version
-	var bve manifest.BulkVersionEdit
+	multipleRunsPerLevel := r.Opts.Experimental.CompactionStyle == pebble.CompactionStyleTiered
+	bve := manifest.BulkVersionEdit{MultipleRunsPerLevel: multipleRunsPerLevel}
 	bve.AddedByFileNum = make(map[base.FileNum]*manifest.FileMetadata)
 	applyVE := func(ve *manifest.VersionEdit) error {
 		return bve.Accumulate(ve)
@@ -722,7 +895,10 @@ func (r *Runner) prepareWorkloadSteps(ctx context.Context) error {
 			r.Opts.Comparer,
 			r.Opts.FlushSplitBytes,
 			r.Opts.Experimental.ReadCompactionRate)
-		bve = manifest.BulkVersionEdit{AddedByFileNum: bve.AddedByFileNum}
+		bve = manifest.BulkVersionEdit{
+			AddedByFileNum:       bve.AddedByFileNum,
+			MultipleRunsPerLevel: multipleRunsPerLevel,
+		}
 		return v, err
 	}
 
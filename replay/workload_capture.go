@@ -9,9 +9,12 @@ import (
 	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble"
 	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/record"
 	"github.com/cockroachdb/pebble/vfs"
 )
 
@@ -35,6 +38,9 @@ type manifestDetails struct {
 // WorkloadCollector is designed to capture workloads by handling manifest
 // files, flushed SSTs and ingested SSTs. The collector hooks into the
 // pebble.EventListener and pebble.Cleaner in order keep track of file states.
+//
+// Optionally, the collector may also capture read operations performed
+// through readers wrapped by WrapReader. See EnableReadCapture.
 type WorkloadCollector struct {
 	mu struct {
 		sync.Mutex
@@ -83,6 +89,37 @@ type WorkloadCollector struct {
 		stop bool
 		done chan struct{}
 	}
+	// reads holds the state used to capture read operations.
+	reads struct {
+		// enabled is set by EnableReadCapture.
+		enabled bool
+		// active is true while the collector is running and read capture is
+		// enabled.
+		active atomic.Bool
+		// file and writer are only accessed by the writeReads goroutine while
+		// it's running, and by stopReadCapture once it has exited.
+		file   vfs.File
+		writer *record.Writer
+		// done is closed when the writeReads goroutine exits.
+		done chan struct{}
+		mu   struct {
+			sync.Mutex
+			// cond is signaled when operations are queued or capture is
+			// stopped.
+			cond sync.Cond
+			// pending holds the encoded operations that are waiting to be
+			// written by the writeReads goroutine. pendingEnds holds the end
+			// offset within pending of each operation.
+			pending     []byte
+			pendingEnds []int
+			stop        bool
+			// startTime is the time at which read capture began.
+			startTime time.Time
+			// err is the first error encountered while capturing reads. Once
+			// set, subsequent reads are dropped.
+			err error
+		}
+	}
 }
 
 // NewWorkloadCollector is used externally to create a New WorkloadCollector.
@@ -93,6 +130,7 @@ func NewWorkloadCollector(srcDir string) *WorkloadCollector {
 	wc.mu.copyCond.L = &wc.mu.Mutex
 	wc.mu.fileState = make(map[string]workloadCaptureState)
 	wc.copier.Cond.L = &wc.mu.Mutex
+	wc.reads.mu.cond.L = &wc.reads.mu.Mutex
 	return wc
 }
 
@@ -117,6 +155,73 @@ func (w *WorkloadCollector) Attach(opts *pebble.Options) {
 	w.config.srcFS = opts.FS
 }
 
+// EnableReadCapture configures the collector to also capture the read
+// operations performed through readers returned by WrapReader. The captured
+// reads are written to a READS file in the workload directory, and may be
+// replayed concurrently with the write workload by setting
+// Runner.ReadConcurrency. It must be called before Start.
+func (w *WorkloadCollector) EnableReadCapture() {
+	w.reads.enabled = true
+}
+
+// capturingReads returns true if read operations should be recorded.
+func (w *WorkloadCollector) capturingReads() bool {
+	return w.reads.active.Load()
+}
+
+// recordRead queues the provided read operation, which began at startTime, to
+// be appended to the captured workload by the writeReads goroutine.
+func (w *WorkloadCollector) recordRead(op ReadOp, startTime time.Time) {
+	w.reads.mu.Lock()
+	defer w.reads.mu.Unlock()
+	if w.reads.mu.stop || w.reads.mu.err != nil {
+		return
+	}
+	op.Start = startTime.Sub(w.reads.mu.startTime)
+	w.reads.mu.pending = op.encode(w.reads.mu.pending)
+	w.reads.mu.pendingEnds = append(w.reads.mu.pendingEnds, len(w.reads.mu.pending))
+	if len(w.reads.mu.pendingEnds) == 1 {
+		w.reads.mu.cond.Signal()
+	}
+}
+
+// writeReads runs in the background while read capture is active, writing
+// queued read operations to the READS file. The first error encountered is
+// latched in w.reads.mu.err and returned by StopErr.
+func (w *WorkloadCollector) writeReads() {
+	defer close(w.reads.done)
+	var buf []byte
+	var ends []int
+	w.reads.mu.Lock()
+	defer w.reads.mu.Unlock()
+	for {
+		for len(w.reads.mu.pendingEnds) == 0 && !w.reads.mu.stop {
+			w.reads.mu.cond.Wait()
+		}
+		if len(w.reads.mu.pendingEnds) == 0 {
+			return
+		}
+		// Swap the pending buffers with the already written ones so that
+		// readers may continue to queue operations while we write.
+		buf, w.reads.mu.pending = w.reads.mu.pending, buf[:0]
+		ends, w.reads.mu.pendingEnds = w.reads.mu.pendingEnds, ends[:0]
+		w.reads.mu.Unlock()
+		var err error
+		start := 0
+		for _, end := range ends {
+			if _, err = w.reads.writer.WriteRecord(buf[start:end]); err != nil {
+				break
+			}
+			start = end
+		}
+		w.reads.mu.Lock()
+		if err != nil {
+			w.reads.mu.err = err
+			return
+		}
+	}
+}
+
 // enqueueCopyLocked enqueues the sstable with the provided filenum be copied in
 // the background. Requires w.mu.
 func (w *WorkloadCollector) enqueueCopyLocked(fileNum base.DiskFileNum) {
@@ -376,37 +481,104 @@ func (w *WorkloadCollector) Start(destFS vfs.FS, destPath string) {
 		w.mu.fileState[fileName] |= readyForProcessing
 	}
 
+	if w.reads.enabled {
+		w.startReadCapture()
+	}
+
 	// Begin copying files asynchronously in the background.
 	w.copier.done = make(chan struct{})
 	w.copier.stop = false
 	go w.copyFiles()
 }
 
+// startReadCapture creates the READS file and begins capturing reads. An error
+// creating the file is latched and returned by StopErr.
+func (w *WorkloadCollector) startReadCapture() {
+	w.reads.mu.Lock()
+	defer w.reads.mu.Unlock()
+	w.reads.mu.pending = w.reads.mu.pending[:0]
+	w.reads.mu.pendingEnds = w.reads.mu.pendingEnds[:0]
+	w.reads.mu.stop = false
+	w.reads.mu.err = nil
+	f, err := w.config.destFS.Create(w.destFilepath(readsFilename), vfs.WriteCategoryUnspecified)
+	if err != nil {
+		w.reads.mu.err = err
+		return
+	}
+	w.reads.file = f
+	w.reads.writer = record.NewWriter(f)
+	w.reads.mu.startTime = time.Now()
+	w.reads.done = make(chan struct{})
+	go w.writeReads()
+	w.reads.active.Store(true)
+}
+
 // WaitAndStop waits for all enqueued sstables to be copied over, and then
 // calls Stop. Gracefully ensures that all sstables referenced in the collected
 // manifest's latest version edit will exist in the copy directory.
 func (w *WorkloadCollector) WaitAndStop() {
+	_ = w.WaitAndStopErr()
+}
+
+// WaitAndStopErr is like WaitAndStop, but returns the first error encountered
+// while capturing reads, if any.
+func (w *WorkloadCollector) WaitAndStopErr() error {
 	w.mu.Lock()
 	for w.mu.tablesEnqueued != w.mu.tablesCopied {
 		w.mu.copyCond.Wait()
 	}
 	w.mu.Unlock()
-	w.Stop()
+	return w.StopErr()
 }
 
-// Stop stops collection of the workload.
+// Stop stops collection of the workload. Errors encountered while capturing
+// reads are discarded; see StopErr.
 func (w *WorkloadCollector) Stop() {
+	_ = w.StopErr()
+}
+
+// StopErr is like Stop, but returns the first error encountered while
+// capturing reads, if any.
+func (w *WorkloadCollector) StopErr() error {
 	w.mu.Lock()
 	// If the collector is running then that means w.enabled == true so swap it to
 	// false and continue else return since it is not running.
 	if !w.enabled.CompareAndSwap(true, false) {
 		w.mu.Unlock()
-		return
+		return nil
 	}
 	w.copier.stop = true
 	w.copier.Broadcast()
 	w.mu.Unlock()
 	<-w.copier.done
+	return w.stopReadCapture()
+}
+
+// stopReadCapture stops capturing reads, waits for the queued reads to be
+// written and closes the READS file.
+func (w *WorkloadCollector) stopReadCapture() error {
+	w.reads.active.Store(false)
+	w.reads.mu.Lock()
+	w.reads.mu.stop = true
+	w.reads.mu.cond.Signal()
+	w.reads.mu.Unlock()
+	if w.reads.done != nil {
+		<-w.reads.done
+		w.reads.done = nil
+	}
+
+	w.reads.mu.Lock()
+	defer w.reads.mu.Unlock()
+	err := w.reads.mu.err
+	if w.reads.writer != nil {
+		err = errors.CombineErrors(err, w.reads.writer.Close())
+		err = errors.CombineErrors(err, w.reads.file.Sync())
+		err = errors.CombineErrors(err, w.reads.file.Close())
+		w.reads.writer = nil
+		w.reads.file = nil
+	}
+	w.reads.mu.err = nil
+	return err
 }
 
 // IsRunning returns whether the WorkloadCollector is currently running.
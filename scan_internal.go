@@ -399,7 +399,9 @@ type IteratorLevel struct {
 	FlushableIndex int
 	// The level within the LSM. Only valid if Kind == IteratorLevelLSM.
 	Level int
-	// Sublevel is only valid if Kind == IteratorLevelLSM and Level == 0.
+	// Sublevel is only valid if Kind == IteratorLevelLSM and Level == 0, or
+	// if the level holds multiple sorted runs under CompactionStyleTiered, in
+	// which case it's the index of the run.
 	Sublevel int
 }
 
@@ -704,6 +706,12 @@ func scanInternalImpl(
 		firstLevelWithRemote := opts.skipLevelForOpts()
 		for level := firstLevelWithRemote; level < numLevels; level++ {
 			files := current.Levels[level].Iter()
+			if current.Runs[level] != nil {
+				// The sorted runs of the level may overlap one another, so seek
+				// among the files overlapping the bounds instead.
+				overlaps := current.Overlaps(level, base.UserKeyBoundsEndExclusive(lower, upper))
+				files = overlaps.Iter()
+			}
 			for f := files.SeekGE(cmp, lower); f != nil && cmp(f.Smallest.UserKey, upper) < 0; f = files.Next() {
 				if cmp(lower, f.Largest.UserKey) == 0 && f.Largest.IsExclusiveSentinel() {
 					continue
@@ -853,14 +861,11 @@ func (i *scanInternalIterator) constructPointIter(
 
 	skipStart := i.opts.skipLevelForOpts()
 	for level := 1; level < len(current.Levels); level++ {
-		if current.Levels[level].Empty() {
-			continue
-		}
 		if level > skipStart {
 			continue
 		}
-		numMergingLevels++
-		numLevelIters++
+		numMergingLevels += current.NumRuns(level)
+		numLevelIters += current.NumRuns(level)
 	}
 
 	if numMergingLevels > cap(mlevels) {
@@ -925,17 +930,14 @@ func (i *scanInternalIterator) constructPointIter(
 		}
 		addLevelIterForFiles(current.L0SublevelFiles[j].Iter(), manifest.L0Sublevel(j))
 	}
-	// Add level iterators for the non-empty non-L0 levels.
-	for level := 1; level < numLevels; level++ {
-		if current.Levels[level].Empty() {
-			continue
-		}
-
-		if level > skipStart {
-			continue
-		}
+	// Add level iterators for the non-empty non-L0 levels. A level holding
+	// multiple sorted runs gets a level iterator for each run, from newest to
+	// oldest.
+	addLevel := func(level int, levIter manifest.LevelIterator, layer manifest.Layer) error {
 		i.iterLevels[mlevelsIndex] = IteratorLevel{Kind: IteratorLevelLSM, Level: level}
-		levIter := current.Levels[level].Iter()
+		if layer.IsLevelRun() {
+			i.iterLevels[mlevelsIndex].Sublevel = layer.Run()
+		}
 		if level == skipStart {
 			nonRemoteFiles := make([]*manifest.FileMetadata, 0)
 			for f := levIter.First(); f != nil; f = levIter.Next() {
@@ -953,8 +955,28 @@ func (i *scanInternalIterator) constructPointIter(
 			levSlice := manifest.NewLevelSliceKeySorted(i.db.cmp, nonRemoteFiles)
 			levIter = levSlice.Iter()
 		}
+		addLevelIterForFiles(levIter, layer)
+		return nil
+	}
+	for level := 1; level < numLevels; level++ {
+		if current.Levels[level].Empty() {
+			continue
+		}
 
-		addLevelIterForFiles(levIter, manifest.Level(level))
+		if level > skipStart {
+			continue
+		}
+		if runs := current.Runs[level]; runs != nil {
+			for run := len(runs) - 1; run >= 0; run-- {
+				if err := addLevel(level, runs[run].Iter(), manifest.LevelRun(level, run)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := addLevel(level, current.Levels[level].Iter(), manifest.Level(level)); err != nil {
+			return err
+		}
 	}
 
 	buf.merging.init(&i.opts.IterOptions, &InternalIteratorStats{}, i.comparer.Compare, i.comparer.Split, mlevels...)
@@ -1036,18 +1058,13 @@ func (i *scanInternalIterator) constructRangeKeyIter() error {
 		}
 		i.rangeKey.iterConfig.AddLevel(spanIter)
 	}
-	// Add level iterators for the non-empty non-L0 levels.
+	// Add level iterators for the non-empty non-L0 levels. A level holding
+	// multiple sorted runs gets a level iterator for each run, from newest to
+	// oldest.
 	skipStart := i.opts.skipLevelForOpts()
-	for level := 1; level < len(current.RangeKeyLevels); level++ {
-		if current.RangeKeyLevels[level].Empty() {
-			continue
-		}
-		if level > skipStart {
-			continue
-		}
+	addLevel := func(level int, levIter manifest.LevelIterator, layer manifest.Layer) error {
 		li := i.rangeKey.iterConfig.NewLevelIter()
 		spanIterOpts := i.opts.SpanIterOptions()
-		levIter := current.RangeKeyLevels[level].Iter()
 		if level == skipStart {
 			nonRemoteFiles := make([]*manifest.FileMetadata, 0)
 			for f := levIter.First(); f != nil; f = levIter.Next() {
@@ -1066,8 +1083,33 @@ func (i *scanInternalIterator) constructRangeKeyIter() error {
 			levIter = levSlice.Iter()
 		}
 		li.Init(i.ctx, spanIterOpts, i.comparer.Compare, i.newIterRangeKey, levIter,
-			manifest.Level(level), manifest.KeyTypeRange)
+			layer, manifest.KeyTypeRange)
 		i.rangeKey.iterConfig.AddLevel(li)
+		return nil
+	}
+	for level := 1; level < len(current.RangeKeyLevels); level++ {
+		if current.RangeKeyLevels[level].Empty() {
+			continue
+		}
+		if level > skipStart {
+			continue
+		}
+		if runs := current.Runs[level]; runs != nil {
+			for run := len(runs) - 1; run >= 0; run-- {
+				levIter := runs[run].Iter()
+				if !containsAnyRangeKeys(levIter) {
+					continue
+				}
+				err := addLevel(level, levIter.Filter(manifest.KeyTypeRange), manifest.LevelRun(level, run))
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := addLevel(level, current.RangeKeyLevels[level].Iter(), manifest.Level(level)); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,839 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ErrIndexBackfillCanceled is returned by IndexBackfill.Wait when the
+// backfill was canceled, either explicitly or because the DB was closed.
+var ErrIndexBackfillCanceled = errors.New("pebble: secondary index backfill canceled")
+
+// indexBackfillBatchKeys is the number of primary keys an IndexBackfill reads
+// before committing the index entries it has accumulated.
+const indexBackfillBatchKeys = 1000
+
+// SecondaryIndex defines a secondary index maintained by the DB. An index maps
+// the index keys extracted from each primary key and value to the primary
+// key. Each mapping is stored as an index entry: a key beginning with the
+// index's Prefix, followed by an order-preserving encoding of the index key,
+// followed by the primary key. Index entries have empty values.
+//
+// The entries of new values are written within the same batch as the
+// primary key by Batch.{Set,Delete,DeleteSized,SingleDelete} (and their DB
+// equivalents). Writes of other kinds — the deferred batch operations, Merge,
+// DeleteRange, and ingestion — don't maintain indexes; data written that way
+// must be indexed through DB.BackfillSecondaryIndex.
+//
+// Writes don't read the previous value of the primary key, so the entries of
+// overwritten and deleted values are left behind as stale entries.
+// IndexIterator verifies every entry against the primary key's current value,
+// so stale entries are never surfaced, and they're deleted lazily: by the
+// IndexIterator that skipped them when it's closed, and by a sweep of the
+// index at the end of every IndexBackfill.
+//
+// The entry encoding relies on keys being ordered bytewise, so secondary
+// indexes may only be used with a Comparer that does so, such as
+// DefaultComparer.
+type SecondaryIndex struct {
+	// Name uniquely identifies the index within Options.SecondaryIndexes.
+	Name string
+	// Prefix is the key prefix under which the index's entries are stored. It
+	// must be non-empty, and must neither be a prefix of nor be prefixed by
+	// another index's Prefix. Keys with the prefix are never indexed, so
+	// primary keys must not begin with it.
+	Prefix []byte
+	// Extract appends the index keys of the provided primary key and value to
+	// dst and returns the result. A primary key may have any number of index
+	// keys, including none. Extract must be deterministic. The returned
+	// slices may alias key and value.
+	Extract func(dst [][]byte, key, value []byte) [][]byte
+}
+
+// appendSeekKey appends the key at which the index's entries for indexKey
+// begin to dst. The result is also a valid exclusive upper bound for the
+// entries of all index keys less than indexKey.
+func (idx *SecondaryIndex) appendSeekKey(dst, indexKey []byte) []byte {
+	dst = append(dst, idx.Prefix...)
+	// Escape 0x00 as 0x00 0xff so that the terminator 0x00 0x01 sorts before
+	// any continuation of the index key.
+	for _, c := range indexKey {
+		if c == 0x00 {
+			dst = append(dst, 0x00, 0xff)
+		} else {
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// appendEntryKey appends the key of the index entry mapping indexKey to
+// primaryKey to dst.
+func (idx *SecondaryIndex) appendEntryKey(dst, indexKey, primaryKey []byte) []byte {
+	dst = idx.appendSeekKey(dst, indexKey)
+	dst = append(dst, 0x00, 0x01)
+	return append(dst, primaryKey...)
+}
+
+// decodeEntryKey decodes the key of an index entry. The index key is
+// unescaped into buf, and the primary key aliases key.
+func (idx *SecondaryIndex) decodeEntryKey(buf, key []byte) (indexKey, primaryKey []byte, ok bool) {
+	if !bytes.HasPrefix(key, idx.Prefix) {
+		return nil, nil, false
+	}
+	key = key[len(idx.Prefix):]
+	indexKey = buf[:0]
+	for i := 0; i+1 < len(key); i++ {
+		if key[i] != 0x00 {
+			indexKey = append(indexKey, key[i])
+			continue
+		}
+		switch key[i+1] {
+		case 0xff:
+			indexKey = append(indexKey, 0x00)
+			i++
+		case 0x01:
+			return indexKey, key[i+2:], true
+		default:
+			return nil, nil, false
+		}
+	}
+	return nil, nil, false
+}
+
+// prefixSuccessor returns the smallest key that is greater than every key
+// with the provided prefix, or nil if there is no such key.
+func prefixSuccessor(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			succ := slices.Clone(prefix[:i+1])
+			succ[i]++
+			return succ
+		}
+	}
+	return nil
+}
+
+// validateSecondaryIndexes validates Options.SecondaryIndexes, writing a line
+// to buf for each problem found.
+func validateSecondaryIndexes(buf *strings.Builder, indexes []SecondaryIndex) {
+	for i := range indexes {
+		idx := &indexes[i]
+		if idx.Name == "" {
+			fmt.Fprintf(buf, "SecondaryIndexes[%d] must have a Name\n", i)
+		}
+		if len(idx.Prefix) == 0 {
+			fmt.Fprintf(buf, "SecondaryIndex %q must have a Prefix\n", idx.Name)
+		}
+		if idx.Extract == nil {
+			fmt.Fprintf(buf, "SecondaryIndex %q must have an Extract function\n", idx.Name)
+		}
+		for j := range indexes[:i] {
+			other := &indexes[j]
+			if idx.Name == other.Name {
+				fmt.Fprintf(buf, "SecondaryIndex name %q is used more than once\n", idx.Name)
+			}
+			if len(idx.Prefix) > 0 && len(other.Prefix) > 0 &&
+				(bytes.HasPrefix(idx.Prefix, other.Prefix) || bytes.HasPrefix(other.Prefix, idx.Prefix)) {
+				fmt.Fprintf(buf, "SecondaryIndex %q and %q have overlapping prefixes\n", other.Name, idx.Name)
+			}
+		}
+	}
+}
+
+// secondaryIndexRegistry holds the secondary indexes of a DB and the
+// backfills running against them.
+type secondaryIndexRegistry struct {
+	indexes []*SecondaryIndex
+	mu      struct {
+		sync.Mutex
+		// closed is set once the DB begins closing, after which no new
+		// backfills may be started.
+		closed    bool
+		backfills map[*IndexBackfill]struct{}
+	}
+	// backfillsWG tracks the goroutines of running backfills.
+	backfillsWG sync.WaitGroup
+}
+
+// newSecondaryIndexRegistry returns the registry of the indexes configured by
+// opts, or nil if there are none.
+func newSecondaryIndexRegistry(opts *Options) *secondaryIndexRegistry {
+	if len(opts.SecondaryIndexes) == 0 {
+		return nil
+	}
+	r := &secondaryIndexRegistry{}
+	for i := range opts.SecondaryIndexes {
+		r.indexes = append(r.indexes, &opts.SecondaryIndexes[i])
+	}
+	r.mu.backfills = make(map[*IndexBackfill]struct{})
+	return r
+}
+
+// lookup returns the index with the provided name.
+func (r *secondaryIndexRegistry) lookup(name string) (*SecondaryIndex, error) {
+	if r != nil {
+		for _, idx := range r.indexes {
+			if idx.Name == name {
+				return idx, nil
+			}
+		}
+	}
+	return nil, errors.Errorf("pebble: unknown secondary index %q", name)
+}
+
+// indexContaining returns the index whose entries include key, or nil if key
+// is not an index entry.
+func (r *secondaryIndexRegistry) indexContaining(key []byte) *SecondaryIndex {
+	for _, idx := range r.indexes {
+		if bytes.HasPrefix(key, idx.Prefix) {
+			return idx
+		}
+	}
+	return nil
+}
+
+// appendEntries appends the keys of the index entries, across all indexes,
+// of the provided primary key and value to dst.
+func (r *secondaryIndexRegistry) appendEntries(dst [][]byte, key, value []byte) [][]byte {
+	var indexKeys [][]byte
+	for _, idx := range r.indexes {
+		indexKeys = idx.Extract(indexKeys[:0], key, value)
+		for _, indexKey := range indexKeys {
+			dst = append(dst, idx.appendEntryKey(nil, indexKey, key))
+		}
+	}
+	return dst
+}
+
+// stopBackfills cancels all running backfills and waits for them to exit.
+// No backfills may be started afterwards.
+func (r *secondaryIndexRegistry) stopBackfills() {
+	r.mu.Lock()
+	r.mu.closed = true
+	for b := range r.mu.backfills {
+		b.Cancel()
+	}
+	r.mu.Unlock()
+	r.backfillsWG.Wait()
+}
+
+// containsKey returns true if keys contains key.
+func containsKey(keys [][]byte, key []byte) bool {
+	return slices.ContainsFunc(keys, func(k []byte) bool { return bytes.Equal(k, key) })
+}
+
+// maintainSecondaryIndexes adds the index entry writes required by a write of
+// key to the batch. The new value of the key is value, or the key is deleted
+// if deleted is true.
+//
+// Only the writes relative to earlier writes of the key within the batch are
+// added. The entries of the key's value within the DB are left behind as
+// stale entries; see DB.deleteStaleIndexEntries.
+func (b *Batch) maintainSecondaryIndexes(key, value []byte, deleted bool) error {
+	r := b.db.secondaryIndexes
+	if r.indexContaining(key) != nil {
+		return nil
+	}
+	// If this is the first write of the key within the batch, prev is empty
+	// and every entry of the new value is set. Setting an entry that already
+	// exists within the DB is harmless.
+	prev := b.secondaryIndexEntries[string(key)]
+	var next [][]byte
+	if !deleted {
+		next = r.appendEntries(nil, key, value)
+	}
+	if err := b.replaceIndexEntries(prev, next); err != nil {
+		return err
+	}
+	if b.secondaryIndexEntries == nil {
+		b.secondaryIndexEntries = make(map[string][][]byte)
+	}
+	b.secondaryIndexEntries[string(key)] = next
+	return nil
+}
+
+// replaceIndexEntries adds the writes that replace the index entries prev with
+// next to the batch.
+func (b *Batch) replaceIndexEntries(prev, next [][]byte) error {
+	for _, e := range prev {
+		if !containsKey(next, e) {
+			if err := b.Delete(e, nil); err != nil {
+				return err
+			}
+		}
+	}
+	for _, e := range next {
+		if !containsKey(prev, e) {
+			if err := b.Set(e, nil, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeSecondaryIndexEntries records the index entries of the primary keys
+// written by batch, which has just been appended to the receiver, deleting
+// the entries set earlier in the receiver that batch's writes made stale.
+func (b *Batch) mergeSecondaryIndexEntries(batch *Batch) error {
+	for key, next := range batch.secondaryIndexEntries {
+		for _, e := range b.secondaryIndexEntries[key] {
+			if !containsKey(next, e) {
+				if err := b.Delete(e, nil); err != nil {
+					return err
+				}
+			}
+		}
+		if b.secondaryIndexEntries == nil {
+			b.secondaryIndexEntries = make(map[string][][]byte)
+		}
+		b.secondaryIndexEntries[key] = next
+	}
+	return nil
+}
+
+// maxStaleIndexEntryAttempts bounds the number of times the deletion of stale
+// index entries is attempted while other batches are committed concurrently.
+const maxStaleIndexEntryAttempts = 3
+
+// maxIndexIterStaleEntries bounds the number of stale index entries an
+// IndexIterator retains for deletion.
+const maxIndexIterStaleEntries = 1000
+
+// isStaleEntry returns true if the index entry with the provided key doesn't
+// match the value of its primary key read from r.
+func (idx *SecondaryIndex) isStaleEntry(r Reader, entryKey []byte) (bool, error) {
+	indexKey, primaryKey, ok := idx.decodeEntryKey(nil, entryKey)
+	if !ok {
+		return false, errors.Errorf("pebble: malformed %q index entry %q", idx.Name, entryKey)
+	}
+	v, closer, err := r.Get(primaryKey)
+	if errors.Is(err, ErrNotFound) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	stale := !containsKey(idx.Extract(nil, primaryKey, v), indexKey)
+	return stale, closer.Close()
+}
+
+// deleteStaleIndexEntries deletes those of the provided entries of idx that
+// are stale, returning the number of entries deleted. Whether an entry is
+// stale is read outside of the commit pipeline, which then only verifies that
+// no batch was sequenced since, so that an entry made valid again by a
+// concurrent write is never deleted. Deleting is best effort: no entries are
+// deleted if batches keep being committed concurrently.
+func (d *DB) deleteStaleIndexEntries(idx *SecondaryIndex, entries [][]byte) (int, error) {
+	if len(entries) == 0 || d.opts.ReadOnly {
+		return 0, nil
+	}
+	for attempt := 0; attempt < maxStaleIndexEntryAttempts; attempt++ {
+		snap := d.NewSnapshot()
+		b := newBatch(d)
+		var err error
+		for _, e := range entries {
+			var stale bool
+			if stale, err = idx.isStaleEntry(snap, e); err != nil {
+				break
+			}
+			if stale {
+				if err = b.Delete(e, nil); err != nil {
+					break
+				}
+			}
+		}
+		seqNum := snap.seqNum
+		err = firstError(err, snap.Close())
+		n := int(b.Count())
+		ok := false
+		if err == nil && n > 0 {
+			ok, err = d.commit.CommitIfUnchanged(b, seqNum)
+		}
+		err = firstError(err, b.Close())
+		if err != nil || n == 0 {
+			return 0, err
+		}
+		if ok {
+			return n, nil
+		}
+	}
+	return 0, nil
+}
+
+// IndexIterOptions configures an IndexIterator.
+type IndexIterOptions struct {
+	// LowerBound and UpperBound restrict the iterator to the index keys within
+	// [LowerBound, UpperBound). Either may be nil to leave that side
+	// unbounded.
+	LowerBound []byte
+	UpperBound []byte
+}
+
+// IndexIterator iterates over the entries of a secondary index in index key
+// order, surfacing the primary key and value of each. Entries with the same
+// index key are ordered by primary key. An IndexIterator reads from an
+// implicit snapshot taken when it's created, and skips stale entries that no
+// longer match the value of their primary key. Closing the iterator deletes
+// the stale entries it skipped.
+//
+// An IndexIterator must be closed after use, but it is not necessary to read
+// an iterator until exhaustion.
+type IndexIterator struct {
+	d    *DB
+	idx  *SecondaryIndex
+	snap *Snapshot
+	iter *Iterator
+	// stale holds the keys of the stale entries skipped by the iterator,
+	// which are deleted when the iterator is closed.
+	stale [][]byte
+
+	indexKeyBuf []byte
+	extractBuf  [][]byte
+	indexKey    []byte
+	primaryKey  []byte
+	value       []byte
+	closer      interface{ Close() error }
+	valid       bool
+	err         error
+}
+
+// NewIndexIter returns an IndexIterator over the named secondary index.
+func (d *DB) NewIndexIter(name string, o *IndexIterOptions) (*IndexIterator, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	idx, err := d.secondaryIndexes.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	iterOpts := &IterOptions{
+		LowerBound: idx.Prefix,
+		UpperBound: prefixSuccessor(idx.Prefix),
+	}
+	if o != nil && o.LowerBound != nil {
+		iterOpts.LowerBound = idx.appendSeekKey(nil, o.LowerBound)
+	}
+	if o != nil && o.UpperBound != nil {
+		iterOpts.UpperBound = idx.appendSeekKey(nil, o.UpperBound)
+	}
+	i := &IndexIterator{d: d, idx: idx, snap: d.NewSnapshot()}
+	if i.iter, err = i.snap.NewIter(iterOpts); err != nil {
+		return nil, errors.CombineErrors(err, i.snap.Close())
+	}
+	return i, nil
+}
+
+// First moves the iterator to the first valid index entry, returning whether
+// one exists.
+func (i *IndexIterator) First() bool {
+	return i.findValidEntry(i.iter.First())
+}
+
+// SeekGE moves the iterator to the first valid index entry with an index key
+// greater than or equal to indexKey, returning whether one exists.
+func (i *IndexIterator) SeekGE(indexKey []byte) bool {
+	return i.findValidEntry(i.iter.SeekGE(i.idx.appendSeekKey(nil, indexKey)))
+}
+
+// Next moves the iterator to the next valid index entry, returning whether one
+// exists.
+func (i *IndexIterator) Next() bool {
+	if !i.valid {
+		return false
+	}
+	return i.findValidEntry(i.iter.Next())
+}
+
+// findValidEntry steps the underlying iterator forward from its current
+// position, which is valid if ok is true, until it's positioned at an index
+// entry that matches its primary key's current value.
+func (i *IndexIterator) findValidEntry(ok bool) bool {
+	i.releaseValue()
+	for ; ok; ok = i.iter.Next() {
+		indexKey, primaryKey, decoded := i.idx.decodeEntryKey(i.indexKeyBuf, i.iter.Key())
+		if !decoded {
+			i.err = errors.Errorf("pebble: malformed %q index entry %q", i.idx.Name, i.iter.Key())
+			break
+		}
+		i.indexKeyBuf = indexKey
+		v, closer, err := i.snap.Get(primaryKey)
+		if errors.Is(err, ErrNotFound) {
+			i.skipStale()
+			continue
+		} else if err != nil {
+			i.err = err
+			break
+		}
+		i.extractBuf = i.idx.Extract(i.extractBuf[:0], primaryKey, v)
+		if !containsKey(i.extractBuf, indexKey) {
+			// The entry is stale.
+			if i.err = closer.Close(); i.err != nil {
+				break
+			}
+			i.skipStale()
+			continue
+		}
+		i.indexKey, i.primaryKey, i.value, i.closer = indexKey, primaryKey, v, closer
+		i.valid = true
+		return true
+	}
+	if i.err == nil {
+		i.err = i.iter.Error()
+	}
+	return false
+}
+
+// skipStale records the entry the underlying iterator is positioned at as
+// stale.
+func (i *IndexIterator) skipStale() {
+	if len(i.stale) < maxIndexIterStaleEntries {
+		i.stale = append(i.stale, slices.Clone(i.iter.Key()))
+	}
+}
+
+// releaseValue releases the value of the current index entry.
+func (i *IndexIterator) releaseValue() {
+	if i.closer != nil {
+		i.err = firstError(i.err, i.closer.Close())
+	}
+	i.indexKey, i.primaryKey, i.value, i.closer = nil, nil, nil, nil
+	i.valid = false
+}
+
+// Valid returns true if the iterator is positioned at a valid index entry.
+func (i *IndexIterator) Valid() bool {
+	return i.valid
+}
+
+// IndexKey returns the index key of the current entry. The returned slice
+// is only valid until the iterator is next repositioned.
+func (i *IndexIterator) IndexKey() []byte {
+	return i.indexKey
+}
+
+// PrimaryKey returns the primary key of the current entry. The returned slice
+// is only valid until the iterator is next repositioned.
+func (i *IndexIterator) PrimaryKey() []byte {
+	return i.primaryKey
+}
+
+// Value returns the value of the current entry's primary key. The returned
+// slice is only valid until the iterator is next repositioned.
+func (i *IndexIterator) Value() []byte {
+	return i.value
+}
+
+// Error returns any accumulated error.
+func (i *IndexIterator) Error() error {
+	return i.err
+}
+
+// Close closes the iterator, deleting the stale entries it skipped, and
+// returns any accumulated error.
+func (i *IndexIterator) Close() error {
+	i.releaseValue()
+	err := firstError(i.err, i.iter.Close())
+	err = firstError(err, i.snap.Close())
+	if err == nil {
+		_, err = i.d.deleteStaleIndexEntries(i.idx, i.stale)
+	}
+	return err
+}
+
+// IndexBackfillProgress describes the progress of an IndexBackfill.
+type IndexBackfillProgress struct {
+	// ResumeKey is the primary key from which a new backfill would continue
+	// where this one left off, or nil if the backfill has indexed all primary
+	// keys. Persisting ResumeKey allows a backfill to be resumed after the DB
+	// is reopened.
+	ResumeKey []byte
+	// Keys is the number of primary keys the backfill has scanned.
+	Keys uint64
+	// StaleEntries is the number of stale index entries the backfill has
+	// deleted.
+	StaleEntries uint64
+	// Paused is true if the backfill is paused.
+	Paused bool
+	// Done is true once the backfill has exited, whether it completed, failed
+	// or was canceled.
+	Done bool
+}
+
+// IndexBackfill is a background job, started by DB.BackfillSecondaryIndex,
+// that writes the index entries of existing primary keys. It scans the
+// primary keys in order, committing the index entries of every few primary
+// keys in their own batch. Once all primary keys are indexed, it sweeps the
+// index, deleting the stale entries left behind by writes. It may be paused
+// and resumed between batches.
+type IndexBackfill struct {
+	d    *DB
+	idx  *SecondaryIndex
+	done chan struct{}
+	mu   struct {
+		sync.Mutex
+		cond         sync.Cond
+		paused       bool
+		canceled     bool
+		resumeKey    []byte
+		keys         uint64
+		staleEntries uint64
+		err          error
+		done         bool
+	}
+}
+
+// BackfillSecondaryIndex starts a background job that writes the entries of
+// the named secondary index for the primary keys greater than or equal to
+// start, which may be nil to backfill all primary keys. Index entries of
+// writes committed since the DB was opened with the index are maintained
+// automatically, so a backfill is only necessary for data written before the
+// index was added to Options.SecondaryIndexes, or written by operations that
+// don't maintain indexes.
+//
+// The backfill is canceled if the DB is closed.
+func (d *DB) BackfillSecondaryIndex(name string, start []byte) (*IndexBackfill, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	idx, err := d.secondaryIndexes.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	b := &IndexBackfill{d: d, idx: idx, done: make(chan struct{})}
+	b.mu.cond.L = &b.mu.Mutex
+	b.mu.resumeKey = slices.Clone(start)
+
+	r := d.secondaryIndexes
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mu.closed {
+		return nil, ErrClosed
+	}
+	r.mu.backfills[b] = struct{}{}
+	r.backfillsWG.Add(1)
+	go b.run()
+	return b, nil
+}
+
+// run is the body of the backfill's goroutine.
+func (b *IndexBackfill) run() {
+	r := b.d.secondaryIndexes
+	defer r.backfillsWG.Done()
+	defer close(b.done)
+
+	var err error
+	// sweepKey is the key of the index entry from which the sweep continues,
+	// once all primary keys are indexed.
+	var sweepKey []byte
+	for {
+		b.mu.Lock()
+		for b.mu.paused && !b.mu.canceled {
+			b.mu.cond.Wait()
+		}
+		canceled := b.mu.canceled
+		start := b.mu.resumeKey
+		b.mu.Unlock()
+		if canceled {
+			err = ErrIndexBackfillCanceled
+			break
+		}
+
+		if sweepKey != nil {
+			var n uint64
+			sweepKey, n, err = b.sweepStep(sweepKey)
+			b.mu.Lock()
+			b.mu.staleEntries += n
+			b.mu.Unlock()
+			if err != nil || sweepKey == nil {
+				break
+			}
+			continue
+		}
+
+		var resumeKey []byte
+		var n uint64
+		resumeKey, n, err = b.step(start)
+		b.mu.Lock()
+		b.mu.keys += n
+		if err == nil {
+			b.mu.resumeKey = resumeKey
+		}
+		b.mu.Unlock()
+		if err != nil {
+			break
+		}
+		if resumeKey == nil {
+			sweepKey = b.idx.Prefix
+		}
+	}
+
+	r.mu.Lock()
+	delete(r.mu.backfills, b)
+	r.mu.Unlock()
+	b.mu.Lock()
+	b.mu.err = err
+	b.mu.done = true
+	b.mu.Unlock()
+}
+
+// step indexes up to indexBackfillBatchKeys primary keys greater than or
+// equal to start, committing their index entries in a single batch. It
+// returns the key from which the next step should continue, or nil if no
+// primary keys remain, and the number of primary keys scanned.
+func (b *IndexBackfill) step(start []byte) (resumeKey []byte, n uint64, err error) {
+	d := b.d
+	r := d.secondaryIndexes
+	iter, err := d.NewIter(&IterOptions{LowerBound: start})
+	if err != nil {
+		return nil, 0, err
+	}
+	batch := d.NewBatch()
+	defer batch.Close()
+
+	var entries [][]byte
+	valid := iter.First()
+	for valid && n < indexBackfillBatchKeys {
+		key := iter.Key()
+		if idx := r.indexContaining(key); idx != nil {
+			// Skip over the entries of the index.
+			succ := prefixSuccessor(idx.Prefix)
+			if succ == nil {
+				valid = false
+				break
+			}
+			valid = iter.SeekGE(succ)
+			continue
+		}
+		var value []byte
+		if value, err = iter.ValueAndErr(); err != nil {
+			break
+		}
+		entries = entries[:0]
+		for _, indexKey := range b.idx.Extract(nil, key, value) {
+			entries = append(entries, b.idx.appendEntryKey(nil, indexKey, key))
+		}
+		for _, e := range entries {
+			if err = batch.Set(e, nil, nil); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+		n++
+		valid = iter.Next()
+	}
+	if valid {
+		resumeKey = slices.Clone(iter.Key())
+	}
+	err = firstError(err, iter.Close())
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := d.Apply(batch, &WriteOptions{Priority: WritePriorityLow}); err != nil {
+		return nil, 0, err
+	}
+	return resumeKey, n, nil
+}
+
+// sweepStep deletes the stale entries among up to indexBackfillBatchKeys
+// entries of the index greater than or equal to start. It returns the key from
+// which the next step should continue, or nil if no entries remain, and the
+// number of stale entries deleted.
+func (b *IndexBackfill) sweepStep(start []byte) (resumeKey []byte, n uint64, err error) {
+	d := b.d
+	snap := d.NewSnapshot()
+	iter, err := snap.NewIter(&IterOptions{
+		LowerBound: start,
+		UpperBound: prefixSuccessor(b.idx.Prefix),
+	})
+	if err != nil {
+		return nil, 0, errors.CombineErrors(err, snap.Close())
+	}
+
+	var stale [][]byte
+	valid := iter.First()
+	for i := 0; valid && i < indexBackfillBatchKeys; i++ {
+		var isStale bool
+		if isStale, err = b.idx.isStaleEntry(snap, iter.Key()); err != nil {
+			break
+		}
+		if isStale {
+			stale = append(stale, slices.Clone(iter.Key()))
+		}
+		valid = iter.Next()
+	}
+	if valid {
+		resumeKey = slices.Clone(iter.Key())
+	}
+	err = firstError(err, iter.Close())
+	err = firstError(err, snap.Close())
+	if err != nil {
+		return nil, 0, err
+	}
+	deleted, err := d.deleteStaleIndexEntries(b.idx, stale)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resumeKey, uint64(deleted), nil
+}
+
+// Pause pauses the backfill once it has committed its current batch of index
+// entries. Pausing a paused or exited backfill has no effect.
+func (b *IndexBackfill) Pause() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mu.paused = true
+}
+
+// Resume resumes a paused backfill.
+func (b *IndexBackfill) Resume() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mu.paused = false
+	b.mu.cond.Broadcast()
+}
+
+// Cancel cancels the backfill once it has committed its current batch of
+// index entries. Canceling an exited backfill has no effect.
+func (b *IndexBackfill) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mu.canceled = true
+	b.mu.cond.Broadcast()
+}
+
+// Wait waits for the backfill to exit, returning ErrIndexBackfillCanceled if
+// it was canceled, or the error that caused it to fail.
+func (b *IndexBackfill) Wait() error {
+	<-b.done
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.mu.err
+}
+
+// Progress returns the progress of the backfill.
+func (b *IndexBackfill) Progress() IndexBackfillProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return IndexBackfillProgress{
+		ResumeKey:    slices.Clone(b.mu.resumeKey),
+		Keys:         b.mu.keys,
+		StaleEntries: b.mu.staleEntries,
+		Paused:       b.mu.paused,
+		Done:         b.mu.done,
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// byCityIndex indexes the primary keys beginning with "user/" by their
+// comma-separated values.
+var byCityIndex = SecondaryIndex{
+	Name:   "by-city",
+	Prefix: []byte("idx/city/"),
+	Extract: func(dst [][]byte, key, value []byte) [][]byte {
+		if !bytes.HasPrefix(key, []byte("user/")) || len(value) == 0 {
+			return dst
+		}
+		return append(dst, bytes.Split(value, []byte(","))...)
+	},
+}
+
+func scanIndex(t *testing.T, d *DB, o *IndexIterOptions) string {
+	iter, err := d.NewIndexIter(byCityIndex.Name, o)
+	require.NoError(t, err)
+	var parts []string
+	for valid := iter.First(); valid; valid = iter.Next() {
+		parts = append(parts, fmt.Sprintf("%s:%s=%s", iter.IndexKey(), iter.PrimaryKey(), iter.Value()))
+	}
+	require.NoError(t, iter.Close())
+	return strings.Join(parts, " ")
+}
+
+// scanRawIndex returns the keys of all of the index entries of byCityIndex,
+// including stale ones.
+func scanRawIndex(t *testing.T, d *DB) string {
+	iter, err := d.NewIter(&IterOptions{
+		LowerBound: byCityIndex.Prefix,
+		UpperBound: prefixSuccessor(byCityIndex.Prefix),
+	})
+	require.NoError(t, err)
+	var keys []string
+	for valid := iter.First(); valid; valid = iter.Next() {
+		keys = append(keys, fmt.Sprintf("%q", iter.Key()))
+	}
+	require.NoError(t, iter.Close())
+	return strings.Join(keys, " ")
+}
+
+func TestSecondaryIndexEntryKey(t *testing.T) {
+	idx := &byCityIndex
+	for _, tc := range []struct{ indexKey, primaryKey string }{
+		{"", ""},
+		{"a", "b"},
+		{"a\x00b", "\x00\x01"},
+		{"\x00\x00", "x"},
+	} {
+		key := idx.appendEntryKey(nil, []byte(tc.indexKey), []byte(tc.primaryKey))
+		indexKey, primaryKey, ok := idx.decodeEntryKey(nil, key)
+		require.True(t, ok)
+		require.Equal(t, tc.indexKey, string(indexKey))
+		require.Equal(t, tc.primaryKey, string(primaryKey))
+	}
+
+	// The encoding orders entries by index key, then primary key.
+	keys := [][]byte{
+		idx.appendEntryKey(nil, []byte("a"), []byte("z")),
+		idx.appendEntryKey(nil, []byte("a\x00"), []byte("a")),
+		idx.appendEntryKey(nil, []byte("ab"), []byte("a")),
+		idx.appendEntryKey(nil, []byte("ab"), []byte("b")),
+	}
+	for i := 1; i < len(keys); i++ {
+		require.Less(t, string(keys[i-1]), string(keys[i]))
+	}
+	require.Less(t, string(keys[0]), string(idx.appendSeekKey(nil, []byte("a\x00"))))
+	require.Less(t, string(idx.appendSeekKey(nil, []byte("a\x00"))), string(keys[1]))
+}
+
+func TestSecondaryIndex(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:               vfs.NewMem(),
+		SecondaryIndexes: []SecondaryIndex{byCityIndex},
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	require.NoError(t, d.Set([]byte("user/1"), []byte("nyc"), nil))
+	require.NoError(t, d.Set([]byte("user/2"), []byte("sf,nyc"), nil))
+	require.NoError(t, d.Set([]byte("user/3"), []byte("la"), nil))
+	require.NoError(t, d.Set([]byte("other"), []byte("nyc"), nil))
+	require.Equal(t, "la:user/3=la nyc:user/1=nyc nyc:user/2=sf,nyc sf:user/2=sf,nyc", scanIndex(t, d, nil))
+
+	// Updates and deletes within a batch replace the entries of the previous
+	// value, including writes earlier in the same batch.
+	b := d.NewBatch()
+	require.NoError(t, b.Set([]byte("user/1"), []byte("sf"), nil))
+	require.NoError(t, b.Set([]byte("user/1"), []byte("la"), nil))
+	require.NoError(t, b.Delete([]byte("user/2"), nil))
+	require.NoError(t, b.Commit(nil))
+	require.NoError(t, b.Close())
+	require.Equal(t, "la:user/1=la la:user/3=la", scanIndex(t, d, nil))
+	require.NoError(t, d.Flush())
+
+	// Writes leave the entries of the values they replace behind, until an
+	// iterator that skips them is closed. Applying one batch to another
+	// replaces the entries of the earlier writes within the batch.
+	b = d.NewBatch()
+	require.NoError(t, b.Set([]byte("user/3"), []byte("sf"), nil))
+	require.NoError(t, d.Set([]byte("user/3"), []byte("nyc"), nil))
+	b2 := d.NewBatch()
+	require.NoError(t, b2.Set([]byte("user/1"), []byte("nyc"), nil))
+	require.NoError(t, b2.Set([]byte("user/3"), []byte("la"), nil))
+	require.NoError(t, b2.Apply(b, nil))
+	require.NoError(t, b2.Commit(nil))
+	require.NoError(t, b.Close())
+	require.NoError(t, b2.Close())
+	require.Equal(t, `"idx/city/la\x00\x01user/1" "idx/city/nyc\x00\x01user/1" "idx/city/nyc\x00\x01user/3" "idx/city/sf\x00\x01user/3"`, scanRawIndex(t, d))
+	require.Equal(t, "nyc:user/1=nyc sf:user/3=sf", scanIndex(t, d, nil))
+	require.Equal(t, `"idx/city/nyc\x00\x01user/1" "idx/city/sf\x00\x01user/3"`, scanRawIndex(t, d))
+
+	// A stale entry made valid again after the iterator's snapshot isn't
+	// deleted.
+	require.NoError(t, d.Set([]byte("user/1"), []byte("la"), nil))
+	require.NoError(t, d.Set([]byte("user/1"), []byte("nyc"), nil))
+	iter, err := d.NewIndexIter(byCityIndex.Name, nil)
+	require.NoError(t, err)
+	require.True(t, iter.First())
+	require.Equal(t, "nyc", string(iter.IndexKey()))
+	require.NoError(t, d.Set([]byte("user/1"), []byte("la"), nil))
+	require.NoError(t, iter.Close())
+	require.Equal(t, "la:user/1=la sf:user/3=sf", scanIndex(t, d, nil))
+	require.NoError(t, d.Set([]byte("user/1"), []byte("la"), nil))
+	require.NoError(t, d.Set([]byte("user/3"), []byte("la"), nil))
+
+	// Bounds restrict the index keys.
+	require.NoError(t, d.Set([]byte("user/4"), []byte("nyc"), nil))
+	require.Equal(t, "nyc:user/4=nyc", scanIndex(t, d, &IndexIterOptions{LowerBound: []byte("m")}))
+	require.Equal(t, "la:user/1=la la:user/3=la", scanIndex(t, d, &IndexIterOptions{UpperBound: []byte("m")}))
+
+	// Writes that don't maintain the index leave stale entries behind, which
+	// the iterator skips.
+	require.NoError(t, d.DeleteRange([]byte("user/3"), []byte("user/4"), nil))
+	require.Equal(t, "la:user/1=la nyc:user/4=nyc", scanIndex(t, d, nil))
+
+	iter, err = d.NewIndexIter(byCityIndex.Name, nil)
+	require.NoError(t, err)
+	require.True(t, iter.SeekGE([]byte("la")))
+	require.Equal(t, "user/1", string(iter.PrimaryKey()))
+	require.True(t, iter.SeekGE([]byte("m")))
+	require.Equal(t, "user/4", string(iter.PrimaryKey()))
+	require.False(t, iter.Next())
+	require.NoError(t, iter.Close())
+
+	_, err = d.NewIndexIter("unknown", nil)
+	require.Error(t, err)
+}
+
+func TestSecondaryIndexBackfill(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("", &Options{FS: mem})
+	require.NoError(t, err)
+	const n = 2500
+	for i := 0; i < n; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("user/%04d", i)), []byte(fmt.Sprint(i%3)), nil))
+	}
+	require.NoError(t, d.Close())
+
+	// Reopen the DB with the index, which doesn't yet have entries for the
+	// existing keys.
+	d, err = Open("", &Options{
+		FS:               mem,
+		SecondaryIndexes: []SecondaryIndex{byCityIndex},
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.Equal(t, "", scanIndex(t, d, nil))
+
+	// Start the backfill paused and cancel it, which leaves its resume key at
+	// the start.
+	b, err := d.BackfillSecondaryIndex(byCityIndex.Name, []byte("user/1000"))
+	require.NoError(t, err)
+	b.Pause()
+	b.Cancel()
+	require.ErrorIs(t, b.Wait(), ErrIndexBackfillCanceled)
+	p := b.Progress()
+	require.True(t, p.Done)
+
+	// Resume from the persisted progress, and then backfill the keys before
+	// the original start key.
+	b, err = d.BackfillSecondaryIndex(byCityIndex.Name, p.ResumeKey)
+	require.NoError(t, err)
+	b.Pause()
+	b.Resume()
+	require.NoError(t, b.Wait())
+	p = b.Progress()
+	require.True(t, p.Done)
+	require.Nil(t, p.ResumeKey)
+
+	b, err = d.BackfillSecondaryIndex(byCityIndex.Name, nil)
+	require.NoError(t, err)
+	require.NoError(t, b.Wait())
+	// The backfill skips the index's own entries.
+	require.Equal(t, uint64(n), b.Progress().Keys)
+
+	iter, err := d.NewIndexIter(byCityIndex.Name, &IndexIterOptions{LowerBound: []byte("1"), UpperBound: []byte("2")})
+	require.NoError(t, err)
+	count := 0
+	for valid := iter.First(); valid; valid = iter.Next() {
+		require.Equal(t, "1", string(iter.Value()))
+		count++
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, n/3, count)
+
+	// Backfills delete the stale entries left behind by writes that don't
+	// maintain the index.
+	require.NoError(t, d.DeleteRange([]byte("user/0000"), []byte("user/0100"), nil))
+	b, err = d.BackfillSecondaryIndex(byCityIndex.Name, []byte("user/2000"))
+	require.NoError(t, err)
+	require.NoError(t, b.Wait())
+	require.Equal(t, uint64(100), b.Progress().StaleEntries)
+	require.Equal(t, n-100, strings.Count(scanRawIndex(t, d), " ")+1)
+
+	// Closing the DB cancels running backfills.
+	_, err = d.BackfillSecondaryIndex(byCityIndex.Name, nil)
+	require.NoError(t, err)
+}
+
+func TestSecondaryIndexOptionsValidate(t *testing.T) {
+	opts := &Options{SecondaryIndexes: []SecondaryIndex{
+		byCityIndex,
+		{Name: "by-city", Prefix: []byte("idx/"), Extract: byCityIndex.Extract},
+		{Name: "empty"},
+	}}
+	err := opts.EnsureDefaults().Validate()
+	require.Error(t, err)
+	for _, s := range []string{
+		`"by-city" is used more than once`,
+		`overlapping prefixes`,
+		`"empty" must have a Prefix`,
+		`"empty" must have an Extract function`,
+	} {
+		require.Contains(t, err.Error(), s)
+	}
+}
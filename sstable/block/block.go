@@ -251,6 +251,10 @@ type IterTransforms struct {
 	HideObsoletePoints bool
 	SyntheticPrefix    SyntheticPrefix
 	SyntheticSuffix    SyntheticSuffix
+
+	// KeysOnly, if true, surfaces every key with an empty value. Values are
+	// never retrieved, so values stored in value blocks are never loaded.
+	KeysOnly bool
 }
 
 // NoTransforms is the default value for IterTransforms.
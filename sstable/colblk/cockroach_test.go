@@ -311,6 +311,8 @@ func (ks *cockroachKeySeeker) seekGEOnSuffix(index int, seekSuffix []byte) (row
 func (ks *cockroachKeySeeker) MaterializeUserKey(ki *PrefixBytesIter, prevRow, row int) []byte {
 	if prevRow+1 == row && prevRow >= 0 {
 		ks.roachKeys.SetNext(ki)
+	} else if prevRow-1 == row && row >= 0 {
+		ks.roachKeys.SetPrev(ki, prevRow)
 	} else {
 		ks.roachKeys.SetAt(ki, row)
 	}
@@ -372,6 +374,8 @@ func (ks *cockroachKeySeeker) MaterializeUserKeyWithSyntheticSuffix(
 ) []byte {
 	if prevRow+1 == row && prevRow >= 0 {
 		ks.roachKeys.SetNext(ki)
+	} else if prevRow-1 == row && row >= 0 {
+		ks.roachKeys.SetPrev(ki, prevRow)
 	} else {
 		ks.roachKeys.SetAt(ki, row)
 	}
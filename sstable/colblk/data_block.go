@@ -368,6 +368,8 @@ func (ks *defaultKeySeeker) seekGEOnSuffix(index int, suffix []byte) (row int) {
 func (ks *defaultKeySeeker) MaterializeUserKey(keyIter *PrefixBytesIter, prevRow, row int) []byte {
 	if row == prevRow+1 && prevRow >= 0 {
 		ks.prefixes.SetNext(keyIter)
+	} else if row == prevRow-1 && row >= 0 {
+		ks.prefixes.SetPrev(keyIter, prevRow)
 	} else {
 		ks.prefixes.SetAt(keyIter, row)
 	}
@@ -387,6 +389,8 @@ func (ks *defaultKeySeeker) MaterializeUserKeyWithSyntheticSuffix(
 ) []byte {
 	if row == prevRow+1 && prevRow >= 0 {
 		ks.prefixes.SetNext(keyIter)
+	} else if row == prevRow-1 && row >= 0 {
+		ks.prefixes.SetPrev(keyIter, prevRow)
 	} else {
 		ks.prefixes.SetAt(keyIter, row)
 	}
@@ -1149,12 +1153,17 @@ func (i *DataBlockIter) Next() *base.InternalKV {
 			i.kv.K.SetSeqNum(base.SeqNum(n))
 		}
 	}
-	// Inline i.r.values.At(row).
-	v := i.r.values.slice(i.r.values.offsets.At2(i.row))
-	if i.r.isValueExternal.At(i.row) {
-		i.kv.V = i.getLazyValuer.GetLazyValueForPrefixAndValueHandle(v)
+	if i.transforms.KeysOnly {
+		// Skip the value columns entirely.
+		i.kv.V = base.LazyValue{}
 	} else {
-		i.kv.V = base.MakeInPlaceValue(v)
+		// Inline i.r.values.At(row).
+		v := i.r.values.slice(i.r.values.offsets.At2(i.row))
+		if i.r.isValueExternal.At(i.row) {
+			i.kv.V = i.getLazyValuer.GetLazyValueForPrefixAndValueHandle(v)
+		} else {
+			i.kv.V = base.MakeInPlaceValue(v)
+		}
 	}
 	i.kvRow = i.row
 	return &i.kv
@@ -1315,13 +1324,18 @@ func (i *DataBlockIter) decodeRow() *base.InternalKV {
 				i.kv.K.SetSeqNum(base.SeqNum(n))
 			}
 		}
-		// Inline i.r.values.At(row).
-		startOffset := i.r.values.offsets.At(i.row)
-		v := unsafe.Slice((*byte)(i.r.values.ptr(startOffset)), i.r.values.offsets.At(i.row+1)-startOffset)
-		if i.r.isValueExternal.At(i.row) {
-			i.kv.V = i.getLazyValuer.GetLazyValueForPrefixAndValueHandle(v)
+		if i.transforms.KeysOnly {
+			// Skip the value columns entirely.
+			i.kv.V = base.LazyValue{}
 		} else {
-			i.kv.V = base.MakeInPlaceValue(v)
+			// Inline i.r.values.At(row).
+			startOffset := i.r.values.offsets.At(i.row)
+			v := unsafe.Slice((*byte)(i.r.values.ptr(startOffset)), i.r.values.offsets.At(i.row+1)-startOffset)
+			if i.r.isValueExternal.At(i.row) {
+				i.kv.V = i.getLazyValuer.GetLazyValueForPrefixAndValueHandle(v)
+			} else {
+				i.kv.V = base.MakeInPlaceValue(v)
+			}
 		}
 		i.kvRow = i.row
 		return &i.kv
@@ -376,6 +376,50 @@ func (b *PrefixBytes) SetNext(it *PrefixBytesIter) {
 		uintptr(rowSuffixLen))
 }
 
+// SetPrev updates the provided PrefixBytesIter to hold the previous []byte
+// slice in the PrefixBytes. SetPrev requires the provided iter to currently
+// hold the i'th slice, positioned by SetAt, SetNext or SetPrev, and requires
+// i > 0. The PrefixBytesIter's buffer must be sufficiently large to hold the
+// previous []byte slice, and the caller is required to statically ensure this.
+func (b *PrefixBytes) SetPrev(it *PrefixBytesIter, i int) {
+	// If the previous row is in the same bundle, we can take a fast path of
+	// only updating the per-row suffix.
+	firstIndex := it.nextBundleOffsetIndex - (1 << b.bundleShift)
+	if it.offsetIndex <= firstIndex {
+		// The current row is the first row of its bundle, so the previous row
+		// has a different bundle prefix.
+		b.SetAt(it, i-1)
+		return
+	}
+	low := b.rawBytes.offsets.At(it.offsetIndex)
+	high := b.rawBytes.offsets.At(it.offsetIndex + 1)
+	it.offsetIndex--
+	if low == high {
+		// The start and end offsets are equal, indicating that the current key
+		// is a duplicate of the previous key. There's nothing left to do, we can
+		// leave buf as-is.
+		return
+	}
+	// Find the previous row's suffix. If it's empty, the previous row is a
+	// duplicate, and we need to step back until we find a non-empty slice or
+	// the start of the bundle (see rowSuffixOffsets).
+	high = low
+	low = b.rawBytes.offsets.At(it.offsetIndex)
+	for j := it.offsetIndex; low == high && j > firstIndex; {
+		j--
+		high = low
+		low = b.rawBytes.offsets.At(j)
+	}
+	rowSuffixLen := high - low
+	it.buf = it.buf[:it.sharedAndBundlePrefixLen+rowSuffixLen]
+	// Copy in the per-row suffix.
+	ptr := unsafe.Pointer(unsafe.SliceData(it.buf))
+	memmove(
+		unsafe.Pointer(uintptr(ptr)+uintptr(it.sharedAndBundlePrefixLen)),
+		unsafe.Pointer(uintptr(b.rawBytes.data)+uintptr(low)),
+		uintptr(rowSuffixLen))
+}
+
 // SharedPrefix return a []byte of the shared prefix that was extracted from
 // all of the values in the Bytes vector. The returned slice should not be
 // mutated.
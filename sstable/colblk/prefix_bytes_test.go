@@ -205,6 +205,27 @@ func TestPrefixBytesRandomized(t *testing.T) {
 			require.LessOrEqual(t, idx, j)
 			require.Equal(t, userKeys[idx], userKeys[j])
 		}
+
+		// Ensure that iterating forward and backward through the keys with a
+		// PrefixBytesIter produces identical keys.
+		var it PrefixBytesIter
+		it.Init(maxLen, nil)
+		for j := 0; j < n; j++ {
+			if j == 0 {
+				pb.SetAt(&it, j)
+			} else {
+				pb.SetNext(&it)
+			}
+			require.Equal(t, userKeys[j], it.buf, "SetNext at index %d", j)
+		}
+		for j := n - 1; j >= 0; j-- {
+			if j == n-1 {
+				pb.SetAt(&it, j)
+			} else {
+				pb.SetPrev(&it, j+1)
+			}
+			require.Equal(t, userKeys[j], it.buf, "SetPrev at index %d", j)
+		}
 	}
 	for _, maxKeyCount := range []int{10, 25, 50, 100, 1000, 10000} {
 		for _, maxKeyLen := range []int{10, 100} {
@@ -297,6 +318,27 @@ func BenchmarkPrefixBytes(b *testing.B) {
 				}
 			}
 		})
+
+		b.Run("reverse-iteration", func(b *testing.B) {
+			n := len(userKeys)
+			buf = build(n)
+			pb, _ := DecodePrefixBytes(buf, 0, n)
+			b.ResetTimer()
+			var pbi PrefixBytesIter
+			pbi.buf = make([]byte, 0, maxLen)
+			for i := 0; i < b.N; i++ {
+				j := n - 1 - i%n
+				if j == n-1 {
+					pb.SetAt(&pbi, j)
+				} else {
+					pb.SetPrev(&pbi, j+1)
+				}
+				if invariants.Enabled && !bytes.Equal(pbi.buf, userKeys[j]) {
+					b.Fatalf("Constructed key %q (%q, %q, %q) for index %d; expected %q",
+						pbi.buf, pb.SharedPrefix(), pb.RowBundlePrefix(j), pb.RowSuffix(j), j, userKeys[j])
+				}
+			}
+		})
 	}
 	for _, alphaLen := range []int{2, 5, 26} {
 		b.Run(fmt.Sprintf("alphaLen=%d", alphaLen), func(b *testing.B) {
@@ -55,6 +55,7 @@ type RawColumnWriter struct {
 	topLevelIndexBlock colblk.IndexBlockWriter
 	rangeDelBlock      colblk.KeyspanBlockWriter
 	rangeKeyBlock      colblk.KeyspanBlockWriter
+	rangeMergeBlock    colblk.KeyspanBlockWriter
 	valueBlock         *valueBlockWriter // nil iff WriterOptions.DisableValueBlocks=true
 	// filter accumulates the filter block. If populated, the filter ingests
 	// either the output of w.split (i.e. a prefix extractor) if w.split is not
@@ -131,6 +132,7 @@ func NewColumnarWriter(writable objstorage.Writable, o WriterOptions) *RawColumn
 	w.topLevelIndexBlock.Init()
 	w.rangeDelBlock.Init(w.comparer.Equal)
 	w.rangeKeyBlock.Init(w.comparer.Equal)
+	w.rangeMergeBlock.Init(w.comparer.Equal)
 	if !o.DisableValueBlocks {
 		w.valueBlock = newValueBlockWriter(
 			w.dataBlockOptions.blockSize, w.dataBlockOptions.blockSizeThreshold,
@@ -201,6 +203,9 @@ func (w *RawColumnWriter) EstimatedSize() uint64 {
 	if w.rangeKeyBlock.KeyCount() > 0 {
 		sz += uint64(w.rangeKeyBlock.Size())
 	}
+	if w.rangeMergeBlock.KeyCount() > 0 {
+		sz += uint64(w.rangeMergeBlock.Size())
+	}
 	for _, blk := range w.valueBlock.blocks {
 		sz += uint64(blk.block.LengthWithTrailer())
 	}
@@ -246,7 +251,7 @@ func (w *RawColumnWriter) Metadata() (*WriterMetadata, error) {
 }
 
 // EncodeSpan encodes the keys in the given span. The span can contain either
-// only RANGEDEL keys or only range keys.
+// only RANGEDEL keys, only RANGEMERGE keys or only range keys.
 func (w *RawColumnWriter) EncodeSpan(span keyspan.Span) error {
 	if span.Empty() {
 		return nil
@@ -256,7 +261,10 @@ func (w *RawColumnWriter) EncodeSpan(span keyspan.Span) error {
 	}
 
 	blockWriter := &w.rangeKeyBlock
-	if span.Keys[0].Kind() == base.InternalKeyKindRangeDelete {
+	switch span.Keys[0].Kind() {
+	case base.InternalKeyKindRangeMerge:
+		blockWriter = &w.rangeMergeBlock
+	case base.InternalKeyKindRangeDelete:
 		blockWriter = &w.rangeDelBlock
 		// Update range delete properties.
 		// NB: These properties are computed differently than the rowblk sstable
@@ -267,7 +275,7 @@ func (w *RawColumnWriter) EncodeSpan(span keyspan.Span) error {
 		w.props.NumEntries += count
 		w.props.NumDeletions += count
 		w.props.NumRangeDeletions += count
-	} else {
+	default:
 		// Update range key properties.
 		// NB: These properties are computed differently than the rowblk sstable
 		// writer because this writer does not flatten them into row key-value
@@ -329,7 +337,8 @@ func (w *RawColumnWriter) AddWithForceObsolete(
 ) error {
 	switch key.Kind() {
 	case base.InternalKeyKindRangeDelete, base.InternalKeyKindRangeKeySet,
-		base.InternalKeyKindRangeKeyUnset, base.InternalKeyKindRangeKeyDelete:
+		base.InternalKeyKindRangeKeyUnset, base.InternalKeyKindRangeKeyDelete,
+		base.InternalKeyKindRangeMerge:
 		return errors.Newf("%s must be added through EncodeSpan", key.Kind())
 	case base.InternalKeyKindMerge:
 		if w.opts.IsStrictObsolete {
@@ -879,6 +888,16 @@ func (w *RawColumnWriter) Close() (err error) {
 		}
 	}
 
+	// Write the range merge block if non-empty.
+	if w.rangeMergeBlock.KeyCount() > 0 {
+		sm, la := w.rangeMergeBlock.UnsafeBoundaryKeys()
+		w.meta.SetSmallestRangeMergeKey(sm)
+		w.meta.SetLargestRangeMergeKey(la)
+		if _, err := w.layout.WriteRangeMergeBlock(w.rangeMergeBlock.Finish()); err != nil {
+			return err
+		}
+	}
+
 	// Write out the value block.
 	if w.valueBlock != nil {
 		_, vbStats, err := w.valueBlock.finish(&w.layout, w.layout.offset)
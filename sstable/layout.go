@@ -37,6 +37,7 @@ type Layout struct {
 	Filter     []NamedBlockHandle
 	RangeDel   block.Handle
 	RangeKey   block.Handle
+	RangeMerge block.Handle
 	ValueBlock []block.Handle
 	ValueIndex block.Handle
 	Properties block.Handle
@@ -81,6 +82,9 @@ func (l *Layout) orderedBlocks() []NamedBlockHandle {
 	if l.RangeKey.Length != 0 {
 		blocks = append(blocks, NamedBlockHandle{l.RangeKey, "range-key"})
 	}
+	if l.RangeMerge.Length != 0 {
+		blocks = append(blocks, NamedBlockHandle{l.RangeMerge, "range-merge"})
+	}
 	for i := range l.ValueBlock {
 		blocks = append(blocks, NamedBlockHandle{l.ValueBlock[i], "value-block"})
 	}
@@ -459,6 +463,7 @@ func decodeLayout(comparer *base.Comparer, data []byte) (Layout, error) {
 		Properties: meta[metaPropertiesName],
 		RangeDel:   meta[metaRangeDelV2Name],
 		RangeKey:   meta[metaRangeKeyName],
+		RangeMerge: meta[metaRangeMergeName],
 		ValueIndex: vbih.h,
 		Footer:     foot.footerBH,
 		Format:     foot.format,
@@ -738,6 +743,13 @@ func (w *layoutWriter) WriteRangeKeyBlock(b []byte) (block.Handle, error) {
 	return w.writeNamedBlock(b, metaRangeKeyName)
 }
 
+// WriteRangeMergeBlock constructs a trailer for the provided range merge block
+// and writes the block and trailer to the writer. It automatically adds the
+// range merge block to the file's meta index when the writer is finished.
+func (w *layoutWriter) WriteRangeMergeBlock(b []byte) (block.Handle, error) {
+	return w.writeNamedBlock(b, metaRangeMergeName)
+}
+
 // WriteRangeDeletionBlock constructs a trailer for the provided range deletion
 // block and writes the block and trailer to the writer. It automatically adds
 // the range deletion block to the file's meta index when the writer is
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"slices"
+	"sync"
 	"time"
 
 	"github.com/cespare/xxhash/v2"
@@ -80,6 +81,9 @@ type Reader struct {
 	Properties   Properties
 	tableFormat  TableFormat
 	checksumType block.ChecksumType
+	// hasRangeMerges is true if the table contains a range-merge block. See
+	// rangeMergeHandle.
+	hasRangeMerges bool
 
 	// metaBufferPool is a buffer pool used exclusively when opening a table and
 	// loading its meta blocks. metaBufferPoolAlloc is used to batch-allocate
@@ -321,6 +325,34 @@ func (r *Reader) NewRawRangeKeyIter(
 	return keyspan.MaybeAssert(iter, r.Compare), nil
 }
 
+// NewRawRangeMergeIter returns an internal iterator for the contents of the
+// range-merge block for the table. Returns nil if the table does not contain
+// any range merges.
+func (r *Reader) NewRawRangeMergeIter(
+	ctx context.Context, transforms FragmentIterTransforms,
+) (iter keyspan.FragmentIterator, err error) {
+	if !r.hasRangeMerges {
+		return nil, nil
+	}
+	bh, err := r.rangeMergeHandle(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h, err := r.readRangeMerge(ctx, bh, nil /* stats */, nil /* iterStats */)
+	if err != nil {
+		return nil, err
+	}
+	if r.tableFormat.BlockColumnar() {
+		iter = colblk.NewKeyspanIter(r.Compare, h, transforms)
+	} else {
+		iter, err = rowblk.NewFragmentIter(r.cacheOpts.FileNum, r.Compare, r.Comparer.CompareSuffixes, r.Split, h, transforms)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keyspan.MaybeAssert(iter, r.Compare), nil
+}
+
 func (r *Reader) readIndex(
 	ctx context.Context,
 	readHandle objstorage.ReadHandle,
@@ -341,6 +373,22 @@ func (r *Reader) readFilter(
 	return r.readBlock(ctx, r.filterBH, nil /* transform */, readHandle, stats, iterStats, nil /* buffer pool */)
 }
 
+// MayContainCached consults the table's filter for prefix without performing
+// any I/O. If the table has a filter and its filter block is in the block
+// cache, it returns whether the table may contain keys with the prefix and
+// ok=true. Otherwise, it returns mayContain=true and ok=false.
+func (r *Reader) MayContainCached(prefix []byte) (mayContain, ok bool) {
+	if r.tableFilter == nil || r.cacheOpts.Cache == nil {
+		return true, false
+	}
+	h := r.cacheOpts.Cache.Get(r.cacheOpts.CacheID, r.cacheOpts.FileNum, r.filterBH.Offset)
+	if h.Get() == nil {
+		return true, false
+	}
+	defer h.Release()
+	return r.tableFilter.mayContain(h.Get(), prefix), true
+}
+
 func (r *Reader) readRangeDel(
 	ctx context.Context, stats *base.InternalIteratorStats, iterStats *iterStatsAccumulator,
 ) (block.BufferHandle, error) {
@@ -355,6 +403,39 @@ func (r *Reader) readRangeKey(
 	return r.readBlock(ctx, r.rangeKeyBH, nil /* transform */, nil /* readHandle */, stats, iterStats, nil /* buffer pool */)
 }
 
+func (r *Reader) readRangeMerge(
+	ctx context.Context,
+	bh block.Handle,
+	stats *base.InternalIteratorStats,
+	iterStats *iterStatsAccumulator,
+) (block.BufferHandle, error) {
+	ctx = objiotracing.WithBlockType(ctx, objiotracing.MetadataBlock)
+	return r.readBlock(ctx, bh, nil /* transform */, nil /* readHandle */, stats, iterStats, nil /* buffer pool */)
+}
+
+// HasRangeMerges returns true if the table contains any range merges.
+func (r *Reader) HasRangeMerges() bool {
+	return r.hasRangeMerges
+}
+
+// rangeMergeHandle returns the handle of the table's range-merge block. Range
+// merges are rare, so rather than retaining the handle in every Reader, it's
+// looked up in the metaindex block when needed.
+func (r *Reader) rangeMergeHandle(ctx context.Context) (block.Handle, error) {
+	ctx = objiotracing.WithBlockType(ctx, objiotracing.MetadataBlock)
+	b, err := r.readBlock(ctx, r.metaIndexBH, nil /* transform */, nil /* readHandle */, nil, /* stats */
+		nil /* iterStats */, nil /* buffer pool */)
+	if err != nil {
+		return block.Handle{}, err
+	}
+	defer b.Release()
+	meta, _, err := decodeMetaindex(b.Get())
+	if err != nil {
+		return block.Handle{}, err
+	}
+	return meta[metaRangeMergeName], nil
+}
+
 func checkChecksum(
 	checksumType block.ChecksumType, b []byte, bh block.Handle, fileNum base.DiskFileNum,
 ) error {
@@ -573,6 +654,7 @@ func (r *Reader) readMetaindex(
 	if bh, ok := meta[metaRangeKeyName]; ok {
 		r.rangeKeyBH = bh
 	}
+	_, r.hasRangeMerges = meta[metaRangeMergeName]
 
 	for name, fp := range filters {
 		if bh, ok := meta["fullfilter."+name]; ok {
@@ -603,6 +685,12 @@ func (r *Reader) Layout() (*Layout, error) {
 	if r.filterBH.Length > 0 {
 		l.Filter = []NamedBlockHandle{{Name: "fullfilter." + r.tableFilter.policy.Name(), Handle: r.filterBH}}
 	}
+	if r.hasRangeMerges {
+		var err error
+		if l.RangeMerge, err = r.rangeMergeHandle(context.Background()); err != nil {
+			return nil, err
+		}
+	}
 
 	indexH, err := r.readIndex(context.Background(), nil, nil, nil)
 	if err != nil {
@@ -706,7 +794,7 @@ func (r *Reader) ValidateBlockChecksums() error {
 	for _, bh := range l.Filter {
 		blocks = append(blocks, bh.Handle)
 	}
-	blocks = append(blocks, l.RangeDel, l.RangeKey, l.Properties, l.MetaIndex)
+	blocks = append(blocks, l.RangeDel, l.RangeKey, l.RangeMerge, l.Properties, l.MetaIndex)
 
 	// Sorting by offset ensures we are performing a sequential scan of the
 	// file.
@@ -871,6 +959,253 @@ func estimateDiskUsage[I any, PI indexBlockIterator[I]](
 		endBH.Offset + endBH.Length + block.TrailerLen - startBH.Offset), nil
 }
 
+// PrefetchDataBlocks reads up to n data blocks into the block cache, starting
+// with the block that may contain key and proceeding forward, or backward if
+// reverse is set. A nil key starts from the first block, or the last if
+// reverse is set. The blocks are read concurrently, which hides the latency of
+// remote storage from an iterator that later reads them in sequence.
+func (r *Reader) PrefetchDataBlocks(ctx context.Context, key []byte, n int, reverse bool) error {
+	var handles []block.Handle
+	var err error
+	if !r.tableFormat.BlockColumnar() {
+		handles, err = dataBlockHandles[rowblk.IndexIter, *rowblk.IndexIter](ctx, r, key, n, reverse)
+	} else {
+		handles, err = dataBlockHandles[colblk.IndexIter, *colblk.IndexIter](ctx, r, key, n, reverse)
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.readBlocksConcurrently(ctx, handles)
+}
+
+// PrefetchPointKeys reads the data blocks that may contain the provided keys
+// into the block cache, in preparation for looking up the keys. The keys must
+// be sorted. If the table has a filter, its filter block is read once and
+// consulted for all of the keys, and the blocks of keys that the filter
+// excludes aren't read. Keys that fall within the same block share the read of
+// the block, and the blocks are read concurrently.
+func (r *Reader) PrefetchPointKeys(ctx context.Context, keys [][]byte) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.tableFilter != nil {
+		filterH, err := r.readFilter(ctx, nil /* readHandle */, nil /* stats */, nil /* iterStats */)
+		if err != nil {
+			return err
+		}
+		filtered := keys[:0:0]
+		for _, key := range keys {
+			if r.tableFilter.mayContain(filterH.Get(), key[:r.Split(key)]) {
+				filtered = append(filtered, key)
+			}
+		}
+		filterH.Release()
+		keys = filtered
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var handles []block.Handle
+	var err error
+	if !r.tableFormat.BlockColumnar() {
+		handles, err = pointBlockHandles[rowblk.IndexIter, *rowblk.IndexIter](ctx, r, keys)
+	} else {
+		handles, err = pointBlockHandles[colblk.IndexIter, *colblk.IndexIter](ctx, r, keys)
+	}
+	if err != nil {
+		return err
+	}
+	return r.readBlocksConcurrently(ctx, handles)
+}
+
+// readBlocksConcurrently reads the provided data blocks into the block cache,
+// reading each block on its own goroutine.
+func (r *Reader) readBlocksConcurrently(ctx context.Context, handles []block.Handle) error {
+	ctx = objiotracing.WithBlockType(ctx, objiotracing.DataBlock)
+	errs := make([]error, len(handles))
+	var wg sync.WaitGroup
+	wg.Add(len(handles))
+	for i := range handles {
+		go func(i int) {
+			defer wg.Done()
+			h, err := r.readBlock(ctx, handles[i], nil, /* transform */
+				nil /* readHandle */, nil /* stats */, nil /* iterStats */, nil /* buffer pool */)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			h.Release()
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// dataBlockHandles returns the handles of up to n data blocks, starting with
+// the block that may contain key. See PrefetchDataBlocks.
+func dataBlockHandles[I any, PI indexBlockIterator[I]](
+	ctx context.Context, r *Reader, key []byte, n int, reverse bool,
+) ([]block.Handle, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	// position positions an index iterator at the first entry to consider.
+	position := func(it PI) bool {
+		switch {
+		case key == nil && reverse:
+			return it.Last()
+		case key == nil:
+			return it.First()
+		case it.SeekGE(key):
+			return true
+		default:
+			// The key lies after the table's last block.
+			return reverse && it.Last()
+		}
+	}
+	step := func(it PI) bool {
+		if reverse {
+			return it.Prev()
+		}
+		return it.Next()
+	}
+
+	indexH, err := r.readIndex(ctx, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer indexH.Release()
+	var indexIter PI = new(I)
+	if err := indexIter.InitHandle(r.Compare, r.Split, indexH, NoTransforms); err != nil {
+		return nil, err
+	}
+
+	handles := make([]block.Handle, 0, n)
+	appendHandles := func(it PI) error {
+		for valid := position(it); valid && len(handles) < n; valid = step(it) {
+			bhp, err := it.BlockHandleWithProperties()
+			if err != nil {
+				return errCorruptIndexEntry(err)
+			}
+			handles = append(handles, bhp.Handle)
+		}
+		return nil
+	}
+	if r.Properties.IndexPartitions == 0 {
+		return handles, appendHandles(indexIter)
+	}
+	// The index is partitioned: indexIter is the top-level index, whose
+	// entries point to the index blocks holding the data block handles.
+	for valid := position(indexIter); valid && len(handles) < n; valid = step(indexIter) {
+		bhp, err := indexIter.BlockHandleWithProperties()
+		if err != nil {
+			return nil, errCorruptIndexEntry(err)
+		}
+		partitionH, err := r.readBlock(ctx, bhp.Handle, nil, /* transform */
+			nil /* readHandle */, nil /* stats */, nil /* iterStats */, nil /* buffer pool */)
+		if err != nil {
+			return nil, err
+		}
+		var partitionIter PI = new(I)
+		err = partitionIter.InitHandle(r.Compare, r.Split, partitionH, NoTransforms)
+		if err == nil {
+			err = appendHandles(partitionIter)
+		}
+		partitionH.Release()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return handles, nil
+}
+
+// pointBlockHandles returns the distinct handles of the data blocks that may
+// contain the provided sorted keys. See PrefetchPointKeys.
+func pointBlockHandles[I any, PI indexBlockIterator[I]](
+	ctx context.Context, r *Reader, keys [][]byte,
+) ([]block.Handle, error) {
+	indexH, err := r.readIndex(ctx, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer indexH.Release()
+	var indexIter PI = new(I)
+	if err := indexIter.InitHandle(r.Compare, r.Split, indexH, NoTransforms); err != nil {
+		return nil, err
+	}
+
+	var handles []block.Handle
+	// appendHandle appends the handle of the block at the index iterator's
+	// position, unless a previous key fell within the same block.
+	appendHandle := func(it PI) error {
+		bhp, err := it.BlockHandleWithProperties()
+		if err != nil {
+			return errCorruptIndexEntry(err)
+		}
+		if n := len(handles); n == 0 || handles[n-1] != bhp.Handle {
+			handles = append(handles, bhp.Handle)
+		}
+		return nil
+	}
+	if r.Properties.IndexPartitions == 0 {
+		for _, key := range keys {
+			if !indexIter.SeekGE(key) {
+				// The remaining keys lie after the table's last block.
+				break
+			}
+			if err := appendHandle(indexIter); err != nil {
+				return nil, err
+			}
+		}
+		return handles, nil
+	}
+
+	// The index is partitioned: indexIter is the top-level index, whose
+	// entries point to the index blocks holding the data block handles.
+	// Consecutive keys usually fall within the same partition, which is only
+	// read once.
+	var partition block.Handle
+	var partitionH block.BufferHandle
+	var partitionIter PI
+	defer func() {
+		if partitionIter != nil {
+			partitionH.Release()
+		}
+	}()
+	for _, key := range keys {
+		if !indexIter.SeekGE(key) {
+			break
+		}
+		bhp, err := indexIter.BlockHandleWithProperties()
+		if err != nil {
+			return nil, errCorruptIndexEntry(err)
+		}
+		if partitionIter == nil || bhp.Handle != partition {
+			h, err := r.readBlock(ctx, bhp.Handle, nil, /* transform */
+				nil /* readHandle */, nil /* stats */, nil /* iterStats */, nil /* buffer pool */)
+			if err != nil {
+				return nil, err
+			}
+			if partitionIter != nil {
+				partitionH.Release()
+			}
+			partition, partitionH = bhp.Handle, h
+			partitionIter = new(I)
+			if err := partitionIter.InitHandle(r.Compare, r.Split, partitionH, NoTransforms); err != nil {
+				return nil, err
+			}
+		}
+		if partitionIter.SeekGE(key) {
+			if err := appendHandle(partitionIter); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return handles, nil
+}
+
 // TableFormat returns the format version for the table.
 func (r *Reader) TableFormat() (TableFormat, error) {
 	if r.err != nil {
@@ -25,6 +25,10 @@ type CommonReader interface {
 		ctx context.Context, transforms FragmentIterTransforms,
 	) (keyspan.FragmentIterator, error)
 
+	NewRawRangeMergeIter(
+		ctx context.Context, transforms FragmentIterTransforms,
+	) (keyspan.FragmentIterator, error)
+
 	NewPointIter(
 		ctx context.Context,
 		transforms IterTransforms,
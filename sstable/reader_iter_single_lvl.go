@@ -232,7 +232,7 @@ func newColumnBlockSingleLevelIterator(
 		stats, categoryAndQoS, statsCollector, bufferPool,
 	)
 	var getLazyValuer block.GetLazyValueForPrefixAndValueHandler
-	if r.Properties.NumValueBlocks > 0 {
+	if r.Properties.NumValueBlocks > 0 && !transforms.KeysOnly {
 		// NB: we cannot avoid this ~248 byte allocation, since valueBlockReader
 		// can outlive the singleLevelIterator due to be being embedded in a
 		// LazyValue. This consumes ~2% in microbenchmark CPU profiles, but we
@@ -297,7 +297,7 @@ func newRowBlockSingleLevelIterator(
 		stats, categoryAndQoS, statsCollector, bufferPool,
 	)
 	if r.tableFormat >= TableFormatPebblev3 {
-		if r.Properties.NumValueBlocks > 0 {
+		if r.Properties.NumValueBlocks > 0 && !transforms.KeysOnly {
 			// NB: we cannot avoid this ~248 byte allocation, since valueBlockReader
 			// can outlive the singleLevelIterator due to be being embedded in a
 			// LazyValue. This consumes ~2% in microbenchmark CPU profiles, but we
@@ -177,7 +177,7 @@ func newColumnBlockTwoLevelIterator(
 		false, // Disable the use of the filter block in the second level.
 		stats, categoryAndQoS, statsCollector, bufferPool)
 	var getLazyValuer block.GetLazyValueForPrefixAndValueHandler
-	if r.Properties.NumValueBlocks > 0 {
+	if r.Properties.NumValueBlocks > 0 && !transforms.KeysOnly {
 		// NB: we cannot avoid this ~248 byte allocation, since valueBlockReader
 		// can outlive the singleLevelIterator due to be being embedded in a
 		// LazyValue. This consumes ~2% in microbenchmark CPU profiles, but we
@@ -241,7 +241,7 @@ func newRowBlockTwoLevelIterator(
 		false, // Disable the use of the filter block in the second level.
 		stats, categoryAndQoS, statsCollector, bufferPool)
 	if r.tableFormat >= TableFormatPebblev3 {
-		if r.Properties.NumValueBlocks > 0 {
+		if r.Properties.NumValueBlocks > 0 && !transforms.KeysOnly {
 			// NB: we cannot avoid this ~248 byte allocation, since valueBlockReader
 			// can outlive the singleLevelIterator due to be being embedded in a
 			// LazyValue. This consumes ~2% in microbenchmark CPU profiles, but we
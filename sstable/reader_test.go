@@ -1777,6 +1777,7 @@ func buildBenchmarkTable(
 	c := cache.New(128 << 20)
 	defer c.Unref()
 	r, err := newReader(f1, ReaderOptions{
+		KeySchema: options.KeySchema,
 		internal: sstableinternal.ReaderOptions{
 			CacheOpts: sstableinternal.CacheOptions{
 				Cache: c,
@@ -1918,6 +1919,54 @@ func BenchmarkTableIterPrev(b *testing.B) {
 	}
 }
 
+// BenchmarkTableIterScan compares the throughput of forward and reverse scans
+// over the row-oriented and columnar data block formats. Reverse scans serve
+// queries for the latest N keys.
+func BenchmarkTableIterScan(b *testing.B) {
+	for _, format := range []TableFormat{TableFormatPebblev4, TableFormatPebblev5} {
+		options := WriterOptions{
+			BlockSize:            32 << 10,
+			BlockRestartInterval: 16,
+			Compression:          block.SnappyCompression,
+			TableFormat:          format,
+		}.ensureDefaults()
+		r, _ := buildBenchmarkTable(b, options, false, 0)
+		for _, reverse := range []bool{false, true} {
+			b.Run(fmt.Sprintf("format=%s/reverse=%t", format, reverse), func(b *testing.B) {
+				it, err := r.NewIter(NoTransforms, nil /* lower */, nil /* upper */)
+				require.NoError(b, err)
+
+				b.ResetTimer()
+				var sum int64
+				var kv *base.InternalKV
+				for i := 0; i < b.N; i++ {
+					switch {
+					case kv != nil && reverse:
+						kv = it.Prev()
+					case kv != nil:
+						kv = it.Next()
+					}
+					if kv == nil {
+						if reverse {
+							kv = it.Last()
+						} else {
+							kv = it.First()
+						}
+					}
+					sum += int64(binary.BigEndian.Uint64(kv.K.UserKey))
+				}
+				if testing.Verbose() {
+					fmt.Fprint(io.Discard, sum)
+				}
+
+				b.StopTimer()
+				it.Close()
+			})
+		}
+		r.Close()
+	}
+}
+
 func BenchmarkLayout(b *testing.B) {
 	r, _ := buildBenchmarkTable(b, WriterOptions{}, false, 0)
 	b.ResetTimer()
@@ -2412,3 +2461,223 @@ func newReader(r ReadableFile, o ReaderOptions) (*Reader, error) {
 	}
 	return NewReader(context.Background(), readable, o)
 }
+
+func TestReaderPrefetchDataBlocks(t *testing.T) {
+	for _, format := range []TableFormat{TableFormatPebblev4, TableFormatPebblev5} {
+		for _, twoLevelIndex := range []bool{false, true} {
+			t.Run(fmt.Sprintf("format=%s,two-level-index=%t", format, twoLevelIndex), func(t *testing.T) {
+				// Create an sstable with a data block for each of 20 keys.
+				mem := vfs.NewMem()
+				f, err := mem.Create("test", vfs.WriteCategoryUnspecified)
+				require.NoError(t, err)
+				const blockSize = 32
+				indexBlockSize := 4096
+				if twoLevelIndex {
+					indexBlockSize = 1
+				}
+				writerOpts := WriterOptions{
+					BlockSize:      blockSize,
+					IndexBlockSize: indexBlockSize,
+					TableFormat:    format,
+				}.ensureDefaults()
+				w := NewWriter(objstorageprovider.NewFileWritable(f), writerOpts)
+				key := func(i int) []byte { return []byte{'a' + byte(i)} }
+				for i := 0; i < 20; i++ {
+					require.NoError(t, w.Set(key(i), bytes.Repeat([]byte("v"), blockSize)))
+				}
+				require.NoError(t, w.Close())
+
+				for _, tc := range []struct {
+					key     []byte
+					reverse bool
+					want    []int
+				}{
+					{key: nil, reverse: false, want: []int{0, 1, 2, 3, 4}},
+					{key: nil, reverse: true, want: []int{15, 16, 17, 18, 19}},
+					{key: key(2), reverse: false, want: []int{2, 3, 4, 5, 6}},
+					{key: key(12), reverse: true, want: []int{8, 9, 10, 11, 12}},
+					{key: key(18), reverse: false, want: []int{18, 19}},
+					{key: []byte("z"), reverse: false, want: nil},
+					{key: []byte("z"), reverse: true, want: []int{15, 16, 17, 18, 19}},
+				} {
+					c := cache.New(1 << 20)
+					f, err := mem.Open("test")
+					require.NoError(t, err)
+					cacheOpts := sstableinternal.CacheOptions{Cache: c, CacheID: c.NewID(), FileNum: base.DiskFileNum(1)}
+					r, err := newReader(f, ReaderOptions{
+						KeySchema: writerOpts.KeySchema,
+						internal:  sstableinternal.ReaderOptions{CacheOpts: cacheOpts},
+					})
+					require.NoError(t, err)
+					layout, err := r.Layout()
+					require.NoError(t, err)
+					require.Len(t, layout.Data, 20)
+
+					require.NoError(t, r.PrefetchDataBlocks(context.Background(), tc.key, 5, tc.reverse))
+					var cached []int
+					for i, bh := range layout.Data {
+						if h := c.Get(cacheOpts.CacheID, cacheOpts.FileNum, bh.Offset); h.Get() != nil {
+							cached = append(cached, i)
+							h.Release()
+						}
+					}
+					require.Equal(t, tc.want, cached, "key=%q reverse=%t", tc.key, tc.reverse)
+					require.NoError(t, r.Close())
+					c.Unref()
+				}
+			})
+		}
+	}
+}
+
+func TestReaderPrefetchPointKeys(t *testing.T) {
+	for _, format := range []TableFormat{TableFormatPebblev4, TableFormatPebblev5} {
+		for _, twoLevelIndex := range []bool{false, true} {
+			t.Run(fmt.Sprintf("format=%s,two-level-index=%t", format, twoLevelIndex), func(t *testing.T) {
+				// Create an sstable with a data block for each of 20 keys, and a
+				// filter.
+				mem := vfs.NewMem()
+				f, err := mem.Create("test", vfs.WriteCategoryUnspecified)
+				require.NoError(t, err)
+				const blockSize = 32
+				indexBlockSize := 4096
+				if twoLevelIndex {
+					indexBlockSize = 1
+				}
+				writerOpts := WriterOptions{
+					BlockSize:      blockSize,
+					IndexBlockSize: indexBlockSize,
+					TableFormat:    format,
+					FilterPolicy:   bloom.FilterPolicy(10),
+				}.ensureDefaults()
+				w := NewWriter(objstorageprovider.NewFileWritable(f), writerOpts)
+				key := func(i int) []byte { return []byte{'a' + byte(i)} }
+				for i := 0; i < 20; i++ {
+					require.NoError(t, w.Set(key(i), bytes.Repeat([]byte("v"), blockSize)))
+				}
+				require.NoError(t, w.Close())
+
+				for _, tc := range []struct {
+					keys [][]byte
+					want []int
+				}{
+					{keys: [][]byte{key(0)}, want: []int{0}},
+					{keys: [][]byte{key(2), key(5), key(5), key(19)}, want: []int{2, 5, 19}},
+					// The filter excludes keys that aren't in the table.
+					{keys: [][]byte{key(1), []byte("bb"), []byte("hh"), key(9)}, want: []int{1, 9}},
+					{keys: [][]byte{key(18), []byte("z")}, want: []int{18}},
+					{keys: [][]byte{[]byte("z")}, want: nil},
+				} {
+					c := cache.New(1 << 20)
+					f, err := mem.Open("test")
+					require.NoError(t, err)
+					cacheOpts := sstableinternal.CacheOptions{Cache: c, CacheID: c.NewID(), FileNum: base.DiskFileNum(1)}
+					r, err := newReader(f, ReaderOptions{
+						KeySchema: writerOpts.KeySchema,
+						Filters: map[string]FilterPolicy{
+							writerOpts.FilterPolicy.Name(): writerOpts.FilterPolicy,
+						},
+						internal: sstableinternal.ReaderOptions{CacheOpts: cacheOpts},
+					})
+					require.NoError(t, err)
+					layout, err := r.Layout()
+					require.NoError(t, err)
+					require.Len(t, layout.Data, 20)
+
+					require.NoError(t, r.PrefetchPointKeys(context.Background(), tc.keys))
+					var cached []int
+					for i, bh := range layout.Data {
+						if h := c.Get(cacheOpts.CacheID, cacheOpts.FileNum, bh.Offset); h.Get() != nil {
+							cached = append(cached, i)
+							h.Release()
+						}
+					}
+					require.Equal(t, tc.want, cached, "keys=%q", tc.keys)
+					require.NoError(t, r.Close())
+					c.Unref()
+				}
+			})
+		}
+	}
+}
+
+func TestReaderKeysOnly(t *testing.T) {
+	for _, format := range []TableFormat{TableFormatPebblev4, TableFormatPebblev5} {
+		t.Run(fmt.Sprintf("format=%s", format), func(t *testing.T) {
+			// Create an sstable holding several versions of each key. Older
+			// versions are stored in value blocks.
+			mem := vfs.NewMem()
+			f, err := mem.Create("test", vfs.WriteCategoryUnspecified)
+			require.NoError(t, err)
+			writerOpts := WriterOptions{
+				Comparer:    testkeys.Comparer,
+				TableFormat: format,
+			}.ensureDefaults()
+			w := NewWriter(objstorageprovider.NewFileWritable(f), writerOpts)
+			var want []string
+			for _, k := range []string{"a", "b", "c", "d"} {
+				for ts := 3; ts >= 1; ts-- {
+					key := fmt.Sprintf("%s@%d", k, ts)
+					require.NoError(t, w.Set([]byte(key), []byte("value-"+key)))
+					want = append(want, key)
+				}
+			}
+			require.NoError(t, w.Close())
+
+			f, err = mem.Open("test")
+			require.NoError(t, err)
+			r, err := newReader(f, ReaderOptions{
+				Comparer:  testkeys.Comparer,
+				KeySchema: writerOpts.KeySchema,
+			})
+			require.NoError(t, err)
+			defer r.Close()
+			require.Greater(t, r.Properties.NumValueBlocks, uint64(0))
+
+			scan := func(keysOnly bool, reverse bool) (keys []string, values []string) {
+				var stats base.InternalIteratorStats
+				iter, err := r.NewPointIter(
+					context.Background(), IterTransforms{KeysOnly: keysOnly}, nil /* lower */, nil, /* upper */
+					nil /* filterer */, NeverUseFilterBlock, &stats, CategoryAndQoS{}, nil, /* statsCollector */
+					MakeTrivialReaderProvider(r))
+				require.NoError(t, err)
+				defer func() { require.NoError(t, iter.Close()) }()
+				kv := iter.First()
+				if reverse {
+					kv = iter.Last()
+				}
+				for kv != nil {
+					v, _, err := kv.Value(nil)
+					require.NoError(t, err)
+					keys = append(keys, string(kv.K.UserKey))
+					values = append(values, string(v))
+					if reverse {
+						kv = iter.Prev()
+					} else {
+						kv = iter.Next()
+					}
+				}
+				if keysOnly {
+					require.Zero(t, stats.SeparatedPointValue.ValueBytesFetched)
+				}
+				return keys, values
+			}
+
+			keys, values := scan(false /* keysOnly */, false /* reverse */)
+			require.Equal(t, want, keys)
+			for i := range keys {
+				require.Equal(t, "value-"+keys[i], values[i])
+			}
+			for _, reverse := range []bool{false, true} {
+				keys, values := scan(true /* keysOnly */, reverse)
+				if reverse {
+					slices.Reverse(keys)
+				}
+				require.Equal(t, want, keys)
+				for i := range values {
+					require.Empty(t, values[i])
+				}
+			}
+		})
+	}
+}
@@ -160,6 +160,25 @@ func (v *VirtualReader) NewRawRangeDelIter(
 	), nil
 }
 
+// NewRawRangeMergeIter wraps Reader.NewRawRangeMergeIter.
+func (v *VirtualReader) NewRawRangeMergeIter(
+	ctx context.Context, transforms FragmentIterTransforms,
+) (keyspan.FragmentIterator, error) {
+	iter, err := v.reader.NewRawRangeMergeIter(ctx, transforms)
+	if err != nil {
+		return nil, err
+	}
+	if iter == nil {
+		return nil, nil
+	}
+	// Range merges are included in the point key bounds, so they're truncated
+	// in the same manner as range deletions.
+	return keyspan.Truncate(
+		v.reader.Compare, iter,
+		base.UserKeyBoundsFromInternal(v.vState.lower, v.vState.upper),
+	), nil
+}
+
 // NewRawRangeKeyIter wraps Reader.NewRawRangeKeyIter.
 func (v *VirtualReader) NewRawRangeKeyIter(
 	ctx context.Context, transforms FragmentIterTransforms,
@@ -16,6 +16,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/keyspan"
 	"github.com/cockroachdb/pebble/internal/rangedel"
 	"github.com/cockroachdb/pebble/internal/rangekey"
+	"github.com/cockroachdb/pebble/internal/rangemerge"
 	"github.com/cockroachdb/pebble/internal/treeprinter"
 	"github.com/cockroachdb/pebble/sstable/block"
 )
@@ -121,9 +122,16 @@ func NewFragmentIter(
 // the range del/key block doesn't use prefix compression, so the key/value will
 // be pointing directly into the buffer data.
 func (i *fragmentIter) initSpan(ik base.InternalKey, internalValue []byte) error {
-	if ik.Kind() == base.InternalKeyKindRangeDelete {
+	switch ik.Kind() {
+	case base.InternalKeyKindRangeDelete:
 		i.span = rangedel.Decode(ik, internalValue, i.span.Keys[:0])
-	} else {
+	case base.InternalKeyKindRangeMerge:
+		var err error
+		i.span, err = rangemerge.Decode(ik, internalValue, i.span.Keys[:0])
+		if err != nil {
+			return err
+		}
+	default:
 		var err error
 		i.span, err = rangekey.Decode(ik, internalValue, i.span.Keys[:0])
 		if err != nil {
@@ -145,9 +153,12 @@ func (i *fragmentIter) addToSpan(
 	cmp base.Compare, ik base.InternalKey, internalValue []byte,
 ) error {
 	var err error
-	if ik.Kind() == base.InternalKeyKindRangeDelete {
+	switch ik.Kind() {
+	case base.InternalKeyKindRangeDelete:
 		err = rangedel.DecodeIntoSpan(cmp, ik, internalValue, &i.span)
-	} else {
+	case base.InternalKeyKindRangeMerge:
+		err = rangemerge.DecodeIntoSpan(cmp, ik, internalValue, &i.span)
+	default:
 		err = rangekey.DecodeIntoSpan(cmp, ik, internalValue, &i.span)
 	}
 	return err
@@ -22,6 +22,9 @@ var _ block.IndexBlockIterator = (*IndexIter)(nil)
 func (i *IndexIter) Init(
 	cmp base.Compare, split base.Split, blk []byte, transforms block.IterTransforms,
 ) error {
+	// The values of index entries are block handles, which are needed even
+	// when iterating over keys only.
+	transforms.KeysOnly = false
 	return i.iter.Init(cmp, split, blk, transforms)
 }
 
@@ -29,6 +32,7 @@ func (i *IndexIter) Init(
 func (i *IndexIter) InitHandle(
 	cmp base.Compare, split base.Split, block block.BufferHandle, transforms block.IterTransforms,
 ) error {
+	transforms.KeysOnly = false
 	return i.iter.InitHandle(cmp, split, block, transforms)
 }
 
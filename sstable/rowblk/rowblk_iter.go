@@ -183,7 +183,14 @@ type Iter struct {
 	// restart points.
 	cached    []blockEntry
 	cachedBuf []byte
-	handle    block.BufferHandle
+	// cachedRestart is the index of the restart point from which cached was
+	// most recently populated. Once reverse iteration exhausts cached, the
+	// iterator is positioned at that restart point, and Prev uses
+	// cachedRestart to locate the preceding restart interval without binary
+	// searching the restart points. It's only a hint, and is validated before
+	// use.
+	cachedRestart int32
+	handle        block.BufferHandle
 	// for block iteration for already loaded blocks.
 	firstUserKey      []byte
 	lazyValueHandling struct {
@@ -682,7 +689,9 @@ func (i *Iter) SeekGE(key []byte, flags base.SeekGEFlags) *base.InternalKV {
 
 	if !hiddenPoint && i.cmp(i.ikv.K.UserKey, key) >= 0 {
 		// Initialize i.lazyValue
-		if !i.lazyValueHandling.hasValuePrefix ||
+		if i.transforms.KeysOnly {
+			i.ikv.V = base.LazyValue{}
+		} else if !i.lazyValueHandling.hasValuePrefix ||
 			i.ikv.K.Kind() != base.InternalKeyKindSet {
 			i.ikv.V = base.MakeInPlaceValue(i.val)
 		} else if i.lazyValueHandling.getValue == nil || !block.ValuePrefix(i.val[0]).IsValueHandle() {
@@ -851,6 +860,7 @@ func (i *Iter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
 	// we can stop searching. targetOffset encodes that offset for index.
 	targetOffset := i.restarts
 	i.offset = decodeRestart(i.data[i.restarts+4*(index-1):])
+	i.cachedRestart = index - 1
 	if index < i.numRestarts {
 		targetOffset = decodeRestart(i.data[i.restarts+4*(index):])
 
@@ -903,6 +913,7 @@ func (i *Iter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
 			// replacement, bump the target offset.
 			if i.cmp(i.ikv.K.UserKey, key) < 0 {
 				i.offset = targetOffset
+				i.cachedRestart = index
 				if index+1 < i.numRestarts {
 					// if index+1 is within the i.data bounds, use it to find the target
 					// offset.
@@ -966,7 +977,9 @@ func (i *Iter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
 	if !i.Valid() {
 		return nil
 	}
-	if !i.lazyValueHandling.hasValuePrefix ||
+	if i.transforms.KeysOnly {
+		i.ikv.V = base.LazyValue{}
+	} else if !i.lazyValueHandling.hasValuePrefix ||
 		i.ikv.K.Kind() != base.InternalKeyKindSet {
 		i.ikv.V = base.MakeInPlaceValue(i.val)
 	} else if i.lazyValueHandling.getValue == nil || !block.ValuePrefix(i.val[0]).IsValueHandle() {
@@ -995,7 +1008,9 @@ func (i *Iter) First() *base.InternalKV {
 		return i.Next()
 	}
 	i.maybeReplaceSuffix()
-	if !i.lazyValueHandling.hasValuePrefix ||
+	if i.transforms.KeysOnly {
+		i.ikv.V = base.LazyValue{}
+	} else if !i.lazyValueHandling.hasValuePrefix ||
 		i.ikv.K.Kind() != base.InternalKeyKindSet {
 		i.ikv.V = base.MakeInPlaceValue(i.val)
 	} else if i.lazyValueHandling.getValue == nil || !block.ValuePrefix(i.val[0]).IsValueHandle() {
@@ -1023,6 +1038,7 @@ func (i *Iter) Last() *base.InternalKV {
 
 	// Seek forward from the last restart point.
 	i.offset = decodeRestart(i.data[i.restarts+4*(i.numRestarts-1):])
+	i.cachedRestart = i.numRestarts - 1
 	if !i.Valid() {
 		return nil
 	}
@@ -1041,7 +1057,9 @@ func (i *Iter) Last() *base.InternalKV {
 		return i.Prev()
 	}
 	i.maybeReplaceSuffix()
-	if !i.lazyValueHandling.hasValuePrefix ||
+	if i.transforms.KeysOnly {
+		i.ikv.V = base.LazyValue{}
+	} else if !i.lazyValueHandling.hasValuePrefix ||
 		i.ikv.K.Kind() != base.InternalKeyKindSet {
 		i.ikv.V = base.MakeInPlaceValue(i.val)
 	} else if i.lazyValueHandling.getValue == nil || !block.ValuePrefix(i.val[0]).IsValueHandle() {
@@ -1100,7 +1118,9 @@ start:
 		i.ikv.K.Trailer = base.InternalKeyTrailer(base.InternalKeyKindInvalid)
 		i.ikv.K.UserKey = nil
 	}
-	if !i.lazyValueHandling.hasValuePrefix ||
+	if i.transforms.KeysOnly {
+		i.ikv.V = base.LazyValue{}
+	} else if !i.lazyValueHandling.hasValuePrefix ||
 		i.ikv.K.Kind() != base.InternalKeyKindSet {
 		i.ikv.V = base.MakeInPlaceValue(i.val)
 	} else if i.lazyValueHandling.getValue == nil || !block.ValuePrefix(i.val[0]).IsValueHandle() {
@@ -1392,7 +1412,9 @@ func (i *Iter) nextPrefixV3(succKey []byte) *base.InternalKV {
 			if invariants.Enabled && !i.lazyValueHandling.hasValuePrefix {
 				panic(errors.AssertionFailedf("nextPrefixV3 being run for non-v3 sstable"))
 			}
-			if i.ikv.K.Kind() != base.InternalKeyKindSet {
+			if i.transforms.KeysOnly {
+				i.ikv.V = base.LazyValue{}
+			} else if i.ikv.K.Kind() != base.InternalKeyKindSet {
 				i.ikv.V = base.MakeInPlaceValue(i.val)
 			} else if i.lazyValueHandling.getValue == nil || !block.ValuePrefix(i.val[0]).IsValueHandle() {
 				i.ikv.V = base.MakeInPlaceValue(i.val[1:])
@@ -1449,7 +1471,9 @@ start:
 			i.ikv.K.UserKey = nil
 		}
 		i.cached = i.cached[:n]
-		if !i.lazyValueHandling.hasValuePrefix ||
+		if i.transforms.KeysOnly {
+			i.ikv.V = base.LazyValue{}
+		} else if !i.lazyValueHandling.hasValuePrefix ||
 			i.ikv.K.Kind() != base.InternalKeyKindSet {
 			i.ikv.V = base.MakeInPlaceValue(i.val)
 		} else if i.lazyValueHandling.getValue == nil || !block.ValuePrefix(i.val[0]).IsValueHandle() {
@@ -1470,7 +1494,13 @@ start:
 	targetOffset := i.offset
 	var index int32
 
-	{
+	if h := i.cachedRestart; h < i.numRestarts && decodeRestart(i.data[i.restarts+4*h:]) == targetOffset {
+		// The iterator is positioned at the restart point from which the cache
+		// was populated, so it's the first restart with offset >= targetOffset.
+		// This is the common case when iterating in reverse across restart
+		// intervals.
+		index = h
+	} else {
 		// NB: manually inlined sort.Sort is ~5% faster.
 		//
 		// Define f(-1) == false and f(n) == true.
@@ -1499,8 +1529,10 @@ start:
 	// be equal to targetOffset since the binary search would have selected that
 	// as the index).
 	i.offset = 0
+	i.cachedRestart = 0
 	if index > 0 {
 		i.offset = decodeRestart(i.data[i.restarts+4*(index-1):])
+		i.cachedRestart = index - 1
 	}
 	// TODO(sumeer): why is the else case not an error given targetOffset is a
 	// valid offset.
@@ -1530,7 +1562,9 @@ start:
 		i.synthSuffixBuf = append(i.synthSuffixBuf, i.transforms.SyntheticSuffix...)
 		i.ikv.K.UserKey = i.synthSuffixBuf
 	}
-	if !i.lazyValueHandling.hasValuePrefix ||
+	if i.transforms.KeysOnly {
+		i.ikv.V = base.LazyValue{}
+	} else if !i.lazyValueHandling.hasValuePrefix ||
 		i.ikv.K.Kind() != base.InternalKeyKindSet {
 		i.ikv.V = base.MakeInPlaceValue(i.val)
 	} else if i.lazyValueHandling.getValue == nil || !block.ValuePrefix(i.val[0]).IsValueHandle() {
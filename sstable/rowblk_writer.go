@@ -21,6 +21,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/keyspan"
 	"github.com/cockroachdb/pebble/internal/rangedel"
 	"github.com/cockroachdb/pebble/internal/rangekey"
+	"github.com/cockroachdb/pebble/internal/rangemerge"
 	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/sstable/rowblk"
@@ -90,6 +91,7 @@ type RawRowWriter struct {
 	indexBlock          *indexBlockBuf
 	rangeDelBlock       rowblk.Writer
 	rangeKeyBlock       rowblk.Writer
+	rangeMergeBlock     rowblk.Writer
 	topLevelIndexBlock  rowblk.Writer
 	props               Properties
 	blockPropCollectors []BlockPropertyCollector
@@ -604,6 +606,9 @@ func (w *RawRowWriter) AddWithForceObsolete(
 		w.err = errors.Errorf(
 			"pebble: range keys must be added via one of the RangeKey* functions")
 		return w.err
+	case base.InternalKeyKindRangeMerge:
+		w.err = errors.Errorf("pebble: range merges must be added through EncodeSpan")
+		return w.err
 	}
 	return w.addPoint(key, value, forceObsolete)
 }
@@ -1038,6 +1043,61 @@ func (w *RawRowWriter) addRangeKey(key InternalKey, value []byte) error {
 	return nil
 }
 
+// addRangeMerge adds a range merge key/value pair to the table being written.
+//
+// Range merges must be supplied in fragmented order: strictly ascending order
+// of start key (i.e. user key ascending, sequence number descending), and
+// spans may not overlap unless they're perfectly aligned.
+func (w *RawRowWriter) addRangeMerge(key InternalKey, value []byte) error {
+	if !w.disableKeyOrderChecks && w.rangeMergeBlock.EntryCount() > 0 {
+		prevKey := w.rangeMergeBlock.CurKey()
+		prevEnd, _, err := rangemerge.DecodeValue(w.rangeMergeBlock.CurValue())
+		if err != nil {
+			w.err = err
+			return w.err
+		}
+		end, _, err := rangemerge.DecodeValue(value)
+		if err != nil {
+			w.err = err
+			return w.err
+		}
+		switch c := w.compare(prevKey.UserKey, key.UserKey); {
+		case c > 0:
+			w.err = errors.Errorf("pebble: keys must be added in order: %s, %s",
+				prevKey.Pretty(w.formatKey), key.Pretty(w.formatKey))
+			return w.err
+		case c == 0:
+			if w.compare(prevEnd, end) != 0 {
+				w.err = errors.Errorf("pebble: overlapping range merges must be fragmented: %s, %s",
+					prevKey.Pretty(w.formatKey), key.Pretty(w.formatKey))
+				return w.err
+			}
+			if prevKey.SeqNum() <= key.SeqNum() {
+				w.err = errors.Errorf("pebble: keys must be added in strictly increasing order: %s, %s",
+					prevKey.Pretty(w.formatKey), key.Pretty(w.formatKey))
+				return w.err
+			}
+		default:
+			if w.compare(prevEnd, key.UserKey) > 0 {
+				w.err = errors.Errorf("pebble: overlapping range merges must be fragmented: %s, %s",
+					prevKey.Pretty(w.formatKey), key.Pretty(w.formatKey))
+				return w.err
+			}
+		}
+	}
+
+	w.meta.updateSeqNum(key.SeqNum())
+
+	// The start key of the first range merge added is the smallest range merge
+	// key. The largest range merge key is determined in Close as the end key of
+	// the last range merge added.
+	if w.rangeMergeBlock.EntryCount() == 0 {
+		w.meta.SetSmallestRangeMergeKey(key.Clone())
+	}
+	w.rangeMergeBlock.Add(key, value)
+	return nil
+}
+
 func (w *RawRowWriter) maybeAddToFilter(key []byte) {
 	if w.filter != nil {
 		prefix := key[:w.split(key)]
@@ -1552,7 +1612,7 @@ func (w *RawRowWriter) ComparePrev(k []byte) int {
 }
 
 // EncodeSpan encodes the keys in the given span. The span can contain either
-// only RANGEDEL keys or only range keys.
+// only RANGEDEL keys, only RANGEMERGE keys or only range keys.
 //
 // This is a low-level API that bypasses the fragmenter. The spans passed to
 // this function must be fragmented and ordered.
@@ -1560,8 +1620,11 @@ func (w *RawRowWriter) EncodeSpan(span keyspan.Span) error {
 	if span.Empty() {
 		return nil
 	}
-	if span.Keys[0].Kind() == base.InternalKeyKindRangeDelete {
+	switch span.Keys[0].Kind() {
+	case base.InternalKeyKindRangeDelete:
 		return rangedel.Encode(span, w.addTombstone)
+	case base.InternalKeyKindRangeMerge:
+		return rangemerge.Encode(span, w.addRangeMerge)
 	}
 	for i := range w.blockPropCollectors {
 		if err := w.blockPropCollectors[i].AddRangeKeys(span); err != nil {
@@ -1700,6 +1763,18 @@ func (w *RawRowWriter) Close() (err error) {
 		}
 	}
 
+	if w.rangeMergeBlock.EntryCount() > 0 {
+		endKey, _, err := rangemerge.DecodeValue(w.rangeMergeBlock.CurValue())
+		if err != nil {
+			return err
+		}
+		k := base.MakeExclusiveSentinelKey(base.InternalKeyKindRangeMerge, endKey).Clone()
+		w.meta.SetLargestRangeMergeKey(k)
+		if _, err := w.layout.WriteRangeMergeBlock(w.rangeMergeBlock.Finish()); err != nil {
+			return err
+		}
+	}
+
 	if w.valueBlockWriter != nil {
 		_, vbStats, err := w.valueBlockWriter.finish(&w.layout, w.layout.offset)
 		if err != nil {
@@ -1836,6 +1911,7 @@ func newRowWriter(writable objstorage.Writable, o WriterOptions) *RawRowWriter {
 		indexBlock:                 newIndexBlockBuf(o.Parallelism),
 		rangeDelBlock:              rowblk.Writer{RestartInterval: 1},
 		rangeKeyBlock:              rowblk.Writer{RestartInterval: 1},
+		rangeMergeBlock:            rowblk.Writer{RestartInterval: 1},
 		topLevelIndexBlock:         rowblk.Writer{RestartInterval: 1},
 		allocatorSizeClasses:       o.AllocatorSizeClasses,
 		numDeletionsThreshold:      o.NumDeletionsThreshold,
@@ -292,7 +292,7 @@ type RawWriter interface {
 		key InternalKey, value []byte, forceObsolete bool,
 	) error
 	// EncodeSpan encodes the keys in the given span. The span can contain
-	// either only RANGEDEL keys or only range keys.
+	// either only RANGEDEL keys, only RANGEMERGE keys or only range keys.
 	//
 	// This is a low-level API that bypasses the fragmenter. The spans passed to
 	// this function must be fragmented and ordered.
@@ -329,20 +329,23 @@ type RawWriter interface {
 type WriterMetadata struct {
 	Size          uint64
 	SmallestPoint InternalKey
-	// LargestPoint, LargestRangeKey, LargestRangeDel should not be accessed
-	// before Writer.Close is called, because they may only be set on
-	// Writer.Close.
-	LargestPoint     InternalKey
-	SmallestRangeDel InternalKey
-	LargestRangeDel  InternalKey
-	SmallestRangeKey InternalKey
-	LargestRangeKey  InternalKey
-	HasPointKeys     bool
-	HasRangeDelKeys  bool
-	HasRangeKeys     bool
-	SmallestSeqNum   base.SeqNum
-	LargestSeqNum    base.SeqNum
-	Properties       Properties
+	// LargestPoint, LargestRangeKey, LargestRangeDel, LargestRangeMerge should
+	// not be accessed before Writer.Close is called, because they may only be
+	// set on Writer.Close.
+	LargestPoint       InternalKey
+	SmallestRangeDel   InternalKey
+	LargestRangeDel    InternalKey
+	SmallestRangeKey   InternalKey
+	LargestRangeKey    InternalKey
+	SmallestRangeMerge InternalKey
+	LargestRangeMerge  InternalKey
+	HasPointKeys       bool
+	HasRangeDelKeys    bool
+	HasRangeKeys       bool
+	HasRangeMergeKeys  bool
+	SmallestSeqNum     base.SeqNum
+	LargestSeqNum      base.SeqNum
+	Properties         Properties
 }
 
 // SetSmallestPointKey sets the smallest point key to the given key.
@@ -393,6 +396,22 @@ func (m *WriterMetadata) SetLargestRangeKey(k InternalKey) {
 	m.HasRangeKeys = true
 }
 
+// SetSmallestRangeMergeKey sets the smallest range merge key to the given key.
+// NB: this method set the "absolute" smallest range merge key. Any existing
+// key is overridden.
+func (m *WriterMetadata) SetSmallestRangeMergeKey(k InternalKey) {
+	m.SmallestRangeMerge = k
+	m.HasRangeMergeKeys = true
+}
+
+// SetLargestRangeMergeKey sets the largest range merge key to the given key.
+// NB: this method set the "absolute" largest range merge key. Any existing key
+// is overridden.
+func (m *WriterMetadata) SetLargestRangeMergeKey(k InternalKey) {
+	m.LargestRangeMerge = k
+	m.HasRangeMergeKeys = true
+}
+
 func (m *WriterMetadata) updateSeqNum(seqNum base.SeqNum) {
 	if m.SmallestSeqNum > seqNum {
 		m.SmallestSeqNum = seqNum
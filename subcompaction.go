@@ -0,0 +1,188 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/compact"
+	"github.com/cockroachdb/pebble/sstable"
+)
+
+// subcompactionMinOutputFiles is the minimum number of target-sized output
+// files worth of input data each subcompaction must process. Splitting smaller
+// compactions isn't worth the cost of the additional input iterators, and
+// would produce needlessly small output files at the split points.
+const subcompactionMinOutputFiles = 4
+
+// subcompaction is the portion of a compaction's key space processed by a
+// single worker. The subcompactions of a compaction partition its key space
+// into disjoint ranges, so their output tables don't overlap and can be
+// installed in a single version edit.
+type subcompaction struct {
+	c *compaction
+	// bounds are the user key bounds of the subcompaction; all its output
+	// tables fall within these bounds.
+	bounds base.UserKeyBounds
+	// lower and upper are the split keys bounding the subcompaction to
+	// [lower, upper). They're nil if the subcompaction extends to the start or
+	// end of the compaction.
+	lower, upper []byte
+
+	bufferPool sstable.BufferPool
+	// A list of fragment iterators to close when the subcompaction finishes.
+	// Used by input iteration to keep rangeDelIters open for the lifetime of
+	// the subcompaction, and only close them when the subcompaction finishes.
+	closers []*noCloseIter
+	stats   base.InternalIteratorStats
+}
+
+// newSubcompactions partitions the compaction into subcompactions at the
+// provided split keys, which must be sorted and fall strictly within the
+// bounds of the compaction. If there are no split keys, a single subcompaction
+// spanning the entire compaction is returned.
+func (c *compaction) newSubcompactions(splitKeys [][]byte) []*subcompaction {
+	bounds := base.UserKeyBoundsFromInternal(c.smallest, c.largest)
+	subs := make([]*subcompaction, len(splitKeys)+1)
+	for i := range subs {
+		s := &subcompaction{c: c, bounds: bounds}
+		if i > 0 {
+			s.lower = splitKeys[i-1]
+			s.bounds.Start = s.lower
+		}
+		if i < len(splitKeys) {
+			s.upper = splitKeys[i]
+			s.bounds.End = base.UserKeyExclusive(s.upper)
+		}
+		subs[i] = s
+	}
+	return subs
+}
+
+// subcompactionSplitKeys returns the keys at which the compaction should be
+// split into subcompactions, or nil if it shouldn't be split. The number of
+// subcompactions is limited by Options.Experimental.MaxSubcompactions and by
+// the number of idle compaction concurrency slots.
+//
+// d.mu must be held when calling this.
+func (d *DB) subcompactionSplitKeys(c *compaction) [][]byte {
+	maxSubcompactions := d.opts.Experimental.MaxSubcompactions
+	if maxSubcompactions <= 1 || c.flushing != nil || c.outputLevel == nil ||
+		c.outputLevel.files.Len() < 2 {
+		return nil
+	}
+	// The compaction itself already occupies one of the slots counted by
+	// compactingCount.
+	idleSlots := d.opts.MaxConcurrentCompactions() - d.mu.compact.compactingCount
+	return c.subcompactionSplitKeys(min(maxSubcompactions, 1+idleSlots))
+}
+
+// subcompactionSplitKeys chooses up to n-1 keys at which to split the
+// compaction into n subcompactions of roughly equal input size. The split
+// keys are chosen among the smallest keys of the files in the output level,
+// so the subcompactions tend to align with the existing partitioning of the
+// output level.
+func (c *compaction) subcompactionSplitKeys(n int) [][]byte {
+	var inputBytes uint64
+	for i := range c.inputs {
+		inputBytes += c.inputs[i].files.SizeSum()
+	}
+	if c.maxOutputFileSize > 0 {
+		n = min(n, int(inputBytes/(subcompactionMinOutputFiles*c.maxOutputFileSize)))
+	}
+	if n <= 1 {
+		return nil
+	}
+
+	// Collect the start keys and sizes of all the input files, sorted by key,
+	// in order to estimate the input bytes preceding each candidate split key.
+	type fileStart struct {
+		key  []byte
+		size uint64
+	}
+	var starts []fileStart
+	for i := range c.inputs {
+		iter := c.inputs[i].files.Iter()
+		for f := iter.First(); f != nil; f = iter.Next() {
+			starts = append(starts, fileStart{key: f.Smallest.UserKey, size: f.Size})
+		}
+	}
+	slices.SortFunc(starts, func(a, b fileStart) int {
+		return c.cmp(a.key, b.key)
+	})
+
+	bounds := base.UserKeyBoundsFromInternal(c.smallest, c.largest)
+	targetBytes := inputBytes / uint64(n)
+	var splitKeys [][]byte
+	var precedingBytes uint64
+	j := 0
+	iter := c.outputLevel.files.Iter()
+	for f := iter.First(); f != nil && len(splitKeys) < n-1; f = iter.Next() {
+		key := f.Smallest.UserKey
+		for ; j < len(starts) && c.cmp(starts[j].key, key) < 0; j++ {
+			precedingBytes += starts[j].size
+		}
+		// The split key must fall strictly within the compaction's bounds, so
+		// that every subcompaction has a non-empty key range.
+		if c.cmp(key, bounds.Start) <= 0 || c.cmp(key, bounds.End.Key) >= 0 {
+			continue
+		}
+		if len(splitKeys) > 0 && c.cmp(key, splitKeys[len(splitKeys)-1]) <= 0 {
+			continue
+		}
+		if precedingBytes >= targetBytes*uint64(len(splitKeys)+1) {
+			splitKeys = append(splitKeys, key)
+		}
+	}
+	return splitKeys
+}
+
+// mergeSubcompactionResults combines the results of the subcompactions of a
+// compaction, in key order, into the result of the compaction.
+func mergeSubcompactionResults(results []compact.Result) compact.Result {
+	var result compact.Result
+	for i := range results {
+		result.Err = errors.CombineErrors(result.Err, results[i].Err)
+		result.Tables = append(result.Tables, results[i].Tables...)
+		result.Stats.CumulativePinnedKeys += results[i].Stats.CumulativePinnedKeys
+		result.Stats.CumulativePinnedSize += results[i].Stats.CumulativePinnedSize
+		result.Stats.CountMissizedDels += results[i].Stats.CountMissizedDels
+	}
+	return result
+}
+
+// subcompactionIter restricts the point keys of a compaction's input iterator
+// to the bounds of a subcompaction. The compaction's input iterators ignore
+// iteration bounds, so they're enforced here. It only supports the forward
+// iteration from First used by the compaction iterator.
+type subcompactionIter struct {
+	internalIterator
+	cmp          base.Compare
+	lower, upper []byte
+}
+
+var _ internalIterator = (*subcompactionIter)(nil)
+
+// First implements internalIterator.
+func (i *subcompactionIter) First() *base.InternalKV {
+	if i.lower == nil {
+		return i.checkUpper(i.internalIterator.First())
+	}
+	return i.checkUpper(i.internalIterator.SeekGE(i.lower, base.SeekGEFlagsNone))
+}
+
+// Next implements internalIterator.
+func (i *subcompactionIter) Next() *base.InternalKV {
+	return i.checkUpper(i.internalIterator.Next())
+}
+
+func (i *subcompactionIter) checkUpper(kv *base.InternalKV) *base.InternalKV {
+	if kv != nil && i.upper != nil && i.cmp(kv.K.UserKey, i.upper) >= 0 {
+		return nil
+	}
+	return kv
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubcompactionSplitKeys(t *testing.T) {
+	cmp := DefaultComparer.Compare
+	newFileMeta := func(size uint64, smallest, largest string) *fileMetadata {
+		m := (&fileMetadata{Size: size}).ExtendPointKeyBounds(cmp,
+			base.ParseInternalKey(smallest), base.ParseInternalKey(largest))
+		m.InitPhysicalBacking()
+		return m
+	}
+	c := &compaction{
+		cmp: cmp,
+		inputs: []compactionLevel{
+			{level: 1, files: manifest.NewLevelSliceKeySorted(cmp, []*fileMetadata{
+				newFileMeta(100, "a.SET.10", "z.SET.10"),
+			})},
+			{level: 2, files: manifest.NewLevelSliceKeySorted(cmp, []*fileMetadata{
+				newFileMeta(100, "a.SET.1", "c.SET.1"),
+				newFileMeta(100, "d.SET.1", "f.SET.1"),
+				newFileMeta(100, "g.SET.1", "i.SET.1"),
+				newFileMeta(100, "j.SET.1", "l.SET.1"),
+			})},
+		},
+		smallest:          base.ParseInternalKey("a.SET.10"),
+		largest:           base.ParseInternalKey("z.SET.10"),
+		maxOutputFileSize: 1,
+	}
+	c.startLevel, c.outputLevel = &c.inputs[0], &c.inputs[1]
+
+	splitKeys := func(n int) string {
+		return fmt.Sprintf("%q", c.subcompactionSplitKeys(n))
+	}
+	require.Equal(t, "[]", splitKeys(1))
+	require.Equal(t, `["g"]`, splitKeys(2))
+	require.Equal(t, `["d" "g" "j"]`, splitKeys(4))
+	// There are only 3 candidate split keys.
+	require.Equal(t, `["d" "g" "j"]`, splitKeys(8))
+
+	// The split keys must fall strictly within the compaction's bounds.
+	c.smallest = base.ParseInternalKey("d.SET.10")
+	require.Equal(t, `["g" "j"]`, splitKeys(4))
+
+	// Compactions that are small relative to the target output file size
+	// aren't split.
+	c.maxOutputFileSize = 100
+	require.Equal(t, "[]", splitKeys(4))
+
+	// The subcompactions partition the compaction's bounds.
+	subs := c.newSubcompactions([][]byte{[]byte("g"), []byte("j")})
+	require.Len(t, subs, 3)
+	require.Equal(t, "[d, g)", subs[0].bounds.String())
+	require.Equal(t, "[g, j)", subs[1].bounds.String())
+	require.Equal(t, "[j, z]", subs[2].bounds.String())
+}
+
+func TestSubcompactions(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{
+		FS:                          mem,
+		DisableAutomaticCompactions: true,
+		Levels:                      []LevelOptions{{TargetFileSize: 1 << 10}},
+		MaxConcurrentCompactions:    func() int { return 4 },
+	}
+	opts.Experimental.MaxSubcompactions = 4
+	d, err := Open("", testingRandomized(t, opts))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	key := func(i int) []byte { return []byte(fmt.Sprintf("key%05d", i)) }
+	const n = 2000
+	expected := make(map[string]string)
+	for i := 0; i < n; i++ {
+		v := fmt.Sprintf("v1-%d", i)
+		require.NoError(t, d.Set(key(i), []byte(v), nil))
+		expected[string(key(i))] = v
+	}
+	require.NoError(t, d.Compact(key(0), key(n), false))
+	require.Greater(t, d.Metrics().Levels[numLevels-1].NumFiles, int64(8))
+
+	// Overwrite and delete keys across the keyspace, including with a range
+	// deletion and a range key that span split keys, and compact the new data
+	// into the bottommost level. The compaction out of L0 is large enough to be
+	// split into subcompactions at the boundaries of the bottommost files.
+	for i := 0; i < n; i += 3 {
+		v := fmt.Sprintf("v2-%d", i)
+		require.NoError(t, d.Set(key(i), []byte(v), nil))
+		expected[string(key(i))] = v
+	}
+	require.NoError(t, d.DeleteRange(key(500), key(1500), nil))
+	for i := 500; i < 1500; i++ {
+		delete(expected, string(key(i)))
+	}
+	require.NoError(t, d.RangeKeySet(key(100), key(1900), nil, []byte("rk"), nil))
+	require.NoError(t, d.Flush())
+
+	subcompactions := d.Metrics().Compact.SubcompactionCount
+	require.NoError(t, d.Compact(key(0), key(n), false))
+	m := d.Metrics()
+	require.Zero(t, m.Levels[0].NumFiles)
+	require.Greater(t, m.Compact.SubcompactionCount-subcompactions, int64(1))
+
+	iter, err := d.NewIter(&IterOptions{KeyTypes: IterKeyTypePointsAndRanges})
+	require.NoError(t, err)
+	count := 0
+	for valid := iter.First(); valid; valid = iter.Next() {
+		hasPoint, hasRange := iter.HasPointAndRange()
+		k := string(iter.Key())
+		if hasPoint {
+			require.Equal(t, expected[k], string(iter.Value()), k)
+			count++
+		}
+		if k >= string(key(100)) && k < string(key(1900)) {
+			require.True(t, hasRange, k)
+		}
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, len(expected), count)
+}
@@ -84,6 +84,18 @@ func tableNewRangeKeyIter(newIters tableNewIters) keyspanimpl.TableNewSpanIter {
 	}
 }
 
+// tableNewRangeMergeIter takes a tableNewIters and returns a TableNewSpanIter
+// for the range merge iterator returned by tableNewIters.
+func tableNewRangeMergeIter(newIters tableNewIters) keyspanimpl.TableNewSpanIter {
+	return func(ctx context.Context, file *manifest.FileMetadata, iterOptions keyspan.SpanIterOptions) (keyspan.FragmentIterator, error) {
+		iters, err := newIters(ctx, file, nil, internalIterOpts{}, iterRangeMerges)
+		if err != nil {
+			return nil, err
+		}
+		return iters.RangeMerge(), nil
+	}
+}
+
 var tableCacheLabels = pprof.Labels("pebble", "table-cache")
 
 // tableCacheOpts contains the db specific fields
@@ -242,6 +254,48 @@ func (c *tableCacheContainer) withCommonReader(
 	return fn(createCommonReader(v, meta))
 }
 
+// prefetch opens the table and reads up to n of its data blocks into the block
+// cache, starting with the block that may contain key and proceeding in the
+// direction dir. See sstable.Reader.PrefetchDataBlocks.
+func (c *tableCacheContainer) prefetch(
+	ctx context.Context, file *fileMetadata, key []byte, dir, n int,
+) error {
+	s := c.tableCache.getShard(file.FileBacking.DiskFileNum)
+	v := s.findNode(ctx, file.FileBacking, &c.dbOpts)
+	defer s.unrefValue(v)
+	if v.err != nil {
+		return v.err
+	}
+	if file.SyntheticPrefix.IsSet() {
+		key = file.SyntheticPrefix.Invert(key)
+	}
+	return v.reader.PrefetchDataBlocks(ctx, key, n, dir < 0)
+}
+
+// prefetchPointKeys opens the table and reads the data blocks that may contain
+// the provided sorted keys into the block cache. The keys must lie within the
+// file's bounds. See sstable.Reader.PrefetchPointKeys.
+func (c *tableCacheContainer) prefetchPointKeys(
+	ctx context.Context, file *fileMetadata, keys [][]byte,
+) error {
+	s := c.tableCache.getShard(file.FileBacking.DiskFileNum)
+	v := s.findNode(ctx, file.FileBacking, &c.dbOpts)
+	defer s.unrefValue(v)
+	if v.err != nil {
+		return v.err
+	}
+	if file.SyntheticPrefix.IsSet() {
+		// The file's bounds carry the synthetic prefix, so every key within
+		// them does too.
+		inverted := make([][]byte, len(keys))
+		for i := range keys {
+			inverted[i] = file.SyntheticPrefix.Invert(keys[i])
+		}
+		keys = inverted
+	}
+	return v.reader.PrefetchPointKeys(ctx, keys)
+}
+
 func (c *tableCacheContainer) withReader(meta physicalMeta, fn func(*sstable.Reader) error) error {
 	s := c.tableCache.getShard(meta.FileBacking.DiskFileNum)
 	v := s.findNode(context.TODO(), meta.FileBacking, &c.dbOpts)
@@ -252,6 +306,22 @@ func (c *tableCacheContainer) withReader(meta physicalMeta, fn func(*sstable.Rea
 	return fn(v.reader)
 }
 
+// withCachedReader invokes fn with the reader of the provided backing if the
+// table cache already holds an open reader for it, without opening the table.
+// It returns whether fn was invoked.
+func (c *tableCacheContainer) withCachedReader(
+	backing *fileBacking, fn func(*sstable.Reader),
+) bool {
+	s := c.tableCache.getShard(backing.DiskFileNum)
+	v := s.peekNode(backing, &c.dbOpts)
+	if v == nil {
+		return false
+	}
+	defer s.unrefValue(v)
+	fn(v.reader)
+	return true
+}
+
 // withVirtualReader fetches a VirtualReader associated with a virtual sstable.
 func (c *tableCacheContainer) withVirtualReader(
 	meta virtualMeta, fn func(sstable.VirtualReader) error,
@@ -459,6 +529,11 @@ func (c *tableCacheShard) newIters(
 	if kinds.RangeDeletion() && file.HasPointKeys && err == nil {
 		iters.rangeDeletion, err = c.newRangeDelIter(ctx, file, cr, dbOpts)
 	}
+	if kinds.RangeMerge() && file.HasRangeMerges && err == nil {
+		// NB: like the range-del iterator, the range-merge iterator does not
+		// maintain a reference to the table.
+		iters.rangeMerge, err = cr.NewRawRangeMergeIter(ctx, file.FragmentIterTransforms())
+	}
 	if kinds.Point() && err == nil {
 		iters.point, err = c.newPointIter(ctx, v, file, cr, opts, internalOpts, dbOpts)
 	}
@@ -574,6 +649,7 @@ func (c *tableCacheShard) newPointIter(
 	var categoryAndQoS sstable.CategoryAndQoS
 	if opts != nil {
 		categoryAndQoS = opts.CategoryAndQoS
+		transforms.KeysOnly = opts.KeysOnly
 	}
 	if internalOpts.compaction {
 		iter, err = cr.NewCompactionIter(
@@ -791,6 +867,32 @@ func (c *tableCacheShard) findNode(
 	return c.findNodeInternal(ctx, info, dbOpts)
 }
 
+// peekNode returns the value of the node for the table with the given backing
+// if the table is already open, or nil otherwise. Unlike findNode, it never
+// opens the table. The caller is responsible for decrementing the returned
+// value's refCount.
+func (c *tableCacheShard) peekNode(b *fileBacking, dbOpts *tableCacheOpts) *tableCacheValue {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	n := c.mu.nodes[tableCacheKey{dbOpts.cacheID, b.DiskFileNum}]
+	if n == nil || n.value == nil {
+		return nil
+	}
+	v := n.value
+	select {
+	case <-v.loaded:
+	default:
+		// The table is still being opened.
+		return nil
+	}
+	if v.err != nil {
+		return nil
+	}
+	v.refCount.Add(1)
+	n.referenced.Store(true)
+	return v
+}
+
 func (c *tableCacheShard) findNodeInternal(
 	ctx context.Context, loadInfo loadInfo, dbOpts *tableCacheOpts,
 ) *tableCacheValue {
@@ -1233,6 +1335,7 @@ type iterSet struct {
 	point         internalIterator
 	rangeDeletion keyspan.FragmentIterator
 	rangeKey      keyspan.FragmentIterator
+	rangeMerge    keyspan.FragmentIterator
 }
 
 // TODO(jackson): Consider adding methods for fast paths that check whether an
@@ -1267,6 +1370,15 @@ func (s *iterSet) RangeKey() keyspan.FragmentIterator {
 	return s.rangeKey
 }
 
+// RangeMerge returns the contained range merge iterator. If there is no range
+// merge iterator, RangeMerge returns a non-nil empty keyspan iterator.
+func (s *iterSet) RangeMerge() keyspan.FragmentIterator {
+	if s.rangeMerge == nil {
+		return emptyKeyspanIter
+	}
+	return s.rangeMerge
+}
+
 // CloseAll closes all of the held iterators. If CloseAll is called, then Close
 // must be not be called on the constituent iterators.
 func (s *iterSet) CloseAll() error {
@@ -1283,20 +1395,26 @@ func (s *iterSet) CloseAll() error {
 		s.rangeKey.Close()
 		s.rangeKey = nil
 	}
+	if s.rangeMerge != nil {
+		s.rangeMerge.Close()
+		s.rangeMerge = nil
+	}
 	return err
 }
 
 // iterKinds is a bitmap indicating a set of kinds of iterators. Callers may
-// bitwise-OR iterPointKeys, iterRangeDeletions and/or iterRangeKeys together to
-// represent a set of desired iterator kinds.
+// bitwise-OR iterPointKeys, iterRangeDeletions, iterRangeKeys and/or
+// iterRangeMerges together to represent a set of desired iterator kinds.
 type iterKinds uint8
 
 func (t iterKinds) Point() bool         { return (t & iterPointKeys) != 0 }
 func (t iterKinds) RangeDeletion() bool { return (t & iterRangeDeletions) != 0 }
 func (t iterKinds) RangeKey() bool      { return (t & iterRangeKeys) != 0 }
+func (t iterKinds) RangeMerge() bool    { return (t & iterRangeMerges) != 0 }
 
 const (
 	iterPointKeys iterKinds = 1 << iota
 	iterRangeDeletions
 	iterRangeKeys
+	iterRangeMerges
 )
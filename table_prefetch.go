@@ -0,0 +1,98 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// tablePrefetcher runs the asynchronous sstable prefetches of an Iterator.
+// See IterOptions.Prefetch. The prefetches must complete before the Iterator
+// releases its version, since the version's sstables may then be deleted.
+type tablePrefetcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	tc     *tableCacheContainer
+	wg     sync.WaitGroup
+}
+
+func newTablePrefetcher(ctx context.Context, tc *tableCacheContainer) *tablePrefetcher {
+	p := &tablePrefetcher{tc: tc}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	return p
+}
+
+// prefetch opens the sstable in the background, and reads n of its data
+// blocks, starting with the block that may contain key and proceeding in the
+// direction dir.
+func (p *tablePrefetcher) prefetch(file *fileMetadata, key []byte, dir, n int) {
+	key = slices.Clone(key)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		// Errors are ignored. An iterator that goes on to read the sstable will
+		// encounter them itself.
+		_ = p.tc.prefetch(p.ctx, file, key, dir, n)
+	}()
+}
+
+// close cancels the outstanding prefetches and waits for them to exit.
+func (p *tablePrefetcher) close() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// maybePrefetch schedules the prefetch of the sstables following the current
+// file in the direction dir, if the levelIter is configured to prefetch. See
+// IterOptions.Prefetch.
+func (l *levelIter) maybePrefetch(dir int) {
+	if l.prefetcher == nil || l.prefix != nil {
+		return
+	}
+	files := l.files.Clone()
+	for i := 0; i < l.prefetch.Files; i++ {
+		var f *fileMetadata
+		if dir > 0 {
+			f = files.Next()
+		} else {
+			f = files.Prev()
+		}
+		if f == nil {
+			return
+		}
+		// Stop at the first file beyond the iteration bounds, and skip the
+		// files that have already been scheduled by a previous call.
+		var key []byte
+		if dir > 0 {
+			if l.upper != nil && l.cmp(f.SmallestPointKey.UserKey, l.upper) >= 0 {
+				return
+			}
+			key = f.SmallestPointKey.UserKey
+			if l.lower != nil && l.cmp(l.lower, key) > 0 {
+				key = l.lower
+			}
+		} else {
+			if l.lower != nil && l.cmp(f.LargestPointKey.UserKey, l.lower) < 0 {
+				return
+			}
+			key = f.LargestPointKey.UserKey
+			if l.upper != nil && l.cmp(l.upper, key) < 0 {
+				key = l.upper
+			}
+		}
+		if !f.HasPointKeys || slices.Contains(l.prefetched, f) {
+			continue
+		}
+		// Only the files within the window of the most recent call need to be
+		// remembered.
+		if len(l.prefetched) == l.prefetch.Files {
+			l.prefetched = append(l.prefetched[:0], l.prefetched[1:]...)
+		}
+		l.prefetched = append(l.prefetched, f)
+		l.prefetcher.prefetch(f, key, dir, max(l.prefetch.Blocks, 1))
+	}
+}
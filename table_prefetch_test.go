@@ -0,0 +1,83 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIteratorPrefetch(t *testing.T) {
+	mem := vfs.NewMem()
+	newOpts := func() *Options {
+		opts := &Options{FS: mem, DisableAutomaticCompactions: true}
+		opts.EnsureDefaults()
+		for i := range opts.Levels {
+			opts.Levels[i].BlockSize = 512
+		}
+		return opts
+	}
+
+	// Write 4 non-overlapping sstables of several blocks each into L0.
+	d, err := Open("", newOpts())
+	require.NoError(t, err)
+	var want []string
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 50; j++ {
+			k := fmt.Sprintf("%d-%03d", i, j)
+			require.NoError(t, d.Set([]byte(k), bytes.Repeat([]byte("v"), 100), nil))
+			want = append(want, k)
+		}
+		require.NoError(t, d.Flush())
+	}
+	require.Equal(t, 4, int(d.Metrics().Levels[0].NumFiles))
+	require.NoError(t, d.Close())
+
+	// scan reopens the DB with cold caches and scans it, returning the keys in
+	// ascending order and the bytes of the data blocks that were found in the
+	// block cache.
+	scan := func(prefetch PrefetchOptions, reverse bool) ([]string, uint64) {
+		d, err := Open("", newOpts())
+		require.NoError(t, err)
+		defer func() { require.NoError(t, d.Close()) }()
+		iter, err := d.NewIter(&IterOptions{Prefetch: prefetch})
+		require.NoError(t, err)
+		first, next := iter.First, iter.Next
+		if reverse {
+			first, next = iter.Last, iter.Prev
+		}
+		var keys []string
+		valid := first()
+		if iter.prefetcher != nil {
+			// Let the prefetches complete before moving on to the next file.
+			iter.prefetcher.wg.Wait()
+		}
+		for ; valid; valid = next() {
+			keys = append(keys, string(iter.Key()))
+		}
+		if reverse {
+			slices.Reverse(keys)
+		}
+		stats := iter.Stats().InternalStats
+		require.NoError(t, iter.Close())
+		return keys, stats.BlockBytesInCache
+	}
+	for _, reverse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("reverse=%t", reverse), func(t *testing.T) {
+			keys, cached := scan(PrefetchOptions{}, reverse)
+			require.Equal(t, want, keys)
+			require.Zero(t, cached)
+
+			keys, cached = scan(PrefetchOptions{Files: 3, Blocks: 2}, reverse)
+			require.Equal(t, want, keys)
+			require.Greater(t, cached, uint64(0))
+		})
+	}
+}
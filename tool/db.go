@@ -44,6 +44,7 @@ type dbT struct {
 	Space      *cobra.Command
 	IOBench    *cobra.Command
 	Excise     *cobra.Command
+	Activity   *cobra.Command
 
 	// Configuration.
 	opts            *pebble.Options
@@ -68,6 +69,9 @@ type dbT struct {
 	verbose       bool
 	bypassPrompt  bool
 	lsmURL        bool
+	buckets       int
+	sampleSize    int
+	hotFactor     float64
 }
 
 func newDB(
@@ -197,10 +201,25 @@ specified database.
 		Run:  d.runIOBench,
 	}
 
-	d.Root.AddCommand(d.Check, d.Checkpoint, d.Get, d.Logs, d.LSM, d.Properties, d.Scan, d.Set, d.Space, d.Excise, d.IOBench)
+	d.Activity = &cobra.Command{
+		Use:   "activity <dir>",
+		Short: "print a histogram of sampled key range activity",
+		Long: `
+Print a histogram of the sampled read and write activity over the key range
+specified by --start and --end, marking buckets whose activity exceeds the
+mean by a factor of --hot-factor as hot. The histogram is built from the
+samples saved when the database was last closed by a process that had key range
+sampling enabled (see Options.Experimental.KeyRangeSampleSize). Requires that
+the specified database not be in use by another process.
+`,
+		Args: cobra.ExactArgs(1),
+		Run:  d.runActivity,
+	}
+
+	d.Root.AddCommand(d.Check, d.Checkpoint, d.Get, d.Logs, d.LSM, d.Properties, d.Scan, d.Set, d.Space, d.Excise, d.IOBench, d.Activity)
 	d.Root.PersistentFlags().BoolVarP(&d.verbose, "verbose", "v", false, "verbose output")
 
-	for _, cmd := range []*cobra.Command{d.Check, d.Checkpoint, d.Get, d.LSM, d.Properties, d.Scan, d.Set, d.Space, d.Excise} {
+	for _, cmd := range []*cobra.Command{d.Check, d.Checkpoint, d.Get, d.LSM, d.Properties, d.Scan, d.Set, d.Space, d.Excise, d.Activity} {
 		cmd.Flags().StringVar(
 			&d.comparerName, "comparer", "", "comparer name (use default if empty)")
 		cmd.Flags().StringVar(
@@ -236,6 +255,19 @@ specified database.
 	d.Excise.Flags().BoolVar(
 		&d.bypassPrompt, "yes", false, "bypass prompt")
 
+	d.Activity.Flags().Var(
+		&d.fmtKey, "key", "key formatter")
+	d.Activity.Flags().Var(
+		&d.start, "start", "start key for the range")
+	d.Activity.Flags().Var(
+		&d.end, "end", "exclusive end key for the range")
+	d.Activity.Flags().IntVar(
+		&d.buckets, "buckets", 16, "maximum number of histogram buckets")
+	d.Activity.Flags().IntVar(
+		&d.sampleSize, "sample-size", 10000, "maximum number of saved sampled keys loaded per operation type")
+	d.Activity.Flags().Float64Var(
+		&d.hotFactor, "hot-factor", 2, "factor by which a bucket's activity must exceed the mean to be marked hot")
+
 	d.IOBench.Flags().BoolVar(
 		&d.allLevels, "all-levels", false, "if set, benchmark all levels (default is only L5/L6)")
 	d.IOBench.Flags().IntVar(
@@ -527,6 +559,56 @@ func (d *dbT) runSpace(cmd *cobra.Command, args []string) {
 	fmt.Fprintf(stdout, "%d\n", bytes)
 }
 
+type keyRangeSampleSize int
+
+func (n keyRangeSampleSize) Apply(dirname string, opts *pebble.Options) {
+	opts.Experimental.KeyRangeSampleSize = int(n)
+}
+
+func (d *dbT) runActivity(cmd *cobra.Command, args []string) {
+	stdout, stderr := cmd.OutOrStdout(), cmd.ErrOrStderr()
+	db, err := d.openDB(args[0], keyRangeSampleSize(d.sampleSize))
+	if err != nil {
+		fmt.Fprintf(stderr, "%s\n", err)
+		return
+	}
+	defer d.closeDB(stderr, db)
+
+	// Update the internal formatter if this comparator has one specified.
+	if d.opts.Comparer != nil {
+		d.fmtKey.setForComparer(d.opts.Comparer.Name, d.comparers)
+	}
+
+	hist, err := db.KeyRangeActivity(pebble.KeyRangeActivityOptions{
+		LowerBound: d.start,
+		UpperBound: d.end,
+		Buckets:    d.buckets,
+		HotFactor:  d.hotFactor,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "%s\n", err)
+		return
+	}
+	tw := tabwriter.NewWriter(stdout, 2, 1, 2, ' ', 0)
+	fmt.Fprintf(tw, "start\tend\treads\twrites\t\n")
+	formatBound := func(k []byte) string {
+		if k == nil {
+			return "-"
+		}
+		return fmt.Sprint(d.fmtKey.fn(k))
+	}
+	for _, a := range hist {
+		var hot string
+		if a.Hot {
+			hot = "hot"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			formatBound(a.Start), formatBound(a.End),
+			humanize.Bytes.Uint64(a.ReadBytes), humanize.Bytes.Uint64(a.WriteBytes), hot)
+	}
+	tw.Flush()
+}
+
 func (d *dbT) getExciseSpan() (pebble.KeyRange, error) {
 	// If a DBExciseSpanFn is specified, try to use it and see if it returns a
 	// valid span.
@@ -655,6 +737,7 @@ func (d *dbT) runProperties(cmd *cobra.Command, args []string) {
 		cmp := base.DefaultComparer
 		var bve manifest.BulkVersionEdit
 		bve.AddedByFileNum = make(map[base.FileNum]*manifest.FileMetadata)
+		bve.MultipleRunsPerLevel = d.opts.Experimental.CompactionStyle == pebble.CompactionStyleTiered
 		rr := record.NewReader(f, 0 /* logNum */)
 		for {
 			r, err := rr.Next()
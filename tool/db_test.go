@@ -4,8 +4,42 @@
 
 package tool
 
-import "testing"
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
 
 func TestDB(t *testing.T) {
 	runTests(t, "testdata/db_*")
 }
+
+func TestDBActivity(t *testing.T) {
+	// Write to a DB with key range sampling enabled, so that the samples are
+	// saved when it's closed.
+	fs := vfs.NewMem()
+	opts := &pebble.Options{FS: fs}
+	opts.Experimental.KeyRangeSampleSize = 100
+	db, err := pebble.Open("db", opts)
+	require.NoError(t, err)
+	require.NoError(t, db.Set([]byte("a"), make([]byte, 4<<20), pebble.NoSync))
+	require.NoError(t, db.Close())
+
+	c := &cobra.Command{}
+	c.AddCommand(New(FS(fs)).Commands...)
+	c.SetArgs([]string{"db", "activity", "--buckets=1", "db"})
+	var buf bytes.Buffer
+	c.SetOut(&buf)
+	c.SetErr(&buf)
+	require.NoError(t, c.Execute())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2, buf.String())
+	fields := strings.Fields(lines[1])
+	require.Equal(t, []string{"-", "-", "0B"}, fields[:3])
+	require.NotEqual(t, "0B", fields[3])
+}
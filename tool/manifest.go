@@ -152,6 +152,7 @@ func (m *manifestT) runDump(cmd *cobra.Command, args []string) {
 
 			var bve manifest.BulkVersionEdit
 			bve.AddedByFileNum = make(map[base.FileNum]*manifest.FileMetadata)
+			bve.MultipleRunsPerLevel = m.opts.Experimental.CompactionStyle == pebble.CompactionStyleTiered
 			var comparer *base.Comparer
 			var editIdx int
 			rr := record.NewReader(f, 0 /* logNum */)
@@ -599,6 +600,7 @@ func (m *manifestT) runCheck(cmd *cobra.Command, args []string) {
 				}
 				var bve manifest.BulkVersionEdit
 				bve.AddedByFileNum = addedByFileNum
+				bve.MultipleRunsPerLevel = m.opts.Experimental.CompactionStyle == pebble.CompactionStyleTiered
 				if err := bve.Accumulate(&ve); err != nil {
 					fmt.Fprintf(stderr, "%s\n", err)
 					ok = false
@@ -334,6 +334,7 @@ func (vs *versionSet) load(
 		}
 	}
 
+	bve.MultipleRunsPerLevel = opts.Experimental.CompactionStyle == CompactionStyleTiered
 	newVersion, err := bve.Apply(nil, opts.Comparer, opts.FlushSplitBytes, opts.Experimental.ReadCompactionRate)
 	if err != nil {
 		return err
@@ -555,7 +556,9 @@ func (vs *versionSet) logAndApply(
 		if vs.getFormatMajorVersion() < FormatVirtualSSTables && len(ve.CreatedBackingTables) > 0 {
 			return base.AssertionFailedf("MANIFEST cannot contain virtual sstable records due to format major version")
 		}
-		var b bulkVersionEdit
+		b := bulkVersionEdit{
+			MultipleRunsPerLevel: vs.opts.Experimental.CompactionStyle == CompactionStyleTiered,
+		}
 		err := b.Accumulate(ve)
 		if err != nil {
 			return errors.Wrap(err, "MANIFEST accumulate failed")
@@ -682,10 +685,7 @@ func (vs *versionSet) logAndApply(
 		l.VirtualSize = newVersion.Levels[i].VirtualSize
 		l.Size = int64(newVersion.Levels[i].Size())
 
-		l.Sublevels = 0
-		if l.NumFiles > 0 {
-			l.Sublevels = 1
-		}
+		l.Sublevels = int32(newVersion.NumRuns(i))
 		if invariants.Enabled {
 			levelFiles := newVersion.Levels[i].Slice()
 			if size := int64(levelFiles.SizeSum()); l.Size != size {
@@ -705,7 +705,6 @@ func (vs *versionSet) logAndApply(
 			}
 		}
 	}
-	vs.metrics.Levels[0].Sublevels = int32(len(newVersion.L0SublevelFiles))
 	vs.metrics.Table.Local.LiveSize = uint64(int64(vs.metrics.Table.Local.LiveSize) + localLiveSizeDelta)
 
 	vs.picker = newCompactionPickerByScore(newVersion, &vs.virtualBackings, vs.opts, inProgress)
@@ -857,6 +856,14 @@ func (vs *versionSet) incrementCompactions(
 		vs.metrics.Compact.Count++
 		vs.metrics.Compact.CopyCount++
 
+	case compactionKindFIFO:
+		vs.metrics.Compact.Count++
+		vs.metrics.Compact.FIFOCount++
+
+	case compactionKindPeriodic:
+		vs.metrics.Compact.Count++
+		vs.metrics.Compact.PeriodicCount++
+
 	default:
 		if invariants.Enabled {
 			panic("unhandled compaction kind")
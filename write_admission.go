@@ -0,0 +1,309 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// writeAdmissionBurst is the duration of writes at the admission rate that
+// may accumulate as tokens while writes are idle, and then be admitted
+// without delay.
+const writeAdmissionBurst = 10 * time.Millisecond
+
+// WriteAdmissionOptions configures write admission control, which delays
+// writes gradually as the DB approaches a write stall, instead of admitting
+// them at full speed until writes stop altogether.
+//
+// Admission control tracks the pressure on the DB: the largest fraction of
+// its stop threshold reached by any of the memtables awaiting flush (relative
+// to Options.MemTableStopWritesThreshold), the L0 sublevels (relative to
+// Options.L0StopWritesThreshold) and the compaction debt (relative to
+// CompactionDebtLimit). The memtables count the bytes reserved by writes in
+// the mutable memtable, so the pressure rises as the mutable memtable fills.
+// Once the pressure exceeds SlowdownFraction, writes must acquire tokens for
+// their bytes. Tokens are issued at a rate that starts at the observed flush
+// throughput and falls towards MinRate as the pressure rises towards 1, so
+// that writes are delayed in proportion to how far the DB has fallen behind.
+// Until a flush throughput has been observed, InitialFlushRate stands in for
+// it. The hard write stalls reported by
+// EventListener.WriteStallBegin and WriteStallEnd remain in effect.
+//
+// Each WritePriority acquires tokens from its own bucket. Writes of
+// WritePriorityLow are throttled from the lower pressure of
+// LowPrioritySlowdownFraction, so that they're delayed before writes of normal
+// priority, and writes of WritePriorityHigh are never delayed.
+type WriteAdmissionOptions struct {
+	// Enabled enables write admission control.
+	Enabled bool
+	// SlowdownFraction is the pressure above which writes are delayed. It
+	// must be within (0, 1). Defaults to 0.5.
+	SlowdownFraction float64
+	// LowPrioritySlowdownFraction is the pressure above which writes of
+	// WritePriorityLow are delayed. It must not exceed SlowdownFraction.
+	// Defaults to half of SlowdownFraction.
+	LowPrioritySlowdownFraction float64
+	// CompactionDebtLimit is the estimated compaction debt at which the
+	// pressure from compaction debt reaches 1. Defaults to 64 GB.
+	CompactionDebtLimit uint64
+	// MinRate is the rate, in bytes per second, at which writes are admitted
+	// when the pressure is 1. Defaults to 1 MB/s.
+	MinRate int64
+	// InitialFlushRate is the flush throughput, in bytes per second, assumed
+	// until a flush has completed. If zero, writes aren't delayed until then.
+	InitialFlushRate int64
+	// MaxDelay bounds the delay imposed on a single batch. Defaults to
+	// 100ms.
+	MaxDelay time.Duration
+	// AdjustDelay, if set, is called for every write with the delay chosen by
+	// admission control, and returns the delay to impose instead (which is
+	// still bounded by MaxDelay). It allows applications to prioritize classes
+	// of writes: returning a shorter delay for a high-priority write still
+	// consumes the write's tokens, so other writes absorb its delay. It's
+	// called before the write is committed, and must not block.
+	AdjustDelay func(info WriteAdmissionInfo) time.Duration
+}
+
+// WriteAdmissionInfo describes a write being admitted. See
+// WriteAdmissionOptions.AdjustDelay.
+type WriteAdmissionInfo struct {
+	// Batch is the batch being committed. It must not be modified.
+	Batch *Batch
+	// Priority is the priority of the write.
+	Priority WritePriority
+	// Bytes is the number of tokens acquired by the batch.
+	Bytes int
+	// Pressure is the current pressure on the DB. Writes are delayed when it
+	// exceeds WriteAdmissionOptions.SlowdownFraction.
+	Pressure float64
+	// Delay is the delay chosen by admission control.
+	Delay time.Duration
+}
+
+// EnsureDefaults ensures that the default values for all of the options have
+// been initialized. It is valid to call EnsureDefaults on a nil receiver. A
+// non-nil result will always be returned.
+func (o *WriteAdmissionOptions) EnsureDefaults() *WriteAdmissionOptions {
+	if o == nil {
+		o = &WriteAdmissionOptions{}
+	}
+	if o.SlowdownFraction <= 0 {
+		o.SlowdownFraction = 0.5
+	}
+	if o.LowPrioritySlowdownFraction <= 0 {
+		o.LowPrioritySlowdownFraction = o.SlowdownFraction / 2
+	}
+	if o.CompactionDebtLimit == 0 {
+		o.CompactionDebtLimit = 64 << 30 // 64 GB
+	}
+	if o.MinRate <= 0 {
+		o.MinRate = 1 << 20 // 1 MB/s
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = 100 * time.Millisecond
+	}
+	return o
+}
+
+// writeAdmissionSignals holds the state of the DB from which the pressure and
+// admission rate are derived.
+type writeAdmissionSignals struct {
+	// immutableMemTableBytes is the size of the immutable memtables, and
+	// mutableMemTableBytes is the number of bytes reserved in the mutable
+	// memtable. memTableStopBytes is the size of the memtables at which writes
+	// stop, which SetOptions may change.
+	immutableMemTableBytes uint64
+	mutableMemTableBytes   uint64
+	memTableStopBytes      uint64
+	// l0Sublevels and compactionDebt are the fractions of their stop
+	// thresholds reached by the L0 sublevels and compaction debt.
+	l0Sublevels    float64
+	compactionDebt float64
+	// flushRate is the peak flush throughput in bytes per second, or zero if
+	// nothing has been flushed.
+	flushRate int64
+}
+
+// writeAdmissionController issues write tokens according to the pressure on
+// the DB. See WriteAdmissionOptions.
+type writeAdmissionController struct {
+	opts *WriteAdmissionOptions
+	mu   struct {
+		sync.Mutex
+		signals  writeAdmissionSignals
+		pressure float64
+		// buckets holds the token bucket of each WritePriority.
+		buckets [NumWritePriorities]tokenBucket
+	}
+	delayedCount  atomic.Int64
+	delayDuration atomic.Int64
+}
+
+// tokenBucket issues write tokens at a rate.
+type tokenBucket struct {
+	// rate is the rate in bytes per second at which tokens are issued, or
+	// zero if writes are admitted without tokens.
+	rate float64
+	// tokens is the number of available tokens. It's negative when admitted
+	// writes have borrowed tokens that are yet to be issued.
+	tokens     float64
+	lastRefill time.Time
+}
+
+// setRate sets the rate at which tokens are issued.
+func (b *tokenBucket) setRate(rate float64, now time.Time) {
+	if rate > 0 && b.rate == 0 {
+		// Throttling is beginning.
+		b.tokens = 0
+		b.lastRefill = now
+	}
+	b.rate = rate
+}
+
+// acquire acquires n tokens, returning the delay until the tokens are issued.
+// No more tokens are borrowed than can be issued within maxDelay, so that the
+// delays of later writes remain bounded.
+func (b *tokenBucket) acquire(n int, now time.Time, maxDelay time.Duration) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+	b.tokens = min(b.tokens+now.Sub(b.lastRefill).Seconds()*b.rate,
+		b.rate*writeAdmissionBurst.Seconds())
+	b.lastRefill = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.tokens = max(b.tokens, -b.rate*maxDelay.Seconds())
+	return delay
+}
+
+// newWriteAdmissionController returns a writeAdmissionController, or nil if
+// write admission control is disabled.
+func newWriteAdmissionController(opts *Options) *writeAdmissionController {
+	if !opts.Experimental.WriteAdmission.Enabled {
+		return nil
+	}
+	return &writeAdmissionController{opts: &opts.Experimental.WriteAdmission}
+}
+
+// update recomputes the pressure and the admission rates from the state of
+// the DB, other than the bytes reserved in the mutable memtable.
+func (c *writeAdmissionController) update(s writeAdmissionSignals) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.mutableMemTableBytes = c.mu.signals.mutableMemTableBytes
+	c.mu.signals = s
+	c.updateLocked()
+}
+
+// updateMutableMemTable recomputes the pressure and the admission rates once
+// writes have reserved the provided number of bytes in the mutable memtable.
+func (c *writeAdmissionController) updateMutableMemTable(reserved uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mu.signals.mutableMemTableBytes = reserved
+	c.updateLocked()
+}
+
+// updateLocked recomputes the pressure and the admission rates from
+// c.mu.signals. c.mu must be held.
+func (c *writeAdmissionController) updateLocked() {
+	s := &c.mu.signals
+	var memTables float64
+	if s.memTableStopBytes > 0 {
+		memTables = float64(s.immutableMemTableBytes+s.mutableMemTableBytes) / float64(s.memTableStopBytes)
+	}
+	pressure := max(memTables, s.l0Sublevels, s.compactionDebt)
+	flushRate := s.flushRate
+	if flushRate == 0 {
+		flushRate = c.opts.InitialFlushRate
+	}
+	// rate returns the admission rate of writes that are throttled from the
+	// provided slowdown fraction.
+	rate := func(slowdownFraction float64) float64 {
+		// throttle is the extent of the throttling, from 0 at the slowdown
+		// fraction to 1 at the stop thresholds.
+		throttle := (pressure - slowdownFraction) / (1 - slowdownFraction)
+		if throttle <= 0 || flushRate == 0 {
+			return 0
+		}
+		return max(float64(c.opts.MinRate), float64(flushRate)*(1-min(throttle, 1)))
+	}
+
+	now := time.Now()
+	c.mu.pressure = pressure
+	c.mu.buckets[WritePriorityNormal].setRate(rate(c.opts.SlowdownFraction), now)
+	c.mu.buckets[WritePriorityLow].setRate(rate(c.opts.LowPrioritySlowdownFraction), now)
+}
+
+// admit acquires tokens for the batch's bytes, and waits for the delay
+// required to issue them. It returns the delay.
+func (c *writeAdmissionController) admit(b *Batch, priority WritePriority) time.Duration {
+	bytes := len(b.data)
+	c.mu.Lock()
+	delay := c.mu.buckets[priority].acquire(bytes, time.Now(), c.opts.MaxDelay)
+	pressure := c.mu.pressure
+	c.mu.Unlock()
+
+	if c.opts.AdjustDelay != nil {
+		delay = c.opts.AdjustDelay(WriteAdmissionInfo{
+			Batch:    b,
+			Priority: priority,
+			Bytes:    bytes,
+			Pressure: pressure,
+			Delay:    delay,
+		})
+	}
+	delay = min(delay, c.opts.MaxDelay)
+	if delay <= 0 {
+		return 0
+	}
+	time.Sleep(delay)
+	c.delayedCount.Add(1)
+	c.delayDuration.Add(int64(delay))
+	return delay
+}
+
+// metrics populates the write admission metrics.
+func (c *writeAdmissionController) metrics(m *Metrics) {
+	c.mu.Lock()
+	m.WriteAdmission.Pressure = c.mu.pressure
+	m.WriteAdmission.Rate = int64(c.mu.buckets[WritePriorityNormal].rate)
+	c.mu.Unlock()
+	m.WriteAdmission.DelayedCount = c.delayedCount.Load()
+	m.WriteAdmission.DelayDuration = time.Duration(c.delayDuration.Load())
+}
+
+// updateWriteAdmissionLocked updates the write admission controller with the
+// current state of the DB. The bytes reserved in the mutable memtable are
+// reported separately, as they're reserved without holding DB.mu. DB.mu must
+// be held.
+func (d *DB) updateWriteAdmissionLocked() {
+	var immutableSize uint64
+	for _, m := range d.mu.mem.queue[:len(d.mu.mem.queue)-1] {
+		immutableSize += m.totalBytes()
+	}
+	s := writeAdmissionSignals{
+		immutableMemTableBytes: immutableSize,
+		memTableStopBytes:      uint64(d.opts.MemTableStopWritesThreshold) * d.opts.MemTableSize,
+		flushRate:              d.mu.compact.flushWriteThroughput.PeakRate(),
+	}
+	// Writes don't stall on the L0 read amplification under
+	// CompactionStyleFIFO (see DB.maybeInduceWriteStall), so it isn't a
+	// signal either.
+	if d.opts.Experimental.CompactionStyle != CompactionStyleFIFO {
+		s.l0Sublevels = float64(d.mu.versions.currentVersion().L0Sublevels.ReadAmplification()) /
+			float64(d.opts.L0StopWritesThreshold)
+	}
+	if p := d.mu.versions.picker; p != nil {
+		s.compactionDebt = float64(p.estimatedCompactionDebt(0)) /
+			float64(d.opts.Experimental.WriteAdmission.CompactionDebtLimit)
+	}
+	d.writeAdmission.update(s)
+}
@@ -0,0 +1,273 @@
+// Copyright 2024 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAdmissionController(t *testing.T) {
+	opts := &Options{}
+	opts.Experimental.WriteAdmission = WriteAdmissionOptions{
+		Enabled:  true,
+		MinRate:  1 << 20,
+		MaxDelay: 20 * time.Millisecond,
+	}
+	opts.EnsureDefaults()
+	c := newWriteAdmissionController(opts)
+	newBatchOfSize := func(n int) *Batch {
+		b := newBatch(nil)
+		require.NoError(t, b.Set(bytes.Repeat([]byte("k"), n), nil, nil))
+		return b
+	}
+
+	// Writes aren't delayed below the slowdown threshold.
+	c.update(writeAdmissionSignals{
+		immutableMemTableBytes: 32 << 20,
+		memTableStopBytes:      64 << 20,
+		l0Sublevels:            0.2,
+		flushRate:              64 << 20,
+	})
+	require.Zero(t, c.admit(newBatchOfSize(10<<20), WritePriorityNormal))
+
+	// Midway to the stop threshold, tokens are issued at half the flush rate.
+	c.update(writeAdmissionSignals{l0Sublevels: 0.75, flushRate: 64 << 20})
+	c.mu.Lock()
+	require.Equal(t, float64(32<<20), c.mu.buckets[WritePriorityNormal].rate)
+	c.mu.Unlock()
+
+	// At the stop threshold, tokens are issued at MinRate. A 15 KB write
+	// borrows at least 5ms of tokens, even if the 10ms burst of tokens has
+	// accumulated.
+	c.update(writeAdmissionSignals{compactionDebt: 1.2, flushRate: 64 << 20})
+	delay := c.admit(newBatchOfSize(15<<10), WritePriorityNormal)
+	require.Greater(t, delay, 4*time.Millisecond)
+	require.LessOrEqual(t, delay, 20*time.Millisecond)
+
+	// A write that's delayed more than MaxDelay is admitted after MaxDelay.
+	require.Equal(t, 20*time.Millisecond, c.admit(newBatchOfSize(1<<20), WritePriorityNormal))
+
+	// AdjustDelay may exempt a write from its delay.
+	var infos []WriteAdmissionInfo
+	c.opts.AdjustDelay = func(info WriteAdmissionInfo) time.Duration {
+		infos = append(infos, info)
+		return 0
+	}
+	require.Zero(t, c.admit(newBatchOfSize(64<<10), WritePriorityNormal))
+	require.Len(t, infos, 1)
+	require.Equal(t, 1.2, infos[0].Pressure)
+	require.Greater(t, infos[0].Delay, time.Duration(0))
+
+	m := &Metrics{}
+	c.metrics(m)
+	require.Equal(t, int64(2), m.WriteAdmission.DelayedCount)
+	require.Equal(t, int64(1<<20), m.WriteAdmission.Rate)
+}
+
+func TestWriteAdmissionSignals(t *testing.T) {
+	opts := &Options{MemTableSize: 4 << 20, MemTableStopWritesThreshold: 4}
+	opts.Experimental.WriteAdmission = WriteAdmissionOptions{Enabled: true}
+	opts.EnsureDefaults()
+	c := newWriteAdmissionController(opts)
+	rate := func() float64 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.mu.buckets[WritePriorityNormal].rate
+	}
+
+	// Until a flush throughput has been measured, writes aren't delayed.
+	c.update(writeAdmissionSignals{l0Sublevels: 0.75})
+	require.Zero(t, rate())
+	c.opts.InitialFlushRate = 64 << 20
+	c.update(writeAdmissionSignals{l0Sublevels: 0.75})
+	require.Equal(t, float64(32<<20), rate())
+
+	// Reservations in the mutable memtable add to the pressure of the
+	// immutable memtables, and are retained across other updates.
+	signals := writeAdmissionSignals{
+		immutableMemTableBytes: 4 << 20,
+		memTableStopBytes:      16 << 20,
+		flushRate:              64 << 20,
+	}
+	c.update(signals)
+	require.Zero(t, rate())
+	c.updateMutableMemTable(8 << 20)
+	require.Equal(t, float64(32<<20), rate())
+	c.update(signals)
+	require.Equal(t, float64(32<<20), rate())
+	c.updateMutableMemTable(0)
+	require.Zero(t, rate())
+}
+
+func TestWriteAdmissionSetOptions(t *testing.T) {
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		MemTableSize:                8 << 20,
+		MemTableStopWritesThreshold: 4,
+	}
+	opts.Experimental.WriteAdmission = WriteAdmissionOptions{Enabled: true}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.NoError(t, d.Set([]byte("a"), make([]byte, 64<<10), nil))
+	before := d.Metrics().WriteAdmission.Pressure
+	require.Greater(t, before, 0.0)
+
+	// Halving the memtable stop threshold doubles the pressure of the same
+	// memtable reservations.
+	o := d.DynamicOptions()
+	o.MemTableStopWritesThreshold = 2
+	require.NoError(t, d.SetOptions(o))
+	require.InDelta(t, 2*before, d.Metrics().WriteAdmission.Pressure, 1e-9)
+}
+
+func TestWriteAdmission(t *testing.T) {
+	var pressures []float64
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		DisableAutomaticCompactions: true,
+		L0CompactionThreshold:       2,
+		L0StopWritesThreshold:       4,
+	}
+	opts.Experimental.WriteAdmission = WriteAdmissionOptions{
+		Enabled: true,
+		AdjustDelay: func(info WriteAdmissionInfo) time.Duration {
+			pressures = append(pressures, info.Pressure)
+			// The tokens accumulated while idle may admit the write without
+			// delay. Delay it regardless.
+			if info.Pressure > 0.5 {
+				return max(info.Delay, time.Millisecond)
+			}
+			return info.Delay
+		},
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Build up L0 sublevels until the pressure exceeds the slowdown threshold
+	// of 0.5.
+	for i := 0; i < 3; i++ {
+		require.Zero(t, d.Metrics().WriteAdmission.Rate)
+		require.NoError(t, d.Set([]byte("a"), []byte("b"), nil))
+		require.NoError(t, d.Flush())
+	}
+	m := d.Metrics()
+	require.Equal(t, 0.75, m.WriteAdmission.Pressure)
+	require.Greater(t, m.WriteAdmission.Rate, int64(0))
+
+	b := d.NewBatch()
+	require.NoError(t, b.Set([]byte("a"), []byte("c"), nil))
+	require.NoError(t, b.Commit(nil))
+	require.GreaterOrEqual(t, b.CommitStats().AdmissionDelayDuration, time.Millisecond)
+	require.NoError(t, b.Close())
+	require.Equal(t, 0.75, pressures[len(pressures)-1])
+	require.Equal(t, int64(1), d.Metrics().WriteAdmission.DelayedCount)
+
+	// Compacting L0 relieves the pressure.
+	require.NoError(t, d.Compact([]byte("a"), []byte("b"), false))
+	m = d.Metrics()
+	require.Less(t, m.WriteAdmission.Pressure, 0.5)
+	require.Zero(t, m.WriteAdmission.Rate)
+}
+
+func TestWriteAdmissionPriorities(t *testing.T) {
+	opts := &Options{}
+	opts.Experimental.WriteAdmission = WriteAdmissionOptions{
+		Enabled:  true,
+		MinRate:  1 << 20,
+		MaxDelay: 20 * time.Millisecond,
+	}
+	opts.EnsureDefaults()
+	require.Equal(t, 0.25, opts.Experimental.WriteAdmission.LowPrioritySlowdownFraction)
+	c := newWriteAdmissionController(opts)
+	batch := newBatch(nil)
+	require.NoError(t, batch.Set(bytes.Repeat([]byte("k"), 4<<20), nil, nil))
+
+	// Between the slowdown fractions, only low-priority writes are delayed.
+	c.update(writeAdmissionSignals{l0Sublevels: 0.4, flushRate: 64 << 20})
+	require.Zero(t, c.admit(batch, WritePriorityNormal))
+	require.Zero(t, c.admit(batch, WritePriorityHigh))
+	require.Equal(t, 20*time.Millisecond, c.admit(batch, WritePriorityLow))
+
+	// High-priority writes are never delayed, and don't consume the tokens of
+	// other priorities.
+	c.update(writeAdmissionSignals{l0Sublevels: 1, flushRate: 64 << 20})
+	require.Zero(t, c.admit(batch, WritePriorityHigh))
+	c.mu.Lock()
+	require.Zero(t, c.mu.buckets[WritePriorityNormal].tokens)
+	require.Zero(t, c.mu.buckets[WritePriorityHigh].rate)
+	c.mu.Unlock()
+	require.Equal(t, 20*time.Millisecond, c.admit(batch, WritePriorityNormal))
+}
+
+func TestWritePriority(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// While the DB is stalled, low-priority writes wait for the stall to end
+	// before committing, while writes of other priorities proceed.
+	d.writeStalled.Store(true)
+	low := d.NewBatch()
+	require.NoError(t, low.Set([]byte("a"), []byte("low"), nil))
+	done := make(chan error)
+	go func() { done <- low.Commit(&WriteOptions{Priority: WritePriorityLow}) }()
+	require.NoError(t, d.Set([]byte("b"), []byte("normal"), nil))
+	require.NoError(t, d.Set([]byte("c"), []byte("high"), &WriteOptions{Priority: WritePriorityHigh}))
+	select {
+	case err := <-done:
+		t.Fatalf("low-priority write committed during stall: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	d.mu.Lock()
+	d.writeStalled.Store(false)
+	d.mu.compact.cond.Broadcast()
+	d.mu.Unlock()
+	require.NoError(t, <-done)
+	require.GreaterOrEqual(t, low.CommitStats().PriorityWaitDuration, 10*time.Millisecond)
+	require.NoError(t, low.Close())
+
+	m := d.Metrics()
+	for _, p := range []WritePriority{WritePriorityNormal, WritePriorityLow, WritePriorityHigh} {
+		require.Equal(t, int64(1), m.Writes[p].Count, p)
+		require.Greater(t, m.Writes[p].Bytes, int64(0), p)
+	}
+	require.GreaterOrEqual(t, m.Writes[WritePriorityLow].StallDuration, 10*time.Millisecond)
+	require.Zero(t, m.Writes[WritePriorityNormal].StallDuration)
+
+	// Writes with an invalid priority are rejected without being applied.
+	for _, p := range []WritePriority{-1, NumWritePriorities} {
+		b := d.NewBatch()
+		require.NoError(t, b.Set([]byte("d"), []byte("invalid"), nil))
+		require.Error(t, b.Commit(&WriteOptions{Priority: p}))
+		require.NoError(t, b.Close())
+		require.Error(t, d.Set([]byte("d"), []byte("invalid"), &WriteOptions{Priority: p}))
+	}
+	_, _, err = d.Get([]byte("d"))
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestWriteAdmissionPriorityOptions(t *testing.T) {
+	opts := &Options{}
+	opts.Experimental.WriteAdmission = WriteAdmissionOptions{
+		Enabled:                     true,
+		LowPrioritySlowdownFraction: 0.1,
+	}
+	opts.EnsureDefaults()
+	require.Contains(t, opts.String(), "write_admission_low_priority_slowdown_fraction=0.1")
+
+	var parsed Options
+	require.NoError(t, parsed.Parse(opts.String(), nil))
+	require.Equal(t, 0.1, parsed.Experimental.WriteAdmission.LowPrioritySlowdownFraction)
+
+	opts.Experimental.WriteAdmission.LowPrioritySlowdownFraction = 0.6
+	require.Error(t, opts.Validate())
+}